@@ -0,0 +1,138 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// CaretAnnotationDef defines a caret annotation, indicating the insertion of text, in the box with
+// the lower left corner at (X,Y). When Paragraph is set, a paragraph symbol is displayed with the
+// caret to indicate the insertion of a new paragraph.
+type CaretAnnotationDef struct {
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+	Color     *pdf.PdfColorDeviceRGB
+	Paragraph bool
+	Contents  string
+	Author    string
+	Opacity   float64 // Alpha value (0-1).
+}
+
+// CreateCaretAnnotation creates a caret annotation object with appearance stream that can be added
+// to page PDF annotations.
+func CreateCaretAnnotation(caretDef CaretAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if caretDef.Width <= 0 || caretDef.Height <= 0 {
+		return nil, errors.New("invalid caret annotation size")
+	}
+	color := caretDef.Color
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(0, 0, 1)
+	}
+
+	annot := pdf.NewPdfAnnotationCaret()
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		caretDef.X, caretDef.Y, caretDef.X + caretDef.Width, caretDef.Y + caretDef.Height,
+	})
+	annot.C = makeColorArray(color)
+	if caretDef.Paragraph {
+		annot.Sy = pdfcore.MakeName("P")
+	} else {
+		annot.Sy = pdfcore.MakeName("None")
+	}
+	if caretDef.Contents != "" {
+		annot.Contents = pdfcore.MakeString(caretDef.Contents)
+	}
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, caretDef.Author, "", caretDef.Opacity)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// makeCaretAnnotationAppearanceStream generates the appearance stream of a caret annotation: a
+// filled caret in the rectangle of the annotation reduced by the rectangle differences (RD).
+func makeCaretAnnotationAppearanceStream(annot *pdf.PdfAnnotationCaret) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	rect, err := annotationRect(annot.PdfAnnotation)
+	if err != nil {
+		return nil, nil, err
+	}
+	box := *rect
+	if arr, ok := pdfcore.GetArray(annot.RD); ok {
+		if rd, err := arr.ToFloat64Array(); err == nil && len(rd) == 4 {
+			box.Llx += rd[0]
+			box.Lly += rd[1]
+			box.Urx -= rd[2]
+			box.Ury -= rd[3]
+		}
+	}
+	if box.Width() <= 0 || box.Height() <= 0 {
+		return nil, nil, errors.New("invalid caret annotation size")
+	}
+
+	color := annotationColor(annot.C)
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(0, 0, 1)
+	}
+	sy, _ := pdfcore.GetNameVal(annot.Sy)
+
+	resources := pdf.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(annot.PdfAnnotationMarkup), ""); err != nil {
+		return nil, nil, err
+	}
+	cc.SetNonStrokingColor(color)
+
+	caret := box
+	if sy == "P" {
+		// The caret takes the left half of the box, the paragraph symbol the right half.
+		caret.Urx = box.Llx + box.Width()/2
+	}
+
+	// Caret with concave sides.
+	w, h := caret.Width(), caret.Height()
+	cx := caret.Llx + w/2
+	cc.Add_m(caret.Llx, caret.Lly).
+		Add_c(cx-w/6, caret.Lly+h/6, cx, caret.Lly+h/2, cx, caret.Ury).
+		Add_c(cx, caret.Lly+h/2, cx+w/6, caret.Lly+h/6, caret.Urx, caret.Lly).
+		Add_h().Add_f()
+
+	if sy == "P" {
+		// Paragraph symbol: a bowl and two stems.
+		x := caret.Urx + w/8
+		pw := box.Urx - x
+		stem := pw / 8
+		bowl := h / 2
+		cc.Add_m(x+pw/2, caret.Ury-bowl).
+			Add_c(x, caret.Ury-bowl, x, caret.Ury, x+pw/2, caret.Ury).
+			Add_l(box.Urx, caret.Ury).
+			Add_l(box.Urx, caret.Ury-stem).
+			Add_l(box.Urx-stem, caret.Ury-stem).
+			Add_l(box.Urx-stem, caret.Lly).
+			Add_l(box.Urx-2*stem, caret.Lly).
+			Add_l(box.Urx-2*stem, caret.Ury-stem).
+			Add_l(x+pw/2+stem, caret.Ury-stem).
+			Add_l(x+pw/2+stem, caret.Lly).
+			Add_l(x+pw/2, caret.Lly).
+			Add_h().Add_f()
+	}
+	cc.Add_Q()
+
+	apDict, err := makeAppearanceDict(cc.Bytes(), rect, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, rect, nil
+}
@@ -182,6 +182,26 @@ func (fa FieldAppearance) GenerateAppearanceDict(form *model.PdfAcroForm, field
 			}
 			return appDict, nil
 		}
+		if fbtn.IsRadio() {
+			// The on state of the button is the name of its existing appearance.
+			onState := widgetOnState(wa)
+			if onState == "" {
+				common.Log.Debug("WARN: on state of radio button %s not found", field.PartialName())
+				return appDict, nil
+			}
+			appDict, err := genFieldRadioButtonAppearance(wa, onState, fa.Style())
+			if err != nil {
+				return nil, err
+			}
+			return appDict, nil
+		}
+		if fbtn.IsPush() {
+			appDict, err := genFieldPushButtonAppearance(form, field, wa, fa.Style())
+			if err != nil {
+				return nil, err
+			}
+			return appDict, nil
+		}
 
 		common.Log.Debug("TODO: UNHANDLED button type: %+v", fbtn.GetType())
 	case *model.PdfFieldChoice:
@@ -194,7 +214,11 @@ func (fa FieldAppearance) GenerateAppearanceDict(form *model.PdfAcroForm, field
 			}
 			return appDict, nil
 		default:
-			common.Log.Debug("TODO: UNHANDLED choice field with flags: %s", fch.Flags().String())
+			appDict, err := genFieldListboxAppearance(form, wa, fch, fa.Style())
+			if err != nil {
+				return nil, err
+			}
+			return appDict, nil
 		}
 
 	default:
@@ -285,10 +309,8 @@ func genFieldTextAppearance(wa *model.PdfAnnotationWidget, ftxt *model.PdfFieldT
 		common.Log.Debug("Error: Unable to get font descriptor")
 	}
 
-	var text string
-	if str, ok := core.GetString(ftxt.V); ok {
-		text = str.Decoded()
-	}
+	// The value as formatted by the format action of the field, if any.
+	text := ftxt.FormattedValue()
 
 	// If no text, no appearance needed.
 	if len(text) == 0 {
@@ -565,10 +587,8 @@ func genFieldTextCombAppearance(wa *model.PdfAnnotationWidget, ftxt *model.PdfFi
 		encoder = textencoding.NewIdentityTextEncoder("Identity-H")
 	}
 
-	var text string
-	if str, ok := core.GetString(ftxt.V); ok {
-		text = str.Decoded()
-	}
+	// The value as formatted by the format action of the field, if any.
+	text := ftxt.FormattedValue()
 
 	cc.Add_Tf(*fontname, fontsize)
 
@@ -961,6 +981,421 @@ func makeComboboxTextXObjForm(field *model.PdfField, width, height float64,
 	return xform, nil
 }
 
+// genFieldRadioButtonAppearance generates an appearance dictionary for a widget annotation `wa`
+// of a radio button group, with the on state `onState`.
+func genFieldRadioButtonAppearance(wa *model.PdfAnnotationWidget, onState string, style AppearanceStyle) (*core.PdfObjectDictionary, error) {
+	// Get bounding Rect.
+	array, ok := core.GetArray(wa.Rect)
+	if !ok {
+		return nil, errors.New("invalid Rect")
+	}
+	rect, err := model.NewPdfRectangle(*array)
+	if err != nil {
+		return nil, err
+	}
+	width, height := rect.Width(), rect.Height()
+
+	mkDict, has := core.GetDict(wa.MK)
+	if has {
+		bsDict, _ := core.GetDict(wa.BS)
+		err := style.applyAppearanceCharacteristics(mkDict, bsDict, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	makeXObjForm := func(on bool) *model.XObjectForm {
+		cc := contentstream.NewContentCreator()
+		w, h := style.applyRotation(mkDict, width, height, cc)
+		r := math.Min(w, h) / 2
+		if style.BorderSize > 0 {
+			cc.Add_q().
+				Add_w(style.BorderSize).
+				SetStrokingColor(style.BorderColor).
+				SetNonStrokingColor(style.FillColor)
+			drawCircle(cc, w/2, h/2, r-style.BorderSize/2)
+			cc.Add_B().Add_Q()
+			r -= style.BorderSize
+		}
+		if on {
+			cc.Add_q().Add_g(0)
+			drawCircle(cc, w/2, h/2, r/2)
+			cc.Add_f().Add_Q()
+		}
+
+		xform := model.NewXObjectForm()
+		xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, width, height})
+		xform.SetContentStream(cc.Bytes(), defStreamEncoder())
+		return xform
+	}
+
+	dchoiceapp := core.MakeDict()
+	dchoiceapp.Set("Off", makeXObjForm(false).ToPdfObject())
+	dchoiceapp.Set(*core.MakeName(onState), makeXObjForm(true).ToPdfObject())
+
+	appDict := core.MakeDict()
+	appDict.Set("N", dchoiceapp)
+
+	return appDict, nil
+}
+
+// widgetOnState returns the name of the on state of the button widget annotation `wa`: the name
+// of its normal appearance other than Off. Returns an empty string if not found.
+func widgetOnState(wa *model.PdfAnnotationWidget) string {
+	apDict, ok := core.GetDict(wa.AP)
+	if !ok {
+		return ""
+	}
+	nDict, ok := core.GetDict(apDict.Get("N"))
+	if !ok {
+		return ""
+	}
+	for _, key := range nDict.Keys() {
+		if key != "Off" {
+			return key.String()
+		}
+	}
+	return ""
+}
+
+// genFieldListboxAppearance generates an appearance dictionary for a widget annotation `wa`
+// referenced by a list box choice field `fch` with form resources (DR) in `form`. The choices
+// which fit in the widget are shown, starting from the top index (TI) of the field, which is
+// updated to show the first selected choice if needed.
+func genFieldListboxAppearance(form *model.PdfAcroForm, wa *model.PdfAnnotationWidget, fch *model.PdfFieldChoice, style AppearanceStyle) (*core.PdfObjectDictionary, error) {
+	// Get bounding Rect.
+	array, ok := core.GetArray(wa.Rect)
+	if !ok {
+		return nil, errors.New("invalid Rect")
+	}
+	rect, err := model.NewPdfRectangle(*array)
+	if err != nil {
+		return nil, err
+	}
+	width, height := rect.Width(), rect.Height()
+	bboxWidth, bboxHeight := width, height
+
+	// Get and process the default appearance string (DA) operands.
+	daOps, err := contentstream.NewContentStreamParser(getDA(fch.PdfField)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	mkDict, has := core.GetDict(wa.MK)
+	if has {
+		bsDict, _ := core.GetDict(wa.BS)
+		err := style.applyAppearanceCharacteristics(mkDict, bsDict, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Options as (export value, displayed text) pairs.
+	type option struct {
+		export, text string
+	}
+	var options []option
+	if fch.Opt != nil {
+		for _, optObj := range fch.Opt.Elements() {
+			exportObj, textObj := optObj, optObj
+			if optArr, ok := core.GetArray(optObj); ok && optArr.Len() == 2 {
+				exportObj, textObj = optArr.Get(0), optArr.Get(1)
+			}
+			options = append(options, option{export: choiceText(exportObj), text: choiceText(textObj)})
+		}
+	}
+
+	// Selected options.
+	selected := map[string]bool{}
+	switch v := core.TraceToDirectObject(fch.V).(type) {
+	case *core.PdfObjectArray:
+		for _, obj := range v.Elements() {
+			selected[choiceText(obj)] = true
+		}
+	case nil:
+	default:
+		selected[choiceText(v)] = true
+	}
+
+	resources := model.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	if style.BorderSize > 0 {
+		drawRect(cc, style, width, height)
+	}
+	if style.DrawAlignmentReticle {
+		// Alignment reticle.
+		style2 := style
+		style2.BorderSize = 0.2
+		drawAlignmentReticle(cc, style2, width, height)
+	}
+	cc.Add_BMC("Tx")
+	cc.Add_q()
+
+	// Apply rotation if present.
+	width, height = style.applyRotation(mkDict, width, height, cc)
+
+	// The DA operands are added after the selection highlights, which depend on the font size.
+	daCC := contentstream.NewContentCreator()
+	apFont, _, err := style.processDA(fch.PdfField, daOps, form.DR, resources, daCC)
+	if err != nil {
+		return nil, err
+	}
+	font := apFont.Font
+	fontsize := apFont.Size
+	if fontsize == 0 {
+		fontsize = 12
+	}
+	lineheight := fontsize * style.MultilineLineHeight
+
+	// Clip to the inside of the border.
+	margin := style.BorderSize
+	cc.Add_re(margin, margin, width-2*margin, height-2*margin).Add_W().Add_n()
+
+	// Scroll to show the first selected option.
+	visible := int((height - 2*margin - 2) / lineheight)
+	if visible < 1 {
+		visible = 1
+	}
+	top := 0
+	if fch.TI != nil {
+		top = int(*fch.TI)
+	}
+	var indices []int
+	for i, opt := range options {
+		if selected[opt.export] {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) > 0 && (indices[0] < top || indices[0] >= top+visible) {
+		top = indices[0]
+	}
+	if top > len(options)-visible {
+		top = len(options) - visible
+	}
+	if top < 0 {
+		top = 0
+	}
+	if top > 0 {
+		fch.TI = core.MakeInteger(int64(top))
+	} else {
+		fch.TI = nil
+	}
+	if fch.Flags().Has(model.FieldFlagMultiSelect) && len(indices) > 0 {
+		fch.I = core.MakeArray()
+		for _, i := range indices {
+			fch.I.Append(core.MakeInteger(int64(i)))
+		}
+	}
+
+	// Selection highlights.
+	ytop := height - margin - 1
+	for i := top; i < len(options) && i < top+visible+1; i++ {
+		if !selected[options[i].export] {
+			continue
+		}
+		y := ytop - float64(i-top+1)*lineheight
+		cc.Add_q().
+			Add_rg(0.6, 0.75, 0.86).
+			Add_re(margin, y, width-2*margin, lineheight).
+			Add_f().
+			Add_Q()
+	}
+
+	encoder := font.Encoder()
+	if encoder == nil {
+		common.Log.Debug("WARN: font encoder is nil. Assuming identity encoder. Output may be incorrect.")
+		encoder = textencoding.NewIdentityTextEncoder("Identity-H")
+	}
+
+	// Black text, unless specified by the DA.
+	cc.Add_BT().Add_g(0)
+	for _, op := range *daCC.Operations() {
+		cc.AddOperand(*op)
+	}
+	cc.Add_Tf(*core.MakeName(apFont.Name), fontsize)
+	tx := margin + 2.0
+	for i := top; i < len(options) && i < top+visible+1; i++ {
+		y := ytop - float64(i-top+1)*lineheight + (lineheight-fontsize)/2 + 0.22*fontsize
+		cc.Add_Tm(1, 0, 0, 1, tx, y)
+		cc.Add_Tj(*core.MakeStringFromBytes(encoder.Encode(options[i].text)))
+	}
+	cc.Add_ET()
+	cc.Add_Q()
+	cc.Add_EMC()
+
+	xform := model.NewXObjectForm()
+	xform.Resources = resources
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, bboxWidth, bboxHeight})
+	xform.SetContentStream(cc.Bytes(), defStreamEncoder())
+
+	appDict := core.MakeDict()
+	appDict.Set("N", xform.ToPdfObject())
+
+	return appDict, nil
+}
+
+// choiceText returns the text of the choice field option or value `obj`.
+func choiceText(obj core.PdfObject) string {
+	if str, ok := core.GetString(obj); ok {
+		return str.Decoded()
+	}
+	if name, ok := core.GetName(obj); ok {
+		return name.String()
+	}
+	return ""
+}
+
+// genFieldPushButtonAppearance generates an appearance dictionary for a widget annotation `wa`
+// of the push button field `field`, with form resources (DR) in `form`. The appearance shows the
+// caption (CA) and the icon (I) of the appearance characteristics (MK) of the widget, laid out as
+// specified by the text position (TP).
+func genFieldPushButtonAppearance(form *model.PdfAcroForm, field *model.PdfField, wa *model.PdfAnnotationWidget, style AppearanceStyle) (*core.PdfObjectDictionary, error) {
+	// Get bounding Rect.
+	array, ok := core.GetArray(wa.Rect)
+	if !ok {
+		return nil, errors.New("invalid Rect")
+	}
+	rect, err := model.NewPdfRectangle(*array)
+	if err != nil {
+		return nil, err
+	}
+	width, height := rect.Width(), rect.Height()
+	bboxWidth, bboxHeight := width, height
+
+	var caption string
+	var icon *model.XObjectForm
+	textPosition := int64(0)
+	mkDict, has := core.GetDict(wa.MK)
+	if has {
+		bsDict, _ := core.GetDict(wa.BS)
+		err := style.applyAppearanceCharacteristics(mkDict, bsDict, nil)
+		if err != nil {
+			return nil, err
+		}
+		if ca, ok := core.GetString(mkDict.Get("CA")); ok {
+			caption = ca.Decoded()
+		}
+		if stream, ok := core.GetStream(mkDict.Get("I")); ok {
+			icon, err = model.NewXObjectFormFromStream(stream)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if tp, ok := core.GetIntVal(mkDict.Get("TP")); ok {
+			textPosition = int64(tp)
+		}
+	}
+	if textPosition == 1 {
+		caption = ""
+	} else if textPosition == 0 {
+		icon = nil
+	}
+
+	resources := model.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	if style.BorderSize > 0 {
+		drawRect(cc, style, width, height)
+	} else if has && mkDict.Get("BG") != nil {
+		cc.Add_q().
+			Add_re(0, 0, width, height).
+			SetNonStrokingColor(style.FillColor).
+			Add_f().
+			Add_Q()
+	}
+
+	cc.Add_q()
+	width, height = style.applyRotation(mkDict, width, height, cc)
+	margin := style.BorderSize + 2
+
+	// Caption font. The text is placed at the bottom of the button when below the icon.
+	var apFont *AppearanceFont
+	var fontsize, textWidth float64
+	if caption != "" {
+		apFont, _, err = style.processDA(field, nil, form.DR, resources, cc)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range caption {
+			if metrics, ok := apFont.Font.GetRuneMetrics(r); ok {
+				textWidth += metrics.Wx
+			}
+		}
+		fontsize = apFont.Size
+		if fontsize == 0 {
+			fontsize = math.Min(12, (height-2*margin)*style.AutoFontSizeFraction)
+			if icon != nil {
+				fontsize = math.Min(fontsize, (height-2*margin)/3)
+			}
+			if textWidth > 0 && textWidth*fontsize/1000 > width-2*margin {
+				fontsize = 1000 * (width - 2*margin) / textWidth
+			}
+		}
+		textWidth *= fontsize / 1000
+	}
+
+	iconArea := [4]float64{margin, margin, width - margin, height - margin}
+	if icon != nil && caption != "" {
+		iconArea[1] += fontsize * 1.2
+	}
+
+	if icon != nil {
+		bboxArr, ok := core.GetArray(icon.BBox)
+		if !ok {
+			return nil, errors.New("invalid icon BBox")
+		}
+		iconBBox, err := bboxArr.ToFloat64Array()
+		if err != nil || len(iconBBox) != 4 {
+			return nil, errors.New("invalid icon BBox")
+		}
+		iw, ih := iconBBox[2]-iconBBox[0], iconBBox[3]-iconBBox[1]
+		aw, ah := iconArea[2]-iconArea[0], iconArea[3]-iconArea[1]
+		if iw > 0 && ih > 0 && aw > 0 && ah > 0 {
+			scale := math.Min(aw/iw, ah/ih)
+			tx := iconArea[0] + (aw-iw*scale)/2 - iconBBox[0]*scale
+			ty := iconArea[1] + (ah-ih*scale)/2 - iconBBox[1]*scale
+			if err := resources.SetXObjectFormByName("Icon", icon); err != nil {
+				return nil, err
+			}
+			cc.Add_q().
+				Add_cm(scale, 0, 0, scale, tx, ty).
+				Add_Do("Icon").
+				Add_Q()
+		}
+	}
+
+	if caption != "" {
+		ty := (height - fontsize) / 2
+		if icon != nil {
+			ty = margin
+		}
+		ty += 0.22 * fontsize
+		tx := (width - textWidth) / 2
+
+		encoder := apFont.Font.Encoder()
+		if encoder == nil {
+			common.Log.Debug("WARN: font encoder is nil. Assuming identity encoder. Output may be incorrect.")
+			encoder = textencoding.NewIdentityTextEncoder("Identity-H")
+		}
+		cc.Add_BT().
+			Add_g(0).
+			Add_Tf(*core.MakeName(apFont.Name), fontsize).
+			Add_Td(tx, ty).
+			Add_Tj(*core.MakeStringFromBytes(encoder.Encode(caption))).
+			Add_ET()
+	}
+	cc.Add_Q()
+
+	xform := model.NewXObjectForm()
+	xform.Resources = resources
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, bboxWidth, bboxHeight})
+	xform.SetContentStream(cc.Bytes(), defStreamEncoder())
+
+	appDict := core.MakeDict()
+	appDict.Set("N", xform.ToPdfObject())
+
+	return appDict, nil
+}
+
 // getDA returns the default appearance text (DA) for a given field `ftxt`.
 // If not set for `ftxt` then checks if set by Parent (inherited), otherwise
 // returns "".
@@ -206,6 +206,328 @@ func NewComboboxField(page *model.PdfPage, name string, rect []float64, opt Comb
 	return chfield, nil
 }
 
+// RadioButtonOption defines a button of a radio button group.
+type RadioButtonOption struct {
+	// Rect is the location of the button on the page.
+	Rect []float64
+
+	// ExportValue is the value of the group when the button is selected, used as name of the
+	// on state of the button.
+	ExportValue string
+}
+
+// RadioGroupFieldOptions defines the parameters of a radio button group form field.
+type RadioGroupFieldOptions struct {
+	// Buttons are the buttons of the group. Buttons with the same export value are turned on and
+	// off together.
+	Buttons []RadioButtonOption
+
+	// Value is the export value of the selected button. No button is selected if empty ("").
+	Value string
+
+	// NoToggleToOff indicates that one button must be selected at all times: clicking the
+	// selected button does not turn it off.
+	NoToggleToOff bool
+}
+
+// NewRadioGroupField generates a new radio button group field with partial name `name` on
+// specified `page` and with field specific options `opt`. Each button is a widget annotation of
+// the field.
+func NewRadioGroupField(page *model.PdfPage, name string, opt RadioGroupFieldOptions) (*model.PdfFieldButton, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	if len(name) <= 0 || len(opt.Buttons) == 0 {
+		return nil, errors.New("required attribute not specified")
+	}
+
+	field := model.NewPdfField()
+	buttonfield := &model.PdfFieldButton{}
+	field.SetContext(buttonfield)
+	buttonfield.PdfField = field
+
+	buttonfield.T = core.MakeString(name)
+	buttonfield.SetType(model.ButtonTypeRadio)
+	if opt.NoToggleToOff {
+		buttonfield.SetFlag(buttonfield.Flags().Set(model.FieldFlagNoToggleToOff))
+	}
+
+	value := "Off"
+	if opt.Value != "" {
+		value = opt.Value
+	}
+	buttonfield.V = core.MakeName(value)
+
+	style := FieldAppearance{}.Style()
+	for _, button := range opt.Buttons {
+		if len(button.Rect) != 4 {
+			return nil, errors.New("invalid range")
+		}
+		if button.ExportValue == "" || button.ExportValue == "Off" {
+			return nil, errors.New("invalid export value")
+		}
+
+		widget := model.NewPdfAnnotationWidget()
+		widget.Rect = core.MakeArrayFromFloats(button.Rect)
+		widget.P = page.ToPdfObject()
+		widget.F = core.MakeInteger(4)
+		widget.Parent = buttonfield.ToPdfObject()
+		widget.MK = makeMKDict(model.NewPdfColorDeviceGray(0), model.NewPdfColorDeviceGray(1))
+		widget.BS = makeWidgetBorderStyle(1)
+
+		appDict, err := genFieldRadioButtonAppearance(widget, button.ExportValue, style)
+		if err != nil {
+			return nil, err
+		}
+		widget.AP = appDict
+
+		state := "Off"
+		if button.ExportValue == value {
+			state = value
+		}
+		widget.AS = core.MakeName(state)
+
+		buttonfield.Annotations = append(buttonfield.Annotations, widget)
+	}
+
+	return buttonfield, nil
+}
+
+// ListboxFieldOptions defines optional parameters for a list box form field.
+type ListboxFieldOptions struct {
+	// Choices is the list of string values that can be selected.
+	Choices []string
+
+	// ExportValues are the values of the choices exported when selected, if different from the
+	// displayed choices. Ignored if empty, otherwise must have the same length as Choices.
+	ExportValues []string
+
+	// Values are the export values of the selected choices.
+	Values []string
+
+	// MultiSelect allows selecting more than one choice.
+	MultiSelect bool
+}
+
+// NewListboxField generates a new list box form field with partial name `name` at location `rect`
+// on specified `page` and with field specific options `opt`. The appearance of the field shows
+// the choices which fit in `rect`, scrolled to the first selected choice.
+func NewListboxField(page *model.PdfPage, name string, rect []float64, opt ListboxFieldOptions) (*model.PdfFieldChoice, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	if len(name) <= 0 {
+		return nil, errors.New("required attribute not specified")
+	}
+	if len(rect) != 4 {
+		return nil, errors.New("invalid range")
+	}
+	if len(opt.ExportValues) > 0 && len(opt.ExportValues) != len(opt.Choices) {
+		return nil, errors.New("export values and choices mismatch")
+	}
+	if len(opt.Values) > 1 && !opt.MultiSelect {
+		return nil, errors.New("multiple values without multiple selection")
+	}
+
+	field := model.NewPdfField()
+	chfield := &model.PdfFieldChoice{}
+	field.SetContext(chfield)
+	chfield.PdfField = field
+
+	chfield.T = core.MakeString(name)
+	chfield.Opt = core.MakeArray()
+	for i, choicestr := range opt.Choices {
+		if len(opt.ExportValues) > 0 {
+			chfield.Opt.Append(core.MakeArray(core.MakeString(opt.ExportValues[i]), core.MakeString(choicestr)))
+		} else {
+			chfield.Opt.Append(core.MakeString(choicestr))
+		}
+	}
+	if opt.MultiSelect {
+		chfield.SetFlag(model.FieldFlagMultiSelect)
+	}
+
+	switch len(opt.Values) {
+	case 0:
+	case 1:
+		chfield.V = core.MakeString(opt.Values[0])
+	default:
+		values := core.MakeArray()
+		for _, val := range opt.Values {
+			values.Append(core.MakeString(val))
+		}
+		chfield.V = values
+	}
+
+	widget := model.NewPdfAnnotationWidget()
+	widget.Rect = core.MakeArrayFromFloats(rect)
+	widget.P = page.ToPdfObject()
+	widget.F = core.MakeInteger(4)
+	widget.Parent = chfield.ToPdfObject()
+	widget.MK = makeMKDict(model.NewPdfColorDeviceGray(0), model.NewPdfColorDeviceGray(1))
+	widget.BS = makeWidgetBorderStyle(1)
+	chfield.Annotations = append(chfield.Annotations, widget)
+
+	form := model.NewPdfAcroForm()
+	appDict, err := genFieldListboxAppearance(form, widget, chfield, FieldAppearance{}.Style())
+	if err != nil {
+		return nil, err
+	}
+	widget.AP = appDict
+
+	return chfield, nil
+}
+
+// PushButtonFieldOptions defines optional parameters for a push button form field.
+type PushButtonFieldOptions struct {
+	// Caption is the text of the button (MK CA).
+	Caption string
+
+	// Icon is the image displayed on the button (MK I). The caption is displayed below the icon
+	// if both are specified.
+	Icon *model.Image
+
+	// Action is triggered when the button is clicked (A), e.g. a submit form or JavaScript action.
+	Action *model.PdfAction
+
+	// BorderColor and FillColor are the colors of the button. Default to gray if nil.
+	BorderColor model.PdfColor
+	FillColor   model.PdfColor
+}
+
+// NewPushButtonField generates a new push button form field with partial name `name` at location
+// `rect` on specified `page` and with field specific options `opt`.
+func NewPushButtonField(page *model.PdfPage, name string, rect []float64, opt PushButtonFieldOptions) (*model.PdfFieldButton, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	if len(name) <= 0 {
+		return nil, errors.New("required attribute not specified")
+	}
+	if len(rect) != 4 {
+		return nil, errors.New("invalid range")
+	}
+
+	field := model.NewPdfField()
+	buttonfield := &model.PdfFieldButton{}
+	field.SetContext(buttonfield)
+	buttonfield.PdfField = field
+
+	buttonfield.T = core.MakeString(name)
+	buttonfield.SetType(model.ButtonTypePush)
+
+	borderColor, fillColor := opt.BorderColor, opt.FillColor
+	if borderColor == nil {
+		borderColor = model.NewPdfColorDeviceGray(0.5)
+	}
+	if fillColor == nil {
+		fillColor = model.NewPdfColorDeviceGray(0.75)
+	}
+
+	widget := model.NewPdfAnnotationWidget()
+	widget.Rect = core.MakeArrayFromFloats(rect)
+	widget.P = page.ToPdfObject()
+	widget.F = core.MakeInteger(4)
+	widget.Parent = buttonfield.ToPdfObject()
+	widget.H = core.MakeName("P")
+	widget.BS = makeWidgetBorderStyle(1)
+
+	mkDict := makeMKDict(borderColor, fillColor)
+	if opt.Caption != "" {
+		mkDict.Set("CA", core.MakeString(opt.Caption))
+	}
+	if opt.Icon != nil {
+		icon, err := makeIconXObjectForm(opt.Icon)
+		if err != nil {
+			return nil, err
+		}
+		mkDict.Set("I", icon.ToPdfObject())
+		// Icon only, or caption below the icon.
+		tp := int64(1)
+		if opt.Caption != "" {
+			tp = 2
+		}
+		mkDict.Set("TP", core.MakeInteger(tp))
+	}
+	widget.MK = mkDict
+
+	if opt.Action != nil {
+		// The specific action writes the common entries.
+		if ctx := opt.Action.GetContext(); ctx != nil {
+			widget.A = ctx.ToPdfObject()
+		} else {
+			widget.A = opt.Action.ToPdfObject()
+		}
+	}
+
+	appDict, err := genFieldPushButtonAppearance(model.NewPdfAcroForm(), buttonfield.PdfField, widget, FieldAppearance{}.Style())
+	if err != nil {
+		return nil, err
+	}
+	widget.AP = appDict
+
+	buttonfield.Annotations = append(buttonfield.Annotations, widget)
+
+	return buttonfield, nil
+}
+
+// makeMKDict returns an appearance characteristics dictionary (MK) with border color `bc` and
+// background color `bg`.
+func makeMKDict(bc, bg model.PdfColor) *core.PdfObjectDictionary {
+	mkDict := core.MakeDict()
+	mkDict.Set("BC", makeColorComponents(bc))
+	mkDict.Set("BG", makeColorComponents(bg))
+	return mkDict
+}
+
+// makeColorComponents returns an array of the components of `color`.
+func makeColorComponents(color model.PdfColor) *core.PdfObjectArray {
+	switch c := color.(type) {
+	case *model.PdfColorDeviceGray:
+		return core.MakeArrayFromFloats([]float64{c.Val()})
+	case *model.PdfColorDeviceRGB:
+		return core.MakeArrayFromFloats([]float64{c.R(), c.G(), c.B()})
+	case *model.PdfColorDeviceCMYK:
+		return core.MakeArrayFromFloats([]float64{c.C(), c.M(), c.Y(), c.K()})
+	}
+	return core.MakeArray()
+}
+
+// makeWidgetBorderStyle returns a solid border style dictionary (BS) of width `width`.
+func makeWidgetBorderStyle(width float64) *core.PdfObjectDictionary {
+	bs := core.MakeDict()
+	bs.Set("W", core.MakeFloat(width))
+	bs.Set("S", core.MakeName("S"))
+	return bs
+}
+
+// makeIconXObjectForm returns a form XObject drawing the image `img`, for use as button icon.
+// The form has the size of the image in pixels.
+func makeIconXObjectForm(img *model.Image) (*model.XObjectForm, error) {
+	ximg, err := model.NewXObjectImageFromImage(img, nil, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	w, h := float64(img.Width), float64(img.Height)
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q().
+		Add_cm(w, 0, 0, h, 0, 0).
+		Add_Do("Img").
+		Add_Q()
+
+	xform := model.NewXObjectForm()
+	xform.Resources = model.NewPdfPageResources()
+	if err := xform.Resources.SetXObjectImageByName("Img", ximg); err != nil {
+		return nil, err
+	}
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, w, h})
+	if err := xform.SetContentStream(cc.Bytes(), defStreamEncoder()); err != nil {
+		return nil, err
+	}
+	return xform, nil
+}
+
 // SignatureLine represents a line of information in the signature field appearance.
 type SignatureLine struct {
 	Desc string
@@ -251,6 +573,10 @@ type SignatureFieldOpts struct {
 
 	// BorderColor represents the border color of the appearance annotation area.
 	BorderColor model.PdfColor
+
+	// Appearance specifies the layout of the signature appearance, with images and wrapped
+	// text. If nil, the appearance only contains the signature lines.
+	Appearance *SignatureAppearance
 }
 
 // NewSignatureFieldOpts returns a new initialized instance of options
@@ -269,13 +595,20 @@ func NewSignatureFieldOpts() *SignatureFieldOpts {
 
 // NewSignatureField returns a new signature field with a visible appearance
 // containing the specified signature lines and styled according to the
-// specified options.
+// specified options. If the options have a signature appearance, the field
+// has a layered appearance laid out by it, followed by the signature lines.
 func NewSignatureField(signature *model.PdfSignature, lines []*SignatureLine, opts *SignatureFieldOpts) (*model.PdfFieldSignature, error) {
 	if signature == nil {
 		return nil, errors.New("signature cannot be nil")
 	}
 
-	apDict, err := genFieldSignatureAppearance(lines, opts)
+	var apDict *core.PdfObjectDictionary
+	var err error
+	if opts != nil && opts.Appearance != nil {
+		apDict, err = genSignatureAppearance(signature, lines, opts)
+	} else {
+		apDict, err = genFieldSignatureAppearance(lines, opts)
+	}
 	if err != nil {
 		return nil, err
 	}
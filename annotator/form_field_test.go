@@ -0,0 +1,157 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// fieldValues is a field value provider for testing.
+type fieldValues map[string]core.PdfObject
+
+func (v fieldValues) FieldValues() (map[string]core.PdfObject, error) {
+	return v, nil
+}
+
+func TestRadioGroupField(t *testing.T) {
+	page := model.NewPdfPage()
+	radio, err := NewRadioGroupField(page, "size", RadioGroupFieldOptions{
+		Buttons: []RadioButtonOption{
+			{Rect: []float64{20, 20, 32, 32}, ExportValue: "S"},
+			{Rect: []float64{40, 20, 52, 32}, ExportValue: "M"},
+			{Rect: []float64{60, 20, 72, 32}, ExportValue: "L"},
+		},
+		Value:         "M",
+		NoToggleToOff: true,
+	})
+	require.NoError(t, err)
+	require.True(t, radio.IsRadio())
+	require.True(t, radio.Flags().Has(model.FieldFlagNoToggleToOff))
+	require.Equal(t, "M", radio.V.String())
+	require.Len(t, radio.Annotations, 3)
+
+	states := func() []string {
+		var s []string
+		for _, wa := range radio.Annotations {
+			s = append(s, wa.AS.String())
+		}
+		return s
+	}
+	require.Equal(t, []string{"Off", "M", "Off"}, states())
+	for i, export := range []string{"S", "M", "L"} {
+		ap, ok := core.GetDict(radio.Annotations[i].AP)
+		require.True(t, ok)
+		n, ok := core.GetDict(ap.Get("N"))
+		require.True(t, ok)
+		require.NotNil(t, n.Get(core.PdfObjectName(export)))
+		require.NotNil(t, n.Get("Off"))
+	}
+
+	// Filling the form selects the button with the value and turns the others off.
+	form := model.NewPdfAcroForm()
+	form.Fields = &[]*model.PdfField{radio.PdfField}
+	err = form.FillWithAppearance(fieldValues{"size": core.MakeName("L")}, FieldAppearance{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Off", "Off", "L"}, states())
+
+	_, err = NewRadioGroupField(page, "size", RadioGroupFieldOptions{
+		Buttons: []RadioButtonOption{{Rect: []float64{20, 20, 32, 32}, ExportValue: "Off"}},
+	})
+	require.Error(t, err)
+}
+
+func TestListboxField(t *testing.T) {
+	page := model.NewPdfPage()
+	_, err := NewListboxField(page, "colors", []float64{20, 20, 120, 60}, ListboxFieldOptions{
+		Choices:      []string{"Red", "Green"},
+		ExportValues: []string{"r"},
+	})
+	require.Error(t, err)
+
+	list, err := NewListboxField(page, "colors", []float64{20, 20, 120, 60}, ListboxFieldOptions{
+		Choices:      []string{"Red", "Green", "Blue", "Cyan", "Magenta", "Yellow"},
+		ExportValues: []string{"r", "g", "b", "c", "m", "y"},
+		Values:       []string{"m", "y"},
+		MultiSelect:  true,
+	})
+	require.NoError(t, err)
+	require.True(t, list.Flags().Has(model.FieldFlagMultiSelect))
+	require.False(t, list.Flags().Has(model.FieldFlagCombo))
+	require.Equal(t, 6, list.Opt.Len())
+	opt, ok := core.GetArray(list.Opt.Get(0))
+	require.True(t, ok)
+	require.Equal(t, "r", opt.Get(0).(*core.PdfObjectString).Decoded())
+	require.Equal(t, "Red", opt.Get(1).(*core.PdfObjectString).Decoded())
+
+	// The list is scrolled to show the first selected choice.
+	require.NotNil(t, list.TI)
+	require.Equal(t, int64(4), int64(*list.TI))
+	indices, err := list.I.ToIntegerArray()
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 5}, indices)
+
+	require.Len(t, list.Annotations, 1)
+	ap, ok := core.GetDict(list.Annotations[0].AP)
+	require.True(t, ok)
+	require.NotNil(t, ap.Get("N"))
+}
+
+func TestPushButtonField(t *testing.T) {
+	page := model.NewPdfPage()
+	img, err := model.ImageHandling.NewImageFromGoImage(image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	require.NoError(t, err)
+
+	action := model.NewPdfActionJavaScript()
+	action.JS = core.MakeString("app.alert('Hello');")
+	button, err := NewPushButtonField(page, "hello", []float64{20, 20, 120, 60}, PushButtonFieldOptions{
+		Caption: "Hello",
+		Icon:    img,
+		Action:  action.PdfAction,
+	})
+	require.NoError(t, err)
+	require.True(t, button.IsPush())
+	require.Len(t, button.Annotations, 1)
+
+	widget := button.Annotations[0]
+	mk, ok := core.GetDict(widget.MK)
+	require.True(t, ok)
+	ca, ok := core.GetString(mk.Get("CA"))
+	require.True(t, ok)
+	require.Equal(t, "Hello", ca.Decoded())
+	_, ok = core.GetStream(mk.Get("I"))
+	require.True(t, ok)
+	tp, ok := core.GetIntVal(mk.Get("TP"))
+	require.True(t, ok)
+	require.Equal(t, 2, tp)
+
+	a, ok := core.GetDict(widget.A)
+	require.True(t, ok)
+	require.Equal(t, "JavaScript", a.Get("S").String())
+
+	ap, ok := core.GetDict(widget.AP)
+	require.True(t, ok)
+	n, ok := core.GetStream(ap.Get("N"))
+	require.True(t, ok)
+	res, ok := core.GetDict(n.Get("Resources"))
+	require.True(t, ok)
+	require.NotNil(t, res.Get("XObject"))
+
+	// Icons with an indirect BBox are drawn, icons without BBox are rejected.
+	icon, ok := core.GetStream(mk.Get("I"))
+	require.True(t, ok)
+	icon.Set("BBox", core.MakeIndirectObject(icon.Get("BBox")))
+	_, err = genFieldPushButtonAppearance(model.NewPdfAcroForm(), button.PdfField, widget, FieldAppearance{}.Style())
+	require.NoError(t, err)
+	icon.Remove("BBox")
+	_, err = genFieldPushButtonAppearance(model.NewPdfAcroForm(), button.PdfField, widget, FieldAppearance{}.Style())
+	require.Error(t, err)
+}
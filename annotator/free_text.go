@@ -0,0 +1,460 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// FreeTextAnnotationDef defines a free text annotation: a text box displayed directly on the page,
+// with the lower left corner at (X,Y). The text is wrapped to the width of the box. A callout line
+// pointing to the annotated area can be specified by CalloutLine as 2 or 3 points (x1, y1, x2, y2
+// [x3, y3]), starting at the annotated area.
+type FreeTextAnnotationDef struct {
+	X              float64
+	Y              float64
+	Width          float64
+	Height         float64
+	Text           string
+	Font           pdf.StdFontName // Helvetica if empty.
+	FontSize       float64
+	TextColor      *pdf.PdfColorDeviceRGB
+	Alignment      TextAlignment
+	FillColor      *pdf.PdfColorDeviceRGB // No fill if nil.
+	BorderColor    *pdf.PdfColorDeviceRGB // Text color if nil.
+	BorderWidth    float64
+	CalloutLine    []float64
+	CalloutLineEnd LineEnding
+	Opacity        float64 // Alpha value (0-1).
+	Author         string
+}
+
+// TextAlignment represents the quadding (justification) of the text of free text annotations.
+type TextAlignment int
+
+// Text alignments.
+const (
+	TextAlignmentLeft   TextAlignment = 0
+	TextAlignmentCenter TextAlignment = 1
+	TextAlignmentRight  TextAlignment = 2
+)
+
+// String returns the CSS name of the text alignment.
+func (a TextAlignment) String() string {
+	switch a {
+	case TextAlignmentCenter:
+		return "center"
+	case TextAlignmentRight:
+		return "right"
+	}
+	return "left"
+}
+
+// stdFontResourceNames maps the standard 14 fonts to the resource names commonly used in DA strings.
+var stdFontResourceNames = map[pdf.StdFontName]string{
+	pdf.HelveticaName:            "Helv",
+	pdf.HelveticaBoldName:        "HeBo",
+	pdf.HelveticaObliqueName:     "HeOb",
+	pdf.HelveticaBoldObliqueName: "HeBO",
+	pdf.CourierName:              "Cour",
+	pdf.CourierBoldName:          "CoBo",
+	pdf.CourierObliqueName:       "CoOb",
+	pdf.CourierBoldObliqueName:   "CoBO",
+	pdf.TimesRomanName:           "TiRo",
+	pdf.TimesBoldName:            "TiBo",
+	pdf.TimesItalicName:          "TiIt",
+	pdf.TimesBoldItalicName:      "TiBI",
+	pdf.SymbolName:               "Symb",
+	pdf.ZapfDingbatsName:         "ZaDb",
+}
+
+// CreateFreeTextAnnotation creates a free text annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreateFreeTextAnnotation(textDef FreeTextAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if textDef.Width <= 0 || textDef.Height <= 0 {
+		return nil, errors.New("invalid free text annotation size")
+	}
+
+	fontName := textDef.Font
+	if fontName == "" {
+		fontName = pdf.HelveticaName
+	}
+	resName, ok := stdFontResourceNames[fontName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported font %s", fontName)
+	}
+	fontSize := textDef.FontSize
+	if fontSize <= 0 {
+		fontSize = 12
+	}
+	textColor := textDef.TextColor
+	if textColor == nil {
+		textColor = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+
+	annot := pdf.NewPdfAnnotationFreeText()
+	annot.Contents = pdfcore.MakeString(textDef.Text)
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		textDef.X, textDef.Y, textDef.X + textDef.Width, textDef.Y + textDef.Height,
+	})
+	annot.DS = pdfcore.MakeString(fmt.Sprintf("font: %s %spt; text-align:%s; color:#%02X%02X%02X",
+		fontName, formatNumber(fontSize), textDef.Alignment,
+		int(math.Round(textColor.R()*255)), int(math.Round(textColor.G()*255)), int(math.Round(textColor.B()*255))))
+	annot.Q = pdfcore.MakeInteger(int64(textDef.Alignment))
+	if textDef.FillColor != nil {
+		annot.C = makeColorArray(textDef.FillColor)
+	}
+	borderColor := textDef.BorderColor
+	if borderColor == nil {
+		borderColor = textColor
+	}
+	// The border color is specified by the stroking color of the DA string.
+	annot.DA = pdfcore.MakeString(fmt.Sprintf("/%s %s Tf %s %s %s rg %s %s %s RG", resName, formatNumber(fontSize),
+		formatNumber(textColor.R()), formatNumber(textColor.G()), formatNumber(textColor.B()),
+		formatNumber(borderColor.R()), formatNumber(borderColor.G()), formatNumber(borderColor.B())))
+	annot.BS = makeBorderStyle(textDef.BorderWidth, nil)
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, textDef.Author, "", textDef.Opacity)
+
+	if len(textDef.CalloutLine) > 0 {
+		if n := len(textDef.CalloutLine); n != 4 && n != 6 {
+			return nil, errors.New("callout line must have 2 or 3 points")
+		}
+		annot.CL = pdfcore.MakeArrayFromFloats(textDef.CalloutLine)
+		annot.IT = pdfcore.MakeName("FreeTextCallout")
+		if textDef.CalloutLineEnd != "" {
+			annot.LE = pdfcore.MakeName(string(textDef.CalloutLineEnd))
+		}
+	}
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// freeTextStyle contains the text style of a free text annotation, from its DA and DS entries.
+type freeTextStyle struct {
+	fontName    string
+	font        *pdf.PdfFont
+	fontSize    float64
+	textColor   pdf.PdfColor
+	borderColor pdf.PdfColor
+	alignment   TextAlignment
+}
+
+// parseFreeTextStyle returns the text style specified by the DA, DS and Q entries of a free text
+// annotation. DA has precedence over DS.
+func parseFreeTextStyle(annot *pdf.PdfAnnotationFreeText) *freeTextStyle {
+	style := &freeTextStyle{fontSize: 12}
+
+	if str, ok := pdfcore.GetString(annot.DS); ok {
+		parseFreeTextDS(str.Decoded(), style)
+	}
+	if str, ok := pdfcore.GetString(annot.DA); ok {
+		ops, err := contentstream.NewContentStreamParser(str.Str()).Parse()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to parse DA: %v", err)
+		} else {
+			for _, op := range *ops {
+				vals, _ := pdfcore.GetNumbersAsFloat(op.Params)
+				switch op.Operand {
+				case "Tf":
+					if len(op.Params) == 2 {
+						if name, ok := pdfcore.GetNameVal(op.Params[0]); ok {
+							style.fontName = name
+						}
+						if size, err := pdfcore.GetNumberAsFloat(op.Params[1]); err == nil && size > 0 {
+							style.fontSize = size
+						}
+					}
+				case "g", "rg", "k":
+					style.textColor = colorFromValues(vals)
+				case "G", "RG", "K":
+					style.borderColor = colorFromValues(vals)
+				}
+			}
+		}
+	}
+	if q, ok := pdfcore.GetIntVal(annot.Q); ok {
+		style.alignment = TextAlignment(q)
+	}
+
+	if style.textColor == nil {
+		style.textColor = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+	if style.borderColor == nil {
+		style.borderColor = style.textColor
+	}
+
+	// The DA fonts are resolved by their names in the AcroForm resources, which are not available
+	// here: the standard 14 fonts are used instead.
+	stdName := pdf.HelveticaName
+	for name, resName := range stdFontResourceNames {
+		if style.fontName == resName || style.fontName == string(name) {
+			stdName = name
+			break
+		}
+	}
+	style.fontName = stdFontResourceNames[stdName]
+	style.font = pdf.NewStandard14FontMustCompile(stdName)
+	return style
+}
+
+var (
+	reDSFontSize = regexp.MustCompile(`(?i)font(?:-size)?\s*:[^;]*?([0-9.]+)\s*pt`)
+	reDSFont     = regexp.MustCompile(`(?i)font\s*:\s*'?([A-Za-z-]+)`)
+	reDSColor    = regexp.MustCompile(`(?i)(?:^|;)\s*color\s*:\s*#([0-9a-f]{6})`)
+	reDSAlign    = regexp.MustCompile(`(?i)text-align\s*:\s*(left|center|right)`)
+)
+
+// parseFreeTextDS parses the default style string `ds` (CSS2 style) of a free text annotation.
+func parseFreeTextDS(ds string, style *freeTextStyle) {
+	if m := reDSFontSize.FindStringSubmatch(ds); m != nil {
+		if size, err := strconv.ParseFloat(m[1], 64); err == nil && size > 0 {
+			style.fontSize = size
+		}
+	}
+	if m := reDSFont.FindStringSubmatch(ds); m != nil {
+		style.fontName = m[1]
+	}
+	if m := reDSColor.FindStringSubmatch(ds); m != nil {
+		if v, err := strconv.ParseUint(m[1], 16, 32); err == nil {
+			style.textColor = pdf.NewPdfColorDeviceRGB(
+				float64(v>>16&0xff)/255, float64(v>>8&0xff)/255, float64(v&0xff)/255)
+		}
+	}
+	if m := reDSAlign.FindStringSubmatch(ds); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "center":
+			style.alignment = TextAlignmentCenter
+		case "right":
+			style.alignment = TextAlignmentRight
+		default:
+			style.alignment = TextAlignmentLeft
+		}
+	}
+}
+
+// colorFromValues returns the device color with the components `vals`.
+func colorFromValues(vals []float64) pdf.PdfColor {
+	switch len(vals) {
+	case 1:
+		return pdf.NewPdfColorDeviceGray(vals[0])
+	case 3:
+		return pdf.NewPdfColorDeviceRGB(vals[0], vals[1], vals[2])
+	case 4:
+		return pdf.NewPdfColorDeviceCMYK(vals[0], vals[1], vals[2], vals[3])
+	}
+	return nil
+}
+
+// makeFreeTextAnnotationAppearanceStream generates the appearance stream of a free text annotation:
+// the box with the wrapped text and the callout line, if any.
+func makeFreeTextAnnotationAppearanceStream(annot *pdf.PdfAnnotationFreeText) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	rect, err := annotationRect(annot.PdfAnnotation)
+	if err != nil {
+		return nil, nil, err
+	}
+	style := parseFreeTextStyle(annot)
+	borderWidth, dash := annotationBorderStyle(annot.BS)
+	fill := annotationColor(annot.C)
+
+	// Text box inside the rectangle, specified by the rectangle differences (RD).
+	box := *rect
+	if arr, ok := pdfcore.GetArray(annot.RD); ok {
+		if rd, err := arr.ToFloat64Array(); err == nil && len(rd) == 4 {
+			box.Llx += rd[0]
+			box.Lly += rd[1]
+			box.Urx -= rd[2]
+			box.Ury -= rd[3]
+		}
+	}
+
+	resources := pdf.NewPdfPageResources()
+	fontResName := pdfcore.PdfObjectName(style.fontName)
+	if err := resources.SetFontByName(fontResName, style.font.ToPdfObject()); err != nil {
+		return nil, nil, err
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(annot.PdfAnnotationMarkup), ""); err != nil {
+		return nil, nil, err
+	}
+
+	// Box.
+	if fill != nil {
+		cc.SetNonStrokingColor(fill).
+			Add_re(box.Llx, box.Lly, box.Width(), box.Height()).
+			Add_f()
+	}
+	if borderWidth > 0 {
+		cc.SetStrokingColor(style.borderColor).Add_w(borderWidth)
+		if len(dash) > 0 {
+			cc.Add_d(dash, 0)
+		}
+		hw := borderWidth / 2
+		cc.Add_re(box.Llx+hw, box.Lly+hw, box.Width()-borderWidth, box.Height()-borderWidth).Add_S()
+		if len(dash) > 0 {
+			cc.Add_d([]int64{}, 0)
+		}
+	}
+
+	// Callout line.
+	bbox := *rect
+	if arr, ok := pdfcore.GetArray(annot.CL); ok {
+		if cl, err := arr.ToFloat64Array(); err == nil && (len(cl) == 4 || len(cl) == 6) {
+			w := math.Max(borderWidth, 1)
+			cc.SetStrokingColor(style.borderColor).Add_w(w)
+			drawPolyline(cc, cl)
+			cc.Add_S()
+
+			le, _ := lineEndings(annot.LE)
+			drawLineEnding(cc, le, cl[0], cl[1], math.Atan2(cl[1]-cl[3], cl[0]-cl[2]), w, fill)
+
+			margin := w
+			if le != LineEndingNone {
+				margin += lineEndingSize(w)
+			}
+			bbox = bbox.Union(*pointsBBox(cl, margin))
+			// The rectangle is extended to the callout line: keep the text box in place.
+			annot.RD = pdfcore.MakeArrayFromFloats([]float64{
+				box.Llx - bbox.Llx, box.Lly - bbox.Lly, bbox.Urx - box.Urx, bbox.Ury - box.Ury,
+			})
+		}
+	}
+
+	// Text.
+	padding := borderWidth + 2
+	textWidth := box.Width() - 2*padding
+	lines := wrapText(annotationText(annot.PdfAnnotation), style.font, style.fontSize, textWidth)
+	if len(lines) > 0 && textWidth > 0 {
+		lineHeight := 1.2 * style.fontSize
+		ascent := 0.8 * style.fontSize
+
+		cc.Add_re(box.Llx+borderWidth, box.Lly+borderWidth, box.Width()-2*borderWidth, box.Height()-2*borderWidth).
+			Add_W().
+			Add_n()
+		cc.Add_BT().
+			SetNonStrokingColor(style.textColor).
+			Add_Tf(fontResName, style.fontSize).
+			Add_TL(lineHeight)
+
+		y := box.Ury - padding - ascent
+		prevX := 0.0
+		for i, line := range lines {
+			x := box.Llx + padding
+			switch style.alignment {
+			case TextAlignmentCenter:
+				x += (textWidth - line.width) / 2
+			case TextAlignmentRight:
+				x += textWidth - line.width
+			}
+			if i == 0 {
+				cc.Add_Td(x, y)
+			} else {
+				cc.Add_Td(x-prevX, -lineHeight)
+			}
+			prevX = x
+
+			encoded, _ := style.font.StringToCharcodeBytes(line.text)
+			cc.Add_Tj(*pdfcore.MakeStringFromBytes(encoded))
+		}
+		cc.Add_ET()
+	}
+	cc.Add_Q()
+
+	apDict, err := makeAppearanceDict(cc.Bytes(), &bbox, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, &bbox, nil
+}
+
+// annotationText returns the text contents of the annotation.
+func annotationText(annot *pdf.PdfAnnotation) string {
+	if str, ok := pdfcore.GetString(annot.Contents); ok {
+		return str.Decoded()
+	}
+	return ""
+}
+
+// textLine is a line of wrapped text.
+type textLine struct {
+	text  string
+	width float64
+}
+
+// wrapText splits `text` into lines which fit the `width` when drawn with `font` of size `fontSize`.
+// Lines are broken at the spaces between words, or inside words which do not fit the width.
+func wrapText(text string, font *pdf.PdfFont, fontSize, width float64) []textLine {
+	runeWidth := func(r rune) float64 {
+		if m, ok := font.GetRuneMetrics(r); ok {
+			return m.Wx * fontSize / 1000
+		}
+		return 0
+	}
+
+	var lines []textLine
+	for _, paragraph := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		paragraph = strings.Replace(paragraph, "\r", "", -1)
+		var line []rune
+		var lineWidth float64
+		// Position of the last space in the line, for breaking between words.
+		lastSpace := -1
+
+		for _, r := range paragraph {
+			w := runeWidth(r)
+			if lineWidth+w > width && len(line) > 0 && !unicode.IsSpace(r) {
+				breakAt := len(line)
+				if lastSpace > 0 {
+					breakAt = lastSpace
+				}
+				text := strings.TrimRightFunc(string(line[:breakAt]), unicode.IsSpace)
+				lines = append(lines, textLine{text: text, width: measureText(text, runeWidth)})
+
+				line = []rune(strings.TrimLeftFunc(string(line[breakAt:]), unicode.IsSpace))
+				lineWidth = measureText(string(line), runeWidth)
+				lastSpace = -1
+			}
+			if unicode.IsSpace(r) {
+				lastSpace = len(line)
+			}
+			line = append(line, r)
+			lineWidth += w
+		}
+
+		text := strings.TrimRightFunc(string(line), unicode.IsSpace)
+		lines = append(lines, textLine{text: text, width: measureText(text, runeWidth)})
+	}
+	return lines
+}
+
+// measureText returns the width of `text` where `runeWidth` is the width of each rune.
+func measureText(text string, runeWidth func(rune) float64) float64 {
+	var w float64
+	for _, r := range text {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// formatNumber returns the shortest representation of the number `v`.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
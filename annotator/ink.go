@@ -0,0 +1,110 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// InkAnnotationDef defines a freehand "scribble" composed of one or more disjoint paths. Each path
+// is specified by its points (x1, y1, x2, y2, ...).
+type InkAnnotationDef struct {
+	Paths     [][]float64
+	LineColor *pdf.PdfColorDeviceRGB
+	LineWidth float64
+	Opacity   float64 // Alpha value (0-1).
+}
+
+// CreateInkAnnotation creates an ink annotation object with appearance stream that can be added to
+// page PDF annotations.
+func CreateInkAnnotation(inkDef InkAnnotationDef) (*pdf.PdfAnnotation, error) {
+	inkList := pdfcore.MakeArray()
+	for _, path := range inkDef.Paths {
+		if len(path) < 2 || len(path)%2 != 0 {
+			return nil, errors.New("invalid number of ink path points")
+		}
+		inkList.Append(pdfcore.MakeArrayFromFloats(path))
+	}
+	if inkList.Len() == 0 {
+		return nil, errors.New("ink paths missing")
+	}
+
+	annot := pdf.NewPdfAnnotationInk()
+	annot.InkList = inkList
+	lineColor := inkDef.LineColor
+	if lineColor == nil {
+		lineColor = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+	annot.C = makeColorArray(lineColor)
+	annot.BS = makeBorderStyle(inkDef.LineWidth, nil)
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, "", "", inkDef.Opacity)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// makeInkAnnotationAppearanceStream generates the appearance stream of an ink annotation from its
+// InkList.
+func makeInkAnnotationAppearanceStream(annot *pdf.PdfAnnotationInk) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	inkList, ok := pdfcore.GetArray(annot.InkList)
+	if !ok {
+		return nil, nil, errors.New("ink list missing")
+	}
+
+	color := annotationColor(annot.C)
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+	lineWidth, dash := annotationBorderStyle(annot.BS)
+
+	resources := pdf.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(annot.PdfAnnotationMarkup), ""); err != nil {
+		return nil, nil, err
+	}
+	cc.SetStrokingColor(color).Add_w(lineWidth)
+	addLineStyle(cc, 1, 1)
+	if len(dash) > 0 {
+		cc.Add_d(dash, 0)
+	}
+
+	var allPoints []float64
+	for _, obj := range inkList.Elements() {
+		arr, ok := pdfcore.GetArray(obj)
+		if !ok {
+			continue
+		}
+		points, err := arr.ToFloat64Array()
+		if err != nil || len(points) < 2 {
+			continue
+		}
+		if len(points) == 2 {
+			// Single point: draw a dot.
+			points = append(points, points[0], points[1])
+		}
+		drawPolyline(cc, points)
+		allPoints = append(allPoints, points...)
+	}
+	if len(allPoints) == 0 {
+		return nil, nil, errors.New("ink list is empty")
+	}
+	cc.Add_S().Add_Q()
+
+	bbox := pointsBBox(allPoints, lineWidth/2+1)
+	apDict, err := makeAppearanceDict(cc.Bytes(), bbox, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, bbox, nil
+}
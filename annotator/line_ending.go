@@ -0,0 +1,151 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// LineEnding represents the line ending styles of PolyLine and FreeText callout annotations
+// (Table 176 p. 405).
+type LineEnding string
+
+// Line ending styles.
+const (
+	LineEndingNone         LineEnding = "None"
+	LineEndingSquare       LineEnding = "Square"
+	LineEndingCircle       LineEnding = "Circle"
+	LineEndingDiamond      LineEnding = "Diamond"
+	LineEndingOpenArrow    LineEnding = "OpenArrow"
+	LineEndingClosedArrow  LineEnding = "ClosedArrow"
+	LineEndingButt         LineEnding = "Butt"
+	LineEndingROpenArrow   LineEnding = "ROpenArrow"
+	LineEndingRClosedArrow LineEnding = "RClosedArrow"
+	LineEndingSlash        LineEnding = "Slash"
+)
+
+// makeLineEndings returns the LE array of the line ending styles `le1` and `le2`.
+func makeLineEndings(le1, le2 LineEnding) *pdfcore.PdfObjectArray {
+	if le1 == "" {
+		le1 = LineEndingNone
+	}
+	if le2 == "" {
+		le2 = LineEndingNone
+	}
+	return pdfcore.MakeArray(pdfcore.MakeName(string(le1)), pdfcore.MakeName(string(le2)))
+}
+
+// lineEndings returns the line ending styles specified by the LE array `obj`.
+func lineEndings(obj pdfcore.PdfObject) (LineEnding, LineEnding) {
+	le1, le2 := LineEndingNone, LineEndingNone
+	switch t := pdfcore.TraceToDirectObject(obj).(type) {
+	case *pdfcore.PdfObjectName:
+		// FreeText annotations have a single line ending.
+		le1 = LineEnding(*t)
+	case *pdfcore.PdfObjectArray:
+		if t.Len() == 2 {
+			if name, ok := pdfcore.GetNameVal(t.Get(0)); ok {
+				le1 = LineEnding(name)
+			}
+			if name, ok := pdfcore.GetNameVal(t.Get(1)); ok {
+				le2 = LineEnding(name)
+			}
+		}
+	}
+	return le1, le2
+}
+
+// lineEndingSize returns the size of the line endings for lines of width `lineWidth`.
+func lineEndingSize(lineWidth float64) float64 {
+	return math.Max(6, 4*lineWidth)
+}
+
+// drawLineEnding draws the line ending `style` at the end point (x, y) of a line going in the
+// direction `angle` (radians). Closed shapes are filled with `fill`, if not nil. The current
+// stroking color and line width are used for the outlines.
+func drawLineEnding(cc *contentstream.ContentCreator, style LineEnding, x, y, angle, lineWidth float64,
+	fill pdf.PdfColor) {
+	size := lineEndingSize(lineWidth)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+
+	// pt returns the point (dx, dy) of the line ending coordinate system, where the x axis is in
+	// the direction of the line.
+	pt := func(dx, dy float64) (float64, float64) {
+		return x + dx*cos - dy*sin, y + dx*sin + dy*cos
+	}
+	closePath := func() {
+		cc.Add_h()
+		if fill != nil {
+			cc.SetNonStrokingColor(fill).Add_B()
+		} else {
+			cc.Add_S()
+		}
+	}
+	polygon := func(points ...[2]float64) {
+		for i, p := range points {
+			px, py := pt(p[0], p[1])
+			if i == 0 {
+				cc.Add_m(px, py)
+			} else {
+				cc.Add_l(px, py)
+			}
+		}
+	}
+
+	h := size / 2
+	arrowDx, arrowDy := -size*math.Cos(math.Pi/6), size*math.Sin(math.Pi/6)
+
+	switch style {
+	case LineEndingSquare:
+		polygon([2]float64{-h, -h}, [2]float64{h, -h}, [2]float64{h, h}, [2]float64{-h, h})
+		closePath()
+	case LineEndingDiamond:
+		polygon([2]float64{-h, 0}, [2]float64{0, -h}, [2]float64{h, 0}, [2]float64{0, h})
+		closePath()
+	case LineEndingCircle:
+		// Bezier approximation of the circle.
+		k := 0.5523 * h
+		px, py := pt(h, 0)
+		cc.Add_m(px, py)
+		arcs := [][6]float64{
+			{h, k, k, h, 0, h},
+			{-k, h, -h, k, -h, 0},
+			{-h, -k, -k, -h, 0, -h},
+			{k, -h, h, -k, h, 0},
+		}
+		for _, a := range arcs {
+			x1, y1 := pt(a[0], a[1])
+			x2, y2 := pt(a[2], a[3])
+			x3, y3 := pt(a[4], a[5])
+			cc.Add_c(x1, y1, x2, y2, x3, y3)
+		}
+		closePath()
+	case LineEndingOpenArrow:
+		polygon([2]float64{arrowDx, arrowDy}, [2]float64{0, 0}, [2]float64{arrowDx, -arrowDy})
+		cc.Add_S()
+	case LineEndingClosedArrow:
+		polygon([2]float64{arrowDx, arrowDy}, [2]float64{0, 0}, [2]float64{arrowDx, -arrowDy})
+		closePath()
+	case LineEndingROpenArrow:
+		polygon([2]float64{-arrowDx, arrowDy}, [2]float64{0, 0}, [2]float64{-arrowDx, -arrowDy})
+		cc.Add_S()
+	case LineEndingRClosedArrow:
+		polygon([2]float64{-arrowDx, arrowDy}, [2]float64{0, 0}, [2]float64{-arrowDx, -arrowDy})
+		closePath()
+	case LineEndingButt:
+		polygon([2]float64{0, h}, [2]float64{0, -h})
+		cc.Add_S()
+	case LineEndingSlash:
+		// Rotated 30 degrees clockwise from the perpendicular.
+		dx, dy := h*math.Cos(math.Pi/3), h*math.Sin(math.Pi/3)
+		polygon([2]float64{dx, dy}, [2]float64{-dx, -dy})
+		cc.Add_S()
+	}
+}
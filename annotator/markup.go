@@ -0,0 +1,264 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// Annotation flags (Table 165 p. 395).
+const (
+	annotationFlagPrint    = 4
+	annotationFlagNoZoom   = 8
+	annotationFlagNoRotate = 16
+)
+
+// GenerateMarkupAppearance generates the normal appearance stream of the markup annotation `annot`
+// from its properties and sets it as the appearance (AP) of the annotation. The Rect of the
+// annotation is updated to enclose the generated appearance.
+// Supported annotation types: Highlight, Underline, StrikeOut, Squiggly, Ink, Polygon, PolyLine,
+// FreeText, Text, Caret and Stamp. It can be used for annotations which have no appearance
+// streams, so that they are displayed uniformly by viewers which do not generate appearances.
+func GenerateMarkupAppearance(annot *pdf.PdfAnnotation) error {
+	var apDict *pdfcore.PdfObjectDictionary
+	var bbox *pdf.PdfRectangle
+	var err error
+
+	switch t := annot.GetContext().(type) {
+	case *pdf.PdfAnnotationHighlight:
+		apDict, bbox, err = makeTextMarkupAppearanceStream(textMarkupHighlight, t.QuadPoints, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationUnderline:
+		apDict, bbox, err = makeTextMarkupAppearanceStream(textMarkupUnderline, t.QuadPoints, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationStrikeOut:
+		apDict, bbox, err = makeTextMarkupAppearanceStream(textMarkupStrikeOut, t.QuadPoints, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationSquiggly:
+		apDict, bbox, err = makeTextMarkupAppearanceStream(textMarkupSquiggly, t.QuadPoints, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationInk:
+		apDict, bbox, err = makeInkAnnotationAppearanceStream(t)
+	case *pdf.PdfAnnotationPolygon:
+		apDict, bbox, err = makePolyAnnotationAppearanceStream(t.Vertices, true, nil, t.BS, t.IC, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationPolyLine:
+		apDict, bbox, err = makePolyAnnotationAppearanceStream(t.Vertices, false, t.LE, t.BS, t.IC, t.PdfAnnotation, t.PdfAnnotationMarkup)
+	case *pdf.PdfAnnotationFreeText:
+		apDict, bbox, err = makeFreeTextAnnotationAppearanceStream(t)
+	case *pdf.PdfAnnotationText:
+		apDict, bbox, err = makeTextAnnotationAppearanceStream(t)
+	case *pdf.PdfAnnotationCaret:
+		apDict, bbox, err = makeCaretAnnotationAppearanceStream(t)
+	case *pdf.PdfAnnotationStamp:
+		apDict, bbox, err = makeStampAnnotationAppearanceStream(t)
+	default:
+		return fmt.Errorf("unsupported annotation type %T", t)
+	}
+	if err != nil {
+		return err
+	}
+
+	annot.AP = apDict
+	annot.Rect = bbox.ToPdfObject()
+	return nil
+}
+
+// makeAppearanceDict returns an appearance dictionary with a normal appearance form XObject drawing
+// `content`, which is drawn in page coordinates. The content is translated so that the local
+// bounding box of the form starts at the origin, while `bbox` is the annotation rectangle.
+func makeAppearanceDict(content []byte, bbox *pdf.PdfRectangle, resources *pdf.PdfPageResources) (*pdfcore.PdfObjectDictionary, error) {
+	cc := contentstream.NewContentCreator()
+	cc.Add_cm(1, 0, 0, 1, -bbox.Llx, -bbox.Lly)
+	content = append(cc.Bytes(), content...)
+
+	form := pdf.NewXObjectForm()
+	form.Resources = resources
+	if err := form.SetContentStream(content, defStreamEncoder()); err != nil {
+		return nil, err
+	}
+	form.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, bbox.Width(), bbox.Height()})
+
+	apDict := pdfcore.MakeDict()
+	apDict.Set("N", form.ToPdfObject())
+	return apDict, nil
+}
+
+// addOpacityGState adds the graphics state setting the `opacity` (and the blend mode, if
+// specified) of the annotation to the `resources` and to the content creator `cc`.
+func addOpacityGState(cc *contentstream.ContentCreator, resources *pdf.PdfPageResources,
+	opacity float64, blendMode string) error {
+	if opacity >= 1 && blendMode == "" {
+		return nil
+	}
+
+	gsState := pdfcore.MakeDict()
+	if opacity < 1 {
+		gsState.Set("ca", pdfcore.MakeFloat(opacity))
+		gsState.Set("CA", pdfcore.MakeFloat(opacity))
+	}
+	if blendMode != "" {
+		gsState.Set("BM", pdfcore.MakeName(blendMode))
+	}
+	if err := resources.AddExtGState("gs1", gsState); err != nil {
+		common.Log.Debug("Unable to add extgstate gs1")
+		return err
+	}
+
+	cc.Add_gs("gs1")
+	return nil
+}
+
+// annotationOpacity returns the constant opacity (CA) of the markup annotation.
+func annotationOpacity(markup *pdf.PdfAnnotationMarkup) float64 {
+	if markup == nil {
+		return 1
+	}
+	if opacity, err := pdfcore.GetNumberAsFloat(markup.CA); err == nil {
+		return math.Max(0, math.Min(1, opacity))
+	}
+	return 1
+}
+
+// annotationColor returns the color specified by the color array `obj` (e.g. the C or IC entries
+// of an annotation). Returns nil if the array is missing or empty (transparent).
+func annotationColor(obj pdfcore.PdfObject) pdf.PdfColor {
+	arr, ok := pdfcore.GetArray(obj)
+	if !ok {
+		return nil
+	}
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil
+	}
+
+	switch len(vals) {
+	case 1:
+		return pdf.NewPdfColorDeviceGray(vals[0])
+	case 3:
+		return pdf.NewPdfColorDeviceRGB(vals[0], vals[1], vals[2])
+	case 4:
+		return pdf.NewPdfColorDeviceCMYK(vals[0], vals[1], vals[2], vals[3])
+	}
+	return nil
+}
+
+// makeColorArray returns the annotation color array of `color`. Returns an empty array (transparent)
+// if `color` is nil.
+func makeColorArray(color *pdf.PdfColorDeviceRGB) *pdfcore.PdfObjectArray {
+	if color == nil {
+		return pdfcore.MakeArray()
+	}
+	return pdfcore.MakeArrayFromFloats([]float64{color.R(), color.G(), color.B()})
+}
+
+// annotationBorderStyle returns the border style of the annotation specified by the border style
+// dictionary `obj`, if any.
+func annotationBorderStyle(obj pdfcore.PdfObject) (width float64, dash []int64) {
+	width = 1
+	dict, ok := pdfcore.GetDict(obj)
+	if !ok {
+		return width, nil
+	}
+
+	if w, err := pdfcore.GetNumberAsFloat(dict.Get("W")); err == nil {
+		width = w
+	}
+	if style, ok := pdfcore.GetNameVal(dict.Get("S")); ok && style == "D" {
+		dash = []int64{3}
+		if arr, ok := pdfcore.GetArray(dict.Get("D")); ok {
+			if vals, err := arr.ToIntegerArray(); err == nil && len(vals) > 0 {
+				dash = make([]int64, len(vals))
+				for i, v := range vals {
+					dash[i] = int64(v)
+				}
+			}
+		}
+	}
+	return width, dash
+}
+
+// makeBorderStyle returns a border style dictionary with the specified `width` and `dash` array.
+func makeBorderStyle(width float64, dash []int64) pdfcore.PdfObject {
+	bs := pdf.NewBorderStyle()
+	bs.SetBorderWidth(width)
+	if len(dash) > 0 {
+		style := pdf.BorderStyleDashed
+		bs.S = &style
+		d := make([]int, len(dash))
+		for i, v := range dash {
+			d[i] = int(v)
+		}
+		bs.D = &d
+	}
+	return bs.ToPdfObject()
+}
+
+// addLineStyle adds the operands setting the line cap and line join styles to `cc`.
+func addLineStyle(cc *contentstream.ContentCreator, lineCap, lineJoin int64) {
+	cc.AddOperand(contentstream.ContentStreamOperation{
+		Operand: "J",
+		Params:  []pdfcore.PdfObject{pdfcore.MakeInteger(lineCap)},
+	})
+	cc.AddOperand(contentstream.ContentStreamOperation{
+		Operand: "j",
+		Params:  []pdfcore.PdfObject{pdfcore.MakeInteger(lineJoin)},
+	})
+}
+
+// pointsBBox returns the bounding box of the points `points` (x1, y1, x2, y2, ...), expanded by
+// `margin` on all sides.
+func pointsBBox(points []float64, margin float64) *pdf.PdfRectangle {
+	if len(points) < 2 {
+		return &pdf.PdfRectangle{}
+	}
+
+	bbox := &pdf.PdfRectangle{Llx: points[0], Lly: points[1], Urx: points[0], Ury: points[1]}
+	for i := 2; i+1 < len(points); i += 2 {
+		bbox.Llx = math.Min(bbox.Llx, points[i])
+		bbox.Lly = math.Min(bbox.Lly, points[i+1])
+		bbox.Urx = math.Max(bbox.Urx, points[i])
+		bbox.Ury = math.Max(bbox.Ury, points[i+1])
+	}
+
+	bbox.Llx -= margin
+	bbox.Lly -= margin
+	bbox.Urx += margin
+	bbox.Ury += margin
+	return bbox
+}
+
+// annotationRect returns the normalized rectangle of the annotation.
+func annotationRect(annot *pdf.PdfAnnotation) (*pdf.PdfRectangle, error) {
+	arr, ok := pdfcore.GetArray(annot.Rect)
+	if !ok {
+		return nil, fmt.Errorf("annotation rectangle missing")
+	}
+	rect, err := pdf.NewPdfRectangle(*arr)
+	if err != nil {
+		return nil, err
+	}
+	return &pdf.PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx),
+		Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx),
+		Ury: math.Max(rect.Lly, rect.Ury),
+	}, nil
+}
+
+// setMarkupFields sets the common fields of the markup annotation.
+func setMarkupFields(markup *pdf.PdfAnnotationMarkup, author, subject string, opacity float64) {
+	if author != "" {
+		markup.T = pdfcore.MakeString(author)
+	}
+	if subject != "" {
+		markup.Subj = pdfcore.MakeString(subject)
+	}
+	if opacity < 1.0 {
+		markup.CA = pdfcore.MakeFloat(opacity)
+	}
+}
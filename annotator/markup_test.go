@@ -0,0 +1,204 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+func TestMarkupAnnotationAppearances(t *testing.T) {
+	red := model.NewPdfColorDeviceRGB(1, 0, 0)
+	quad := []float64{100, 720, 300, 720, 100, 700, 300, 700}
+
+	create := []func() (*model.PdfAnnotation, error){
+		func() (*model.PdfAnnotation, error) {
+			return CreateHighlightAnnotation(TextMarkupAnnotationDef{QuadPoints: quad, Opacity: 1})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateUnderlineAnnotation(TextMarkupAnnotationDef{QuadPoints: quad, Color: red, Opacity: 1})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateStrikeOutAnnotation(TextMarkupAnnotationDef{QuadPoints: quad, Color: red, Opacity: 1})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateSquigglyAnnotation(TextMarkupAnnotationDef{QuadPoints: quad, Color: red, Opacity: 0.5})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateInkAnnotation(InkAnnotationDef{
+				Paths:     [][]float64{{100, 600, 120, 620, 140, 600}, {150, 600, 170, 640}},
+				LineWidth: 2,
+				Opacity:   1,
+			})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreatePolygonAnnotation(PolygonAnnotationDef{
+				Vertices:    []float64{200, 600, 260, 600, 230, 650},
+				FillColor:   model.NewPdfColorDeviceRGB(0, 1, 0),
+				BorderColor: red,
+				BorderWidth: 1,
+				Opacity:     1,
+			})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreatePolyLineAnnotation(PolyLineAnnotationDef{
+				Vertices:         []float64{300, 600, 350, 640, 400, 600},
+				LineWidth:        1,
+				LineEndingStyle1: LineEndingCircle,
+				LineEndingStyle2: LineEndingClosedArrow,
+				Opacity:          1,
+			})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateFreeTextAnnotation(FreeTextAnnotationDef{
+				X: 100, Y: 400, Width: 150, Height: 80,
+				Text:           "Free text annotation with text wrapped to the width of the box.",
+				FontSize:       10,
+				Alignment:      TextAlignmentCenter,
+				FillColor:      model.NewPdfColorDeviceRGB(1, 1, 0.8),
+				BorderWidth:    1,
+				CalloutLine:    []float64{300, 350, 270, 440, 250, 440},
+				CalloutLineEnd: LineEndingOpenArrow,
+				Opacity:        1,
+			})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateTextAnnotation(TextAnnotationDef{X: 400, Y: 400, Icon: NoteIconComment, Contents: "Note"})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateCaretAnnotation(CaretAnnotationDef{X: 450, Y: 400, Width: 20, Height: 12, Paragraph: true, Opacity: 1})
+		},
+		func() (*model.PdfAnnotation, error) {
+			return CreateStampAnnotation(StampAnnotationDef{X: 100, Y: 200, Width: 200, Height: 50, Name: StampNotApproved, Opacity: 1})
+		},
+	}
+
+	page := model.NewPdfPage()
+	for _, f := range create {
+		annot, err := f()
+		require.NoError(t, err)
+
+		apDict, ok := core.GetDict(annot.AP)
+		require.True(t, ok)
+		stream, ok := core.GetStream(apDict.Get("N"))
+		require.True(t, ok)
+		bbox, ok := core.GetArray(stream.Get("BBox"))
+		require.True(t, ok)
+		rect, ok := core.GetArray(annot.Rect)
+		require.True(t, ok)
+		bboxVals, err := bbox.ToFloat64Array()
+		require.NoError(t, err)
+		rectVals, err := rect.ToFloat64Array()
+		require.NoError(t, err)
+		require.Equal(t, []float64{0, 0, rectVals[2] - rectVals[0], rectVals[3] - rectVals[1]}, bboxVals)
+		requireVisibleAppearance(t, annot, stream)
+
+		page.AddAnnotation(annot)
+	}
+
+	var buf bytes.Buffer
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	readPage, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := readPage.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, len(create))
+}
+
+// requireVisibleAppearance checks that the appearance stream `stream` of the annotation `annot`
+// paints something, and that neither the annotation nor the graphics states of the appearance
+// make it fully transparent.
+func requireVisibleAppearance(t *testing.T, annot *model.PdfAnnotation, stream *core.PdfObjectStream) {
+	annotDict, ok := core.GetDict(annot.GetContainingPdfObject())
+	require.True(t, ok)
+	if ca, err := core.GetNumberAsFloat(annotDict.Get("CA")); err == nil {
+		require.NotZero(t, ca)
+	}
+
+	data, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	require.NoError(t, err)
+	painted := false
+	for _, op := range *ops {
+		switch op.Operand {
+		case "f", "f*", "F", "B", "B*", "b", "b*", "S", "s", "Tj", "TJ", "Do":
+			painted = true
+		}
+	}
+	require.True(t, painted, "no painting operator in appearance: %s", data)
+
+	resources, ok := core.GetDict(stream.Get("Resources"))
+	if !ok {
+		return
+	}
+	extGStates, ok := core.GetDict(resources.Get("ExtGState"))
+	if !ok {
+		return
+	}
+	for _, name := range extGStates.Keys() {
+		gs, ok := core.GetDict(extGStates.Get(name))
+		require.True(t, ok)
+		for _, key := range []core.PdfObjectName{"CA", "ca"} {
+			if val, err := core.GetNumberAsFloat(gs.Get(key)); err == nil {
+				require.NotZero(t, val, "%s of graphics state %s", key, name)
+			}
+		}
+	}
+}
+
+func TestFreeTextStyle(t *testing.T) {
+	annot := model.NewPdfAnnotationFreeText()
+	annot.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 50})
+	annot.Contents = core.MakeString("text")
+	annot.DS = core.MakeString("font: Helvetica 9pt; text-align:right; color:#FF0000")
+
+	style := parseFreeTextStyle(annot)
+	require.Equal(t, 9.0, style.fontSize)
+	require.Equal(t, TextAlignmentRight, style.alignment)
+	rgb, ok := style.textColor.(*model.PdfColorDeviceRGB)
+	require.True(t, ok)
+	require.Equal(t, 1.0, rgb.R())
+	require.Equal(t, 0.0, rgb.G())
+
+	// DA has precedence over DS.
+	annot.DA = core.MakeString("/Cour 14 Tf 0 0 1 rg")
+	style = parseFreeTextStyle(annot)
+	require.Equal(t, 14.0, style.fontSize)
+	require.Equal(t, "Cour", style.fontName)
+
+	require.NoError(t, GenerateMarkupAppearance(annot.PdfAnnotation))
+}
+
+func TestWrapText(t *testing.T) {
+	font := model.NewStandard14FontMustCompile(model.CourierName)
+	// Courier glyphs are 600 units wide: 10 characters per line of 60 points at size 10.
+	lines := wrapText("aaaa bbbb cccc dddddddddddddd\nee", font, 10, 60)
+
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.text)
+	}
+	require.Equal(t, "aaaa bbbb|cccc|dddddddddd|dddd|ee", strings.Join(texts, "|"))
+	require.InDelta(t, 54, lines[0].width, 1e-9)
+}
+
+func TestStampLabel(t *testing.T) {
+	require.Equal(t, "NOT FOR PUBLIC RELEASE", stampLabel(StampNotForPublicRelease))
+	require.Equal(t, "AS IS", stampLabel(StampAsIs))
+}
@@ -0,0 +1,181 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// NoteIcon represents the icon of text (sticky note) annotations.
+type NoteIcon string
+
+// Text annotation icons.
+const (
+	NoteIconNote         NoteIcon = "Note"
+	NoteIconComment      NoteIcon = "Comment"
+	NoteIconKey          NoteIcon = "Key"
+	NoteIconHelp         NoteIcon = "Help"
+	NoteIconNewParagraph NoteIcon = "NewParagraph"
+	NoteIconParagraph    NoteIcon = "Paragraph"
+	NoteIconInsert       NoteIcon = "Insert"
+)
+
+// noteIconSize is the size of the text annotation icons.
+const noteIconSize = 20
+
+// TextAnnotationDef defines a text annotation (sticky note) displayed as an icon with the lower left
+// corner at (X,Y). The contents are displayed in a pop-up window by the viewers.
+type TextAnnotationDef struct {
+	X        float64
+	Y        float64
+	Icon     NoteIcon // Note if empty.
+	Color    *pdf.PdfColorDeviceRGB
+	Contents string
+	Author   string
+	Open     bool
+}
+
+// CreateTextAnnotation creates a text annotation object with appearance stream that can be added to
+// page PDF annotations.
+func CreateTextAnnotation(noteDef TextAnnotationDef) (*pdf.PdfAnnotation, error) {
+	icon := noteDef.Icon
+	if icon == "" {
+		icon = NoteIconNote
+	}
+	color := noteDef.Color
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(1, 0.82, 0)
+	}
+
+	annot := pdf.NewPdfAnnotationText()
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		noteDef.X, noteDef.Y, noteDef.X + noteIconSize, noteDef.Y + noteIconSize,
+	})
+	annot.Name = pdfcore.MakeName(string(icon))
+	annot.Open = pdfcore.MakeBool(noteDef.Open)
+	annot.C = makeColorArray(color)
+	if noteDef.Contents != "" {
+		annot.Contents = pdfcore.MakeString(noteDef.Contents)
+	}
+	// The icons keep their size and orientation when the page is zoomed or rotated.
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint | annotationFlagNoZoom | annotationFlagNoRotate)
+	setMarkupFields(annot.PdfAnnotationMarkup, noteDef.Author, "", 1.0)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// makeTextAnnotationAppearanceStream generates the appearance stream of a text annotation: the icon
+// specified by its Name, drawn at the lower left corner of its rectangle.
+func makeTextAnnotationAppearanceStream(annot *pdf.PdfAnnotationText) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	rect, err := annotationRect(annot.PdfAnnotation)
+	if err != nil {
+		return nil, nil, err
+	}
+	bbox := &pdf.PdfRectangle{Llx: rect.Llx, Lly: rect.Lly, Urx: rect.Llx + noteIconSize, Ury: rect.Lly + noteIconSize}
+
+	color := annotationColor(annot.C)
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(1, 0.82, 0)
+	}
+	icon := NoteIconNote
+	if name, ok := pdfcore.GetNameVal(annot.Name); ok {
+		icon = NoteIcon(name)
+	}
+
+	resources := pdf.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(annot.PdfAnnotationMarkup), ""); err != nil {
+		return nil, nil, err
+	}
+	cc.Add_cm(1, 0, 0, 1, bbox.Llx, bbox.Lly)
+	drawNoteIcon(cc, icon, color)
+	cc.Add_Q()
+
+	apDict, err := makeAppearanceDict(cc.Bytes(), bbox, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, bbox, nil
+}
+
+// drawNoteIcon draws the text annotation `icon` in the 20x20 box at the origin, filled with `color`
+// and outlined in black. Unknown icons are drawn as notes.
+func drawNoteIcon(cc *contentstream.ContentCreator, icon NoteIcon, color pdf.PdfColor) {
+	black := pdf.NewPdfColorDeviceGray(0)
+	cc.SetNonStrokingColor(color).SetStrokingColor(black).Add_w(1)
+	addLineStyle(cc, 1, 1)
+
+	switch icon {
+	case NoteIconComment:
+		// Speech bubble.
+		cc.Add_m(2.5, 17.5).Add_l(17.5, 17.5).Add_l(17.5, 6.5).Add_l(9, 6.5).
+			Add_l(5, 2.5).Add_l(5.5, 6.5).Add_l(2.5, 6.5).Add_h().Add_B()
+		for _, y := range []float64{14, 10} {
+			cc.Add_m(5.5, y).Add_l(14.5, y)
+		}
+		cc.Add_S()
+	case NoteIconKey:
+		// Key with a round head.
+		drawCircle(cc, 6.5, 13.5, 4.5)
+		cc.Add_B()
+		drawCircle(cc, 5.5, 14.5, 1.2)
+		cc.SetNonStrokingColor(black).Add_f()
+		cc.SetNonStrokingColor(color).
+			Add_m(9.5, 10.5).Add_l(17.5, 2.5).Add_l(17.5, 5.5).Add_l(15.5, 5.5).
+			Add_l(15.5, 7.5).Add_l(13.5, 7.5).Add_l(11.5, 9.5).Add_h().Add_B()
+	case NoteIconHelp:
+		// Question mark in a circle.
+		drawCircle(cc, 10, 10, 8.5)
+		cc.Add_B()
+		cc.Add_w(2).
+			Add_m(7, 12.5).Add_c(7, 16.5, 13, 16.5, 13, 12.5).
+			Add_c(13, 10, 10, 10.5, 10, 7.5).Add_S()
+		drawCircle(cc, 10, 4.5, 1.2)
+		cc.SetNonStrokingColor(black).Add_f()
+	case NoteIconParagraph, NoteIconNewParagraph:
+		// Pilcrow, on a colored page.
+		cc.Add_re(2.5, 1.5, 15, 17).Add_B()
+		cc.SetNonStrokingColor(black).
+			Add_m(9.5, 5).Add_l(9.5, 10.5).Add_c(6, 10.5, 6, 16, 9.5, 16).
+			Add_l(14, 16).Add_l(14, 15).Add_l(12.5, 15).Add_l(12.5, 5).
+			Add_l(11.5, 5).Add_l(11.5, 15).Add_l(10.5, 15).Add_l(10.5, 5).Add_h().Add_f()
+		if icon == NoteIconNewParagraph {
+			// Insertion mark under the paragraph symbol.
+			cc.Add_m(4.5, 3).Add_l(6.5, 6.5).Add_l(8.5, 3).Add_S()
+		}
+	case NoteIconInsert:
+		// Caret.
+		cc.Add_m(2.5, 2.5).Add_l(10, 17.5).Add_l(17.5, 2.5).Add_l(14, 2.5).
+			Add_l(10, 10.5).Add_l(6, 2.5).Add_h().Add_B()
+	default:
+		// Page with a folded corner and text lines.
+		cc.Add_m(3.5, 1.5).Add_l(3.5, 18.5).Add_l(12.5, 18.5).Add_l(16.5, 14.5).
+			Add_l(16.5, 1.5).Add_h().Add_B()
+		cc.Add_m(12.5, 18.5).Add_l(12.5, 14.5).Add_l(16.5, 14.5)
+		for _, y := range []float64{11.5, 8.5, 5.5} {
+			cc.Add_m(6, y).Add_l(14, y)
+		}
+		cc.Add_S()
+	}
+}
+
+// drawCircle adds a circle path with center (x, y) and radius `r` to `cc`.
+func drawCircle(cc *contentstream.ContentCreator, x, y, r float64) {
+	// Bezier approximation of the circle.
+	k := 0.5523 * r
+	cc.Add_m(x+r, y).
+		Add_c(x+r, y+k, x+k, y+r, x, y+r).
+		Add_c(x-k, y+r, x-r, y+k, x-r, y).
+		Add_c(x-r, y-k, x-k, y-r, x, y-r).
+		Add_c(x+k, y-r, x+r, y-k, x+r, y).
+		Add_h()
+}
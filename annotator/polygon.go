@@ -0,0 +1,179 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// PolygonAnnotationDef defines a closed polygon with the vertices specified by Vertices
+// (x1, y1, x2, y2, ...). The polygon can optionally have a border and a filling color.
+type PolygonAnnotationDef struct {
+	Vertices    []float64
+	FillColor   *pdf.PdfColorDeviceRGB // No fill if nil.
+	BorderColor *pdf.PdfColorDeviceRGB // No border if nil.
+	BorderWidth float64
+	BorderDash  []int64 // Dash pattern of the border (solid if empty).
+	Opacity     float64 // Alpha value (0-1).
+}
+
+// PolyLineAnnotationDef defines an open polyline with the vertices specified by Vertices
+// (x1, y1, x2, y2, ...). The line ending styles apply to the first and last vertices, and the
+// closed line endings are filled with FillColor, if set.
+type PolyLineAnnotationDef struct {
+	Vertices         []float64
+	LineColor        *pdf.PdfColorDeviceRGB
+	LineWidth        float64
+	LineDash         []int64    // Dash pattern of the line (solid if empty).
+	LineEndingStyle1 LineEnding // Line ending style of the first vertex.
+	LineEndingStyle2 LineEnding // Line ending style of the last vertex.
+	FillColor        *pdf.PdfColorDeviceRGB
+	Opacity          float64 // Alpha value (0-1).
+}
+
+// CreatePolygonAnnotation creates a polygon annotation object with appearance stream that can be added
+// to page PDF annotations.
+func CreatePolygonAnnotation(polyDef PolygonAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if len(polyDef.Vertices) < 6 || len(polyDef.Vertices)%2 != 0 {
+		return nil, errors.New("invalid number of polygon vertices")
+	}
+
+	annot := pdf.NewPdfAnnotationPolygon()
+	annot.Vertices = pdfcore.MakeArrayFromFloats(polyDef.Vertices)
+	annot.C = makeColorArray(polyDef.BorderColor)
+	annot.IC = makeColorArray(polyDef.FillColor)
+	annot.BS = makeBorderStyle(polyDef.BorderWidth, polyDef.BorderDash)
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, "", "", polyDef.Opacity)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// CreatePolyLineAnnotation creates a polyline annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreatePolyLineAnnotation(lineDef PolyLineAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if len(lineDef.Vertices) < 4 || len(lineDef.Vertices)%2 != 0 {
+		return nil, errors.New("invalid number of polyline vertices")
+	}
+
+	annot := pdf.NewPdfAnnotationPolyLine()
+	annot.Vertices = pdfcore.MakeArrayFromFloats(lineDef.Vertices)
+	annot.LE = makeLineEndings(lineDef.LineEndingStyle1, lineDef.LineEndingStyle2)
+	lineColor := lineDef.LineColor
+	if lineColor == nil {
+		lineColor = pdf.NewPdfColorDeviceRGB(0, 0, 0)
+	}
+	annot.C = makeColorArray(lineColor)
+	if lineDef.FillColor != nil {
+		annot.IC = makeColorArray(lineDef.FillColor)
+	}
+	annot.BS = makeBorderStyle(lineDef.LineWidth, lineDef.LineDash)
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, "", "", lineDef.Opacity)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// makePolyAnnotationAppearanceStream generates the appearance stream of Polygon (`closed`) and
+// PolyLine annotations from their vertices, border style (`bsObj`), line endings (`leObj`) and
+// interior color (`icObj`).
+func makePolyAnnotationAppearanceStream(verticesObj pdfcore.PdfObject, closed bool, leObj, bsObj, icObj pdfcore.PdfObject,
+	annot *pdf.PdfAnnotation, markup *pdf.PdfAnnotationMarkup) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	arr, ok := pdfcore.GetArray(verticesObj)
+	if !ok {
+		return nil, nil, errors.New("vertices missing")
+	}
+	vertices, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(vertices) < 4 {
+		return nil, nil, errors.New("invalid number of vertices")
+	}
+
+	stroke := annotationColor(annot.C)
+	fill := annotationColor(icObj)
+	lineWidth, dash := annotationBorderStyle(bsObj)
+	if lineWidth <= 0 {
+		stroke = nil
+	}
+
+	resources := pdf.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(markup), ""); err != nil {
+		return nil, nil, err
+	}
+	if stroke != nil {
+		cc.SetStrokingColor(stroke).Add_w(lineWidth)
+		if len(dash) > 0 {
+			cc.Add_d(dash, 0)
+		}
+	}
+	if closed && fill != nil {
+		cc.SetNonStrokingColor(fill)
+	}
+
+	drawPolyline(cc, vertices)
+	margin := lineWidth/2 + 1
+	switch {
+	case closed && stroke != nil && fill != nil:
+		cc.Add_b()
+	case closed && stroke != nil:
+		cc.Add_s()
+	case closed && fill != nil:
+		cc.Add_h().Add_f()
+	case stroke != nil:
+		cc.Add_S()
+	default:
+		cc.Add_n()
+	}
+
+	if !closed && stroke != nil {
+		le1, le2 := lineEndings(leObj)
+		n := len(vertices)
+		if dash != nil {
+			cc.Add_d([]int64{}, 0)
+		}
+		drawLineEnding(cc, le1, vertices[0], vertices[1],
+			math.Atan2(vertices[1]-vertices[3], vertices[0]-vertices[2]), lineWidth, fill)
+		drawLineEnding(cc, le2, vertices[n-2], vertices[n-1],
+			math.Atan2(vertices[n-1]-vertices[n-3], vertices[n-2]-vertices[n-4]), lineWidth, fill)
+		if le1 != LineEndingNone || le2 != LineEndingNone {
+			margin += lineEndingSize(lineWidth)
+		}
+	}
+	cc.Add_Q()
+
+	bbox := pointsBBox(vertices, margin)
+	apDict, err := makeAppearanceDict(cc.Bytes(), bbox, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, bbox, nil
+}
+
+// drawPolyline adds the path through the points `points` (x1, y1, x2, y2, ...) to `cc`.
+func drawPolyline(cc *contentstream.ContentCreator, points []float64) {
+	for i := 0; i+1 < len(points); i += 2 {
+		if i == 0 {
+			cc.Add_m(points[i], points[i+1])
+		} else {
+			cc.Add_l(points[i], points[i+1])
+		}
+	}
+}
@@ -0,0 +1,419 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"crypto/x509"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// DefaultSignatureAppearanceText is the default text of signature appearances.
+const DefaultSignatureAppearanceText = "Digitally signed by {name}\nDate: {date}\nReason: {reason}\nLocation: {location}"
+
+// SignatureImagePosition represents the position of the signature image relative to the text
+// of a signature appearance.
+type SignatureImagePosition int
+
+const (
+	// SignatureImageLeft places the image on the left half of the appearance, and the text
+	// on the right half.
+	SignatureImageLeft SignatureImagePosition = iota
+
+	// SignatureImageTop places the image on the top half of the appearance, and the text on
+	// the bottom half.
+	SignatureImageTop
+)
+
+// SignatureAppearance represents the layout of a visible signature appearance, made of a
+// handwritten signature image, a watermark and a text. The appearance is set in the Appearance
+// field of SignatureFieldOpts. The fonts, colors and borders of the appearance are the ones of
+// the options.
+//
+// The text may contain the following placeholders, replaced by the details of the signature
+// when the signature field is created, so that the field is to be created at signing time:
+//
+//	{name}     - the common name of the signer certificate, or the name of the signature.
+//	{date}     - the signing date of the signature, or the current time.
+//	{reason}   - the reason of the signature.
+//	{location} - the location of the signature.
+//	{contact}  - the contact information of the signature.
+//
+// The lines whose placeholders are all empty are omitted.
+type SignatureAppearance struct {
+	// Image is the handwritten signature image, whose alpha channel, if any, is preserved.
+	Image *model.Image
+
+	// ImagePosition is the position of the image relative to the text.
+	ImagePosition SignatureImagePosition
+
+	// Watermark is an image, such as a company logo, drawn in the background of the appearance.
+	Watermark *model.Image
+
+	// WatermarkOpacity is the opacity of the watermark, between 0 and 1. 0.2 by default.
+	WatermarkOpacity float64
+
+	// Text is the text of the appearance, wrapped to fit its area. New lines start new
+	// paragraphs.
+	Text string
+
+	// Certificate is the signer certificate, whose common name replaces the {name}
+	// placeholder.
+	Certificate *x509.Certificate
+
+	// DateFormat is the layout of the {date} placeholder, as defined by time.Time.Format.
+	DateFormat string
+
+	// MinFontSize is the minimum size of the text when auto sized.
+	MinFontSize float64
+
+	// Padding is the space between the border of the appearance and its contents.
+	Padding float64
+}
+
+// NewSignatureAppearance returns a new signature appearance with the default text.
+func NewSignatureAppearance() *SignatureAppearance {
+	return &SignatureAppearance{
+		WatermarkOpacity: 0.2,
+		Text:             DefaultSignatureAppearanceText,
+		DateFormat:       "2006-01-02 15:04:05 -07:00",
+		MinFontSize:      4,
+		Padding:          2,
+	}
+}
+
+// resolveText returns the text of the appearance, with the placeholders replaced by the
+// details of `signature`.
+func (a *SignatureAppearance) resolveText(signature *model.PdfSignature) string {
+	decoded := func(str *core.PdfObjectString) string {
+		if str == nil {
+			return ""
+		}
+		return str.Decoded()
+	}
+
+	name := decoded(signature.Name)
+	if a.Certificate != nil && a.Certificate.Subject.CommonName != "" {
+		name = a.Certificate.Subject.CommonName
+	}
+	date := time.Now()
+	if signature.M != nil {
+		if d, err := model.NewPdfDate(signature.M.Decoded()); err == nil {
+			date = d.ToGoTime()
+		}
+	}
+	format := a.DateFormat
+	if format == "" {
+		format = "2006-01-02 15:04:05 -07:00"
+	}
+
+	values := map[string]string{
+		"{name}":     name,
+		"{date}":     date.Format(format),
+		"{reason}":   decoded(signature.Reason),
+		"{location}": decoded(signature.Location),
+		"{contact}":  decoded(signature.ContactInfo),
+	}
+	var lines []string
+	for _, line := range strings.Split(a.Text, "\n") {
+		placeholders, empty := 0, 0
+		for placeholder, value := range values {
+			if n := strings.Count(line, placeholder); n > 0 {
+				placeholders++
+				if value == "" {
+					empty++
+				}
+				line = strings.Replace(line, placeholder, value, -1)
+			}
+		}
+		if placeholders > 0 && placeholders == empty {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signatureFont returns the font of the signature appearances with the options `opts`, and
+// its resource name.
+func signatureFont(opts *SignatureFieldOpts) (*model.PdfFont, core.PdfObjectName, error) {
+	font := opts.Font
+	if font == nil {
+		font, err := model.NewStandard14Font("Helvetica")
+		if err != nil {
+			return nil, "", err
+		}
+		return font, "Helv", nil
+	}
+
+	descriptor, _ := font.GetFontDescriptor()
+	if descriptor != nil {
+		if name, ok := descriptor.FontName.(*core.PdfObjectName); ok {
+			return font, *name, nil
+		}
+	}
+	return font, "Font1", nil
+}
+
+// layoutText returns the lines of `text` wrapped in an area of size `width`x`height`, and
+// the font size. If `autoSize` is true, the font size is decreased from `fontSize` down to
+// `minFontSize` until the text fits.
+func layoutText(font *model.PdfFont, text string, fontSize, minFontSize, lineHeight, width, height float64,
+	autoSize bool) ([]textLine, float64) {
+	lines := wrapText(text, font, fontSize, width)
+	if !autoSize {
+		return lines, fontSize
+	}
+
+	// The wrapped lines fit the width, unless a single glyph is wider.
+	fits := func(lines []textLine, size float64) bool {
+		if float64(len(lines))*size*lineHeight > height {
+			return false
+		}
+		for _, line := range lines {
+			if line.width > width {
+				return false
+			}
+		}
+		return true
+	}
+	for !fits(lines, fontSize) && fontSize > minFontSize {
+		fontSize = math.Max(fontSize-0.5, minFontSize)
+		lines = wrapText(text, font, fontSize, width)
+	}
+	return lines, fontSize
+}
+
+// fitImage draws the XObject image `name` of `img`, scaled to fit in the area at (`x`, `y`)
+// of size `width`x`height` with its aspect ratio preserved, and centered in the area.
+func fitImage(cc *contentstream.ContentCreator, name core.PdfObjectName, img *model.Image,
+	x, y, width, height float64) {
+	if img.Width == 0 || img.Height == 0 || width <= 0 || height <= 0 {
+		return
+	}
+	scale := math.Min(width/float64(img.Width), height/float64(img.Height))
+	w, h := float64(img.Width)*scale, float64(img.Height)*scale
+	cc.Add_q().
+		Add_cm(w, 0, 0, h, x+(width-w)/2, y+(height-h)/2).
+		Add_Do(name).
+		Add_Q()
+}
+
+// makeLayerXObjectForm returns a form XObject of size `width`x`height`, drawing the form
+// XObjects `names` of `layers`.
+func makeLayerXObjectForm(width, height float64, names []core.PdfObjectName,
+	layers []*model.XObjectForm) (*model.XObjectForm, error) {
+	cc := contentstream.NewContentCreator()
+	xform := model.NewXObjectForm()
+	xform.Resources = model.NewPdfPageResources()
+	for i, name := range names {
+		cc.Add_q().
+			Add_cm(1, 0, 0, 1, 0, 0).
+			Add_Do(name).
+			Add_Q()
+		if err := xform.Resources.SetXObjectFormByName(name, layers[i]); err != nil {
+			return nil, err
+		}
+	}
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, width, height})
+	if err := xform.SetContentStream(cc.Bytes(), defStreamEncoder()); err != nil {
+		return nil, err
+	}
+	return xform, nil
+}
+
+// genSignatureAppearance generates the layered appearance dictionary of a signature widget
+// with the appearance of `opts`, completed by the signature lines `fields`.
+// The normal appearance draws the form XObject FRM, which draws the layers n0 (background,
+// empty) and n2 (signature appearance), as described in the Adobe Digital Signature
+// Appearances specification.
+func genSignatureAppearance(signature *model.PdfSignature, fields []*SignatureLine,
+	opts *SignatureFieldOpts) (*core.PdfObjectDictionary, error) {
+	appearance := opts.Appearance
+	if len(opts.Rect) != 4 {
+		return nil, errors.New("signature appearance requires a rectangle")
+	}
+	width := math.Abs(opts.Rect[2] - opts.Rect[0])
+	height := math.Abs(opts.Rect[3] - opts.Rect[1])
+
+	font, fontName, err := signatureFont(opts)
+	if err != nil {
+		return nil, err
+	}
+	fontSize := opts.FontSize
+	if fontSize <= 0 {
+		fontSize = 10
+	}
+	lineHeight := opts.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = 1
+	}
+	textColor := opts.TextColor
+	if textColor == nil {
+		textColor = model.NewPdfColorDeviceGray(0)
+	}
+
+	text := appearance.resolveText(signature)
+	for _, field := range fields {
+		if field.Text == "" {
+			continue
+		}
+		line := field.Text
+		if field.Desc != "" {
+			line = field.Desc + ": " + line
+		}
+		if text != "" {
+			text += "\n"
+		}
+		text += line
+	}
+
+	resources := model.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+
+	// Background and border.
+	border := math.Max(opts.BorderSize, 0)
+	if opts.FillColor != nil {
+		cc.Add_q().
+			SetNonStrokingColor(opts.FillColor).
+			Add_re(0, 0, width, height).
+			Add_f().
+			Add_Q()
+	}
+	if border > 0 && opts.BorderColor != nil {
+		cc.Add_q().
+			Add_w(border).
+			SetStrokingColor(opts.BorderColor).
+			Add_re(border/2, border/2, width-border, height-border).
+			Add_S().
+			Add_Q()
+	}
+
+	padding := math.Max(appearance.Padding, 0) + border
+	x, y := padding, padding
+	w, h := width-2*padding, height-2*padding
+
+	// Watermark.
+	if appearance.Watermark != nil {
+		ximg, err := model.NewXObjectImageFromImage(appearance.Watermark, nil, core.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		if err := resources.SetXObjectImageByName("Wm", ximg); err != nil {
+			return nil, err
+		}
+		opacity := appearance.WatermarkOpacity
+		if opacity <= 0 || opacity > 1 {
+			opacity = 0.2
+		}
+		gs := core.MakeDict()
+		gs.Set("ca", core.MakeFloat(opacity))
+		gs.Set("CA", core.MakeFloat(opacity))
+		if err := resources.AddExtGState("GS0", gs); err != nil {
+			return nil, err
+		}
+		cc.Add_q().Add_gs("GS0")
+		fitImage(cc, "Wm", appearance.Watermark, x, y, w, h)
+		cc.Add_Q()
+	}
+
+	// Signature image, sharing the area with the text.
+	textX, textY, textW, textH := x, y, w, h
+	if appearance.Image != nil {
+		ximg, err := model.NewXObjectImageFromImage(appearance.Image, nil, core.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		if err := resources.SetXObjectImageByName("Img", ximg); err != nil {
+			return nil, err
+		}
+
+		imgX, imgY, imgW, imgH := x, y, w, h
+		if text != "" {
+			switch appearance.ImagePosition {
+			case SignatureImageTop:
+				imgH = h / 2
+				imgY = y + h - imgH
+				textH = h - imgH - padding
+			default:
+				imgW = w / 2
+				textX = x + imgW + padding
+				textW = w - imgW - padding
+			}
+		}
+		fitImage(cc, "Img", appearance.Image, imgX, imgY, imgW, imgH)
+	}
+
+	// Text, vertically centered in its area.
+	if text != "" && textW > 0 && textH > 0 {
+		minFontSize := appearance.MinFontSize
+		if minFontSize <= 0 {
+			minFontSize = 4
+		}
+		lines, size := layoutText(font, text, fontSize, math.Min(minFontSize, fontSize),
+			lineHeight, textW, textH, opts.AutoSize)
+		leading := size * lineHeight
+		offsetY := math.Max((textH-float64(len(lines))*leading)/2, 0)
+
+		if err := resources.SetFontByName(fontName, font.ToPdfObject()); err != nil {
+			return nil, err
+		}
+		cc.Add_q().
+			Add_re(textX, textY, textW, textH).
+			Add_W().
+			Add_n().
+			Add_BT().
+			SetNonStrokingColor(textColor).
+			Add_Tf(fontName, size).
+			Add_TL(leading).
+			Add_Td(textX, textY+textH-offsetY-size)
+		for i, line := range lines {
+			if i > 0 {
+				cc.Add_Td(0, -leading)
+			}
+			encoded, _ := font.StringToCharcodeBytes(line.text)
+			cc.Add_Tj(*core.MakeStringFromBytes(encoded))
+		}
+		cc.Add_ET().Add_Q()
+	}
+
+	// Signature appearance layer.
+	n2 := model.NewXObjectForm()
+	n2.Resources = resources
+	n2.BBox = core.MakeArrayFromFloats([]float64{0, 0, width, height})
+	if err := n2.SetContentStream(cc.Bytes(), defStreamEncoder()); err != nil {
+		return nil, err
+	}
+
+	// Background layer, empty as recommended since PDF 1.5.
+	n0 := model.NewXObjectForm()
+	n0.Resources = model.NewPdfPageResources()
+	n0.BBox = core.MakeArrayFromFloats([]float64{0, 0, width, height})
+	if err := n0.SetContentStream([]byte("% DSBlank\n"), defStreamEncoder()); err != nil {
+		return nil, err
+	}
+
+	frm, err := makeLayerXObjectForm(width, height, []core.PdfObjectName{"n0", "n2"},
+		[]*model.XObjectForm{n0, n2})
+	if err != nil {
+		return nil, err
+	}
+	xform, err := makeLayerXObjectForm(width, height, []core.PdfObjectName{"FRM"},
+		[]*model.XObjectForm{frm})
+	if err != nil {
+		return nil, err
+	}
+
+	apDict := core.MakeDict()
+	apDict.Set("N", xform.ToPdfObject())
+	return apDict, nil
+}
@@ -0,0 +1,123 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// newTestSignatureImage returns an image with a transparent background.
+func newTestSignatureImage(t *testing.T, width, height int) *model.Image {
+	goimg := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		goimg.Set(x, height/2, color.NRGBA{B: 255, A: 255})
+	}
+	img, err := model.ImageHandling.NewImageFromGoImage(goimg)
+	require.NoError(t, err)
+	return img
+}
+
+// appearanceLayer returns the form XObject `name` of the resources of `xform`, and its content.
+func appearanceLayer(t *testing.T, xform *model.XObjectForm, name core.PdfObjectName) (*model.XObjectForm, string) {
+	layer, err := xform.Resources.GetXObjectFormByName(name)
+	require.NoError(t, err)
+	require.NotNil(t, layer, name)
+	content, err := layer.GetContentStream()
+	require.NoError(t, err)
+	return layer, string(content)
+}
+
+func TestSignatureAppearance(t *testing.T) {
+	signature := model.NewPdfSignature(nil)
+	signature.SetName("Signature Name")
+	signature.SetReason("Approval")
+	signature.SetDate(time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC), "")
+
+	appearance := NewSignatureAppearance()
+	appearance.Image = newTestSignatureImage(t, 120, 40)
+	appearance.Watermark = newTestSignatureImage(t, 50, 50)
+	appearance.Certificate = &x509.Certificate{Subject: pkix.Name{CommonName: "John Doe"}}
+	appearance.DateFormat = "2006-01-02"
+
+	opts := NewSignatureFieldOpts()
+	opts.Rect = []float64{100, 100, 500, 180}
+	opts.BorderSize = 1
+	opts.Appearance = appearance
+	field, err := NewSignatureField(signature, []*SignatureLine{NewSignatureLine("Department", "Sales")}, opts)
+	require.NoError(t, err)
+
+	// Layered appearance: N -> FRM -> n0, n2.
+	ap, ok := core.GetDict(field.AP)
+	require.True(t, ok)
+	stream, ok := core.GetStream(ap.Get("N"))
+	require.True(t, ok)
+	xform, err := model.NewXObjectFormFromStream(stream)
+	require.NoError(t, err)
+	bbox, ok := core.GetArray(xform.BBox)
+	require.True(t, ok)
+	values, err := bbox.ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 0, 400, 80}, values)
+	frm, _ := appearanceLayer(t, xform, "FRM")
+	_, n0 := appearanceLayer(t, frm, "n0")
+	require.Equal(t, "% DSBlank\n", n0)
+	n2, content := appearanceLayer(t, frm, "n2")
+
+	// Images, the signature image keeping its alpha channel.
+	ximg, err := n2.Resources.GetXObjectImageByName("Img")
+	require.NoError(t, err)
+	require.NotNil(t, ximg.SMask)
+	ximg, err = n2.Resources.GetXObjectImageByName("Wm")
+	require.NoError(t, err)
+	require.NotNil(t, ximg)
+	_, ok = n2.Resources.GetExtGState("GS0")
+	require.True(t, ok)
+	require.Contains(t, content, "/Img Do")
+	require.Contains(t, content, "/GS0 gs")
+
+	// Dynamic fields, the empty location line being omitted.
+	require.Contains(t, content, "(Digitally signed by John Doe)")
+	require.Contains(t, content, "(Date: 2020-05-17)")
+	require.Contains(t, content, "(Reason: Approval)")
+	require.Contains(t, content, "(Department: Sales)")
+	require.NotContains(t, content, "Location")
+
+	// Rectangle required.
+	opts.Rect = nil
+	_, err = NewSignatureField(signature, nil, opts)
+	require.Error(t, err)
+}
+
+func TestSignatureAppearanceText(t *testing.T) {
+	signature := model.NewPdfSignature(nil)
+	signature.SetName("Signature Name")
+	appearance := &SignatureAppearance{Text: "Signed by {name} {contact}\nContact: {contact}"}
+	require.Equal(t, "Signed by Signature Name ", appearance.resolveText(signature))
+
+	// Auto sized text, wrapped to fit the area.
+	font := model.DefaultFont()
+	text := strings.Repeat("wrapped text ", 20)
+	lines, size := layoutText(font, text, 12, 4, 1, 100, 50, true)
+	require.True(t, size < 12)
+	require.True(t, float64(len(lines))*size <= 50)
+	for _, line := range lines {
+		require.True(t, line.width <= 100)
+	}
+	lines, size = layoutText(font, text, 12, 4, 1, 100, 50, false)
+	require.Equal(t, 12.0, size)
+	require.True(t, float64(len(lines))*size > 50)
+}
@@ -0,0 +1,203 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// StampName represents the standard names of rubber stamp annotations.
+type StampName string
+
+// Standard stamp names.
+const (
+	StampApproved            StampName = "Approved"
+	StampExperimental        StampName = "Experimental"
+	StampNotApproved         StampName = "NotApproved"
+	StampAsIs                StampName = "AsIs"
+	StampExpired             StampName = "Expired"
+	StampNotForPublicRelease StampName = "NotForPublicRelease"
+	StampConfidential        StampName = "Confidential"
+	StampFinal               StampName = "Final"
+	StampSold                StampName = "Sold"
+	StampDepartmental        StampName = "Departmental"
+	StampForComment          StampName = "ForComment"
+	StampTopSecret           StampName = "TopSecret"
+	StampDraft               StampName = "Draft"
+	StampForPublicRelease    StampName = "ForPublicRelease"
+)
+
+// StampAnnotationDef defines a rubber stamp annotation in the box with the lower left corner at
+// (X,Y). The stamp displays its Name as upper case words, or Text if set. The color depends on the
+// name when Color is not set.
+type StampAnnotationDef struct {
+	X       float64
+	Y       float64
+	Width   float64
+	Height  float64
+	Name    StampName // Draft if empty.
+	Text    string
+	Color   *pdf.PdfColorDeviceRGB
+	Opacity float64 // Alpha value (0-1).
+	Author  string
+}
+
+// CreateStampAnnotation creates a rubber stamp annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreateStampAnnotation(stampDef StampAnnotationDef) (*pdf.PdfAnnotation, error) {
+	if stampDef.Width <= 0 || stampDef.Height <= 0 {
+		return nil, errors.New("invalid stamp annotation size")
+	}
+	name := stampDef.Name
+	if name == "" {
+		name = StampDraft
+	}
+
+	annot := pdf.NewPdfAnnotationStamp()
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		stampDef.X, stampDef.Y, stampDef.X + stampDef.Width, stampDef.Y + stampDef.Height,
+	})
+	annot.Name = pdfcore.MakeName(string(name))
+	if stampDef.Color != nil {
+		annot.C = makeColorArray(stampDef.Color)
+	}
+	if stampDef.Text != "" {
+		annot.Contents = pdfcore.MakeString(stampDef.Text)
+	}
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(annot.PdfAnnotationMarkup, stampDef.Author, string(name), stampDef.Opacity)
+
+	if err := GenerateMarkupAppearance(annot.PdfAnnotation); err != nil {
+		return nil, err
+	}
+	return annot.PdfAnnotation, nil
+}
+
+// stampColor returns the default color of the stamp `name`: green for approvals, red for
+// restrictions and rejections, and blue otherwise.
+func stampColor(name StampName) *pdf.PdfColorDeviceRGB {
+	switch name {
+	case StampApproved, StampFinal, StampForPublicRelease, StampSold:
+		return pdf.NewPdfColorDeviceRGB(0.1, 0.5, 0.1)
+	case StampNotApproved, StampExpired, StampNotForPublicRelease, StampConfidential, StampTopSecret:
+		return pdf.NewPdfColorDeviceRGB(0.75, 0.1, 0.1)
+	}
+	return pdf.NewPdfColorDeviceRGB(0.1, 0.2, 0.6)
+}
+
+// stampLabel returns the label of the stamp `name`: its upper case words, e.g. "NOT APPROVED" for
+// NotApproved.
+func stampLabel(name StampName) string {
+	var b strings.Builder
+	runes := []rune(string(name))
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// makeStampAnnotationAppearanceStream generates the appearance stream of a rubber stamp annotation:
+// a rounded frame with the label of the stamp fitted to the rectangle of the annotation. The label
+// is the Contents of the annotation if set, or is derived from its Name.
+func makeStampAnnotationAppearanceStream(annot *pdf.PdfAnnotationStamp) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	rect, err := annotationRect(annot.PdfAnnotation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rect.Width() <= 0 || rect.Height() <= 0 {
+		return nil, nil, errors.New("invalid stamp annotation size")
+	}
+
+	name := StampDraft
+	if val, ok := pdfcore.GetNameVal(annot.Name); ok {
+		name = StampName(val)
+	}
+	label := annotationText(annot.PdfAnnotation)
+	if label == "" {
+		label = stampLabel(name)
+	}
+	color := annotationColor(annot.C)
+	if color == nil {
+		color = stampColor(name)
+	}
+
+	font := pdf.NewStandard14FontMustCompile(pdf.HelveticaBoldName)
+	resources := pdf.NewPdfPageResources()
+	fontResName := pdfcore.PdfObjectName("HeBo")
+	if err := resources.SetFontByName(fontResName, font.ToPdfObject()); err != nil {
+		return nil, nil, err
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if err := addOpacityGState(cc, resources, annotationOpacity(annot.PdfAnnotationMarkup), ""); err != nil {
+		return nil, nil, err
+	}
+
+	// Frame.
+	lineWidth := math.Max(1, math.Min(rect.Width(), rect.Height())/12)
+	hw := lineWidth / 2
+	r := math.Min(rect.Width(), rect.Height()) / 5
+	drawRoundedRect(cc, rect.Llx+hw, rect.Lly+hw, rect.Urx-hw, rect.Ury-hw, r)
+	cc.SetStrokingColor(color).Add_w(lineWidth).Add_S()
+
+	// Label, scaled to fit the frame.
+	var textWidth float64
+	for _, r := range label {
+		if m, ok := font.GetRuneMetrics(r); ok {
+			textWidth += m.Wx / 1000
+		}
+	}
+	padding := 2 * lineWidth
+	fontSize := (rect.Height() - 2*padding) / 0.75
+	if textWidth > 0 {
+		fontSize = math.Min(fontSize, (rect.Width()-2*padding)/textWidth)
+	}
+	if fontSize > 0 {
+		// The cap height of Helvetica is approximately 0.72 em.
+		x := rect.Llx + (rect.Width()-textWidth*fontSize)/2
+		y := rect.Lly + (rect.Height()-0.72*fontSize)/2
+		encoded, _ := font.StringToCharcodeBytes(label)
+		cc.Add_BT().
+			SetNonStrokingColor(color).
+			Add_Tf(fontResName, fontSize).
+			Add_Td(x, y).
+			Add_Tj(*pdfcore.MakeStringFromBytes(encoded)).
+			Add_ET()
+	}
+	cc.Add_Q()
+
+	apDict, err := makeAppearanceDict(cc.Bytes(), rect, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, rect, nil
+}
+
+// drawRoundedRect adds a rectangle path with rounded corners of radius `r` to `cc`.
+func drawRoundedRect(cc *contentstream.ContentCreator, llx, lly, urx, ury, r float64) {
+	k := 0.5523 * r
+	cc.Add_m(llx+r, lly).
+		Add_l(urx-r, lly).
+		Add_c(urx-r+k, lly, urx, lly+r-k, urx, lly+r).
+		Add_l(urx, ury-r).
+		Add_c(urx, ury-r+k, urx-r+k, ury, urx-r, ury).
+		Add_l(llx+r, ury).
+		Add_c(llx+r-k, ury, llx, ury-r+k, llx, ury-r).
+		Add_l(llx, lly+r).
+		Add_c(llx, lly+r-k, llx+r-k, lly, llx+r, lly).
+		Add_h()
+}
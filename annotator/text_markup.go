@@ -0,0 +1,260 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package annotator
+
+import (
+	"errors"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	pdfcore "github.com/loxiouve/unipdf/v3/core"
+	pdf "github.com/loxiouve/unipdf/v3/model"
+)
+
+// textMarkupType represents the type of a text markup annotation.
+type textMarkupType int
+
+// Text markup annotation types.
+const (
+	textMarkupHighlight textMarkupType = iota
+	textMarkupUnderline
+	textMarkupStrikeOut
+	textMarkupSquiggly
+)
+
+// TextMarkupAnnotationDef defines a text markup annotation (Highlight, Underline, StrikeOut or
+// Squiggly) over the areas specified by QuadPoints. Each area is a quadrilateral specified by 8
+// numbers: the upper left, upper right, lower left and lower right corners, in this order, which
+// is the order used by the common viewers. The quadrilaterals can be rotated with the text.
+type TextMarkupAnnotationDef struct {
+	QuadPoints []float64
+	Color      *pdf.PdfColorDeviceRGB
+	Opacity    float64 // Alpha value (0-1).
+	Contents   string
+	Author     string
+}
+
+// CreateHighlightAnnotation creates a highlight annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreateHighlightAnnotation(def TextMarkupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	annot := pdf.NewPdfAnnotationHighlight()
+	annot.QuadPoints = pdfcore.MakeArrayFromFloats(def.QuadPoints)
+	return createTextMarkupAnnotation(def, annot.PdfAnnotation, annot.PdfAnnotationMarkup)
+}
+
+// CreateUnderlineAnnotation creates an underline annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreateUnderlineAnnotation(def TextMarkupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	annot := pdf.NewPdfAnnotationUnderline()
+	annot.QuadPoints = pdfcore.MakeArrayFromFloats(def.QuadPoints)
+	return createTextMarkupAnnotation(def, annot.PdfAnnotation, annot.PdfAnnotationMarkup)
+}
+
+// CreateStrikeOutAnnotation creates a strikeout annotation object with appearance stream that can be
+// added to page PDF annotations.
+func CreateStrikeOutAnnotation(def TextMarkupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	annot := pdf.NewPdfAnnotationStrikeOut()
+	annot.QuadPoints = pdfcore.MakeArrayFromFloats(def.QuadPoints)
+	return createTextMarkupAnnotation(def, annot.PdfAnnotation, annot.PdfAnnotationMarkup)
+}
+
+// CreateSquigglyAnnotation creates a squiggly underline annotation object with appearance stream that
+// can be added to page PDF annotations.
+func CreateSquigglyAnnotation(def TextMarkupAnnotationDef) (*pdf.PdfAnnotation, error) {
+	annot := pdf.NewPdfAnnotationSquiggly()
+	annot.QuadPoints = pdfcore.MakeArrayFromFloats(def.QuadPoints)
+	return createTextMarkupAnnotation(def, annot.PdfAnnotation, annot.PdfAnnotationMarkup)
+}
+
+// createTextMarkupAnnotation sets the common fields of the text markup annotation and generates its
+// appearance stream.
+func createTextMarkupAnnotation(def TextMarkupAnnotationDef, annot *pdf.PdfAnnotation,
+	markup *pdf.PdfAnnotationMarkup) (*pdf.PdfAnnotation, error) {
+	if len(def.QuadPoints) == 0 || len(def.QuadPoints)%8 != 0 {
+		return nil, errors.New("invalid number of quad points")
+	}
+
+	color := def.Color
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(1, 1, 0)
+	}
+	annot.C = makeColorArray(color)
+	if def.Contents != "" {
+		annot.Contents = pdfcore.MakeString(def.Contents)
+	}
+	annot.F = pdfcore.MakeInteger(annotationFlagPrint)
+	setMarkupFields(markup, def.Author, "", def.Opacity)
+
+	if err := GenerateMarkupAppearance(annot); err != nil {
+		return nil, err
+	}
+	return annot, nil
+}
+
+// makeTextMarkupAppearanceStream generates the appearance stream of a text markup annotation of
+// type `markupType` from its QuadPoints.
+func makeTextMarkupAppearanceStream(markupType textMarkupType, quadPoints pdfcore.PdfObject,
+	annot *pdf.PdfAnnotation, markup *pdf.PdfAnnotationMarkup) (*pdfcore.PdfObjectDictionary, *pdf.PdfRectangle, error) {
+	arr, ok := pdfcore.GetArray(quadPoints)
+	if !ok {
+		return nil, nil, errors.New("quad points missing")
+	}
+	points, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(points) < 8 {
+		return nil, nil, errors.New("invalid number of quad points")
+	}
+
+	color := annotationColor(annot.C)
+	if color == nil {
+		color = pdf.NewPdfColorDeviceRGB(1, 1, 0)
+	}
+
+	resources := pdf.NewPdfPageResources()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+
+	blendMode := ""
+	if markupType == textMarkupHighlight {
+		// Highlights do not hide the underlying text.
+		blendMode = "Multiply"
+	}
+	if err := addOpacityGState(cc, resources, annotationOpacity(markup), blendMode); err != nil {
+		return nil, nil, err
+	}
+
+	// Bounding box of the drawn content, extended by the squiggles and rounded highlight ends.
+	var margin float64
+	for i := 0; i+8 <= len(points); i += 8 {
+		q := newTextQuad(points[i : i+8])
+		height := q.height()
+		if height <= 0 || q.width() <= 0 {
+			continue
+		}
+
+		switch markupType {
+		case textMarkupHighlight:
+			cc.SetNonStrokingColor(color)
+			q.drawHighlight(cc)
+			margin = math.Max(margin, height/4)
+		case textMarkupUnderline:
+			w := math.Max(0.5, height/14)
+			cc.SetStrokingColor(color).Add_w(w)
+			q.drawLine(cc, w/height)
+			cc.Add_S()
+		case textMarkupStrikeOut:
+			w := math.Max(0.5, height/14)
+			cc.SetStrokingColor(color).Add_w(w)
+			q.drawLine(cc, 0.45)
+			cc.Add_S()
+		case textMarkupSquiggly:
+			w := math.Max(0.5, height/20)
+			cc.SetStrokingColor(color).Add_w(w)
+			addLineStyle(cc, 1, 1)
+			q.drawSquiggle(cc)
+			cc.Add_S()
+		}
+	}
+	cc.Add_Q()
+
+	bbox := pointsBBox(points, margin+1)
+	apDict, err := makeAppearanceDict(cc.Bytes(), bbox, resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apDict, bbox, nil
+}
+
+// textQuad represents a quadrilateral covering text, with the origin at the lower left corner.
+// The points are located by their coordinates (s, t) along the baseline vector `u` and the
+// vertical vector `v` of the quadrilateral.
+type textQuad struct {
+	x, y   float64
+	ux, uy float64
+	vx, vy float64
+}
+
+// newTextQuad returns the quadrilateral specified by 8 quad point coordinates (upper left, upper
+// right, lower left and lower right corners).
+func newTextQuad(p []float64) textQuad {
+	return textQuad{
+		x:  p[4],
+		y:  p[5],
+		ux: p[6] - p[4],
+		uy: p[7] - p[5],
+		vx: p[0] - p[4],
+		vy: p[1] - p[5],
+	}
+}
+
+// point returns the point with the quadrilateral coordinates (s, t).
+func (q textQuad) point(s, t float64) (float64, float64) {
+	return q.x + s*q.ux + t*q.vx, q.y + s*q.uy + t*q.vy
+}
+
+// width returns the length of the baseline of the quadrilateral.
+func (q textQuad) width() float64 {
+	return math.Hypot(q.ux, q.uy)
+}
+
+// height returns the height of the quadrilateral.
+func (q textQuad) height() float64 {
+	return math.Hypot(q.vx, q.vy)
+}
+
+// drawHighlight draws the quadrilateral with slightly rounded ends.
+func (q textQuad) drawHighlight(cc *contentstream.ContentCreator) {
+	// The ends are curved outwards by a fraction of the height, relative to the width.
+	k := q.height() / 4 / q.width()
+
+	x, y := q.point(0, 0)
+	cc.Add_m(x, y)
+	x, y = q.point(1, 0)
+	cc.Add_l(x, y)
+	x1, y1 := q.point(1+k, 0.25)
+	x2, y2 := q.point(1+k, 0.75)
+	x, y = q.point(1, 1)
+	cc.Add_c(x1, y1, x2, y2, x, y)
+	x, y = q.point(0, 1)
+	cc.Add_l(x, y)
+	x1, y1 = q.point(-k, 0.75)
+	x2, y2 = q.point(-k, 0.25)
+	x, y = q.point(0, 0)
+	cc.Add_c(x1, y1, x2, y2, x, y)
+	cc.Add_f()
+}
+
+// drawLine draws a line along the quadrilateral at the relative height `t`.
+func (q textQuad) drawLine(cc *contentstream.ContentCreator, t float64) {
+	x, y := q.point(0, t)
+	cc.Add_m(x, y)
+	x, y = q.point(1, t)
+	cc.Add_l(x, y)
+}
+
+// drawSquiggle draws a wavy line along the bottom of the quadrilateral.
+func (q textQuad) drawSquiggle(cc *contentstream.ContentCreator) {
+	height := q.height()
+	amplitude := 1.0 / 12
+	period := height / 6
+	n := int(math.Ceil(q.width() / period * 2))
+	if n < 2 {
+		n = 2
+	}
+
+	x, y := q.point(0, 0)
+	cc.Add_m(x, y)
+	for i := 1; i <= n; i++ {
+		t := 0.0
+		if i%2 == 1 {
+			t = 2 * amplitude
+		}
+		x, y = q.point(float64(i)/float64(n), t)
+		cc.Add_l(x, y)
+	}
+}
@@ -745,7 +745,7 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		isSig := false
 		if t := obj.Get("Type"); t != nil {
 			typeStr, ok := t.(*PdfObjectName)
-			if ok && *typeStr == "Sig" {
+			if ok && (*typeStr == "Sig" || *typeStr == "DocTimeStamp") {
 				isSig = true
 			}
 		}
@@ -928,7 +928,7 @@ func (crypt *PdfCrypt) Encrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		isSig := false
 		if t := obj.Get("Type"); t != nil {
 			typeStr, ok := t.(*PdfObjectName)
-			if ok && *typeStr == "Sig" {
+			if ok && (*typeStr == "Sig" || *typeStr == "DocTimeStamp") {
 				isSig = true
 			}
 		}
@@ -66,6 +66,11 @@ type Creator struct {
 	// Page labels.
 	pageLabels core.PdfObject
 
+	// Viewer preferences, page layout and page mode.
+	viewerPreferences *model.ViewerPreferences
+	pageLayout        model.PageLayout
+	pageMode          model.PageMode
+
 	// Optimizer.
 	optimizer model.Optimizer
 
@@ -98,6 +103,24 @@ func (c *Creator) SetPageLabels(pageLabels core.PdfObject) {
 	c.pageLabels = pageLabels
 }
 
+// SetViewerPreferences sets the viewer preferences of the PDF file generated by the creator,
+// e.g. to display the document title in the window title bar.
+func (c *Creator) SetViewerPreferences(prefs *model.ViewerPreferences) {
+	c.viewerPreferences = prefs
+}
+
+// SetPageLayout sets the page layout used by viewers when the PDF file generated by the
+// creator is opened.
+func (c *Creator) SetPageLayout(layout model.PageLayout) {
+	c.pageLayout = layout
+}
+
+// SetPageMode sets how viewers display the PDF file generated by the creator when opened,
+// e.g. model.PageModeUseOutlines to show the bookmarks.
+func (c *Creator) SetPageMode(mode model.PageMode) {
+	c.pageMode = mode
+}
+
 // FrontpageFunctionArgs holds the input arguments to a front page drawing function.
 // It is designed as a struct, so additional parameters can be added in the future with backwards
 // compatibility.
@@ -682,6 +705,20 @@ func (c *Creator) Write(ws io.Writer) error {
 		}
 	}
 
+	// Viewer preferences, page layout and page mode.
+	if c.viewerPreferences != nil {
+		if err := pdfWriter.SetViewerPreferences(c.viewerPreferences); err != nil {
+			common.Log.Debug("ERROR: Could not set viewer preferences: %v", err)
+			return err
+		}
+	}
+	if c.pageLayout != "" {
+		pdfWriter.SetPageLayout(c.pageLayout)
+	}
+	if c.pageMode != "" {
+		pdfWriter.SetPageMode(c.pageMode)
+	}
+
 	if c.subsetFonts != nil {
 		for _, font := range c.subsetFonts {
 			err := font.SubsetRegistered()
@@ -889,3 +926,9 @@ func (c *Creator) NewImageFromFile(path string) (*Image, error) {
 func (c *Creator) NewImageFromGoImage(goimg goimage.Image) (*Image, error) {
 	return newImageFromGoImage(goimg)
 }
+
+// NewImposition creates a new imposition with the specified layout. The
+// imposed sheets have the page size of the creator.
+func (c *Creator) NewImposition(layout ImpositionLayout) *Imposition {
+	return newImposition(layout)
+}
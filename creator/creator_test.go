@@ -3065,6 +3065,32 @@ func TestPageLabels(t *testing.T) {
 	require.Equal(t, core.EqualObjects(genPageLabels, pageLabels), true)
 }
 
+func TestViewerPreferences(t *testing.T) {
+	c := New()
+	c.AddOutlines = true
+	c.NewPage()
+	ch := c.NewChapter("Introduction")
+	require.NoError(t, c.Draw(ch))
+
+	prefs := model.NewViewerPreferences()
+	prefs.DisplayDocTitle = true
+	c.SetViewerPreferences(prefs)
+	c.SetPageMode(model.PageModeUseOutlines)
+	c.SetPageLayout(model.PageLayoutOneColumn)
+
+	outBuf := bytes.NewBuffer(nil)
+	require.NoError(t, c.Write(outBuf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(outBuf.Bytes()))
+	require.NoError(t, err)
+	readPrefs, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.True(t, readPrefs.DisplayDocTitle)
+	require.False(t, readPrefs.HideToolbar)
+	require.Equal(t, model.PageModeUseOutlines, reader.GetPageMode())
+	require.Equal(t, model.PageLayoutOneColumn, reader.GetPageLayout())
+}
+
 func TestReferencedPageDestinations(t *testing.T) {
 	testPages := func(buf *bytes.Buffer, expectedPages, expectedNullDestPages int) {
 		reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
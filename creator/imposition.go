@@ -0,0 +1,560 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// ImpositionLayout represents the number of source pages placed on each
+// sheet side of an imposition.
+type ImpositionLayout int
+
+// Supported imposition layouts.
+const (
+	ImpositionLayout2Up ImpositionLayout = 2
+	ImpositionLayout4Up ImpositionLayout = 4
+	ImpositionLayout6Up ImpositionLayout = 6
+	ImpositionLayout9Up ImpositionLayout = 9
+)
+
+// ImpositionReadingDirection specifies the order in which source pages fill
+// the cells of an imposed sheet.
+type ImpositionReadingDirection int
+
+// Supported reading directions.
+const (
+	// ImpositionLeftToRightTopToBottom fills rows from left to right, starting with the top row.
+	ImpositionLeftToRightTopToBottom ImpositionReadingDirection = iota
+
+	// ImpositionRightToLeftTopToBottom fills rows from right to left, starting with the top row.
+	ImpositionRightToLeftTopToBottom
+
+	// ImpositionTopToBottomLeftToRight fills columns from top to bottom, starting with the left column.
+	ImpositionTopToBottomLeftToRight
+
+	// ImpositionTopToBottomRightToLeft fills columns from top to bottom, starting with the right column.
+	ImpositionTopToBottomRightToLeft
+)
+
+// ImpositionScaling specifies how source pages are scaled into the sheet cells.
+type ImpositionScaling int
+
+// Supported scaling modes.
+const (
+	// ImpositionScaleToFit scales pages up or down to fit the cells, maintaining the aspect ratio.
+	ImpositionScaleToFit ImpositionScaling = iota
+
+	// ImpositionShrinkToFit scales pages down to fit the cells, but never enlarges them.
+	ImpositionShrinkToFit
+
+	// ImpositionScaleNone places the pages at their original size.
+	ImpositionScaleNone
+)
+
+// ImpositionRotation specifies the rotation applied to source pages when
+// placing them on the sheet.
+type ImpositionRotation int
+
+// Supported rotation modes. The explicit angles are clockwise.
+const (
+	// ImpositionRotationAuto rotates pages by 90 degrees when it allows them to be placed larger.
+	ImpositionRotationAuto ImpositionRotation = iota
+	ImpositionRotation0
+	ImpositionRotation90
+	ImpositionRotation180
+	ImpositionRotation270
+)
+
+// Imposition places several source pages on each output page (sheet side),
+// either as an N-up grid or as a saddle-stitch booklet. The source pages are
+// embedded as Form XObjects which are shared by all the sheets referencing
+// them, so that pages used multiple times do not increase the output size.
+// The size of the sheets is given by the page size of the creator.
+// Implements the Drawable interface and can be drawn on PDF using the Creator.
+// Each sheet side is output as a separate page.
+type Imposition struct {
+	layout ImpositionLayout
+	pages  []*model.PdfPage
+
+	// Sheet margins and spacing between the cells.
+	margins margins
+	hGutter float64
+	vGutter float64
+
+	scaling   ImpositionScaling
+	rotation  ImpositionRotation
+	direction ImpositionReadingDirection
+
+	// Crop mark settings.
+	cropMarks      bool
+	cropMarkLength float64
+	cropMarkOffset float64
+	cropMarkWidth  float64
+	cropMarkColor  Color
+
+	// Booklet settings.
+	booklet bool
+	creep   float64
+
+	// Form XObjects of the source pages, shared by all the sheets.
+	xforms map[*model.PdfPage]*impositionXObject
+}
+
+// impositionXObject is a source page embedded as a Form XObject.
+type impositionXObject struct {
+	stream *core.PdfObjectStream
+	bbox   model.PdfRectangle
+	rotate int64
+}
+
+// impositionCell represents the position of a cell on the sheet, in PDF
+// coordinates (origin at the bottom left corner of the sheet).
+type impositionCell struct {
+	x, y          float64
+	width, height float64
+}
+
+// newImposition returns a new imposition with the specified layout.
+func newImposition(layout ImpositionLayout) *Imposition {
+	return &Imposition{
+		layout:         layout,
+		cropMarkLength: 10,
+		cropMarkOffset: 3,
+		cropMarkWidth:  0.25,
+		cropMarkColor:  ColorBlack,
+		xforms:         map[*model.PdfPage]*impositionXObject{},
+	}
+}
+
+// AddPage adds a source page to the imposition. A nil page is output as a blank cell.
+func (imp *Imposition) AddPage(page *model.PdfPage) {
+	imp.pages = append(imp.pages, page)
+}
+
+// AddPages adds the specified source pages to the imposition.
+func (imp *Imposition) AddPages(pages ...*model.PdfPage) {
+	imp.pages = append(imp.pages, pages...)
+}
+
+// SetMargins sets the margins of the sheet: left, right, top, bottom.
+func (imp *Imposition) SetMargins(left, right, top, bottom float64) {
+	imp.margins.left = left
+	imp.margins.right = right
+	imp.margins.top = top
+	imp.margins.bottom = bottom
+}
+
+// SetGutter sets the horizontal spacing between the columns and the vertical
+// spacing between the rows of the sheet. For booklets, the horizontal gutter
+// is the spacing at the spine.
+func (imp *Imposition) SetGutter(horizontal, vertical float64) {
+	imp.hGutter = horizontal
+	imp.vGutter = vertical
+}
+
+// SetScaling sets the scaling mode used for placing the source pages.
+func (imp *Imposition) SetScaling(scaling ImpositionScaling) {
+	imp.scaling = scaling
+}
+
+// SetRotation sets the rotation of the source pages on the sheet.
+func (imp *Imposition) SetRotation(rotation ImpositionRotation) {
+	imp.rotation = rotation
+}
+
+// SetReadingDirection sets the order in which the source pages fill the
+// cells of the sheet. For booklets, the right to left directions produce
+// booklets bound on the right side.
+func (imp *Imposition) SetReadingDirection(direction ImpositionReadingDirection) {
+	imp.direction = direction
+}
+
+// SetCropMarks enables or disables drawing crop marks around the placed pages.
+// The length of the marks and their offset from the page corners are
+// specified in points.
+func (imp *Imposition) SetCropMarks(enable bool, length, offset float64) {
+	imp.cropMarks = enable
+	imp.cropMarkLength = length
+	imp.cropMarkOffset = offset
+}
+
+// SetCropMarkStyle sets the line width and color of the crop marks.
+func (imp *Imposition) SetCropMarkStyle(width float64, color Color) {
+	imp.cropMarkWidth = width
+	imp.cropMarkColor = color
+}
+
+// SetBooklet enables or disables saddle-stitch booklet ordering. In booklet
+// mode, two pages are placed side by side on each sheet side, in the order
+// required to fold and stitch the printed sheets. The number of pages is
+// padded with blank pages to a multiple of four. The layout of the
+// imposition is ignored in booklet mode.
+func (imp *Imposition) SetBooklet(enable bool) {
+	imp.booklet = enable
+}
+
+// SetCreep sets the creep compensation of the booklet, in points. The pages
+// of the innermost sheet are shifted towards the spine by the creep value.
+// The shift of the other sheets is proportional to their distance from the
+// outermost sheet, which is not shifted.
+func (imp *Imposition) SetCreep(creep float64) {
+	imp.creep = creep
+}
+
+// SheetSides returns the number of output pages produced by the imposition.
+func (imp *Imposition) SheetSides() int {
+	if imp.booklet {
+		return len(imp.bookletOrder()) / 2
+	}
+
+	n := int(imp.layout)
+	if n <= 0 {
+		return 0
+	}
+	return (len(imp.pages) + n - 1) / n
+}
+
+// GeneratePageBlocks draws the imposed sheets on new blocks, each one
+// representing a page. Implements the Drawable interface.
+func (imp *Imposition) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	if len(imp.pages) == 0 {
+		return nil, ctx, errors.New("imposition has no pages")
+	}
+
+	var cols, rows int
+	var pages []*model.PdfPage
+	if imp.booklet {
+		cols, rows = 2, 1
+		pages = imp.bookletOrder()
+	} else {
+		var err error
+		cols, rows, err = imp.grid(ctx.PageWidth, ctx.PageHeight)
+		if err != nil {
+			return nil, ctx, err
+		}
+		pages = imp.pages
+	}
+	cells := imp.cells(cols, rows, ctx.PageWidth, ctx.PageHeight)
+
+	var blocks []*Block
+	for start := 0; start < len(pages); start += len(cells) {
+		end := start + len(cells)
+		if end > len(pages) {
+			end = len(pages)
+		}
+
+		block := NewBlock(ctx.PageWidth, ctx.PageHeight)
+		side := len(blocks)
+		for i, page := range pages[start:end] {
+			if page == nil {
+				continue
+			}
+
+			cellIdx := i
+			if imp.booklet {
+				cellIdx = imp.bookletCell(i)
+			}
+
+			shift := 0.0
+			if imp.booklet {
+				shift = imp.creepShift(side/2, len(pages)/4)
+			}
+
+			if err := imp.drawPage(block, page, cells[cellIdx], i, shift); err != nil {
+				return nil, ctx, err
+			}
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, ctx, nil
+}
+
+// grid returns the number of columns and rows of the layout for a sheet of
+// the specified size. Landscape sheets get more columns than rows.
+func (imp *Imposition) grid(width, height float64) (int, int, error) {
+	var cols, rows int
+	switch imp.layout {
+	case ImpositionLayout2Up:
+		cols, rows = 2, 1
+	case ImpositionLayout4Up:
+		cols, rows = 2, 2
+	case ImpositionLayout6Up:
+		cols, rows = 3, 2
+	case ImpositionLayout9Up:
+		cols, rows = 3, 3
+	default:
+		return 0, 0, fmt.Errorf("unsupported imposition layout: %d", imp.layout)
+	}
+
+	if width < height {
+		cols, rows = rows, cols
+	}
+	return cols, rows, nil
+}
+
+// cells returns the cells of the sheet, in reading order.
+func (imp *Imposition) cells(cols, rows int, width, height float64) []impositionCell {
+	m := imp.margins
+	cellWidth := (width - m.left - m.right - float64(cols-1)*imp.hGutter) / float64(cols)
+	cellHeight := (height - m.top - m.bottom - float64(rows-1)*imp.vGutter) / float64(rows)
+
+	cells := make([]impositionCell, cols*rows)
+	for i := range cells {
+		var col, row int
+		switch imp.direction {
+		case ImpositionRightToLeftTopToBottom:
+			col, row = cols-1-i%cols, i/cols
+		case ImpositionTopToBottomLeftToRight:
+			col, row = i/rows, i%rows
+		case ImpositionTopToBottomRightToLeft:
+			col, row = cols-1-i/rows, i%rows
+		default:
+			col, row = i%cols, i/cols
+		}
+		if imp.booklet {
+			// Booklets have a single row; the binding side is handled by bookletCell.
+			col, row = i, 0
+		}
+
+		cells[i] = impositionCell{
+			x:      m.left + float64(col)*(cellWidth+imp.hGutter),
+			y:      height - m.top - float64(row+1)*cellHeight - float64(row)*imp.vGutter,
+			width:  cellWidth,
+			height: cellHeight,
+		}
+	}
+
+	return cells
+}
+
+// bookletOrder returns the source pages in the order in which they are placed
+// on the booklet sheet sides. Each group of two pages represents one sheet
+// side, left page first. Blank pages are represented by nil entries.
+func (imp *Imposition) bookletOrder() []*model.PdfPage {
+	n := len(imp.pages)
+	if rem := n % 4; rem != 0 {
+		n += 4 - rem
+	}
+
+	page := func(i int) *model.PdfPage {
+		if i < len(imp.pages) {
+			return imp.pages[i]
+		}
+		return nil
+	}
+
+	order := make([]*model.PdfPage, 0, n)
+	for sheet := 0; sheet < n/4; sheet++ {
+		// Front side.
+		order = append(order, page(n-1-2*sheet), page(2*sheet))
+		// Back side.
+		order = append(order, page(2*sheet+1), page(n-2-2*sheet))
+	}
+
+	return order
+}
+
+// bookletCell returns the cell index of the page at position i of a booklet
+// sheet side, taking into account the binding side.
+func (imp *Imposition) bookletCell(i int) int {
+	switch imp.direction {
+	case ImpositionRightToLeftTopToBottom, ImpositionTopToBottomRightToLeft:
+		return 1 - i
+	}
+	return i
+}
+
+// creepShift returns the creep compensation of the specified booklet sheet.
+func (imp *Imposition) creepShift(sheet, numSheets int) float64 {
+	if numSheets < 2 {
+		return 0
+	}
+	return imp.creep * float64(sheet) / float64(numSheets-1)
+}
+
+// drawPage draws the specified page in the cell of the sheet block.
+// The shift is the horizontal displacement towards the spine (booklets only).
+func (imp *Imposition) drawPage(block *Block, page *model.PdfPage, cell impositionCell, pos int, shift float64) error {
+	xobj, err := imp.pageXObject(page)
+	if err != nil {
+		return err
+	}
+
+	bbox := xobj.bbox
+	width, height := bbox.Width(), bbox.Height()
+
+	// Total clockwise rotation of the page on the sheet.
+	rotate := xobj.rotate
+	switch imp.rotation {
+	case ImpositionRotation90:
+		rotate += 90
+	case ImpositionRotation180:
+		rotate += 180
+	case ImpositionRotation270:
+		rotate += 270
+	case ImpositionRotationAuto:
+		w, h := width, height
+		if rotate%180 != 0 {
+			w, h = h, w
+		}
+		if imp.fitScale(h, w, cell) > imp.fitScale(w, h, cell) {
+			rotate += 90
+		}
+	}
+	rotate = ((rotate % 360) + 360) % 360
+
+	// Size of the page after rotation.
+	rw, rh := width, height
+	if rotate%180 != 0 {
+		rw, rh = rh, rw
+	}
+	scale := imp.fitScale(rw, rh, cell)
+	sw, sh := rw*scale, rh*scale
+
+	// Position of the placed page. Booklet pages are aligned to the spine.
+	x := cell.x + (cell.width-sw)/2
+	y := cell.y + (cell.height-sh)/2
+	if imp.booklet {
+		if imp.bookletCell(pos) == 0 {
+			x = cell.x + cell.width - sw + shift
+		} else {
+			x = cell.x - shift
+		}
+	}
+
+	// Matrix rotating the page clockwise around its bounding box.
+	var a, b, c, d, e, f float64
+	switch rotate {
+	case 90:
+		a, b, c, d, e, f = 0, -1, 1, 0, 0, width
+	case 180:
+		a, b, c, d, e, f = -1, 0, 0, -1, width, height
+	case 270:
+		a, b, c, d, e, f = 0, 1, -1, 0, height, 0
+	default:
+		a, b, c, d, e, f = 1, 0, 0, 1, 0, 0
+	}
+	e -= a*bbox.Llx + c*bbox.Lly
+	f -= b*bbox.Llx + d*bbox.Lly
+
+	name := block.resources.GenerateXObjectName()
+	if err := block.resources.SetXObjectByName(name, xobj.stream); err != nil {
+		return err
+	}
+
+	cc := contentstream.NewContentCreator().
+		Add_q().
+		Add_re(x, y, sw, sh).
+		Add_W().
+		Add_n().
+		Add_cm(a*scale, b*scale, c*scale, d*scale, e*scale+x, f*scale+y).
+		Add_Do(name).
+		Add_Q()
+
+	if imp.cropMarks {
+		imp.drawCropMarks(cc, x, y, sw, sh)
+	}
+
+	block.addContents(cc.Operations())
+	return nil
+}
+
+// fitScale returns the scale factor of a page of the specified size placed
+// in the cell, according to the scaling mode.
+func (imp *Imposition) fitScale(width, height float64, cell impositionCell) float64 {
+	if imp.scaling == ImpositionScaleNone || width <= 0 || height <= 0 {
+		return 1
+	}
+
+	scale := math.Min(cell.width/width, cell.height/height)
+	if imp.scaling == ImpositionShrinkToFit && scale > 1 {
+		scale = 1
+	}
+	return scale
+}
+
+// drawCropMarks adds crop marks around the rectangle to the content creator.
+func (imp *Imposition) drawCropMarks(cc *contentstream.ContentCreator, x, y, width, height float64) {
+	length, offset := imp.cropMarkLength, imp.cropMarkOffset
+	r, g, b := imp.cropMarkColor.ToRGB()
+
+	cc.Add_q().
+		Add_w(imp.cropMarkWidth).
+		Add_RG(r, g, b)
+
+	for _, cx := range []float64{x, x + width} {
+		for _, cy := range []float64{y, y + height} {
+			// Direction away from the rectangle.
+			dx, dy := 1.0, 1.0
+			if cx == x {
+				dx = -1
+			}
+			if cy == y {
+				dy = -1
+			}
+
+			// Horizontal and vertical marks.
+			cc.Add_m(cx+dx*offset, cy).Add_l(cx+dx*(offset+length), cy).Add_S()
+			cc.Add_m(cx, cy+dy*offset).Add_l(cx, cy+dy*(offset+length)).Add_S()
+		}
+	}
+
+	cc.Add_Q()
+}
+
+// pageXObject returns the Form XObject of the specified page, creating it
+// the first time the page is used.
+func (imp *Imposition) pageXObject(page *model.PdfPage) (*impositionXObject, error) {
+	if xobj, ok := imp.xforms[page]; ok {
+		return xobj, nil
+	}
+
+	bbox, err := page.GetCropBox()
+	if err != nil {
+		return nil, err
+	}
+	rotate, err := page.GetRotate()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	xform := model.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = bbox.ToPdfObject()
+	if err := xform.SetContentStream([]byte(content), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+
+	stream, ok := xform.ToPdfObject().(*core.PdfObjectStream)
+	if !ok {
+		common.Log.Debug("ERROR: invalid form XObject")
+		return nil, core.ErrTypeError
+	}
+
+	xobj := &impositionXObject{
+		stream: stream,
+		bbox:   *bbox,
+		rotate: rotate,
+	}
+
+	imp.xforms[page] = xobj
+	return xobj, nil
+}
@@ -0,0 +1,119 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+func TestImpositionBookletOrder(t *testing.T) {
+	pages := make([]*model.PdfPage, 6)
+	for i := range pages {
+		pages[i] = model.NewPdfPage()
+	}
+
+	imp := newImposition(ImpositionLayout2Up)
+	imp.AddPages(pages...)
+	imp.SetBooklet(true)
+	require.Equal(t, 4, imp.SheetSides())
+
+	// 6 pages are padded to 8: sheet 1 [8 1] [2 7], sheet 2 [6 3] [4 5].
+	expected := []int{-1, 0, 1, -1, 5, 2, 3, 4}
+	order := imp.bookletOrder()
+	require.Len(t, order, len(expected))
+	for i, idx := range expected {
+		if idx < 0 {
+			require.Nil(t, order[i])
+			continue
+		}
+		require.True(t, pages[idx] == order[i], "position %d", i)
+	}
+
+	imp.SetCreep(2)
+	require.Equal(t, 0.0, imp.creepShift(0, 2))
+	require.Equal(t, 2.0, imp.creepShift(1, 2))
+}
+
+func TestImpositionNUp(t *testing.T) {
+	f, err := os.Open(testPdfLoremIpsumFile)
+	require.NoError(t, err)
+	defer f.Close()
+
+	pages, err := loadPagesFromFile(f)
+	require.NoError(t, err)
+	require.NotEmpty(t, pages)
+
+	// Use the first page multiple times to check that it is shared.
+	src := []*model.PdfPage{pages[0], pages[0], pages[0], pages[0], pages[0]}
+
+	c := New()
+	c.SetPageSize(PageSizeA3)
+
+	imp := c.NewImposition(ImpositionLayout4Up)
+	imp.AddPages(src...)
+	imp.SetMargins(20, 20, 20, 20)
+	imp.SetGutter(15, 15)
+	imp.SetCropMarks(true, 8, 3)
+	require.Equal(t, 2, imp.SheetSides())
+
+	require.NoError(t, c.Draw(imp))
+	require.Len(t, c.pages, 2)
+
+	// All the placed copies must reference the same Form XObject.
+	var shared *core.PdfObjectStream
+	numXObjects := 0
+	for _, page := range c.pages {
+		block := c.pageBlocks[page]
+		require.NotNil(t, block)
+
+		xobjDict, ok := core.GetDict(block.resources.XObject)
+		require.True(t, ok)
+		for _, key := range xobjDict.Keys() {
+			stream, ok := core.GetStream(xobjDict.Get(key))
+			require.True(t, ok)
+			if shared == nil {
+				shared = stream
+			}
+			require.True(t, shared == stream)
+			numXObjects++
+		}
+	}
+	require.Equal(t, 5, numXObjects)
+
+	testWriteAndRender(t, c, "imposition_4up.pdf")
+}
+
+func TestImpositionBooklet(t *testing.T) {
+	f, err := os.Open(testPdfLoremIpsumFile)
+	require.NoError(t, err)
+	defer f.Close()
+
+	pages, err := loadPagesFromFile(f)
+	require.NoError(t, err)
+	require.NotEmpty(t, pages)
+
+	c := New()
+	c.SetPageSize(PageSize{PageSizeA3[1], PageSizeA3[0]})
+
+	imp := c.NewImposition(ImpositionLayout2Up)
+	for i := 0; i < 10; i++ {
+		imp.AddPage(pages[i%len(pages)])
+	}
+	imp.SetBooklet(true)
+	imp.SetCreep(4)
+	imp.SetGutter(10, 0)
+
+	require.NoError(t, c.Draw(imp))
+	require.Len(t, c.pages, 6)
+
+	testWriteAndRender(t, c, "imposition_booklet.pdf")
+}
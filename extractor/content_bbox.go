@@ -0,0 +1,265 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/transform"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// ContentBBox returns the bounding box of the visible content of the page:
+// text, vector paths, images and shadings. The bounds of the painted objects
+// are reduced to the clipping paths in effect (approximated by their bounding
+// boxes) and to the CropBox of the page. The returned flag is false if the
+// page has no visible content. The bounding box can be used for trimming the
+// white space around the page contents, e.g. by setting it as the CropBox.
+func (e *Extractor) ContentBBox() (model.PdfRectangle, bool, error) {
+	ctx := &contentBBoxContext{}
+	err := ctx.process(e.contents, e.resources, transform.IdentityMatrix(), nil, 0)
+	if err != nil {
+		return model.PdfRectangle{}, false, err
+	}
+
+	// Text marks are computed by the text extraction, which takes care of
+	// the font metrics.
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return model.PdfRectangle{}, false, err
+	}
+	if bbox, ok := pageText.Marks().BBox(); ok {
+		ctx.add(bbox, nil)
+	}
+
+	if !ctx.found {
+		return model.PdfRectangle{}, false, nil
+	}
+	if e.cropBox == nil {
+		return ctx.bbox, true, nil
+	}
+	bbox, ok := rectIntersection(ctx.bbox, *e.cropBox)
+	return bbox, ok, nil
+}
+
+// contentBBoxContext tracks the bounds of the painted content of a content stream.
+type contentBBoxContext struct {
+	bbox  model.PdfRectangle
+	found bool
+}
+
+// contentBBoxState is the part of the graphics state which is saved and
+// restored by the q/Q operators.
+type contentBBoxState struct {
+	clip      *model.PdfRectangle
+	lineWidth float64
+}
+
+// add adds the rectangle `r`, reduced to the clipping rectangle `clip`, to the bounds.
+func (ctx *contentBBoxContext) add(r model.PdfRectangle, clip *model.PdfRectangle) {
+	if clip != nil {
+		var ok bool
+		if r, ok = rectIntersection(r, *clip); !ok {
+			return
+		}
+	}
+
+	if ctx.found {
+		ctx.bbox = ctx.bbox.Union(r)
+	} else {
+		ctx.bbox = r
+		ctx.found = true
+	}
+}
+
+// process processes the content stream `contents`. `base` is the matrix
+// mapping the content stream space to the page space and `clip` is the
+// clipping rectangle in page space, if any.
+func (ctx *contentBBoxContext) process(contents string, resources *model.PdfPageResources,
+	base transform.Matrix, clip *model.PdfRectangle, level int) error {
+	if level > maxFormStack {
+		return errors.New("form stack overflow")
+	}
+
+	operations, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return err
+	}
+
+	state := contentBBoxState{clip: clip, lineWidth: 1}
+	var stack []contentBBoxState
+
+	// Bounds of the current path in page space.
+	var path model.PdfRectangle
+	var hasPath, pendingClip bool
+
+	addPoint := func(m transform.Matrix, x, y float64) {
+		x, y = m.Transform(x, y)
+		if !hasPath {
+			path = model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y}
+			hasPath = true
+			return
+		}
+		path.Llx = math.Min(path.Llx, x)
+		path.Lly = math.Min(path.Lly, y)
+		path.Urx = math.Max(path.Urx, x)
+		path.Ury = math.Max(path.Ury, y)
+	}
+
+	// addRect adds the rectangle `r` transformed by `m` to the bounds.
+	addRect := func(m transform.Matrix, r model.PdfRectangle) {
+		hasPath = false
+		addPoint(m, r.Llx, r.Lly)
+		addPoint(m, r.Urx, r.Lly)
+		addPoint(m, r.Llx, r.Ury)
+		addPoint(m, r.Urx, r.Ury)
+		ctx.add(path, state.clip)
+		hasPath = false
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			ctm := base.Mult(gs.CTM)
+
+			params, _ := core.GetNumbersAsFloat(op.Params)
+			switch op.Operand {
+			case "q":
+				stack = append(stack, state)
+			case "Q":
+				if len(stack) > 0 {
+					state = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			case "w":
+				if len(params) == 1 {
+					state.lineWidth = params[0]
+				}
+			case "m", "l":
+				if len(params) == 2 {
+					addPoint(ctm, params[0], params[1])
+				}
+			case "c", "v", "y":
+				// The curve is contained in the convex hull of its control points.
+				for i := 0; i+1 < len(params); i += 2 {
+					addPoint(ctm, params[i], params[i+1])
+				}
+			case "re":
+				if len(params) == 4 {
+					x, y, w, h := params[0], params[1], params[2], params[3]
+					addPoint(ctm, x, y)
+					addPoint(ctm, x+w, y)
+					addPoint(ctm, x, y+h)
+					addPoint(ctm, x+w, y+h)
+				}
+			case "W", "W*":
+				pendingClip = true
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+				if hasPath && op.Operand != "n" {
+					r := path
+					switch op.Operand {
+					case "S", "s", "B", "B*", "b", "b*":
+						hw := state.lineWidth / 2 * math.Max(ctm.ScalingFactorX(), ctm.ScalingFactorY())
+						r.Llx, r.Lly, r.Urx, r.Ury = r.Llx-hw, r.Lly-hw, r.Urx+hw, r.Ury+hw
+					}
+					ctx.add(r, state.clip)
+				}
+				if pendingClip && hasPath {
+					clipRect := path
+					if state.clip != nil {
+						var ok bool
+						if clipRect, ok = rectIntersection(clipRect, *state.clip); !ok {
+							// Nothing is visible inside an empty clipping region.
+							clipRect = model.PdfRectangle{Llx: path.Llx, Lly: path.Lly, Urx: path.Llx, Ury: path.Lly}
+						}
+					}
+					state.clip = &clipRect
+				}
+				hasPath, pendingClip = false, false
+			case "sh":
+				// Shadings fill the current clipping region.
+				if state.clip != nil {
+					ctx.add(*state.clip, nil)
+				}
+			case "BI":
+				addRect(ctm, model.PdfRectangle{Urx: 1, Ury: 1})
+			case "Do":
+				if len(op.Params) != 1 {
+					return nil
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					return nil
+				}
+				_, xtype := resources.GetXObjectByName(*name)
+				switch xtype {
+				case model.XObjectTypeImage:
+					addRect(ctm, model.PdfRectangle{Urx: 1, Ury: 1})
+				case model.XObjectTypeForm:
+					return ctx.processForm(name, resources, ctm, state.clip, level)
+				}
+			}
+			return nil
+		})
+
+	return processor.Process(resources)
+}
+
+// processForm processes the content stream of the form XObject `name`.
+func (ctx *contentBBoxContext) processForm(name *core.PdfObjectName, resources *model.PdfPageResources,
+	ctm transform.Matrix, clip *model.PdfRectangle, level int) error {
+	xform, err := resources.GetXObjectFormByName(*name)
+	if err != nil || xform == nil {
+		return err
+	}
+
+	formContent, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		if f, err := arr.ToFloat64Array(); err == nil && len(f) == 6 {
+			ctm = ctm.Mult(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	}
+
+	// The form contents are clipped to the form bounding box.
+	if arr, ok := core.GetArray(xform.BBox); ok {
+		if bbox, err := model.NewPdfRectangle(*arr); err == nil {
+			var r model.PdfRectangle
+			corners := [][2]float64{
+				{bbox.Llx, bbox.Lly}, {bbox.Urx, bbox.Lly}, {bbox.Llx, bbox.Ury}, {bbox.Urx, bbox.Ury},
+			}
+			for i, c := range corners {
+				x, y := ctm.Transform(c[0], c[1])
+				if i == 0 {
+					r = model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y}
+					continue
+				}
+				r = r.Union(model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y})
+			}
+			if clip != nil {
+				if r, ok = rectIntersection(r, *clip); !ok {
+					return nil
+				}
+			}
+			clip = &r
+		}
+	}
+
+	common.Log.Trace("contentBBox form %s: ctm=%s", *name, ctm)
+	return ctx.process(string(formContent), formResources, ctm, clip, level+1)
+}
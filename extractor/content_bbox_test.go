@@ -0,0 +1,99 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+func TestContentBBox(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Contents string
+		Found    bool
+		Expected model.PdfRectangle
+	}{
+		{
+			"empty",
+			"q Q",
+			false,
+			model.PdfRectangle{},
+		},
+		{
+			"filled rectangle",
+			"100 200 50 60 re f",
+			true,
+			model.PdfRectangle{Llx: 100, Lly: 200, Urx: 150, Ury: 260},
+		},
+		{
+			"stroked line with transform",
+			"q 2 0 0 2 10 10 cm 4 w 0 0 m 100 0 l S Q",
+			true,
+			model.PdfRectangle{Llx: 6, Lly: 6, Urx: 214, Ury: 14},
+		},
+		{
+			"clipped path",
+			"q 0 0 100 100 re W n 50 50 200 200 re f Q 300 300 10 10 re n",
+			true,
+			model.PdfRectangle{Llx: 50, Lly: 50, Urx: 100, Ury: 100},
+		},
+		{
+			"form xobject",
+			"q 1 0 0 1 100 100 cm /Fm1 Do Q",
+			true,
+			model.PdfRectangle{Llx: 110, Lly: 110, Urx: 140, Ury: 160},
+		},
+	}
+
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromIntegers([]int{0, 0, 30, 50})
+	xform.Matrix = core.MakeArrayFromIntegers([]int{1, 0, 0, 1, 10, 10})
+	require.NoError(t, xform.SetContentStream([]byte("0 0 100 100 re f"), nil))
+	stream, ok := xform.ToPdfObject().(*core.PdfObjectStream)
+	require.True(t, ok)
+
+	resources := model.NewPdfPageResources()
+	require.NoError(t, resources.SetXObjectByName("Fm1", stream))
+
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			e, err := NewFromContents(tc.Contents, resources)
+			require.NoError(t, err)
+
+			bbox, found, err := e.ContentBBox()
+			require.NoError(t, err)
+			require.Equal(t, tc.Found, found)
+			if found {
+				require.InDelta(t, tc.Expected.Llx, bbox.Llx, 1e-6)
+				require.InDelta(t, tc.Expected.Lly, bbox.Lly, 1e-6)
+				require.InDelta(t, tc.Expected.Urx, bbox.Urx, 1e-6)
+				require.InDelta(t, tc.Expected.Ury, bbox.Ury, 1e-6)
+			}
+		})
+	}
+}
+
+func TestContentBBoxInvalidCropBox(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	parent := core.MakeDict()
+	parent.Set("CropBox", core.MakeName("Invalid"))
+	page.Parent = parent
+	require.NoError(t, page.AddContentStreamByString("100 100 500 500 re f"))
+
+	// The content is clipped to the MediaBox.
+	e, err := New(page)
+	require.NoError(t, err)
+	bbox, found, err := e.ContentBBox()
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, model.PdfRectangle{Llx: 100, Lly: 100, Urx: 200, Ury: 200}, bbox)
+}
@@ -8,6 +8,7 @@ package extractor
 import (
 	"fmt"
 
+	"github.com/loxiouve/unipdf/v3/common"
 	"github.com/loxiouve/unipdf/v3/model"
 )
 
@@ -18,6 +19,9 @@ type Extractor struct {
 	resources *model.PdfPageResources
 	mediaBox  model.PdfRectangle
 
+	// cropBox is the visible region of the page. Nil if not known.
+	cropBox *model.PdfRectangle
+
 	// fontCache is a simple LRU cache that is used to prevent redundant constructions of PdfFonts
 	// from PDF objects. NOTE: This is not a conventional glyph cache. It only caches PdfFonts.
 	fontCache map[string]fontEntry
@@ -31,6 +35,10 @@ type Extractor struct {
 
 	// textCount is an incrementing number used to identify XYTest objects.
 	textCount int
+
+	// markedContent contains the marked-content identifiers (MCID) of the open marked-content
+	// sequences, -1 for the sequences without identifier.
+	markedContent []int
 }
 
 // New returns an Extractor instance for extracting content from the input PDF page.
@@ -49,10 +57,18 @@ func New(page *model.PdfPage) (*Extractor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("extractor requires mediaBox. %v", err)
 	}
+	cropBox, err := page.GetCropBox()
+	if err != nil {
+		common.Log.Debug("ERROR: invalid cropBox, using the mediaBox. err=%v", err)
+		if cropBox, err = page.GetBox(model.PdfPageBoxMedia); err != nil {
+			return nil, fmt.Errorf("extractor requires mediaBox. %v", err)
+		}
+	}
 	e := &Extractor{
 		contents:    contents,
 		resources:   page.Resources,
 		mediaBox:    *mediaBox,
+		cropBox:     cropBox,
 		fontCache:   map[string]fontEntry{},
 		formResults: map[string]textResult{},
 	}
@@ -0,0 +1,563 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// HeadingOptions contains the options of the detection of the headings of a document.
+type HeadingOptions struct {
+	// MinSizeRatio is the minimum ratio of the font size of the headings to the font size of
+	// the body text. Bold or numbered lines in the font size of the body text are also
+	// considered as headings. 1.15 by default.
+	MinSizeRatio float64
+
+	// MaxLevels is the maximum number of heading levels. 3 by default.
+	MaxLevels int
+
+	// MaxLength is the maximum number of characters of the headings. 150 by default.
+	MaxLength int
+
+	// IgnoreStructTree disables the detection of the headings from the structure tree of
+	// tagged documents.
+	IgnoreStructTree bool
+}
+
+// Heading represents a heading of a document.
+type Heading struct {
+	// Title is the text of the heading.
+	Title string
+
+	// Level is the level of the heading, starting from 1.
+	Level int
+
+	// PageNum is the number of the page of the heading, starting from 1.
+	PageNum int
+
+	// BBox is the bounding box of the heading on its page.
+	BBox model.PdfRectangle
+
+	// FontSize is the font size of the heading and Bold is true if the heading is in bold.
+	FontSize float64
+	Bold     bool
+}
+
+// DetectHeadings returns the headings of the document of `reader`, in reading order. The
+// headings of tagged documents are the heading elements (H, H1 to H6) of their structure tree.
+// Otherwise, the headings are the lines of text which stand out from the body text by their
+// font size or weight, their level being given by their numbering (e.g. 2.1) or by their
+// style, larger fonts giving higher levels. The opts parameter may be nil for the default
+// options.
+func DetectHeadings(reader *model.PdfReader, opts *HeadingOptions) ([]Heading, error) {
+	var o HeadingOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinSizeRatio <= 0 {
+		o.MinSizeRatio = 1.15
+	}
+	if o.MaxLevels <= 0 {
+		o.MaxLevels = 3
+	}
+	if o.MaxLength <= 0 {
+		o.MaxLength = 150
+	}
+
+	d := &headingDetector{reader: reader, opts: o, pages: map[int]*PageText{}}
+	if !o.IgnoreStructTree {
+		headings, err := d.structHeadings()
+		if err != nil {
+			return nil, err
+		}
+		if len(headings) > 0 {
+			return headings, nil
+		}
+	}
+	return d.styleHeadings()
+}
+
+// HeadingsOutline returns the outline of the headings `headings` of the document of `reader`,
+// the headings being nested according to their levels. The destinations of the outline items
+// point at the positions of the headings, and refer to the pages of `reader`: the outline can
+// be written with a PdfAppender of the reader.
+func HeadingsOutline(reader *model.PdfReader, headings []Heading) *model.Outline {
+	type node struct {
+		level int
+		item  *model.OutlineItem
+	}
+
+	outline := model.NewOutline()
+	var stack []node
+	for _, h := range headings {
+		dest := model.NewOutlineDest(int64(h.PageNum-1), h.BBox.Llx, h.BBox.Ury)
+		if h.PageNum >= 1 && h.PageNum <= len(reader.PageList) {
+			dest.PageObj = reader.PageList[h.PageNum-1].GetPageAsIndirectObject()
+		}
+		item := model.NewOutlineItem(h.Title, dest)
+
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			outline.Add(item)
+		} else {
+			stack[len(stack)-1].item.Add(item)
+		}
+		stack = append(stack, node{level: h.Level, item: item})
+	}
+	return outline
+}
+
+// headingDetector detects the headings of a document.
+type headingDetector struct {
+	reader *model.PdfReader
+	opts   HeadingOptions
+
+	// Extracted text of the pages, by page number.
+	pages map[int]*PageText
+}
+
+// pageText returns the extracted text of the page `pageNum`, or nil if the text cannot be
+// extracted.
+func (d *headingDetector) pageText(pageNum int) *PageText {
+	if pageText, ok := d.pages[pageNum]; ok {
+		return pageText
+	}
+
+	var pageText *PageText
+	page, err := d.reader.GetPage(pageNum)
+	if err == nil {
+		var e *Extractor
+		if e, err = New(page); err == nil {
+			pageText, _, _, err = e.ExtractPageText()
+		}
+	}
+	if err != nil {
+		common.Log.Debug("ERROR: text extraction of page %d failed: %v", pageNum, err)
+		pageText = nil
+	}
+	d.pages[pageNum] = pageText
+	return pageText
+}
+
+// headingLine is a line of text which may be a heading.
+type headingLine struct {
+	text     string
+	index    int
+	pageNum  int
+	bbox     model.PdfRectangle
+	size     float64
+	bold     bool
+	numbered bool
+	level    int
+}
+
+// styleHeadings returns the headings of the document detected from the style and numbering of
+// the lines of text.
+func (d *headingDetector) styleHeadings() ([]Heading, error) {
+	numPages, err := d.reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	// The font size of the body text is the size of most of the characters.
+	var lines []*headingLine
+	sizeChars := map[float64]int{}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pageText := d.pageText(pageNum)
+		if pageText == nil {
+			continue
+		}
+		for _, line := range textLines(pageText.Marks().Elements()) {
+			line.pageNum = pageNum
+			line.index = len(lines)
+			lines = append(lines, line)
+			sizeChars[line.size] += len([]rune(line.text))
+		}
+	}
+	var bodySize float64
+	bodyChars := 0
+	for size, chars := range sizeChars {
+		if chars > bodyChars || (chars == bodyChars && size < bodySize) {
+			bodySize, bodyChars = size, chars
+		}
+	}
+
+	// Heading candidates.
+	var candidates []*headingLine
+	for _, line := range lines {
+		if len([]rune(line.text)) > d.opts.MaxLength || strings.IndexFunc(line.text, unicode.IsLetter) < 0 {
+			continue
+		}
+		line.level, line.numbered = numberingLevel(line.text)
+		switch {
+		case line.size >= bodySize*d.opts.MinSizeRatio:
+		case line.size >= bodySize && line.bold && (line.numbered || !endsSentence(line.text)):
+		default:
+			continue
+		}
+		candidates = append(candidates, line)
+	}
+
+	// The levels of the headings without numbering are the ranks of their styles.
+	type style struct {
+		size float64
+		bold bool
+	}
+	var styles []style
+	for _, line := range candidates {
+		s := style{line.size, line.bold}
+		found := false
+		for _, other := range styles {
+			found = found || other == s
+		}
+		if !found {
+			styles = append(styles, s)
+		}
+	}
+	sort.Slice(styles, func(i, j int) bool {
+		if styles[i].size != styles[j].size {
+			return styles[i].size > styles[j].size
+		}
+		return styles[i].bold && !styles[j].bold
+	})
+
+	var headings []Heading
+	var prev *headingLine
+	for _, line := range candidates {
+		level := line.level
+		if level == 0 {
+			for i, s := range styles {
+				if s == (style{line.size, line.bold}) {
+					level = i + 1
+				}
+			}
+		}
+		if level > d.opts.MaxLevels {
+			prev = nil
+			continue
+		}
+
+		// Headings wrapped over several consecutive lines.
+		if prev != nil && line.index == prev.index+1 && line.pageNum == prev.pageNum &&
+			!line.numbered && prev.size == line.size && prev.bold == line.bold &&
+			math.Abs(prev.bbox.Lly-line.bbox.Ury) < line.size {
+			h := &headings[len(headings)-1]
+			h.Title += " " + line.text
+			h.BBox = h.BBox.Union(line.bbox)
+			prev = line
+			continue
+		}
+
+		headings = append(headings, Heading{
+			Title:    line.text,
+			Level:    level,
+			PageNum:  line.pageNum,
+			BBox:     line.bbox,
+			FontSize: line.size,
+			Bold:     line.bold,
+		})
+		prev = line
+	}
+	return headings, nil
+}
+
+// textLines returns the lines of the text marks `marks`.
+func textLines(marks []TextMark) []*headingLine {
+	var lines []*headingLine
+	var text strings.Builder
+	var line *headingLine
+	allBold := true
+	flush := func() {
+		if line != nil {
+			line.text = strings.Join(strings.Fields(text.String()), " ")
+			line.bold = allBold
+			if line.text != "" {
+				lines = append(lines, line)
+			}
+		}
+		line = nil
+		text.Reset()
+		allBold = true
+	}
+
+	for _, mark := range marks {
+		if mark.Meta {
+			if strings.Contains(mark.Text, "\n") {
+				flush()
+			} else {
+				text.WriteString(" ")
+			}
+			continue
+		}
+		text.WriteString(mark.Text)
+		if strings.TrimSpace(mark.Text) == "" {
+			continue
+		}
+
+		// Font sizes are rounded to half points.
+		size := math.Round(mark.FontSize*2) / 2
+		if line == nil {
+			line = &headingLine{bbox: mark.BBox, size: size}
+		} else {
+			line.bbox = line.bbox.Union(mark.BBox)
+			line.size = math.Max(line.size, size)
+		}
+		allBold = allBold && isBoldFont(mark.Font)
+	}
+	flush()
+	return lines
+}
+
+// isBoldFont returns true if `font` is a bold font, according to its name or its descriptor.
+func isBoldFont(font *model.PdfFont) bool {
+	if font == nil {
+		return false
+	}
+	name := strings.ToLower(font.BaseFont())
+	for _, weight := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		if strings.Contains(name, weight) {
+			return true
+		}
+	}
+	if _, err := model.NewStandard14Font(model.StdFontName(font.BaseFont())); err == nil {
+		// The standard fonts may have no descriptor, their weight is in their name.
+		return false
+	}
+	if descriptor := font.FontDescriptor(); descriptor != nil {
+		if weight, err := core.GetNumberAsFloat(descriptor.FontWeight); err == nil && weight >= 600 {
+			return true
+		}
+		if flags, ok := core.GetIntVal(descriptor.Flags); ok && flags&0x40000 != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// decimalNumberingRe matches decimal heading numbers, such as 2 or 2.1.
+	decimalNumberingRe = regexp.MustCompile(`^(\d+(?:\.\d+)*)\.?\s+\S`)
+
+	// otherNumberingRe matches the other heading numberings, such as Chapter 1, IV. or B).
+	otherNumberingRe = regexp.MustCompile(`^(?:(?i:chapter|part|section|appendix)\s+\w+|[IVXLC]+[.)]|[A-Z][.)])\s*\S`)
+)
+
+// numberingLevel returns the level of the heading numbering of `text`, 0 if unknown, and true
+// if `text` is numbered.
+func numberingLevel(text string) (int, bool) {
+	if m := decimalNumberingRe.FindStringSubmatch(text); m != nil {
+		return strings.Count(m[1], ".") + 1, true
+	}
+	return 0, otherNumberingRe.MatchString(text)
+}
+
+// endsSentence returns true if `text` ends with a sentence punctuation.
+func endsSentence(text string) bool {
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, ",") ||
+		strings.HasSuffix(text, ";")
+}
+
+// structHeadings returns the headings of the structure tree of the document, if any.
+func (d *headingDetector) structHeadings() ([]Heading, error) {
+	trailer, err := d.reader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	if !ok {
+		return nil, nil
+	}
+	root, ok := core.GetDict(catalog.Get("StructTreeRoot"))
+	if !ok {
+		return nil, nil
+	}
+
+	w := &structWalker{
+		detector: d,
+		visited:  map[*core.PdfObjectDictionary]struct{}{},
+	}
+	w.roleMap, _ = core.GetDict(root.Get("RoleMap"))
+	w.walk(root.Get("K"), nil, 0)
+	return w.headings, nil
+}
+
+// structWalker collects the heading elements of a structure tree.
+type structWalker struct {
+	detector *headingDetector
+	roleMap  *core.PdfObjectDictionary
+	visited  map[*core.PdfObjectDictionary]struct{}
+	headings []Heading
+}
+
+// role returns the standard structure type of the structure type `name`.
+func (w *structWalker) role(name string) string {
+	for i := 0; i < 10 && w.roleMap != nil; i++ {
+		mapped, ok := core.GetNameVal(w.roleMap.Get(core.PdfObjectName(name)))
+		if !ok || mapped == name {
+			break
+		}
+		name = mapped
+	}
+	return name
+}
+
+// walk collects the headings of the structure elements `obj`, whose page is `page` unless
+// specified otherwise, nested in `sections` sections.
+func (w *structWalker) walk(obj core.PdfObject, page *core.PdfIndirectObject, sections int) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			w.walk(elem, page, sections)
+		}
+	case *core.PdfObjectDictionary:
+		if _, ok := w.visited[t]; ok {
+			return
+		}
+		w.visited[t] = struct{}{}
+		if pg, ok := core.GetIndirect(t.Get("Pg")); ok {
+			page = pg
+		}
+
+		role, _ := core.GetNameVal(t.Get("S"))
+		role = w.role(role)
+		level := 0
+		switch {
+		case role == "H":
+			level = sections
+			if level < 1 {
+				level = 1
+			}
+		case len(role) == 2 && role[0] == 'H' && role[1] >= '1' && role[1] <= '6':
+			level, _ = strconv.Atoi(role[1:])
+		case role == "Sect":
+			sections++
+		}
+		if level > 0 {
+			if level <= w.detector.opts.MaxLevels {
+				w.addHeading(t, page, level)
+			}
+			return
+		}
+		w.walk(t.Get("K"), page, sections)
+	}
+}
+
+// markedContentRef is a reference to a marked-content sequence of a page.
+type markedContentRef struct {
+	page *core.PdfIndirectObject
+	mcid int
+}
+
+// markedContent returns the marked-content sequences of the content `obj` of a structure
+// element, whose page is `page` unless specified otherwise.
+func (w *structWalker) markedContent(obj core.PdfObject, page *core.PdfIndirectObject,
+	refs []markedContentRef) []markedContentRef {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectInteger:
+		refs = append(refs, markedContentRef{page, int(*t)})
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			refs = w.markedContent(elem, page, refs)
+		}
+	case *core.PdfObjectDictionary:
+		if pg, ok := core.GetIndirect(t.Get("Pg")); ok {
+			page = pg
+		}
+		if mcid, ok := core.GetIntVal(t.Get("MCID")); ok {
+			refs = append(refs, markedContentRef{page, mcid})
+		} else if t.Get("Obj") == nil {
+			refs = w.markedContent(t.Get("K"), page, refs)
+		}
+	}
+	return refs
+}
+
+// addHeading adds the heading of the structure element `elem` of the page `page`.
+func (w *structWalker) addHeading(elem *core.PdfObjectDictionary, page *core.PdfIndirectObject, level int) {
+	h := Heading{Level: level}
+	refs := w.markedContent(elem.Get("K"), page, nil)
+	if page == nil && len(refs) > 0 {
+		page = refs[0].page
+	}
+	if page == nil {
+		return
+	}
+	if _, pageNum, err := w.detector.reader.PageFromIndirectObject(page); err == nil {
+		h.PageNum = pageNum
+	} else {
+		return
+	}
+
+	// Text and position of the marked content of the heading.
+	var marks []*textMark
+	if pageText := w.detector.pageText(h.PageNum); pageText != nil {
+		for _, ref := range refs {
+			if ref.page != page {
+				continue
+			}
+			for _, mark := range pageText.marks {
+				if mark.mcid == ref.mcid {
+					marks = append(marks, mark)
+				}
+			}
+		}
+	}
+	for i, mark := range marks {
+		if i == 0 {
+			h.BBox = mark.originaBBox
+		} else {
+			h.BBox = h.BBox.Union(mark.originaBBox)
+		}
+		h.FontSize = math.Max(h.FontSize, mark.fontsize)
+	}
+	if len(marks) > 0 {
+		h.Bold = isBoldFont(marks[0].font)
+	}
+
+	if text, ok := core.GetString(elem.Get("ActualText")); ok {
+		h.Title = text.Decoded()
+	}
+	if h.Title == "" {
+		h.Title = joinMarks(marks)
+	}
+	if h.Title == "" {
+		if text, ok := core.GetString(elem.Get("T")); ok {
+			h.Title = text.Decoded()
+		}
+	}
+	h.Title = strings.Join(strings.Fields(h.Title), " ")
+	if h.Title == "" || len([]rune(h.Title)) > w.detector.opts.MaxLength {
+		return
+	}
+	w.headings = append(w.headings, h)
+}
+
+// joinMarks returns the text of the text marks `marks`, separated by spaces where the marks
+// are apart.
+func joinMarks(marks []*textMark) string {
+	var text strings.Builder
+	for i, mark := range marks {
+		if i > 0 {
+			prev := marks[i-1]
+			if math.Abs(mark.Lly-prev.Lly) > prev.fontsize/2 || mark.Llx-prev.Urx > mark.fontsize*0.15 {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(mark.text)
+	}
+	return text.String()
+}
@@ -0,0 +1,153 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/creator"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// headingTitles returns the titles and levels of `headings`.
+func headingTitles(headings []Heading) []string {
+	var titles []string
+	for _, h := range headings {
+		titles = append(titles, fmt.Sprintf("%d:%d:%s", h.PageNum, h.Level, h.Title))
+	}
+	return titles
+}
+
+func TestDetectHeadings(t *testing.T) {
+	regular, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	bold, err := model.NewStandard14Font(model.HelveticaBoldName)
+	require.NoError(t, err)
+
+	c := creator.New()
+	draw := func(text string, font *model.PdfFont, size float64) {
+		p := c.NewParagraph(text)
+		p.SetFont(font)
+		p.SetFontSize(size)
+		p.SetMargins(0, 0, 0, 10)
+		require.NoError(t, c.Draw(p))
+	}
+	body := strings.Repeat("The body text of the section spans several lines of the page. ", 8)
+	c.NewPage()
+	draw("1 Introduction", bold, 18)
+	draw(body, regular, 10)
+	draw("1.1 Scope", bold, 14)
+	draw(body, regular, 10)
+	draw("Overview", bold, 14)
+	draw(body, regular, 10)
+	c.NewPage()
+	draw("2 Results", bold, 18)
+	draw(body, regular, 10)
+	draw("An emphasized sentence.", bold, 10)
+	draw("Notes", bold, 10)
+	draw(body, regular, 10)
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	headings, err := DetectHeadings(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"1:1:1 Introduction",
+		"1:2:1.1 Scope",
+		"1:2:Overview",
+		"2:1:2 Results",
+		"2:3:Notes",
+	}, headingTitles(headings))
+	require.True(t, headings[0].Bold)
+	require.InDelta(t, 18, headings[0].FontSize, 0.5)
+	require.True(t, headings[0].BBox.Ury > headings[1].BBox.Ury)
+
+	// Limited levels.
+	limited, err := DetectHeadings(reader, &HeadingOptions{MaxLevels: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1:1:1 Introduction", "2:1:2 Results"}, headingTitles(limited))
+
+	// Outline of the headings, written with an appender.
+	outline := HeadingsOutline(reader, headings)
+	require.Len(t, outline.Entries, 2)
+	require.Len(t, outline.Entries[0].Entries, 2)
+	require.Equal(t, "Notes", outline.Entries[1].Entries[0].Title)
+
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.SetOutlineTree(outline.ToOutlineTree())
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err = reader.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 2)
+	results := outline.Entries[1]
+	require.Equal(t, "2 Results", results.Title)
+	require.Equal(t, int64(1), results.Dest.Page)
+	require.InDelta(t, headings[3].BBox.Ury, results.Dest.Y, 0.01)
+}
+
+// buildTestPDF returns a PDF file with the objects `objects`, the first one being the catalog.
+func buildTestPDF(objects []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestDetectHeadingsStructTree(t *testing.T) {
+	content := "BT /F1 20 Tf 72 700 Td /H1 <</MCID 0>> BDC (Tagged Title) Tj EMC ET\n" +
+		"BT /F1 10 Tf 72 680 Td /P <</MCID 1>> BDC (Body text of the tagged document.) Tj EMC ET\n" +
+		"BT /F1 14 Tf 72 660 Td /Sub /MC0 BDC (Sub) Tj EMC ET\n"
+	data := buildTestPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 5 0 R /MarkInfo << /Marked true >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> " +
+			"/Properties << /MC0 << /MCID 2 >> >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		"<< /Type /StructTreeRoot /K 6 0 R /RoleMap << /Sub /H2 >> >>",
+		"<< /Type /StructElem /S /Document /P 5 0 R /K [7 0 R 8 0 R 9 0 R] >>",
+		"<< /Type /StructElem /S /H1 /P 6 0 R /Pg 3 0 R /K 0 >>",
+		"<< /Type /StructElem /S /P /P 6 0 R /Pg 3 0 R /K 1 >>",
+		"<< /Type /StructElem /S /Sub /P 6 0 R /Pg 3 0 R /K [<< /Type /MCR /MCID 2 >>] " +
+			"/ActualText (Subsection) >>",
+	})
+
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	headings, err := DetectHeadings(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1:1:Tagged Title", "1:2:Subsection"}, headingTitles(headings))
+	require.InDelta(t, 72, headings[0].BBox.Llx, 0.01)
+	require.InDelta(t, 700, headings[0].BBox.Lly, 1)
+	require.InDelta(t, 20, headings[0].FontSize, 0.01)
+	require.InDelta(t, 660, headings[1].BBox.Lly, 1)
+
+	// Detection from the text style.
+	headings, err = DetectHeadings(reader, &HeadingOptions{IgnoreStructTree: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1:1:Tagged Title", "1:2:Sub"}, headingTitles(headings))
+}
@@ -353,6 +353,14 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				pageText.marks = append(pageText.marks, formResult.pageText.marks...)
 				state.numChars += formResult.numChars
 				state.numMisses += formResult.numMisses
+			case "BMC": // Begin marked-content sequence.
+				e.markedContent = append(e.markedContent, -1)
+			case "BDC": // Begin marked-content sequence with property list.
+				e.markedContent = append(e.markedContent, markedContentID(op, resources))
+			case "EMC": // End marked-content sequence.
+				if n := len(e.markedContent); n > 0 {
+					e.markedContent = e.markedContent[:n-1]
+				}
 			case "rg", "g", "k", "cs", "sc", "scn":
 				// Set non-stroking color/colorspace.
 				to.gs.ColorspaceNonStroking = gs.ColorspaceNonStroking
@@ -855,6 +863,44 @@ func (to *textObject) renderText(data []byte) error {
 	return nil
 }
 
+// markedContentID returns the marked-content identifier (MCID) of the property list of the BDC
+// operator `op`, either inline or named in the Properties of `resources`, or -1 if none.
+func markedContentID(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) int {
+	if len(op.Params) != 2 {
+		return -1
+	}
+	var props *core.PdfObjectDictionary
+	switch t := core.TraceToDirectObject(op.Params[1]).(type) {
+	case *core.PdfObjectDictionary:
+		props = t
+	case *core.PdfObjectName:
+		if resources != nil {
+			if properties, ok := core.GetDict(resources.Properties); ok {
+				props, _ = core.GetDict(properties.Get(*t))
+			}
+		}
+	}
+	if props == nil {
+		return -1
+	}
+	if mcid, ok := core.GetIntVal(props.Get("MCID")); ok {
+		return mcid
+	}
+	return -1
+}
+
+// currentMCID returns the marked-content identifier of the innermost marked-content sequence
+// with identifier, or -1 if none.
+func (e *Extractor) currentMCID() int {
+	for i := len(e.markedContent) - 1; i >= 0; i-- {
+		if e.markedContent[i] >= 0 {
+			return e.markedContent[i]
+		}
+	}
+	return -1
+}
+
 // glyphTextRatio converts Glyph metrics units to unscaled text space units.
 const glyphTextRatio = 1.0 / 1000.0
 
@@ -1037,7 +1083,7 @@ func (ma *TextMarkArray) BBox() (model.PdfRectangle, bool) {
 			continue
 		}
 		if found {
-			bbox = rectUnion(bbox, tm.BBox)
+			bbox = bbox.Union(tm.BBox)
 		} else {
 			bbox = tm.BBox
 			found = true
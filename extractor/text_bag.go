@@ -230,7 +230,7 @@ func (b *wordBag) stratum(depthIdx int) []*textWord {
 // `depthIdx` is the depth index of `word` in all wordBags.
 // TODO(peterwilliams97): Compute depthIdx from `word` instead of passing it around.
 func (b *wordBag) pullWord(bag *wordBag, word *textWord, depthIdx int) {
-	b.PdfRectangle = rectUnion(b.PdfRectangle, word.PdfRectangle)
+	b.PdfRectangle = b.PdfRectangle.Union(word.PdfRectangle)
 	if word.fontsize > b.fontsize {
 		b.fontsize = word.fontsize
 	}
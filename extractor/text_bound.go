@@ -97,16 +97,6 @@ func partial(overlap func(*wordBag, *textWord, float64) bool,
 	}
 }
 
-// rectUnion returns the smallest axis-aligned rectangle that contains `b1` and `b2`.
-func rectUnion(b1, b2 model.PdfRectangle) model.PdfRectangle {
-	return model.PdfRectangle{
-		Llx: math.Min(b1.Llx, b2.Llx),
-		Lly: math.Min(b1.Lly, b2.Lly),
-		Urx: math.Max(b1.Urx, b2.Urx),
-		Ury: math.Max(b1.Ury, b2.Ury),
-	}
-}
-
 // rectIntersection returns the largest axis-aligned rectangle that is contained by `b1` and `b2`.
 func rectIntersection(b1, b2 model.PdfRectangle) (model.PdfRectangle, bool) {
 	if !intersects(b1, b2) {
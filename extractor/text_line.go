@@ -82,7 +82,7 @@ func (l *textLine) pullWord(bag *wordBag, word *textWord, depthIdx int) {
 // `l.fontsize` is the largest of the fontsizes of the words in line.
 func (l *textLine) appendWord(word *textWord) {
 	l.words = append(l.words, word)
-	l.PdfRectangle = rectUnion(l.PdfRectangle, word.PdfRectangle)
+	l.PdfRectangle = l.PdfRectangle.Union(word.PdfRectangle)
 	if word.fontsize > l.fontsize {
 		l.fontsize = word.fontsize
 	}
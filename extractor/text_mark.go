@@ -30,6 +30,7 @@ type textMark struct {
 	originaBBox        model.PdfRectangle // Bounding box without orientation correction.
 	fillColor          color.Color        // Text fill color.
 	strokeColor        color.Color        // Text stroke color.
+	mcid               int                // Marked-content identifier, -1 if none.
 }
 
 // newTextMark returns a textMark for text `text` rendered with text rendering matrix (TRM) `trm`
@@ -123,6 +124,7 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 		orient:       orient,
 		fillColor:    fillColor,
 		strokeColor:  strokeColor,
+		mcid:         to.e.currentMCID(),
 	}
 	if verboseGeom {
 		common.Log.Info("newTextMark: start=%.2f end=%.2f %s", start, end, tm.String())
@@ -247,11 +247,11 @@ func (t *textTable) newTablePara() *textPara {
 func (t *textTable) computeBbox() model.PdfRectangle {
 	r := t.get(0, 0).PdfRectangle
 	for x := 1; x < t.w; x++ {
-		r = rectUnion(r, t.get(x, 0).PdfRectangle)
+		r = r.Union(t.get(x, 0).PdfRectangle)
 	}
 	for y := 1; y < t.h; y++ {
 		for x := 0; x < t.w; x++ {
-			r = rectUnion(r, t.get(x, y).PdfRectangle)
+			r = r.Union(t.get(x, y).PdfRectangle)
 		}
 	}
 	return r
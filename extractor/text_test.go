@@ -6,6 +6,7 @@
 package extractor
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -21,6 +22,8 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/loxiouve/unipdf/v3/common"
 	"github.com/loxiouve/unipdf/v3/creator"
 	"github.com/loxiouve/unipdf/v3/model"
@@ -168,6 +171,43 @@ func TestTextLocations(t *testing.T) {
 	}
 }
 
+// TestType3FontText tests the extraction of text shown with a Type 3 font, the codes being mapped
+// through the Encoding and the ToUnicode CMap and the widths through the FontMatrix.
+func TestType3FontText(t *testing.T) {
+	toUnicode := "begincmap\n1 begincodespacerange\n<00> <FF>\nendcodespacerange\n" +
+		"1 beginbfchar\n<42> <0042>\nendbfchar\nendcmap\n"
+	glyph := "50 0 0 0 50 100 d1\n0 0 50 100 re f\n"
+	content := "BT /F1 20 Tf 72 700 Td (AB) Tj ET\n"
+	data := buildTestPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type3 /FontBBox [0 0 100 100] /FontMatrix [0.01 0 0 0.01 0 0] " +
+			"/CharProcs << /A 6 0 R /g66 6 0 R >> /Encoding << /Differences [65 /A /g66] >> " +
+			"/FirstChar 65 /LastChar 66 /Widths [50 100] /ToUnicode 7 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(glyph), glyph),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(toUnicode), toUnicode),
+	})
+
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+
+	text, marks := pageTextAndMarks(t, "type3", page)
+	require.Equal(t, "AB", strings.TrimSpace(text))
+	elements := marks.Elements()
+	require.True(t, len(elements) >= 2)
+	require.Equal(t, "A", elements[0].Text)
+	require.Equal(t, "B", elements[1].Text)
+	require.InDelta(t, 72, elements[0].BBox.Llx, 0.01)
+	require.InDelta(t, 82, elements[0].BBox.Urx, 0.01)
+	require.InDelta(t, 82, elements[1].BBox.Llx, 0.01)
+	require.InDelta(t, 102, elements[1].BBox.Urx, 0.01)
+}
+
 // TestTermMarksFiles stress tests testTermMarksMulti() by running it on all files in the corpus.
 // It can take several minutes to run.
 func TestTermMarksFiles(t *testing.T) {
@@ -115,7 +115,7 @@ func newTextWord(marks []*textMark, pageSize model.PdfRectangle) *textWord {
 	r := marks[0].PdfRectangle
 	fontsize := marks[0].fontsize
 	for _, tm := range marks[1:] {
-		r = rectUnion(r, tm.PdfRectangle)
+		r = r.Union(tm.PdfRectangle)
 		if tm.fontsize > fontsize {
 			fontsize = tm.fontsize
 		}
@@ -144,7 +144,7 @@ func (w *textWord) bbox() model.PdfRectangle {
 // `pageSize` is used to calculate the word's depth on the page.
 func (w *textWord) appendMark(tm *textMark, pageSize model.PdfRectangle) {
 	w.marks = append(w.marks, tm)
-	w.PdfRectangle = rectUnion(w.PdfRectangle, tm.PdfRectangle)
+	w.PdfRectangle = w.PdfRectangle.Union(tm.PdfRectangle)
 	if tm.fontsize > w.fontsize {
 		w.fontsize = tm.fontsize
 	}
@@ -161,7 +161,7 @@ func (w *textWord) addDiacritic(text string) {
 
 // absorb combines `word` into `w`.
 func (w *textWord) absorb(word *textWord) {
-	w.PdfRectangle = rectUnion(w.PdfRectangle, word.PdfRectangle)
+	w.PdfRectangle = w.PdfRectangle.Union(word.PdfRectangle)
 	w.marks = append(w.marks, word.marks...)
 }
 
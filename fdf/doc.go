@@ -3,5 +3,7 @@
  * file 'LICENSE.md', which is part of this source code package.
  */
 
-// Package fdf provides support for loading form field data from Form Field Data (FDF) files.
+// Package fdf provides support for loading and writing form field data and annotations in Forms
+// Data Format (FDF) files, and for importing and exporting them in the XML Forms Data Format
+// (XFDF).
 package fdf
@@ -6,12 +6,13 @@
 package fdf
 
 import (
-	"errors"
 	"io"
 	"os"
 	"sort"
 
+	"github.com/loxiouve/unipdf/v3/common"
 	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
 )
 
 // Data represents forms data format (FDF) file data.
@@ -20,6 +21,37 @@ type Data struct {
 	fields *core.PdfObjectArray
 }
 
+// Template represents a page template of the FDF data, used for spawning a new page in the target
+// document, with the values of its fields.
+type Template struct {
+	// PageNum is the number of the FDF page which contains the template. The templates of the same
+	// FDF page are overlaid into a single page.
+	PageNum int
+
+	// Name of the template in the document containing it.
+	Name string
+
+	// File is the file containing the template, or empty if it is the target document.
+	File string
+
+	// Rename indicates whether the fields of the template are renamed when spawning the page,
+	// to avoid conflicts with the fields of the existing pages.
+	Rename bool
+
+	fields *core.PdfObjectArray
+}
+
+// NewData returns new empty FDF data.
+func NewData() *Data {
+	fields := core.MakeArray()
+	root := core.MakeDict()
+	root.Set("Fields", fields)
+	return &Data{
+		root:   root,
+		fields: fields,
+	}
+}
+
 // Load loads FDF form data from `r`.
 func Load(r io.ReadSeeker) (*Data, error) {
 	p, err := newParser(r)
@@ -31,10 +63,12 @@ func Load(r io.ReadSeeker) (*Data, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.resolveReferences(fdfDict, map[core.PdfObject]struct{}{})
 
+	// The fields are optional, e.g. for FDF files containing only annotations.
 	fields, found := core.GetArray(fdfDict.Get("Fields"))
 	if !found {
-		return nil, errors.New("fields missing")
+		fields = core.MakeArray()
 	}
 
 	return &Data{
@@ -54,31 +88,62 @@ func LoadFromPath(fdfPath string) (*Data, error) {
 	return Load(f)
 }
 
-// FieldDictionaries returns a map of field names to field dictionaries.
+// FieldDictionaries returns a map of field names to field dictionaries. The kids of hierarchical
+// fields are mapped by their full names, e.g. "address.city".
 func (fdf *Data) FieldDictionaries() (map[string]*core.PdfObjectDictionary, error) {
+	return fieldDictionaries(fdf.fields), nil
+}
+
+// fieldDictionaries returns a map of the full names of the `fields` and their kids to the field
+// dictionaries.
+func fieldDictionaries(fields *core.PdfObjectArray) map[string]*core.PdfObjectDictionary {
 	fieldDataMap := map[string]*core.PdfObjectDictionary{}
 
-	for i := 0; i < fdf.fields.Len(); i++ {
-		fieldDict, has := core.GetDict(fdf.fields.Get(i))
-		if has {
+	var add func(prefix string, fields *core.PdfObjectArray, depth int)
+	add = func(prefix string, fields *core.PdfObjectArray, depth int) {
+		if depth > maxFieldDepth {
+			common.Log.Debug("ERROR: field hierarchy too deep")
+			return
+		}
+		for _, obj := range fields.Elements() {
+			fieldDict, has := core.GetDict(obj)
+			if !has {
+				continue
+			}
 			// Key value field data.
 			t, _ := core.GetString(fieldDict.Get("T"))
-			if t != nil {
-				fieldDataMap[t.Str()] = fieldDict
+			if t == nil {
+				continue
+			}
+			name := t.Decoded()
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			fieldDataMap[name] = fieldDict
+
+			if kids, ok := core.GetArray(fieldDict.Get("Kids")); ok {
+				add(name, kids, depth+1)
 			}
 		}
 	}
+	add("", fields, 0)
 
-	return fieldDataMap, nil
+	return fieldDataMap
 }
 
+// maxFieldDepth is the maximum depth of the field hierarchies.
+const maxFieldDepth = 100
+
 // FieldValues implements interface model.FieldValueProvider.
 // Returns a map of field names to values (PdfObjects).
 func (fdf *Data) FieldValues() (map[string]core.PdfObject, error) {
-	fieldDictMap, err := fdf.FieldDictionaries()
-	if err != nil {
-		return nil, err
-	}
+	return fieldValues(fdf.fields), nil
+}
+
+// fieldValues returns a map of the full names of the `fields` and their kids to their values.
+// Non terminal fields without values are skipped.
+func fieldValues(fields *core.PdfObjectArray) map[string]core.PdfObject {
+	fieldDictMap := fieldDictionaries(fields)
 
 	var keys []string
 	for fieldName := range fieldDictMap {
@@ -90,8 +155,181 @@ func (fdf *Data) FieldValues() (map[string]core.PdfObject, error) {
 	for _, fieldName := range keys {
 		fieldDict := fieldDictMap[fieldName]
 		val := core.TraceToDirectObject(fieldDict.Get("V"))
+		if val == nil && fieldDict.Get("Kids") != nil {
+			continue
+		}
 		fieldValMap[fieldName] = val
 	}
 
-	return fieldValMap, nil
+	return fieldValMap
+}
+
+// Status returns the status message of the FDF data (Status), to be displayed by the viewer
+// when importing the data. Returns an empty string if not set.
+func (fdf *Data) Status() string {
+	if str, ok := core.GetString(fdf.root.Get("Status")); ok {
+		return str.Decoded()
+	}
+	return ""
+}
+
+// SetStatus sets the status message of the FDF data.
+func (fdf *Data) SetStatus(status string) {
+	if status == "" {
+		fdf.root.Remove("Status")
+		return
+	}
+	fdf.root.Set("Status", core.MakeString(status))
+}
+
+// TargetFile returns the file specification of the document the FDF data was exported from, or
+// is intended to be imported into (F). Returns an empty string if not set.
+func (fdf *Data) TargetFile() string {
+	return fileSpecName(fdf.root.Get("F"))
+}
+
+// SetTargetFile sets the file specification of the target document of the FDF data.
+func (fdf *Data) SetTargetFile(file string) {
+	if file == "" {
+		fdf.root.Remove("F")
+		return
+	}
+	fdf.root.Set("F", core.MakeString(file))
+}
+
+// fileSpecName returns the file name of the file specification `obj`, which is either a string
+// or a file specification dictionary.
+func fileSpecName(obj core.PdfObject) string {
+	obj = core.TraceToDirectObject(obj)
+	if str, ok := obj.(*core.PdfObjectString); ok {
+		return str.Decoded()
+	}
+	dict, ok := obj.(*core.PdfObjectDictionary)
+	if !ok {
+		return ""
+	}
+	for _, key := range []core.PdfObjectName{"UF", "F", "Unix", "DOS", "Mac"} {
+		if str, ok := core.GetString(dict.Get(key)); ok {
+			return str.Decoded()
+		}
+	}
+	return ""
+}
+
+// Annotations returns the annotations of the FDF data (Annots). The popups follow their parent
+// annotations.
+func (fdf *Data) Annotations() ([]*Annotation, error) {
+	annots, err := fdf.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	return pageAnnotations(annots), nil
+}
+
+// loadAnnotations returns the annotations of the FDF data. The popups of the markup annotations
+// are loaded with their parent annotations.
+func (fdf *Data) loadAnnotations() ([]*pageAnnotation, error) {
+	annotsArr, ok := core.GetArray(fdf.root.Get("Annots"))
+	if !ok {
+		return nil, nil
+	}
+
+	var annots []*pageAnnotation
+	parents := map[core.PdfObject]struct{}{}
+	for _, obj := range annotsArr.Elements() {
+		annot, err := model.NewPdfAnnotationFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		a := &pageAnnotation{annot: annot}
+		if str, ok := core.GetString(annot.NM); ok {
+			a.name = str.Decoded()
+		}
+		if dict, ok := core.GetDict(obj); ok {
+			if page, ok := core.GetIntVal(dict.Get("Page")); ok {
+				a.page = page
+			}
+		}
+		if a.markup = annotationMarkup(annot); a.markup != nil {
+			a.popup = a.markup.Popup
+		}
+		if a.popup != nil {
+			parents[annot.GetContainingPdfObject()] = struct{}{}
+		}
+		annots = append(annots, a)
+	}
+
+	// Skip the popups loaded with their parents.
+	var filtered []*pageAnnotation
+	for _, a := range annots {
+		if popup, ok := a.annot.GetContext().(*model.PdfAnnotationPopup); ok {
+			if _, has := parents[popup.Parent]; has {
+				continue
+			}
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered, nil
+}
+
+// Templates returns the page templates of the FDF data (Pages).
+func (fdf *Data) Templates() ([]*Template, error) {
+	pages, ok := core.GetArray(fdf.root.Get("Pages"))
+	if !ok {
+		return nil, nil
+	}
+
+	var templates []*Template
+	for i, obj := range pages.Elements() {
+		pageDict, ok := core.GetDict(obj)
+		if !ok {
+			common.Log.Debug("ERROR: invalid FDF page (%T)", obj)
+			continue
+		}
+		list, ok := core.GetArray(pageDict.Get("Templates"))
+		if !ok {
+			continue
+		}
+		for _, tobj := range list.Elements() {
+			tdict, ok := core.GetDict(tobj)
+			if !ok {
+				common.Log.Debug("ERROR: invalid FDF template (%T)", tobj)
+				continue
+			}
+			t := &Template{PageNum: i + 1, Rename: true}
+			if tref, ok := core.GetDict(tdict.Get("TRef")); ok {
+				if name, ok := core.GetString(tref.Get("Name")); ok {
+					t.Name = name.Decoded()
+				}
+				t.File = fileSpecName(tref.Get("F"))
+			}
+			if rename, ok := core.GetBoolVal(tdict.Get("Rename")); ok {
+				t.Rename = rename
+			}
+			if fields, ok := core.GetArray(tdict.Get("Fields")); ok {
+				t.fields = fields
+			} else {
+				t.fields = core.MakeArray()
+			}
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+// FieldValues implements interface model.FieldValueProvider.
+// Returns a map of the names of the template fields to values (PdfObjects).
+func (t *Template) FieldValues() (map[string]core.PdfObject, error) {
+	return fieldValues(t.fields), nil
+}
+
+// Import imports the FDF data into the document of `appender`. The form is filled with the field
+// values, and the annotations are added to their pages. The annotations of the document with the
+// same names (NM) as the FDF annotations are replaced.
+func (fdf *Data) Import(appender *model.PdfAppender, opts *ImportOptions) error {
+	annots, err := fdf.loadAnnotations()
+	if err != nil {
+		return err
+	}
+	return importFormData(appender, fdf, fdf.fields.Len() > 0, annots, opts)
 }
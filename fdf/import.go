@@ -0,0 +1,189 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// Annotation is an annotation loaded from FDF or XFDF data, with the number of the page it
+// belongs to.
+type Annotation struct {
+	PageNum    int
+	Annotation *model.PdfAnnotation
+}
+
+// ImportOptions define the options for importing FDF or XFDF data into a document.
+type ImportOptions struct {
+	// FieldAppearance generates the appearances of the filled form fields, if not nil.
+	// e.g.: annotator.FieldAppearance{OnlyIfMissing: true, RegenerateTextFields: true}
+	FieldAppearance model.FieldAppearanceGenerator
+
+	// AnnotationAppearance generates the appearance of the imported annotations, if not nil.
+	// XFDF data does not contain the appearances of the annotations, which are generated by
+	// most viewers. e.g.: annotator.GenerateMarkupAppearance
+	AnnotationAppearance func(annot *model.PdfAnnotation) error
+}
+
+// pageAnnotation is an annotation to be imported to a page, loaded from FDF or XFDF data.
+type pageAnnotation struct {
+	name      string
+	page      int    // Zero based page index.
+	inReplyTo string // Name of the annotation replied to, when not referred by IRT.
+	annot     *model.PdfAnnotation
+	markup    *model.PdfAnnotationMarkup
+	popup     *model.PdfAnnotationPopup
+}
+
+// pageAnnotations returns the annotations `annots` with their page numbers. The popups follow
+// their parent annotations.
+func pageAnnotations(annots []*pageAnnotation) []*Annotation {
+	var list []*Annotation
+	for _, a := range annots {
+		list = append(list, &Annotation{PageNum: a.page + 1, Annotation: a.annot})
+		if a.popup != nil {
+			list = append(list, &Annotation{PageNum: a.page + 1, Annotation: a.popup.PdfAnnotation})
+		}
+	}
+	return list
+}
+
+// importFormData imports form data and annotations into the document of `appender`. The form is
+// filled with the field values of `provider` if `hasFields` is true, and the annotations `annots`
+// are added to their pages. The annotations of the document with the same names (NM) as the
+// imported annotations are replaced.
+func importFormData(appender *model.PdfAppender, provider model.FieldValueProvider, hasFields bool,
+	annots []*pageAnnotation, opts *ImportOptions) error {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+	reader := appender.Reader
+
+	if hasFields && reader.AcroForm != nil {
+		if err := reader.AcroForm.FillWithAppearance(provider, opts.FieldAppearance); err != nil {
+			return err
+		}
+		appender.ReplaceAcroForm(reader.AcroForm)
+	}
+	if len(annots) == 0 {
+		return nil
+	}
+
+	// Annotations of the document, by name.
+	type location struct {
+		page  int
+		annot *model.PdfAnnotation
+	}
+	existing := map[string]location{}
+	pageAnnots := make([][]*model.PdfAnnotation, len(reader.PageList))
+	for i, page := range reader.PageList {
+		list, err := page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		pageAnnots[i] = list
+		for _, annot := range list {
+			if str, ok := core.GetString(annot.NM); ok && str.Decoded() != "" {
+				existing[str.Decoded()] = location{page: i, annot: annot}
+			}
+		}
+	}
+
+	// Containers of the replaced annotations, mapped to their replacements.
+	replaced := map[core.PdfObject]core.PdfObject{}
+	changedPages := map[int]bool{}
+	byName := map[string]*model.PdfAnnotation{}
+
+	for _, a := range annots {
+		if a.page < 0 || a.page >= len(pageAnnots) {
+			return fmt.Errorf("annotation %s: page %d not found", a.name, a.page+1)
+		}
+		if opts.AnnotationAppearance != nil {
+			if err := opts.AnnotationAppearance(a.annot); err != nil {
+				common.Log.Debug("ERROR: unable to generate appearance of annotation %s: %v", a.name, err)
+			}
+		}
+
+		added := []*model.PdfAnnotation{a.annot}
+		if a.popup != nil {
+			added = append(added, a.popup.PdfAnnotation)
+		}
+
+		old, found := existing[a.name]
+		if found {
+			// Remove the replaced annotation and its popup.
+			oldObj := old.annot.GetContainingPdfObject()
+			replaced[oldObj] = a.annot.GetContainingPdfObject()
+
+			var list []*model.PdfAnnotation
+			for _, annot := range pageAnnots[old.page] {
+				if annot == old.annot && old.page == a.page {
+					list = append(list, added...)
+					added = nil
+					continue
+				}
+				if annot == old.annot || isPopupOf(annot, oldObj) {
+					continue
+				}
+				list = append(list, annot)
+			}
+			pageAnnots[old.page] = list
+			changedPages[old.page] = true
+		}
+		pageAnnots[a.page] = append(pageAnnots[a.page], added...)
+		changedPages[a.page] = true
+
+		if a.name != "" {
+			byName[a.name] = a.annot
+		}
+	}
+
+	// Link the replies to the annotations they reply to.
+	for _, a := range annots {
+		if a.inReplyTo == "" || a.markup == nil {
+			continue
+		}
+		if parent, ok := byName[a.inReplyTo]; ok {
+			a.markup.IRT = parent.GetContainingPdfObject()
+		} else if loc, ok := existing[a.inReplyTo]; ok {
+			a.markup.IRT = loc.annot.GetContainingPdfObject()
+		} else {
+			common.Log.Debug("WARN: annotation %s replies to missing annotation %s", a.name, a.inReplyTo)
+		}
+	}
+	// The replies of the replaced annotations refer to the replacements.
+	for i, list := range pageAnnots {
+		for _, annot := range list {
+			markup := annotationMarkup(annot)
+			if markup == nil || markup.IRT == nil {
+				continue
+			}
+			if obj, ok := replaced[markup.IRT]; ok {
+				markup.IRT = obj
+				changedPages[i] = true
+			}
+		}
+	}
+
+	for i, page := range reader.PageList {
+		if !changedPages[i] {
+			continue
+		}
+		page.SetAnnotations(pageAnnots[i])
+		appender.UpdatePage(page)
+	}
+	return nil
+}
+
+// isPopupOf returns true if `annot` is a popup annotation of the parent annotation `parentObj`.
+func isPopupOf(annot *model.PdfAnnotation, parentObj core.PdfObject) bool {
+	popup, ok := annot.GetContext().(*model.PdfAnnotationPopup)
+	return ok && popup.Parent == parentObj
+}
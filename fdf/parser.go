@@ -880,6 +880,47 @@ func (parser *fdfParser) trace(obj core.PdfObject) core.PdfObject {
 	return obj
 }
 
+// resolveReferences replaces the references contained in `obj` by the indirect objects and streams
+// they refer to, recursively. References to missing objects are replaced by null objects.
+func (parser *fdfParser) resolveReferences(obj core.PdfObject, traversed map[core.PdfObject]struct{}) {
+	if _, has := traversed[obj]; has {
+		return
+	}
+	traversed[obj] = struct{}{}
+
+	resolve := func(o core.PdfObject) core.PdfObject {
+		ref, ok := o.(*core.PdfObjectReference)
+		if !ok {
+			return o
+		}
+		if resolved, ok := parser.objCache[ref.ObjectNumber]; ok {
+			return resolved
+		}
+		common.Log.Debug("ERROR: object %d not found", ref.ObjectNumber)
+		return core.MakeNull()
+	}
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		t.PdfObject = resolve(t.PdfObject)
+		parser.resolveReferences(t.PdfObject, traversed)
+	case *core.PdfObjectStream:
+		parser.resolveReferences(t.PdfObjectDictionary, traversed)
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			val := resolve(t.Get(key))
+			t.Set(key, val)
+			parser.resolveReferences(val, traversed)
+		}
+	case *core.PdfObjectArray:
+		for i, o := range t.Elements() {
+			val := resolve(o)
+			t.Set(i, val)
+			parser.resolveReferences(val, traversed)
+		}
+	}
+}
+
 // parse runs through the file and parses indirect objects and loads into cache.
 func (parser *fdfParser) parse() error {
 	// Go to beginning, reset reader.
@@ -0,0 +1,267 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// ExportFDF returns the FDF data of the document loaded by `reader`: the field tree of its form
+// with the field values, and its annotations. Widget annotations are exported as form fields.
+func ExportFDF(reader *model.PdfReader) (*Data, error) {
+	fdf := NewData()
+	if reader.AcroForm != nil && reader.AcroForm.Fields != nil {
+		for _, field := range exportFDFFields(*reader.AcroForm.Fields) {
+			fdf.fields.Append(field)
+		}
+	}
+
+	// Copy the annotation dictionaries, replacing their pages (P) by page indices (Page).
+	pages := map[core.PdfObject]int{}
+	for i, page := range reader.PageList {
+		pages[page.GetContainingPdfObject()] = i
+	}
+	copies := map[core.PdfObject]*core.PdfIndirectObject{}
+	annots := core.MakeArray()
+	for i, page := range reader.PageList {
+		list, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annot := range list {
+			if _, ok := annot.GetContext().(*model.PdfAnnotationWidget); ok {
+				continue
+			}
+			container, ok := annot.GetContainingPdfObject().(*core.PdfIndirectObject)
+			if !ok {
+				continue
+			}
+			dict, ok := core.GetDict(container)
+			if !ok {
+				continue
+			}
+
+			d := core.MakeDict()
+			replaced := map[core.PdfObject]core.PdfObject{}
+			for _, key := range dict.Keys() {
+				switch key {
+				case "P":
+				case "Dest", "A", "AA":
+					// The destinations and actions refer to the pages by index.
+					d.Set(key, replacePageReferences(dict.Get(key), pages, replaced))
+				default:
+					d.Set(key, dict.Get(key))
+				}
+			}
+			d.Set("Page", core.MakeInteger(int64(i)))
+			obj := core.MakeIndirectObject(d)
+			copies[container] = obj
+			annots.Append(obj)
+		}
+	}
+
+	// The references between the annotations refer to the copies.
+	for _, obj := range annots.Elements() {
+		d := obj.(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+		for _, key := range []core.PdfObjectName{"Popup", "Parent", "IRT"} {
+			ref := d.Get(key)
+			if ref == nil {
+				continue
+			}
+			if c, ok := copies[core.ResolveReference(ref)]; ok {
+				d.Set(key, c)
+			} else {
+				d.Remove(key)
+			}
+		}
+	}
+	if annots.Len() > 0 {
+		fdf.root.Set("Annots", annots)
+	}
+	return fdf, nil
+}
+
+// replacePageReferences returns a copy of `obj` whose references to the pages of the document,
+// indexed by `pages`, are replaced by page indices, so that the page tree is not exported.
+// References to other pages are replaced by null. `replaced` contains the copies of the indirect
+// objects already replaced.
+func replacePageReferences(obj core.PdfObject, pages map[core.PdfObject]int,
+	replaced map[core.PdfObject]core.PdfObject) core.PdfObject {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		return replacePageReferences(t.Resolve(), pages, replaced)
+	case *core.PdfIndirectObject:
+		if i, ok := pages[t]; ok {
+			return core.MakeInteger(int64(i))
+		}
+		if c, ok := replaced[t]; ok {
+			return c
+		}
+		if d, ok := core.GetDict(t.PdfObject); ok {
+			if name, ok := core.GetNameVal(d.Get("Type")); ok && name == "Page" {
+				return core.MakeNull()
+			}
+		}
+		c := core.MakeIndirectObject(nil)
+		replaced[t] = c
+		c.PdfObject = replacePageReferences(t.PdfObject, pages, replaced)
+		return c
+	case *core.PdfObjectDictionary:
+		d := core.MakeDict()
+		for _, key := range t.Keys() {
+			d.Set(key, replacePageReferences(t.Get(key), pages, replaced))
+		}
+		return d
+	case *core.PdfObjectArray:
+		arr := core.MakeArray()
+		for _, elem := range t.Elements() {
+			arr.Append(replacePageReferences(elem, pages, replaced))
+		}
+		return arr
+	}
+	return obj
+}
+
+// exportFDFFields returns the FDF field dictionaries representing the form `fields`.
+func exportFDFFields(fields []*model.PdfField) []*core.PdfObjectDictionary {
+	var dicts []*core.PdfObjectDictionary
+	for _, f := range fields {
+		if f.T == nil {
+			// Fields without partial names are merged with their parents. Export the kids as
+			// fields of the form.
+			dicts = append(dicts, exportFDFFields(f.Kids)...)
+			continue
+		}
+
+		d := core.MakeDict()
+		d.Set("T", f.T)
+		if kids := exportFDFFields(f.Kids); len(kids) > 0 {
+			arr := core.MakeArray()
+			for _, kid := range kids {
+				arr.Append(kid)
+			}
+			d.Set("Kids", arr)
+		}
+		if v := core.TraceToDirectObject(f.V); v != nil {
+			d.Set("V", v)
+		}
+		if ft, ok := f.GetContext().(*model.PdfFieldText); ok && ft.RV != nil {
+			d.Set("RV", core.TraceToDirectObject(ft.RV))
+		}
+
+		if d.Get("Kids") == nil && d.Get("V") == nil {
+			continue
+		}
+		dicts = append(dicts, d)
+	}
+	return dicts
+}
+
+// Write writes the FDF data to `w`.
+func (fdf *Data) Write(w io.Writer) error {
+	fw := &fdfWriter{
+		w:       bufio.NewWriter(w),
+		numbers: map[core.PdfObject]int64{},
+	}
+
+	catalog := core.MakeDict()
+	catalog.Set("FDF", fdf.root)
+
+	fw.w.WriteString("%FDF-1.2\n%\xe2\xe3\xcf\xd3\n")
+	fw.w.WriteString("1 0 obj\n")
+	fw.writeObject(catalog)
+	fw.w.WriteString("\nendobj\n")
+
+	// Write the indirect objects referred by the written objects, which can refer to new ones.
+	for i := 0; i < len(fw.objects); i++ {
+		obj := fw.objects[i]
+		fmt.Fprintf(fw.w, "%d 0 obj\n", fw.numbers[obj])
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			fw.writeObject(t.PdfObject)
+		case *core.PdfObjectStream:
+			dict := core.MakeDict()
+			dict.Merge(t.PdfObjectDictionary)
+			dict.Set("Length", core.MakeInteger(int64(len(t.Stream))))
+			fw.writeObject(dict)
+			fw.w.WriteString("\nstream\n")
+			fw.w.Write(t.Stream)
+			fw.w.WriteString("\nendstream")
+		}
+		fw.w.WriteString("\nendobj\n")
+	}
+
+	fw.w.WriteString("trailer\n<</Root 1 0 R>>\n%%EOF\n")
+	return fw.w.Flush()
+}
+
+// WriteToFile writes the FDF data to file `outputPath`.
+func (fdf *Data) WriteToFile(outputPath string) error {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return fdf.Write(f)
+}
+
+// fdfWriter writes objects in FDF files. The indirect objects are numbered in the order they
+// are referred, starting after the catalog object.
+type fdfWriter struct {
+	w       *bufio.Writer
+	numbers map[core.PdfObject]int64
+	objects []core.PdfObject
+}
+
+// ref returns the reference to the indirect object or stream `obj`, to be written later.
+func (fw *fdfWriter) ref(obj core.PdfObject) string {
+	num, ok := fw.numbers[obj]
+	if !ok {
+		num = int64(len(fw.objects) + 2)
+		fw.numbers[obj] = num
+		fw.objects = append(fw.objects, obj)
+	}
+	return fmt.Sprintf("%d 0 R", num)
+}
+
+// writeObject writes the direct object `obj`, with references to the indirect objects it contains.
+func (fw *fdfWriter) writeObject(obj core.PdfObject) {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		// References to the objects of PDF documents loaded lazily. Null if not resolved.
+		fw.writeObject(t.Resolve())
+	case *core.PdfIndirectObject, *core.PdfObjectStream:
+		fw.w.WriteString(fw.ref(t))
+	case *core.PdfObjectDictionary:
+		fw.w.WriteString("<<")
+		for _, key := range t.Keys() {
+			fw.w.WriteString(key.WriteString())
+			fw.w.WriteByte(' ')
+			fw.writeObject(t.Get(key))
+		}
+		fw.w.WriteString(">>")
+	case *core.PdfObjectArray:
+		fw.w.WriteByte('[')
+		for i, o := range t.Elements() {
+			if i > 0 {
+				fw.w.WriteByte(' ')
+			}
+			fw.writeObject(o)
+		}
+		fw.w.WriteByte(']')
+	case nil:
+		fw.w.WriteString("null")
+	default:
+		fw.w.WriteString(obj.WriteString())
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+const fdfExample2 = `%FDF-1.2
+%âãÏÓ
+1 0 obj
+<</FDF<</F<</Type/Filespec/F(form.pdf)/UF(form.pdf)>>/Status(Data imported)
+/Fields[<</T(full_name)/V(John Doe)>><</T(address)/Kids[<</T(city)/V(Paris)>><</T(zip)/V 2 0 R>>]>>]
+/Annots[3 0 R 4 0 R]
+/Pages[<</Templates[<</TRef<</Name(invoice)>>/Fields[<</T(total)/V(42)>>]/Rename false>>]>>]>>>>
+endobj
+2 0 obj
+(75001)
+endobj
+3 0 obj
+<</Type/Annot/Subtype/Highlight/Page 0/NM(h1)/Rect[100 700 300 720]/C[1 0 0]
+/QuadPoints[100 720 300 720 100 700 300 700]/Contents(Check this)/Popup 4 0 R>>
+endobj
+4 0 obj
+<</Type/Annot/Subtype/Popup/Page 0/Rect[320 650 480 720]/Parent 3 0 R/Open true>>
+endobj
+trailer
+<</Root 1 0 R>>
+%%EOF
+`
+
+func TestFDFFeatures(t *testing.T) {
+	fdf, err := Load(bytes.NewReader([]byte(fdfExample2)))
+	require.NoError(t, err)
+
+	require.Equal(t, "Data imported", fdf.Status())
+	require.Equal(t, "form.pdf", fdf.TargetFile())
+
+	vals, err := fdf.FieldValues()
+	require.NoError(t, err)
+	require.Len(t, vals, 3)
+	require.Equal(t, "John Doe", vals["full_name"].String())
+	require.Equal(t, "Paris", vals["address.city"].String())
+	require.Equal(t, "75001", vals["address.zip"].String())
+
+	annots, err := fdf.Annotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 2)
+	highlight, ok := annots[0].Annotation.GetContext().(*model.PdfAnnotationHighlight)
+	require.True(t, ok)
+	require.Equal(t, 1, annots[0].PageNum)
+	require.NotNil(t, highlight.Popup)
+	require.Equal(t, highlight.Popup.PdfAnnotation, annots[1].Annotation)
+	require.Equal(t, highlight.GetContainingPdfObject(), highlight.Popup.Parent)
+
+	templates, err := fdf.Templates()
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	require.Equal(t, "invoice", templates[0].Name)
+	require.Equal(t, 1, templates[0].PageNum)
+	require.False(t, templates[0].Rename)
+	tvals, err := templates[0].FieldValues()
+	require.NoError(t, err)
+	require.Equal(t, "42", tvals["total"].String())
+}
+
+func TestFDFWrite(t *testing.T) {
+	fdf, err := Load(bytes.NewReader([]byte(fdfExample2)))
+	require.NoError(t, err)
+	fdf.SetStatus("Updated")
+
+	var buf bytes.Buffer
+	require.NoError(t, fdf.Write(&buf))
+
+	reloaded, err := Load(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "Updated", reloaded.Status())
+	require.Equal(t, "form.pdf", reloaded.TargetFile())
+
+	vals, err := reloaded.FieldValues()
+	require.NoError(t, err)
+	require.Equal(t, "75001", vals["address.zip"].String())
+
+	annots, err := reloaded.Annotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 2)
+	templates, err := reloaded.Templates()
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+}
+
+func TestFDFExportImport(t *testing.T) {
+	f, err := os.Open("../fjson/testdata/basicform.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	fdf, err := Load(bytes.NewReader([]byte(fdfExample2)))
+	require.NoError(t, err)
+	require.NoError(t, fdf.Import(appender, nil))
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	// Export the filled form and the annotations.
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	exported, err := ExportFDF(reader)
+	require.NoError(t, err)
+	exported.SetTargetFile("basicform.pdf")
+
+	buf.Reset()
+	require.NoError(t, exported.Write(&buf))
+	reloaded, err := Load(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "basicform.pdf", reloaded.TargetFile())
+
+	vals, err := reloaded.FieldValues()
+	require.NoError(t, err)
+	val, ok := core.GetString(vals["full_name"])
+	require.True(t, ok)
+	require.Equal(t, "John Doe", val.Decoded())
+
+	annots, err := reloaded.Annotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 2)
+	highlight, ok := annots[0].Annotation.GetContext().(*model.PdfAnnotationHighlight)
+	require.True(t, ok)
+	require.Equal(t, "Check this", highlight.Contents.(*core.PdfObjectString).Decoded())
+	require.Equal(t, highlight.GetContainingPdfObject(), highlight.Popup.Parent)
+}
+
+func TestFDFExportPageReferences(t *testing.T) {
+	f, err := os.Open("../fjson/testdata/basicform.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	// Add links to the page with a destination and a go to action.
+	page := reader.PageList[0]
+	dest := model.NewPdfAnnotationLink()
+	dest.Rect = core.MakeArrayFromFloats([]float64{100, 100, 200, 120})
+	dest.Dest = core.MakeArray(page.GetContainingPdfObject(), core.MakeName("Fit"))
+	page.AddAnnotation(dest.PdfAnnotation)
+	action := model.NewPdfActionGoTo()
+	action.D = core.MakeArray(page.GetContainingPdfObject(), core.MakeName("XYZ"),
+		core.MakeInteger(0), core.MakeInteger(792), core.MakeNull())
+	goTo := model.NewPdfAnnotationLink()
+	goTo.Rect = core.MakeArrayFromFloats([]float64{100, 200, 200, 220})
+	goTo.A = action.ToPdfObject()
+	page.AddAnnotation(goTo.PdfAnnotation)
+	appender.UpdatePage(page)
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	exported, err := ExportFDF(reader)
+	require.NoError(t, err)
+
+	// The page tree is not exported.
+	buf.Reset()
+	require.NoError(t, exported.Write(&buf))
+	require.NotRegexp(t, `/Type\s*/Pages?\b`, buf.String())
+
+	reloaded, err := Load(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	annots, err := reloaded.Annotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 2)
+
+	link, ok := annots[0].Annotation.GetContext().(*model.PdfAnnotationLink)
+	require.True(t, ok)
+	arr, ok := core.GetArray(link.Dest)
+	require.True(t, ok)
+	index, ok := core.GetIntVal(arr.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 0, index)
+
+	link, ok = annots[1].Annotation.GetContext().(*model.PdfAnnotationLink)
+	require.True(t, ok)
+	a, ok := core.GetDict(link.A)
+	require.True(t, ok)
+	arr, ok = core.GetArray(a.Get("D"))
+	require.True(t, ok)
+	index, ok = core.GetIntVal(arr.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 0, index)
+}
@@ -0,0 +1,321 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// xfdfNamespace is the namespace of the XFDF elements.
+const xfdfNamespace = "http://ns.adobe.com/xfdf/"
+
+// XFDF represents XML Forms Data Format (XFDF) data: form field values and annotations.
+type XFDF struct {
+	fields []*xfdfField
+	annots []*xmlElement
+}
+
+// xfdfField represents a field of the XFDF data. Terminal fields have values, while the others
+// have kids.
+type xfdfField struct {
+	name     string
+	values   []string
+	richText string
+	kids     []*xfdfField
+}
+
+// LoadXFDF loads XFDF data from `r`.
+func LoadXFDF(r io.Reader) (*XFDF, error) {
+	var root xmlElement
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "xfdf" {
+		return nil, errors.New("xfdf root element missing")
+	}
+	root.markRichText()
+
+	x := &XFDF{}
+	for _, child := range root.Children {
+		switch child.XMLName.Local {
+		case "fields":
+			x.fields = loadXFDFFields(child)
+		case "annots":
+			x.annots = append(x.annots, child.Children...)
+		}
+	}
+	return x, nil
+}
+
+// LoadXFDFFromPath loads XFDF data from file path `xfdfPath`.
+func LoadXFDFFromPath(xfdfPath string) (*XFDF, error) {
+	f, err := os.Open(xfdfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadXFDF(f)
+}
+
+// loadXFDFFields returns the fields of the `fields` element, or of a parent field element.
+func loadXFDFFields(parent *xmlElement) []*xfdfField {
+	var fields []*xfdfField
+	for _, e := range parent.Children {
+		if e.XMLName.Local != "field" {
+			continue
+		}
+
+		field := &xfdfField{name: e.attr("name")}
+		for _, child := range e.Children {
+			switch child.XMLName.Local {
+			case "value":
+				field.values = append(field.values, child.Text)
+			case "value-richtext":
+				field.richText = strings.TrimSpace(child.Inner)
+			}
+		}
+		field.kids = loadXFDFFields(e)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// ExportXFDF returns the XFDF data of the document loaded by `reader`: the values of its form fields
+// and its annotations. Popup annotations are exported with their parent annotations. Widget
+// annotations are exported as form fields, while annotation types which cannot be represented
+// in XFDF (e.g. movie, screen and 3D annotations) are skipped.
+func ExportXFDF(reader *model.PdfReader) (*XFDF, error) {
+	x := &XFDF{}
+	if reader.AcroForm != nil && reader.AcroForm.Fields != nil {
+		x.fields = exportXFDFFields(*reader.AcroForm.Fields)
+	}
+
+	// The annotations are referred by name (NM) from their replies: generate names for the
+	// annotations which do not have one.
+	names := map[core.PdfObject]string{}
+	pageAnnots := make([][]*model.PdfAnnotation, len(reader.PageList))
+	for i, page := range reader.PageList {
+		annots, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		pageAnnots[i] = annots
+		for j, annot := range annots {
+			name := ""
+			if str, ok := core.GetString(annot.NM); ok {
+				name = str.Decoded()
+			}
+			if name == "" {
+				name = fmt.Sprintf("annot-%d-%d", i+1, j+1)
+			}
+			names[annot.GetContainingPdfObject()] = name
+		}
+	}
+
+	for i, annots := range pageAnnots {
+		for _, annot := range annots {
+			e := exportXFDFAnnotation(annot, i, names)
+			if e != nil {
+				x.annots = append(x.annots, e)
+			}
+		}
+	}
+	return x, nil
+}
+
+// exportXFDFFields returns the XFDF fields representing the form `fields`.
+func exportXFDFFields(fields []*model.PdfField) []*xfdfField {
+	var xfields []*xfdfField
+	for _, f := range fields {
+		if f.T == nil {
+			// Fields without partial names are merged with their parents, which does not happen
+			// for the kids of the form. Export the kids as fields of the form.
+			xfields = append(xfields, exportXFDFFields(f.Kids)...)
+			continue
+		}
+
+		field := &xfdfField{name: f.T.Decoded()}
+		if len(f.Kids) > 0 {
+			field.kids = exportXFDFFields(f.Kids)
+		}
+		switch v := core.TraceToDirectObject(f.V).(type) {
+		case *core.PdfObjectString:
+			field.values = []string{v.Decoded()}
+		case *core.PdfObjectName:
+			field.values = []string{v.String()}
+		case *core.PdfObjectArray:
+			for _, obj := range v.Elements() {
+				if str, ok := core.GetString(obj); ok {
+					field.values = append(field.values, str.Decoded())
+				}
+			}
+		}
+		if ft, ok := f.GetContext().(*model.PdfFieldText); ok {
+			if rv, ok := core.GetString(ft.RV); ok {
+				field.richText = stripXMLDeclaration(rv.Decoded())
+			}
+		}
+
+		if len(field.kids) == 0 && len(field.values) == 0 && field.richText == "" {
+			continue
+		}
+		xfields = append(xfields, field)
+	}
+	return xfields
+}
+
+// Write writes the XFDF data to `w`.
+func (x *XFDF) Write(w io.Writer) error {
+	root := newXMLElement("xfdf")
+	root.setAttr("xmlns", xfdfNamespace)
+	root.setAttr("xml:space", "preserve")
+
+	if len(x.fields) > 0 {
+		fields := newXMLElement("fields")
+		for _, f := range x.fields {
+			fields.addChild(f.toXML())
+		}
+		root.addChild(fields)
+	}
+	annots := newXMLElement("annots")
+	annots.Children = x.annots
+	root.addChild(annots)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	root.write(bw, 0)
+	return bw.Flush()
+}
+
+// WriteToFile writes the XFDF data to file `outputPath`.
+func (x *XFDF) WriteToFile(outputPath string) error {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return x.Write(f)
+}
+
+// toXML returns the XML element representing the field.
+func (f *xfdfField) toXML() *xmlElement {
+	e := newXMLElement("field")
+	e.setAttr("name", f.name)
+	for _, kid := range f.kids {
+		e.addChild(kid.toXML())
+	}
+	for _, val := range f.values {
+		e.addChild(newXMLTextElement("value", val))
+	}
+	if f.richText != "" {
+		e.addChild(newXMLRawElement("value-richtext", f.richText))
+	}
+	return e
+}
+
+// FieldValues implements interface model.FieldValueProvider.
+// Returns a map of the full field names to values (PdfObjects). Fields with multiple values have
+// arrays of values.
+func (x *XFDF) FieldValues() (map[string]core.PdfObject, error) {
+	fieldValMap := map[string]core.PdfObject{}
+
+	var add func(prefix string, fields []*xfdfField)
+	add = func(prefix string, fields []*xfdfField) {
+		for _, f := range fields {
+			name := f.name
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			switch len(f.values) {
+			case 0:
+			case 1:
+				fieldValMap[name] = core.MakeString(f.values[0])
+			default:
+				arr := core.MakeArray()
+				for _, val := range f.values {
+					arr.Append(core.MakeString(val))
+				}
+				fieldValMap[name] = arr
+			}
+			add(name, f.kids)
+		}
+	}
+	add("", x.fields)
+
+	return fieldValMap, nil
+}
+
+// Annotations returns the annotations of the XFDF data. The replies refer to the annotations they
+// reply to, when found in the XFDF data.
+func (x *XFDF) Annotations() ([]*Annotation, error) {
+	annots, err := x.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*model.PdfAnnotation{}
+	for _, a := range annots {
+		byName[a.name] = a.annot
+	}
+
+	for _, a := range annots {
+		if parent, ok := byName[a.inReplyTo]; ok && a.markup != nil {
+			a.markup.IRT = parent.GetContainingPdfObject()
+		}
+	}
+	return pageAnnotations(annots), nil
+}
+
+// loadAnnotations returns the annotations specified by the XFDF annotation elements.
+func (x *XFDF) loadAnnotations() ([]*pageAnnotation, error) {
+	var annots []*pageAnnotation
+	for _, e := range x.annots {
+		a, err := importXFDFAnnotation(e)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil {
+			common.Log.Debug("WARN: unsupported XFDF annotation %s. Skipping.", e.XMLName.Local)
+			continue
+		}
+		annots = append(annots, a)
+	}
+	return annots, nil
+}
+
+// Import imports the XFDF data into the document of `appender`. The form is filled with the field
+// values, and the annotations are added to their pages. The annotations of the document with the
+// same names (NM) as the XFDF annotations are replaced.
+func (x *XFDF) Import(appender *model.PdfAppender, opts *ImportOptions) error {
+	annots, err := x.loadAnnotations()
+	if err != nil {
+		return err
+	}
+	return importFormData(appender, x, len(x.fields) > 0, annots, opts)
+}
+
+// stripXMLDeclaration removes the XML declaration from the XML document `s`.
+func stripXMLDeclaration(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "<?xml") {
+		if i := strings.Index(s, "?>"); i >= 0 {
+			s = strings.TrimSpace(s[i+2:])
+		}
+	}
+	return s
+}
@@ -0,0 +1,907 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// xfdfFlags maps the XFDF annotation flag names to the annotation flags (Table 165 p. 395).
+var xfdfFlags = []struct {
+	name string
+	flag int64
+}{
+	{"invisible", 1},
+	{"hidden", 2},
+	{"print", 4},
+	{"nozoom", 8},
+	{"norotate", 16},
+	{"noview", 32},
+	{"readonly", 64},
+	{"locked", 128},
+	{"togglenoview", 256},
+	{"lockedcontents", 512},
+}
+
+// xfdfBorderStyles maps the XFDF border style names to the border style dictionary styles (S).
+var xfdfBorderStyles = map[string]string{
+	"solid":     "S",
+	"dash":      "D",
+	"bevelled":  "B",
+	"inset":     "I",
+	"underline": "U",
+}
+
+// xfdfJustifications are the XFDF names of the quadding values (Q) 0, 1 and 2.
+var xfdfJustifications = []string{"left", "centered", "right"}
+
+// annotationMarkup returns the markup part of `annot`, or nil if it is not a markup annotation.
+func annotationMarkup(annot *model.PdfAnnotation) *model.PdfAnnotationMarkup {
+	switch t := annot.GetContext().(type) {
+	case *model.PdfAnnotationText:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationFreeText:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationLine:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationSquare:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationCircle:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationPolygon:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationPolyLine:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationHighlight:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationUnderline:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationSquiggly:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationStrikeOut:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationCaret:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationStamp:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationInk:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationFileAttachment:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationSound:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationRedact:
+		return t.PdfAnnotationMarkup
+	case *model.PdfAnnotationProjection:
+		return t.PdfAnnotationMarkup
+	}
+	return nil
+}
+
+// exportXFDFAnnotation returns the XFDF element representing the annotation `annot` of the page
+// with index `page`. The annotations are referred by their names in `names`. Returns nil if
+// the annotation cannot be represented in XFDF.
+func exportXFDFAnnotation(annot *model.PdfAnnotation, page int, names map[core.PdfObject]string) *xmlElement {
+	var e *xmlElement
+	switch t := annot.GetContext().(type) {
+	case *model.PdfAnnotationText:
+		e = newXMLElement("text")
+		e.setAttr("icon", nameVal(t.Name))
+		e.setAttr("state", stringVal(t.State))
+		e.setAttr("statemodel", stringVal(t.StateModel))
+	case *model.PdfAnnotationLink:
+		e = newXMLElement("link")
+		e.setAttr("Highlight", exportHighlightMode(t.H))
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+		exportBorder(e, t.BS, nil)
+		if action, ok := core.GetDict(t.A); ok && nameVal(action.Get("S")) == "URI" {
+			uri := newXMLElement("URI")
+			uri.setAttr("Name", stringVal(action.Get("URI")))
+			act := newXMLElement("Action")
+			act.addChild(uri)
+			activation := newXMLElement("OnActivation")
+			activation.addChild(act)
+			e.addChild(activation)
+		}
+	case *model.PdfAnnotationFreeText:
+		e = newXMLElement("freetext")
+		if q, ok := core.GetIntVal(t.Q); ok && q >= 0 && q < len(xfdfJustifications) {
+			e.setAttr("justification", xfdfJustifications[q])
+		}
+		e.setAttr("callout", formatFloats(floatsVal(t.CL)))
+		e.setAttr("head", nameVal(t.LE))
+		e.setAttr("fringe", formatFloats(floatsVal(t.RD)))
+		e.setAttr("intent", nameVal(t.IT))
+		exportBorder(e, t.BS, t.BE)
+		if da := stringVal(t.DA); da != "" {
+			e.addChild(newXMLTextElement("defaultappearance", da))
+		}
+		if ds := stringVal(t.DS); ds != "" {
+			e.addChild(newXMLTextElement("defaultstyle", ds))
+		}
+	case *model.PdfAnnotationLine:
+		e = newXMLElement("line")
+		if l := floatsVal(t.L); len(l) == 4 {
+			e.setAttr("start", formatFloats(l[:2]))
+			e.setAttr("end", formatFloats(l[2:]))
+		}
+		if le, ok := core.GetArray(t.LE); ok && le.Len() == 2 {
+			e.setAttr("head", nameVal(le.Get(0)))
+			e.setAttr("tail", nameVal(le.Get(1)))
+		}
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("leaderLength", numberVal(t.LL))
+		e.setAttr("leaderExtend", numberVal(t.LLE))
+		e.setAttr("leaderOffset", numberVal(t.LLO))
+		if b, ok := core.GetBoolVal(t.Cap); ok {
+			e.setAttr("caption", exportBool(b))
+		}
+		e.setAttr("caption-style", nameVal(t.CP))
+		if co := floatsVal(t.CO); len(co) == 2 {
+			e.setAttr("caption-offset-h", numberVal(core.MakeFloat(co[0])))
+			e.setAttr("caption-offset-v", numberVal(core.MakeFloat(co[1])))
+		}
+		e.setAttr("intent", nameVal(t.IT))
+		exportBorder(e, t.BS, nil)
+	case *model.PdfAnnotationSquare:
+		e = newXMLElement("square")
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("fringe", formatFloats(floatsVal(t.RD)))
+		exportBorder(e, t.BS, t.BE)
+	case *model.PdfAnnotationCircle:
+		e = newXMLElement("circle")
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("fringe", formatFloats(floatsVal(t.RD)))
+		exportBorder(e, t.BS, t.BE)
+	case *model.PdfAnnotationPolygon:
+		e = newXMLElement("polygon")
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("intent", nameVal(t.IT))
+		exportBorder(e, t.BS, t.BE)
+		e.addChild(newXMLTextElement("vertices", formatPoints(floatsVal(t.Vertices))))
+	case *model.PdfAnnotationPolyLine:
+		e = newXMLElement("polyline")
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("intent", nameVal(t.IT))
+		if le, ok := core.GetArray(t.LE); ok && le.Len() == 2 {
+			e.setAttr("head", nameVal(le.Get(0)))
+			e.setAttr("tail", nameVal(le.Get(1)))
+		}
+		exportBorder(e, t.BS, t.BE)
+		e.addChild(newXMLTextElement("vertices", formatPoints(floatsVal(t.Vertices))))
+	case *model.PdfAnnotationHighlight:
+		e = newXMLElement("highlight")
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+	case *model.PdfAnnotationUnderline:
+		e = newXMLElement("underline")
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+	case *model.PdfAnnotationSquiggly:
+		e = newXMLElement("squiggly")
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+	case *model.PdfAnnotationStrikeOut:
+		e = newXMLElement("strikeout")
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+	case *model.PdfAnnotationCaret:
+		e = newXMLElement("caret")
+		if nameVal(t.Sy) == "P" {
+			e.setAttr("symbol", "paragraph")
+		} else {
+			e.setAttr("symbol", "None")
+		}
+		e.setAttr("fringe", formatFloats(floatsVal(t.RD)))
+	case *model.PdfAnnotationStamp:
+		e = newXMLElement("stamp")
+		e.setAttr("icon", nameVal(t.Name))
+	case *model.PdfAnnotationInk:
+		e = newXMLElement("ink")
+		exportBorder(e, t.BS, nil)
+		inkList := newXMLElement("inklist")
+		if arr, ok := core.GetArray(t.InkList); ok {
+			for _, obj := range arr.Elements() {
+				inkList.addChild(newXMLTextElement("gesture", formatPoints(floatsVal(obj))))
+			}
+		}
+		e.addChild(inkList)
+	case *model.PdfAnnotationFileAttachment:
+		e = newXMLElement("fileattachment")
+		e.setAttr("icon", nameVal(t.Name))
+		if fs, err := model.NewPdfFilespecFromObj(t.FS); err == nil {
+			name := stringVal(fs.UF)
+			if name == "" {
+				name = stringVal(fs.F)
+			}
+			e.setAttr("file", name)
+			if ef, ok := core.GetDict(fs.EF); ok {
+				if stream, ok := core.GetStream(ef.Get("F")); ok {
+					e.setAttr("mimetype", nameVal(stream.Get("Subtype")))
+					if data := exportData(stream); data != nil {
+						e.addChild(data)
+					}
+				}
+			}
+		} else if t.FS != nil {
+			e.setAttr("file", stringVal(t.FS))
+		}
+	case *model.PdfAnnotationSound:
+		e = newXMLElement("sound")
+		e.setAttr("icon", nameVal(t.Name))
+		if stream, ok := core.GetStream(t.Sound); ok {
+			e.setAttr("rate", numberVal(stream.Get("R")))
+			e.setAttr("channels", numberVal(stream.Get("C")))
+			e.setAttr("bits", numberVal(stream.Get("B")))
+			e.setAttr("encoding", nameVal(stream.Get("E")))
+			if data := exportData(stream); data != nil {
+				e.addChild(data)
+			}
+		}
+	case *model.PdfAnnotationRedact:
+		e = newXMLElement("redact")
+		e.setAttr("coords", formatFloats(floatsVal(t.QuadPoints)))
+		e.setAttr("interior-color", exportColor(t.IC))
+		e.setAttr("overlay-text", stringVal(t.OverlayText))
+		if b, ok := core.GetBoolVal(t.Repeat); ok {
+			e.setAttr("repeat", exportBool(b))
+		}
+		if q, ok := core.GetIntVal(t.Q); ok && q >= 0 && q < len(xfdfJustifications) {
+			e.setAttr("justification", xfdfJustifications[q])
+		}
+		if da := stringVal(t.DA); da != "" {
+			e.addChild(newXMLTextElement("defaultappearance", da))
+		}
+	case *model.PdfAnnotationPopup:
+		// Exported with the parent annotation.
+		return nil
+	default:
+		common.Log.Debug("WARN: annotation %T cannot be exported to XFDF. Skipping.", t)
+		return nil
+	}
+
+	// Attributes and elements common to all annotations, followed by the specific ones.
+	typeAttrs, typeChildren := e.Attrs, e.Children
+	e.Attrs, e.Children = nil, nil
+
+	e.setAttr("page", strconv.Itoa(page))
+	e.setAttr("rect", formatFloats(floatsVal(annot.Rect)))
+	e.setAttr("name", names[annot.GetContainingPdfObject()])
+	e.setAttr("flags", exportFlags(annot.F))
+	e.setAttr("color", exportColor(annot.C))
+	e.setAttr("date", stringVal(annot.M))
+	if contents := stringVal(annot.Contents); contents != "" {
+		e.addChild(newXMLTextElement("contents", contents))
+	}
+
+	if markup := annotationMarkup(annot); markup != nil {
+		exportXFDFMarkup(e, markup, page, names)
+		if nameVal(markup.IT) != "" {
+			// The intent is also an entry of the specific annotation types.
+			for i := 0; i < len(typeAttrs); i++ {
+				if typeAttrs[i].Name.Local == "intent" {
+					typeAttrs = append(typeAttrs[:i], typeAttrs[i+1:]...)
+				}
+			}
+		}
+	}
+
+	e.Attrs = append(e.Attrs, typeAttrs...)
+	e.Children = append(e.Children, typeChildren...)
+	return e
+}
+
+// exportXFDFMarkup sets the markup annotation attributes and elements of `e`.
+func exportXFDFMarkup(e *xmlElement, markup *model.PdfAnnotationMarkup, page int, names map[core.PdfObject]string) {
+	e.setAttr("title", stringVal(markup.T))
+	e.setAttr("subject", stringVal(markup.Subj))
+	e.setAttr("creationdate", stringVal(markup.CreationDate))
+	if markup.CA != nil {
+		e.setAttr("opacity", numberVal(markup.CA))
+	}
+	if markup.IRT != nil {
+		e.setAttr("inreplyto", names[markup.IRT])
+		if nameVal(markup.RT) == "Group" {
+			e.setAttr("replyType", "group")
+		} else {
+			e.setAttr("replyType", "reply")
+		}
+	}
+	e.setAttr("intent", nameVal(markup.IT))
+	if rc := stringVal(markup.RC); rc != "" {
+		e.addChild(newXMLRawElement("contents-richtext", stripXMLDeclaration(rc)))
+	}
+	if popup := markup.Popup; popup != nil {
+		p := newXMLElement("popup")
+		p.setAttr("page", strconv.Itoa(page))
+		p.setAttr("rect", formatFloats(floatsVal(popup.Rect)))
+		p.setAttr("flags", exportFlags(popup.F))
+		if b, ok := core.GetBoolVal(popup.Open); ok {
+			p.setAttr("open", exportBool(b))
+		}
+		e.addChild(p)
+	}
+}
+
+// importXFDFAnnotation returns the annotation specified by the XFDF element `e`. Returns nil if
+// the element does not represent a supported annotation type.
+func importXFDFAnnotation(e *xmlElement) (*pageAnnotation, error) {
+	a := &pageAnnotation{
+		name:      e.attr("name"),
+		inReplyTo: e.attr("inreplyto"),
+	}
+	if page := e.attr("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation page %q", page)
+		}
+		a.page = p
+	}
+
+	switch e.XMLName.Local {
+	case "text":
+		t := model.NewPdfAnnotationText()
+		t.Name = importName(e.attr("icon"))
+		t.State = importString(e.attr("state"))
+		t.StateModel = importString(e.attr("statemodel"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "link":
+		t := model.NewPdfAnnotationLink()
+		t.H = importHighlightMode(e.attr("Highlight"))
+		t.QuadPoints = importFloats(e.attr("coords"))
+		t.BS, _ = importBorder(e)
+		if activation := e.child("OnActivation"); activation != nil {
+			if action := activation.child("Action"); action != nil {
+				if uri := action.child("URI"); uri != nil {
+					actionDict := core.MakeDict()
+					actionDict.Set("S", core.MakeName("URI"))
+					actionDict.Set("URI", core.MakeString(uri.attr("Name")))
+					t.A = actionDict
+				}
+			}
+		}
+		a.annot = t.PdfAnnotation
+	case "freetext":
+		t := model.NewPdfAnnotationFreeText()
+		t.Q = importJustification(e.attr("justification"))
+		t.CL = importFloats(e.attr("callout"))
+		t.LE = importName(e.attr("head"))
+		t.RD = importFloats(e.attr("fringe"))
+		t.BS, t.BE = importBorder(e)
+		if da := e.child("defaultappearance"); da != nil {
+			t.DA = core.MakeString(da.Text)
+		}
+		if ds := e.child("defaultstyle"); ds != nil {
+			t.DS = core.MakeString(ds.Text)
+		}
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "line":
+		t := model.NewPdfAnnotationLine()
+		start, end := parseFloats(e.attr("start")), parseFloats(e.attr("end"))
+		if len(start) == 2 && len(end) == 2 {
+			t.L = core.MakeArrayFromFloats(append(start, end...))
+		}
+		t.LE = importLineEndings(e)
+		t.IC = importColor(e.attr("interior-color"))
+		t.LL = importNumber(e.attr("leaderLength"))
+		t.LLE = importNumber(e.attr("leaderExtend"))
+		t.LLO = importNumber(e.attr("leaderOffset"))
+		t.Cap = importBool(e.attr("caption"))
+		t.CP = importName(e.attr("caption-style"))
+		h, v := e.attr("caption-offset-h"), e.attr("caption-offset-v")
+		if h != "" || v != "" {
+			t.CO = core.MakeArrayFromFloats(append(parseFloats(h), parseFloats(v)...))
+		}
+		t.BS, _ = importBorder(e)
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "square":
+		t := model.NewPdfAnnotationSquare()
+		t.IC = importColor(e.attr("interior-color"))
+		t.RD = importFloats(e.attr("fringe"))
+		t.BS, t.BE = importBorder(e)
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "circle":
+		t := model.NewPdfAnnotationCircle()
+		t.IC = importColor(e.attr("interior-color"))
+		t.RD = importFloats(e.attr("fringe"))
+		t.BS, t.BE = importBorder(e)
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "polygon":
+		t := model.NewPdfAnnotationPolygon()
+		t.IC = importColor(e.attr("interior-color"))
+		t.BS, t.BE = importBorder(e)
+		if v := e.child("vertices"); v != nil {
+			t.Vertices = importFloats(v.Text)
+		}
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "polyline":
+		t := model.NewPdfAnnotationPolyLine()
+		t.IC = importColor(e.attr("interior-color"))
+		t.LE = importLineEndings(e)
+		t.BS, t.BE = importBorder(e)
+		if v := e.child("vertices"); v != nil {
+			t.Vertices = importFloats(v.Text)
+		}
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "highlight":
+		t := model.NewPdfAnnotationHighlight()
+		t.QuadPoints = importFloats(e.attr("coords"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "underline":
+		t := model.NewPdfAnnotationUnderline()
+		t.QuadPoints = importFloats(e.attr("coords"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "squiggly":
+		t := model.NewPdfAnnotationSquiggly()
+		t.QuadPoints = importFloats(e.attr("coords"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "strikeout":
+		t := model.NewPdfAnnotationStrikeOut()
+		t.QuadPoints = importFloats(e.attr("coords"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "caret":
+		t := model.NewPdfAnnotationCaret()
+		if e.attr("symbol") == "paragraph" {
+			t.Sy = core.MakeName("P")
+		} else {
+			t.Sy = core.MakeName("None")
+		}
+		t.RD = importFloats(e.attr("fringe"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "stamp":
+		t := model.NewPdfAnnotationStamp()
+		t.Name = importName(e.attr("icon"))
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "ink":
+		t := model.NewPdfAnnotationInk()
+		t.BS, _ = importBorder(e)
+		inkList := core.MakeArray()
+		if list := e.child("inklist"); list != nil {
+			for _, gesture := range list.Children {
+				if gesture.XMLName.Local == "gesture" {
+					inkList.Append(core.MakeArrayFromFloats(parseFloats(gesture.Text)))
+				}
+			}
+		}
+		t.InkList = inkList
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "fileattachment":
+		t := model.NewPdfAnnotationFileAttachment()
+		t.Name = importName(e.attr("icon"))
+		fs := model.NewPdfFilespec()
+		fs.F = core.MakeString(e.attr("file"))
+		fs.UF = core.MakeEncodedString(e.attr("file"), true)
+		if data := e.child("data"); data != nil {
+			stream, err := importData(data)
+			if err != nil {
+				return nil, err
+			}
+			stream.Set("Type", core.MakeName("EmbeddedFile"))
+			if mime := e.attr("mimetype"); mime != "" {
+				stream.Set("Subtype", core.MakeName(mime))
+			}
+			ef := core.MakeDict()
+			ef.Set("F", stream)
+			fs.EF = ef
+		}
+		t.FS = fs.ToPdfObject()
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "sound":
+		t := model.NewPdfAnnotationSound()
+		t.Name = importName(e.attr("icon"))
+		if data := e.child("data"); data != nil {
+			stream, err := importData(data)
+			if err != nil {
+				return nil, err
+			}
+			stream.Set("Type", core.MakeName("Sound"))
+			stream.SetIfNotNil("R", importNumber(e.attr("rate")))
+			stream.SetIfNotNil("C", importNumber(e.attr("channels")))
+			stream.SetIfNotNil("B", importNumber(e.attr("bits")))
+			stream.SetIfNotNil("E", importName(e.attr("encoding")))
+			t.Sound = stream
+		}
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	case "redact":
+		t := model.NewPdfAnnotationRedact()
+		t.QuadPoints = importFloats(e.attr("coords"))
+		t.IC = importColor(e.attr("interior-color"))
+		t.OverlayText = importString(e.attr("overlay-text"))
+		t.Repeat = importBool(e.attr("repeat"))
+		t.Q = importJustification(e.attr("justification"))
+		if da := e.child("defaultappearance"); da != nil {
+			t.DA = core.MakeString(da.Text)
+		}
+		a.annot, a.markup = t.PdfAnnotation, t.PdfAnnotationMarkup
+	default:
+		return nil, nil
+	}
+
+	// Attributes common to all annotations.
+	annot := a.annot
+	annot.Rect = importFloats(e.attr("rect"))
+	if annot.Rect == nil {
+		return nil, fmt.Errorf("annotation %s: rect missing", a.name)
+	}
+	annot.NM = importString(a.name)
+	annot.F = importFlags(e.attr("flags"))
+	annot.C = importColor(e.attr("color"))
+	annot.M = importString(e.attr("date"))
+	if contents := e.child("contents"); contents != nil {
+		annot.Contents = core.MakeEncodedString(contents.Text, true)
+	}
+
+	markup := a.markup
+	if markup == nil {
+		return a, nil
+	}
+	markup.T = importString(e.attr("title"))
+	markup.Subj = importString(e.attr("subject"))
+	markup.CreationDate = importString(e.attr("creationdate"))
+	markup.CA = importNumber(e.attr("opacity"))
+	markup.IT = importName(e.attr("intent"))
+	if a.inReplyTo != "" {
+		if e.attr("replyType") == "group" {
+			markup.RT = core.MakeName("Group")
+		} else {
+			markup.RT = core.MakeName("R")
+		}
+	}
+	if rc := e.child("contents-richtext"); rc != nil && rc.Inner != "" {
+		markup.RC = core.MakeEncodedString(rc.Inner, true)
+	}
+	if p := e.child("popup"); p != nil {
+		popup := model.NewPdfAnnotationPopup()
+		popup.Rect = importFloats(p.attr("rect"))
+		popup.F = importFlags(p.attr("flags"))
+		popup.Open = importBool(p.attr("open"))
+		popup.Parent = annot.GetContainingPdfObject()
+		markup.Popup = popup
+		a.popup = popup
+	}
+	return a, nil
+}
+
+// nameVal returns the value of the name object `obj`, or an empty string if not a name.
+func nameVal(obj core.PdfObject) string {
+	name, _ := core.GetNameVal(obj)
+	return name
+}
+
+// stringVal returns the decoded value of the string object `obj`, or an empty string if not
+// a string.
+func stringVal(obj core.PdfObject) string {
+	if str, ok := core.GetString(obj); ok {
+		return str.Decoded()
+	}
+	return ""
+}
+
+// numberVal returns the value of the number object `obj` formatted as a string, or an empty string
+// if not a number.
+func numberVal(obj core.PdfObject) string {
+	val, err := core.GetNumberAsFloat(core.TraceToDirectObject(obj))
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(val, 'f', -1, 64)
+}
+
+// floatsVal returns the numbers of the array object `obj`, or nil if not an array of numbers.
+func floatsVal(obj core.PdfObject) []float64 {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil
+	}
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil
+	}
+	return vals
+}
+
+// formatFloats returns the numbers `vals` separated by commas.
+func formatFloats(vals []float64) string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
+
+// formatPoints returns the points `vals` (x1, y1, x2, y2, ...) as "x1,y1;x2,y2;...".
+func formatPoints(vals []float64) string {
+	var points []string
+	for i := 0; i+1 < len(vals); i += 2 {
+		points = append(points, formatFloats(vals[i:i+2]))
+	}
+	return strings.Join(points, ";")
+}
+
+// parseFloats returns the numbers of `s`, separated by commas, semicolons or spaces.
+func parseFloats(s string) []float64 {
+	var vals []float64
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			common.Log.Debug("ERROR: invalid XFDF number %q", f)
+			continue
+		}
+		vals = append(vals, v)
+	}
+	return vals
+}
+
+// importFloats returns the array of the numbers of `s`, or nil if `s` has no numbers.
+func importFloats(s string) core.PdfObject {
+	vals := parseFloats(s)
+	if len(vals) == 0 {
+		return nil
+	}
+	return core.MakeArrayFromFloats(vals)
+}
+
+// importNumber returns the number object of `s`, or nil if `s` is empty or invalid.
+func importNumber(s string) core.PdfObject {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		common.Log.Debug("ERROR: invalid XFDF number %q", s)
+		return nil
+	}
+	if v == math.Trunc(v) && !strings.Contains(s, ".") {
+		return core.MakeInteger(int64(v))
+	}
+	return core.MakeFloat(v)
+}
+
+// importName returns the name object `s`, or nil if `s` is empty.
+func importName(s string) core.PdfObject {
+	if s == "" {
+		return nil
+	}
+	return core.MakeName(s)
+}
+
+// importString returns the string object `s`, or nil if `s` is empty.
+func importString(s string) core.PdfObject {
+	if s == "" {
+		return nil
+	}
+	return core.MakeEncodedString(s, true)
+}
+
+// exportBool returns the XFDF representation of `b`.
+func exportBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// importBool returns the boolean object of the XFDF value `s`, or nil if `s` is empty.
+func importBool(s string) core.PdfObject {
+	switch strings.ToLower(s) {
+	case "":
+		return nil
+	case "yes", "true", "1":
+		return core.MakeBool(true)
+	}
+	return core.MakeBool(false)
+}
+
+// exportColor returns the color array `obj` as an XFDF color (#RRGGBB). Gray and CMYK colors are
+// converted to RGB.
+func exportColor(obj core.PdfObject) string {
+	vals := floatsVal(obj)
+	var r, g, b float64
+	switch len(vals) {
+	case 1:
+		r, g, b = vals[0], vals[0], vals[0]
+	case 3:
+		r, g, b = vals[0], vals[1], vals[2]
+	case 4:
+		k := vals[3]
+		r, g, b = (1-vals[0])*(1-k), (1-vals[1])*(1-k), (1-vals[2])*(1-k)
+	default:
+		return ""
+	}
+	toByte := func(v float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return fmt.Sprintf("#%02X%02X%02X", toByte(r), toByte(g), toByte(b))
+}
+
+// importColor returns the RGB color array of the XFDF color `s` (#RRGGBB), or nil if `s` is
+// empty or invalid.
+func importColor(s string) core.PdfObject {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		common.Log.Debug("ERROR: invalid XFDF color %q", s)
+		return nil
+	}
+	return core.MakeArrayFromFloats([]float64{
+		float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255,
+	})
+}
+
+// exportFlags returns the annotation flags `obj` as a list of XFDF flag names.
+func exportFlags(obj core.PdfObject) string {
+	flags, ok := core.GetIntVal(obj)
+	if !ok {
+		return ""
+	}
+	var names []string
+	for _, f := range xfdfFlags {
+		if int64(flags)&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// importFlags returns the annotation flags of the list of XFDF flag names `s`.
+func importFlags(s string) core.PdfObject {
+	if s == "" {
+		return nil
+	}
+	var flags int64
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, f := range xfdfFlags {
+			if f.name == name {
+				flags |= f.flag
+			}
+		}
+	}
+	return core.MakeInteger(flags)
+}
+
+// importJustification returns the quadding of the XFDF justification `s`.
+func importJustification(s string) core.PdfObject {
+	for i, name := range xfdfJustifications {
+		if s == name {
+			return core.MakeInteger(int64(i))
+		}
+	}
+	return nil
+}
+
+// importLineEndings returns the LE array specified by the head and tail attributes of `e`.
+func importLineEndings(e *xmlElement) core.PdfObject {
+	head, tail := e.attr("head"), e.attr("tail")
+	if head == "" && tail == "" {
+		return nil
+	}
+	if head == "" {
+		head = "None"
+	}
+	if tail == "" {
+		tail = "None"
+	}
+	return core.MakeArray(core.MakeName(head), core.MakeName(tail))
+}
+
+// exportHighlightMode returns the XFDF name of the link highlighting mode `obj`.
+func exportHighlightMode(obj core.PdfObject) string {
+	switch nameVal(obj) {
+	case "N":
+		return "none"
+	case "I":
+		return "invert"
+	case "O":
+		return "outline"
+	case "P":
+		return "push"
+	}
+	return ""
+}
+
+// importHighlightMode returns the link highlighting mode of the XFDF name `s`.
+func importHighlightMode(s string) core.PdfObject {
+	switch s {
+	case "none":
+		return core.MakeName("N")
+	case "invert":
+		return core.MakeName("I")
+	case "outline":
+		return core.MakeName("O")
+	case "push":
+		return core.MakeName("P")
+	}
+	return nil
+}
+
+// exportBorder sets the border attributes (width, style, dashes, intensity) of `e` from the border
+// style dictionary `bsObj` and the border effect dictionary `beObj`.
+func exportBorder(e *xmlElement, bsObj, beObj core.PdfObject) {
+	if bs, ok := core.GetDict(bsObj); ok {
+		e.setAttr("width", numberVal(bs.Get("W")))
+		s := nameVal(bs.Get("S"))
+		for name, style := range xfdfBorderStyles {
+			if style == s {
+				e.setAttr("style", name)
+			}
+		}
+		e.setAttr("dashes", formatFloats(floatsVal(bs.Get("D"))))
+	}
+	if be, ok := core.GetDict(beObj); ok && nameVal(be.Get("S")) == "C" {
+		e.setAttr("style", "cloudy")
+		e.setAttr("intensity", numberVal(be.Get("I")))
+	}
+}
+
+// importBorder returns the border style and border effect dictionaries specified by the border
+// attributes of `e`.
+func importBorder(e *xmlElement) (bs, be core.PdfObject) {
+	width, style, dashes := e.attr("width"), e.attr("style"), e.attr("dashes")
+	if width == "" && style == "" && dashes == "" {
+		return nil, nil
+	}
+
+	bsDict := core.MakeDict()
+	bsDict.Set("Type", core.MakeName("Border"))
+	bsDict.SetIfNotNil("W", importNumber(width))
+	if s, ok := xfdfBorderStyles[style]; ok {
+		bsDict.Set("S", core.MakeName(s))
+	}
+	if d := parseFloats(dashes); len(d) > 0 {
+		bsDict.Set("D", core.MakeArrayFromFloats(d))
+	}
+
+	if style == "cloudy" {
+		beDict := core.MakeDict()
+		beDict.Set("S", core.MakeName("C"))
+		beDict.SetIfNotNil("I", importNumber(e.attr("intensity")))
+		be = beDict
+	}
+	return bsDict, be
+}
+
+// exportData returns the XFDF data element with the decoded contents of `stream`.
+func exportData(stream *core.PdfObjectStream) *xmlElement {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode annotation data: %v", err)
+		return nil
+	}
+	e := newXMLTextElement("data", strings.ToUpper(hex.EncodeToString(data)))
+	e.setAttr("MODE", "raw")
+	e.setAttr("encoding", "hex")
+	e.setAttr("length", strconv.Itoa(len(data)))
+	return e
+}
+
+// importData returns a stream with the contents of the XFDF data element `e`.
+func importData(e *xmlElement) (*core.PdfObjectStream, error) {
+	if enc := e.attr("encoding"); enc != "" && enc != "hex" {
+		return nil, fmt.Errorf("unsupported data encoding %s", enc)
+	}
+	data, err := hex.DecodeString(strings.Join(strings.Fields(e.Text), ""))
+	if err != nil {
+		return nil, err
+	}
+	if e.attr("filter") == "FlateDecode" {
+		data, err = core.NewFlateEncoder().DecodeBytes(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return core.MakeStream(data, core.NewFlateEncoder())
+}
@@ -0,0 +1,220 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+const xfdfExample1 = `<?xml version="1.0" encoding="UTF-8"?>
+<xfdf xmlns="http://ns.adobe.com/xfdf/" xml:space="preserve">
+  <fields>
+    <field name="full_name"><value>John Doe</value></field>
+    <field name="address">
+      <field name="city"><value>Paris</value></field>
+    </field>
+    <field name="colors"><value>red</value><value>blue</value></field>
+  </fields>
+  <annots>
+    <highlight page="0" rect="100,700,300,720" name="h1" color="#FF0000" title="Jane" flags="print"
+      coords="100,720,300,720,100,700,300,700">
+      <contents>Check this</contents>
+      <popup page="0" rect="320,650,480,720" open="yes"/>
+    </highlight>
+    <text page="0" rect="300,700,320,720" name="r1" inreplyto="h1" replyType="reply" icon="Comment">
+      <contents>Done</contents>
+      <contents-richtext><body xmlns="http://www.w3.org/1999/xhtml"><p>Done <b>now</b></p></body></contents-richtext>
+    </text>
+    <ink page="0" rect="90,590,180,650" name="i1" width="2" style="dash" dashes="3,2">
+      <inklist>
+        <gesture>100,600;120,620;140,600</gesture>
+        <gesture>150,600;170,640</gesture>
+      </inklist>
+    </ink>
+    <movie page="0" rect="0,0,10,10"/>
+  </annots>
+</xfdf>
+`
+
+func TestXFDFLoading(t *testing.T) {
+	xfdf, err := LoadXFDF(strings.NewReader(xfdfExample1))
+	require.NoError(t, err)
+
+	vals, err := xfdf.FieldValues()
+	require.NoError(t, err)
+	require.Len(t, vals, 3)
+	require.Equal(t, "John Doe", vals["full_name"].String())
+	require.Equal(t, "Paris", vals["address.city"].String())
+	colors, ok := core.GetArray(vals["colors"])
+	require.True(t, ok)
+	require.Equal(t, 2, colors.Len())
+
+	annots, err := xfdf.Annotations()
+	require.NoError(t, err)
+	// The movie annotation is not supported. The popup follows its parent.
+	require.Len(t, annots, 4)
+
+	highlight, ok := annots[0].Annotation.GetContext().(*model.PdfAnnotationHighlight)
+	require.True(t, ok)
+	require.Equal(t, 1, annots[0].PageNum)
+	require.Equal(t, "Check this", highlight.Contents.(*core.PdfObjectString).Decoded())
+	color, err := highlight.C.(*core.PdfObjectArray).ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 0, 0}, color)
+	require.Equal(t, int64(4), int64(*highlight.F.(*core.PdfObjectInteger)))
+	require.NotNil(t, highlight.Popup)
+
+	popup, ok := annots[1].Annotation.GetContext().(*model.PdfAnnotationPopup)
+	require.True(t, ok)
+	require.Equal(t, highlight.Popup, popup)
+	require.Equal(t, highlight.GetContainingPdfObject(), popup.Parent)
+
+	text, ok := annots[2].Annotation.GetContext().(*model.PdfAnnotationText)
+	require.True(t, ok)
+	require.Equal(t, highlight.GetContainingPdfObject(), text.IRT)
+	require.Equal(t, "Comment", text.Name.String())
+	require.Contains(t, text.RC.(*core.PdfObjectString).Decoded(), "<b>now</b>")
+
+	ink, ok := annots[3].Annotation.GetContext().(*model.PdfAnnotationInk)
+	require.True(t, ok)
+	inkList, ok := core.GetArray(ink.InkList)
+	require.True(t, ok)
+	require.Equal(t, 2, inkList.Len())
+	bs, ok := core.GetDict(ink.BS)
+	require.True(t, ok)
+	require.Equal(t, "D", bs.Get("S").String())
+}
+
+func TestXFDFExport(t *testing.T) {
+	xfdf, err := LoadXFDF(strings.NewReader(xfdfExample1))
+	require.NoError(t, err)
+	annots, err := xfdf.Annotations()
+	require.NoError(t, err)
+
+	// Write a document with the annotations.
+	page := model.NewPdfPage()
+	for _, annot := range annots {
+		page.AddAnnotation(annot.Annotation)
+	}
+	// Annotation without name.
+	square := model.NewPdfAnnotationSquare()
+	square.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+	square.IC = core.MakeArrayFromFloats([]float64{0, 0, 1})
+	page.AddAnnotation(square.PdfAnnotation)
+
+	var buf bytes.Buffer
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	exported, err := ExportXFDF(reader)
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, exported.Write(&buf))
+	out := buf.String()
+	require.Contains(t, out, `<highlight `)
+	require.Contains(t, out, `name="h1"`)
+	require.Contains(t, out, `inreplyto="h1"`)
+	require.Contains(t, out, `<popup page="0" rect="320,650,480,720" open="yes"/>`)
+	require.Contains(t, out, `<gesture>100,600;120,620;140,600</gesture>`)
+	require.Contains(t, out, `<body xmlns="http://www.w3.org/1999/xhtml"><p>Done <b>now</b></p></body>`)
+	require.Contains(t, out, `<square page="0" rect="10,10,50,50" name="annot-1-5" interior-color="#0000FF"/>`)
+
+	// Load the exported data.
+	reloaded, err := LoadXFDF(strings.NewReader(out))
+	require.NoError(t, err)
+	reloadedAnnots, err := reloaded.Annotations()
+	require.NoError(t, err)
+	require.Len(t, reloadedAnnots, 5)
+}
+
+func TestXFDFImport(t *testing.T) {
+	f, err := os.Open("../fjson/testdata/basicform.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	xfdf, err := LoadXFDF(strings.NewReader(xfdfExample1))
+	require.NoError(t, err)
+	require.NoError(t, xfdf.Import(appender, nil))
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	// Import an update of the highlight annotation in the new revision.
+	update := strings.Replace(xfdfExample1, `color="#FF0000"`, `color="#00FF00"`, 1)
+	xfdf, err = LoadXFDF(strings.NewReader(update))
+	require.NoError(t, err)
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, xfdf.Import(appender, nil))
+
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	fullName := ""
+	for _, field := range reader.AcroForm.AllFields() {
+		if field.PartialName() == "full_name" {
+			fullName = field.V.(*core.PdfObjectString).Decoded()
+		}
+	}
+	require.Equal(t, "John Doe", fullName)
+
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+
+	var highlight *model.PdfAnnotationHighlight
+	var text *model.PdfAnnotationText
+	for _, annot := range annots {
+		switch t := annot.GetContext().(type) {
+		case *model.PdfAnnotationHighlight:
+			highlight = t
+		case *model.PdfAnnotationText:
+			text = t
+		}
+	}
+	require.NotNil(t, highlight)
+	require.NotNil(t, text)
+	color, err := highlight.C.(*core.PdfObjectArray).ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, 1, 0}, color)
+	require.Equal(t, highlight.GetContainingPdfObject(), text.IRT)
+
+	// The annotations are replaced, not duplicated.
+	var nHighlights, nPopups int
+	for _, annot := range annots {
+		switch annot.GetContext().(type) {
+		case *model.PdfAnnotationHighlight:
+			nHighlights++
+		case *model.PdfAnnotationPopup:
+			nPopups++
+		}
+	}
+	require.Equal(t, 1, nHighlights)
+	require.Equal(t, 1, nPopups)
+}
@@ -0,0 +1,137 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fdf
+
+import (
+	"bufio"
+	"encoding/xml"
+	"strings"
+)
+
+// xmlElement is a generic XML element, used for reading and writing XFDF.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr    `xml:",any,attr"`
+	Children []*xmlElement `xml:",any"`
+	Text     string        `xml:",chardata"`
+	Inner    string        `xml:",innerxml"`
+
+	// raw indicates that Inner is written verbatim, e.g. for rich text XHTML contents.
+	raw bool
+}
+
+// newXMLElement returns a new element named `name`.
+func newXMLElement(name string) *xmlElement {
+	return &xmlElement{XMLName: xml.Name{Local: name}}
+}
+
+// newXMLTextElement returns a new element named `name` with the text contents `text`.
+func newXMLTextElement(name, text string) *xmlElement {
+	e := newXMLElement(name)
+	e.Text = text
+	return e
+}
+
+// newXMLRawElement returns a new element named `name` with the XML contents `inner`.
+func newXMLRawElement(name, inner string) *xmlElement {
+	e := newXMLElement(name)
+	e.Inner = inner
+	e.raw = true
+	return e
+}
+
+// attr returns the value of the attribute `name`, or an empty string if not found.
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// setAttr sets the value of the attribute `name`. Empty values are not set.
+func (e *xmlElement) setAttr(name, value string) {
+	if value == "" {
+		return
+	}
+	for i, a := range e.Attrs {
+		if a.Name.Local == name {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// addChild appends the element `child` to the children of the element.
+func (e *xmlElement) addChild(child *xmlElement) {
+	e.Children = append(e.Children, child)
+}
+
+// child returns the first child element named `name`, or nil if not found.
+func (e *xmlElement) child(name string) *xmlElement {
+	for _, c := range e.Children {
+		if c.XMLName.Local == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// markRichText marks the rich text elements (contents-richtext, value-richtext) of the element tree
+// as raw, so that their XHTML contents are written back as is.
+func (e *xmlElement) markRichText() {
+	if strings.HasSuffix(e.XMLName.Local, "-richtext") {
+		e.raw = true
+		e.Inner = strings.TrimSpace(e.Inner)
+		return
+	}
+	for _, c := range e.Children {
+		c.markRichText()
+	}
+}
+
+// write writes the element to `w`, indented by `depth` levels. The text contents of the elements
+// are written as is, without indentation, as XFDF preserves spaces.
+func (e *xmlElement) write(w *bufio.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	w.WriteString(indent)
+	w.WriteByte('<')
+	w.WriteString(e.XMLName.Local)
+	for _, a := range e.Attrs {
+		name := a.Name.Local
+		if a.Name.Space == "xml" || a.Name.Space == "http://www.w3.org/XML/1998/namespace" {
+			name = "xml:" + name
+		}
+		w.WriteByte(' ')
+		w.WriteString(name)
+		w.WriteString(`="`)
+		xml.EscapeText(w, []byte(a.Value))
+		w.WriteByte('"')
+	}
+
+	switch {
+	case e.raw:
+		w.WriteByte('>')
+		w.WriteString(e.Inner)
+	case len(e.Children) > 0:
+		w.WriteString(">\n")
+		for _, c := range e.Children {
+			c.write(w, depth+1)
+		}
+		w.WriteString(indent)
+	case e.Text != "":
+		w.WriteByte('>')
+		xml.EscapeText(w, []byte(e.Text))
+	default:
+		w.WriteString("/>\n")
+		return
+	}
+	w.WriteString("</")
+	w.WriteString(e.XMLName.Local)
+	w.WriteString(">\n")
+}
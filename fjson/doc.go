@@ -3,5 +3,6 @@
  * file 'LICENSE.md', which is part of this source code package.
  */
 
-// Package fjson provides support for loading PDF form field data from JSON data/files.
+// Package fjson provides support for loading PDF form field data from JSON data/files, and for
+// exporting the schema of PDF forms to JSON and validating field data against it.
 package fjson
@@ -0,0 +1,491 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// Field types of the form schema.
+const (
+	FieldTypeText       = "text"
+	FieldTypeCheckbox   = "checkbox"
+	FieldTypeRadio      = "radio"
+	FieldTypePushButton = "pushbutton"
+	FieldTypeComboBox   = "combobox"
+	FieldTypeListBox    = "listbox"
+	FieldTypeSignature  = "signature"
+)
+
+// Schema describes the fields of a PDF form: their types, flags, allowed values and widgets.
+// It can be exported to JSON, e.g. for building web forms from PDF forms, and used for validating
+// the values filled in forms.
+type Schema struct {
+	fields []*FieldSchema
+}
+
+// FieldSchema describes a terminal field of a PDF form.
+type FieldSchema struct {
+	// Name is the full name of the field.
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Value is the current value of the field. Multiple selection list boxes have Values instead.
+	Value        string   `json:"value,omitempty"`
+	Values       []string `json:"values,omitempty"`
+	DefaultValue string   `json:"default_value,omitempty"`
+
+	// Tooltip is the alternate field name (TU), displayed as tooltip by the viewers.
+	Tooltip string `json:"tooltip,omitempty"`
+
+	Required    bool `json:"required,omitempty"`
+	ReadOnly    bool `json:"read_only,omitempty"`
+	Multiline   bool `json:"multiline,omitempty"`
+	Comb        bool `json:"comb,omitempty"`
+	Password    bool `json:"password,omitempty"`
+	MultiSelect bool `json:"multi_select,omitempty"`
+	Editable    bool `json:"editable,omitempty"`
+
+	// MaxLen is the maximum length of the text field values, or 0 if not limited.
+	MaxLen int `json:"max_len,omitempty"`
+
+	// OnStates are the names of the on states of checkboxes and radio buttons.
+	OnStates []string `json:"on_states,omitempty"`
+
+	// ExportValues are the values of the buttons of radio button groups, or the export values of
+	// checkboxes (Opt).
+	ExportValues []string `json:"export_values,omitempty"`
+
+	// Choices are the options of combo boxes and list boxes.
+	Choices []ChoiceOption `json:"choices,omitempty"`
+
+	Widgets []WidgetSchema `json:"widgets,omitempty"`
+}
+
+// ChoiceOption is an option of a choice field: the value exported when selected and the text
+// displayed.
+type ChoiceOption struct {
+	Export  string `json:"export"`
+	Display string `json:"display"`
+}
+
+// WidgetSchema describes a widget annotation of a field: the number of the page it is on and its
+// rectangle in page coordinates (llx, lly, urx, ury).
+type WidgetSchema struct {
+	Page int        `json:"page"`
+	Rect [4]float64 `json:"rect"`
+}
+
+// ValidationError is an error of a field value which does not match the schema of the field.
+type ValidationError struct {
+	Name   string
+	Reason string
+}
+
+// Error implements interface error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Name, e.Reason)
+}
+
+// ValidationErrors is the list of the errors found when validating field values.
+type ValidationErrors []*ValidationError
+
+// Error implements interface error.
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// NewSchema returns the schema of the form of the document loaded by `reader`.
+func NewSchema(reader *model.PdfReader) (*Schema, error) {
+	schema := &Schema{}
+	if reader.AcroForm == nil {
+		return schema, nil
+	}
+
+	// Page numbers of the widget annotations.
+	pages := map[core.PdfObject]int{}
+	for i, page := range reader.PageList {
+		annots, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annot := range annots {
+			pages[annot.GetContainingPdfObject()] = i + 1
+		}
+	}
+
+	for _, f := range reader.AcroForm.AllFields() {
+		if !f.IsTerminal() {
+			continue
+		}
+		fs, err := newFieldSchema(f, pages)
+		if err != nil {
+			return nil, err
+		}
+		schema.fields = append(schema.fields, fs)
+	}
+	return schema, nil
+}
+
+// newFieldSchema returns the schema of the terminal field `f`. The page numbers of the widgets
+// are looked up in `pages`.
+func newFieldSchema(f *model.PdfField, pages map[core.PdfObject]int) (*FieldSchema, error) {
+	name, err := f.FullName()
+	if err != nil {
+		return nil, err
+	}
+	flags := f.Flags()
+
+	fs := &FieldSchema{
+		Name:         name,
+		Tooltip:      stringVal(f.TU),
+		DefaultValue: strings.Join(objectValues(f.DV), ", "),
+		Required:     flags.Has(model.FieldFlagRequired),
+		ReadOnly:     flags.Has(model.FieldFlagReadOnly),
+	}
+
+	switch t := f.GetContext().(type) {
+	case *model.PdfFieldText:
+		fs.Type = FieldTypeText
+		fs.Multiline = flags.Has(model.FieldFlagMultiline)
+		fs.Comb = flags.Has(model.FieldFlagComb)
+		fs.Password = flags.Has(model.FieldFlagPassword)
+		if t.MaxLen != nil {
+			fs.MaxLen = int(*t.MaxLen)
+		}
+	case *model.PdfFieldButton:
+		switch {
+		case t.IsPush():
+			fs.Type = FieldTypePushButton
+		case t.IsRadio():
+			fs.Type = FieldTypeRadio
+			fs.OnStates = widgetOnStates(f)
+			fs.ExportValues = fs.OnStates
+		default:
+			fs.Type = FieldTypeCheckbox
+			fs.OnStates = widgetOnStates(f)
+		}
+		// The export values of the buttons, when different from their on states.
+		if t.Opt != nil && !t.IsPush() {
+			var exportValues []string
+			for _, obj := range t.Opt.Elements() {
+				exportValues = append(exportValues, stringVal(obj))
+			}
+			fs.ExportValues = exportValues
+		}
+	case *model.PdfFieldChoice:
+		fs.Type = FieldTypeListBox
+		if flags.Has(model.FieldFlagCombo) {
+			fs.Type = FieldTypeComboBox
+			fs.Editable = flags.Has(model.FieldFlagEdit)
+		}
+		fs.MultiSelect = flags.Has(model.FieldFlagMultiSelect)
+		if t.Opt != nil {
+			for _, obj := range t.Opt.Elements() {
+				fs.Choices = append(fs.Choices, choiceOption(obj))
+			}
+		}
+	case *model.PdfFieldSignature:
+		fs.Type = FieldTypeSignature
+	default:
+		common.Log.Debug("WARN: unknown type of field %s", name)
+	}
+
+	values := objectValues(f.V)
+	if fs.MultiSelect && len(values) > 1 {
+		fs.Values = values
+	} else if len(values) > 0 {
+		fs.Value = values[0]
+	}
+
+	for _, wa := range f.Annotations {
+		w := WidgetSchema{Page: pages[wa.GetContainingPdfObject()]}
+		if rect, ok := core.GetArray(wa.Rect); ok {
+			if vals, err := rect.ToFloat64Array(); err == nil && len(vals) == 4 {
+				copy(w.Rect[:], vals)
+			}
+		}
+		fs.Widgets = append(fs.Widgets, w)
+	}
+	return fs, nil
+}
+
+// widgetOnStates returns the names of the on states of the widgets of the button field `f`, which
+// are the names of the normal appearances other than Off.
+func widgetOnStates(f *model.PdfField) []string {
+	var states []string
+	seen := map[string]struct{}{}
+	for _, wa := range f.Annotations {
+		apDict, ok := core.GetDict(wa.AP)
+		if !ok {
+			continue
+		}
+		nDict, ok := core.GetDict(apDict.Get("N"))
+		if !ok {
+			continue
+		}
+		for _, key := range nDict.Keys() {
+			state := key.String()
+			if _, has := seen[state]; has || state == "Off" {
+				continue
+			}
+			seen[state] = struct{}{}
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// choiceOption returns the option represented by the choice field option `obj`, which is either
+// a string or an array of the export value and the displayed text.
+func choiceOption(obj core.PdfObject) ChoiceOption {
+	if arr, ok := core.GetArray(obj); ok && arr.Len() == 2 {
+		return ChoiceOption{Export: stringVal(arr.Get(0)), Display: stringVal(arr.Get(1))}
+	}
+	str := stringVal(obj)
+	return ChoiceOption{Export: str, Display: str}
+}
+
+// stringVal returns the text of the string or name `obj`.
+func stringVal(obj core.PdfObject) string {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectString:
+		return t.Decoded()
+	case *core.PdfObjectName:
+		return t.String()
+	}
+	return ""
+}
+
+// objectValues returns the field values represented by `obj`: a string, a name, or an array of
+// strings.
+func objectValues(obj core.PdfObject) []string {
+	obj = core.TraceToDirectObject(obj)
+	if arr, ok := obj.(*core.PdfObjectArray); ok {
+		var values []string
+		for _, o := range arr.Elements() {
+			values = append(values, stringVal(o))
+		}
+		return values
+	}
+	if val := stringVal(obj); val != "" {
+		return []string{val}
+	}
+	return nil
+}
+
+// LoadSchemaFromPDF loads the form schema of a PDF.
+func LoadSchemaFromPDF(rs io.ReadSeeker) (*Schema, error) {
+	pdfReader, err := model.NewPdfReader(rs)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchema(pdfReader)
+}
+
+// LoadSchemaFromPDFFile loads the form schema of a PDF file.
+func LoadSchemaFromPDFFile(filePath string) (*Schema, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadSchemaFromPDF(f)
+}
+
+// LoadSchemaFromJSON loads a JSON form schema from `r`.
+func LoadSchemaFromJSON(r io.Reader) (*Schema, error) {
+	var schema Schema
+	if err := json.NewDecoder(r).Decode(&schema.fields); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchemaFromJSONFile loads a form schema from a JSON file.
+func LoadSchemaFromJSONFile(filePath string) (*Schema, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadSchemaFromJSON(f)
+}
+
+// Fields returns the schemas of the fields of the form.
+func (s *Schema) Fields() []*FieldSchema {
+	return s.fields
+}
+
+// Field returns the schema of the field with the full name `name`, or nil if not found.
+func (s *Schema) Field(name string) *FieldSchema {
+	for _, fs := range s.fields {
+		if fs.Name == name {
+			return fs
+		}
+	}
+	return nil
+}
+
+// JSON returns the schema as a string in JSON format.
+func (s *Schema) JSON() (string, error) {
+	data, err := json.MarshalIndent(s.fields, "", "    ")
+	return string(data), err
+}
+
+// Validate validates the field values of `provider` against the schema, before filling a form
+// with them. The values are matched to the fields like model.PdfAcroForm.Fill does, by partial
+// name, then by full name. Returns ValidationErrors listing the invalid values and the missing
+// values of required fields.
+func (s *Schema) Validate(provider model.FieldValueProvider) error {
+	objMap, err := provider.FieldValues()
+	if err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+	matched := map[string]struct{}{}
+	for _, fs := range s.fields {
+		partialName := fs.Name
+		if i := strings.LastIndex(partialName, "."); i >= 0 {
+			partialName = partialName[i+1:]
+		}
+		key := partialName
+		obj, found := objMap[key]
+		if !found {
+			key = fs.Name
+			obj, found = objMap[key]
+		}
+		if !found {
+			if fs.Required && fs.isEmpty(fs.currentValues()) {
+				errs = append(errs, &ValidationError{Name: fs.Name, Reason: "required value missing"})
+			}
+			continue
+		}
+		matched[key] = struct{}{}
+
+		if reason := fs.validate(objectValues(obj)); reason != "" {
+			errs = append(errs, &ValidationError{Name: fs.Name, Reason: reason})
+		}
+	}
+
+	var unknown []string
+	for name := range objMap {
+		if _, ok := matched[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, &ValidationError{Name: name, Reason: "field not found"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate returns the reason why the field `values` are invalid, or an empty string if valid.
+func (fs *FieldSchema) validate(values []string) string {
+	value := strings.Join(values, "")
+	empty := fs.isEmpty(values)
+	if fs.Required && empty {
+		return "required value missing"
+	}
+	if fs.ReadOnly {
+		if strings.Join(fs.currentValues(), "\n") != strings.Join(values, "\n") {
+			return "read-only field"
+		}
+		return ""
+	}
+	if len(values) > 1 && !fs.MultiSelect {
+		return "multiple values not allowed"
+	}
+
+	switch fs.Type {
+	case FieldTypeText:
+		if fs.MaxLen > 0 && utf8.RuneCountInString(value) > fs.MaxLen {
+			return fmt.Sprintf("value longer than %d characters", fs.MaxLen)
+		}
+		if !fs.Multiline && strings.ContainsAny(value, "\r\n") {
+			return "multiple lines not allowed"
+		}
+	case FieldTypeCheckbox, FieldTypeRadio:
+		if !empty && !containsString(fs.OnStates, value) && !containsString(fs.ExportValues, value) {
+			return fmt.Sprintf("invalid %s value %q", fs.Type, value)
+		}
+	case FieldTypeComboBox, FieldTypeListBox:
+		if fs.Editable {
+			break
+		}
+		for _, val := range values {
+			if val != "" && !fs.hasChoice(val) {
+				return fmt.Sprintf("invalid choice %q", val)
+			}
+		}
+	case FieldTypePushButton, FieldTypeSignature:
+		if !empty {
+			return fmt.Sprintf("%s fields cannot be filled", fs.Type)
+		}
+	}
+	return ""
+}
+
+// currentValues returns the current values of the field.
+func (fs *FieldSchema) currentValues() []string {
+	if len(fs.Values) > 0 {
+		return fs.Values
+	}
+	if fs.Value != "" {
+		return []string{fs.Value}
+	}
+	return nil
+}
+
+// isEmpty returns true if the field `values` represent an empty field: no value, or the off
+// state of buttons.
+func (fs *FieldSchema) isEmpty(values []string) bool {
+	value := strings.Join(values, "")
+	isButton := fs.Type == FieldTypeCheckbox || fs.Type == FieldTypeRadio
+	return value == "" || value == "Off" && isButton
+}
+
+// hasChoice returns true if `val` is the export value of one of the options of the field.
+func (fs *FieldSchema) hasChoice(val string) bool {
+	for _, opt := range fs.Choices {
+		if opt.Export == val {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true if `list` contains `s`.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
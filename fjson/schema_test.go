@@ -0,0 +1,123 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+func TestSchemaExport(t *testing.T) {
+	schema, err := LoadSchemaFromPDFFile("./testdata/basicform.pdf")
+	require.NoError(t, err)
+	require.Len(t, schema.Fields(), 9)
+
+	fullName := schema.Field("full_name")
+	require.NotNil(t, fullName)
+	require.Equal(t, FieldTypeText, fullName.Type)
+	require.Len(t, fullName.Widgets, 1)
+	require.Equal(t, 1, fullName.Widgets[0].Page)
+	require.Equal(t, [4]float64{123.97, 619.02, 343.99, 633.6}, fullName.Widgets[0].Rect)
+
+	male := schema.Field("male")
+	require.Equal(t, FieldTypeCheckbox, male.Type)
+	require.Equal(t, []string{"Yes"}, male.OnStates)
+
+	favColor := schema.Field("fav_color")
+	require.Equal(t, FieldTypeComboBox, favColor.Type)
+	require.Equal(t, ChoiceOption{Export: "Black", Display: "Black"}, favColor.Choices[0])
+
+	// JSON round trip.
+	data, err := schema.JSON()
+	require.NoError(t, err)
+	loaded, err := LoadSchemaFromJSON(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, schema.Fields(), loaded.Fields())
+
+	schema, err = LoadSchemaFromPDFFile("./testdata/advancedform.pdf")
+	require.NoError(t, err)
+	var readOnly, maxLen int
+	for _, fs := range schema.Fields() {
+		if fs.ReadOnly {
+			readOnly++
+		}
+		if fs.MaxLen > 0 {
+			maxLen++
+		}
+	}
+	require.Equal(t, 4, readOnly)
+	require.NotZero(t, maxLen)
+}
+
+func TestSchemaValidation(t *testing.T) {
+	schema, err := LoadSchemaFromPDFFile("./testdata/basicform.pdf")
+	require.NoError(t, err)
+
+	fdata, err := LoadFromJSONFile("./testdata/formdata.json")
+	require.NoError(t, err)
+	require.NoError(t, schema.Validate(fdata))
+
+	data := `[
+		{"name": "full_name", "value": "John\nDoe"},
+		{"name": "male", "value": "Maybe"},
+		{"name": "fav_color", "value": "Purple"},
+		{"name": "unknown", "value": "x"}
+	]`
+	fdata, err = LoadFromJSON(strings.NewReader(data))
+	require.NoError(t, err)
+	err = schema.Validate(fdata)
+	require.Error(t, err)
+	errs, ok := err.(ValidationErrors)
+	require.True(t, ok)
+	require.Len(t, errs, 4)
+	require.Equal(t, "field full_name: multiple lines not allowed", errs[0].Error())
+	require.Equal(t, "field male: invalid checkbox value \"Maybe\"", errs[1].Error())
+	require.Equal(t, "field fav_color: invalid choice \"Purple\"", errs[2].Error())
+	require.Equal(t, "field unknown: field not found", errs[3].Error())
+
+	// Flags.
+	age := schema.Field("age")
+	age.MaxLen = 2
+	age.Required = true
+	fdata, err = LoadFromJSON(strings.NewReader(`[{"name": "age", "value": "100"}]`))
+	require.NoError(t, err)
+	require.EqualError(t, schema.Validate(fdata), "field age: value longer than 2 characters")
+	fdata, err = LoadFromJSON(strings.NewReader(`[{"name": "city", "value": "Paris"}]`))
+	require.NoError(t, err)
+	require.EqualError(t, schema.Validate(fdata), "field age: required value missing")
+
+	schema.Field("city").ReadOnly = true
+	require.EqualError(t, schema.Validate(fdata),
+		"field age: required value missing; field city: read-only field")
+}
+
+func TestSchemaChoiceOptions(t *testing.T) {
+	opt := core.MakeArray(
+		core.MakeArray(core.MakeString("fr"), core.MakeString("France")),
+		core.MakeString("Iceland"),
+	)
+	field := model.NewPdfField()
+	choice := &model.PdfFieldChoice{PdfField: field, Opt: opt}
+	field.SetContext(choice)
+	field.T = core.MakeString("country")
+	field.SetFlag(model.FieldFlagMultiSelect)
+	field.V = core.MakeArray(core.MakeString("fr"), core.MakeString("Iceland"))
+
+	fs, err := newFieldSchema(field, nil)
+	require.NoError(t, err)
+	require.Equal(t, FieldTypeListBox, fs.Type)
+	require.True(t, fs.MultiSelect)
+	require.Equal(t, []ChoiceOption{{"fr", "France"}, {"Iceland", "Iceland"}}, fs.Choices)
+	require.Equal(t, []string{"fr", "Iceland"}, fs.Values)
+
+	require.Equal(t, "", fs.validate([]string{"fr"}))
+	require.Equal(t, "invalid choice \"France\"", fs.validate([]string{"France"}))
+}
@@ -0,0 +1,252 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package cms implements the Cryptographic Message Syntax (CMS) signed data used by PDF signatures
+// internally (RFC 5652), with the CAdES signed attributes (RFC 5035) and the time-stamp tokens
+// (RFC 3161).
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Object identifiers.
+var (
+	OIDData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDTSTInfo                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	OIDAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	OIDAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	OIDAttributeSigningCertV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	OIDAttributeTimestamp     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	OIDAttributeRevocation    = asn1.ObjectIdentifier{1, 2, 840, 113583, 1, 1, 8}
+
+	OIDDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	OIDDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	OIDDigestSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	OIDDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	OIDSignatureRSA       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	OIDSignatureRSASHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	OIDSignatureRSASHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	OIDSignatureRSASHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	OIDSignatureRSASHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	OIDSignatureRSAPSS    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	OIDMGF1               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	OIDSignatureECDSA     = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	OIDSignatureECDSASHA1 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	OIDSignatureECDSA256  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	OIDSignatureECDSA384  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	OIDSignatureECDSA512  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	OIDSignatureEd25519   = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// hashOIDs maps the supported digest algorithms to their object identifiers.
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   OIDDigestSHA1,
+	crypto.SHA256: OIDDigestSHA256,
+	crypto.SHA384: OIDDigestSHA384,
+	crypto.SHA512: OIDDigestSHA512,
+}
+
+// HashOID returns the object identifier of the digest algorithm `hash`.
+func HashOID(hash crypto.Hash) (asn1.ObjectIdentifier, error) {
+	oid, ok := hashOIDs[hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest algorithm %v", hash)
+	}
+	return oid, nil
+}
+
+// HashFromOID returns the digest algorithm identified by `oid`.
+func HashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	for hash, hashOID := range hashOIDs {
+		if oid.Equal(hashOID) {
+			return hash, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported digest algorithm %v", oid)
+}
+
+// Attribute is a signed or unsigned attribute of a signer, with a single value.
+type Attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// attribute is the ASN.1 structure of the attributes.
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// NewAttribute returns the attribute of type `oid` with the value `value`, marshaled to DER.
+func NewAttribute(oid asn1.ObjectIdentifier, value interface{}) (Attribute, error) {
+	data, err := asn1.Marshal(value)
+	if err != nil {
+		return Attribute{}, err
+	}
+	return Attribute{Type: oid, Value: asn1.RawValue{FullBytes: data}}, nil
+}
+
+// marshalAttributes returns the DER encoding of the set of `attrs`, with the tag `tag` in the
+// context specific class, or as a universal SET if `tag` is negative.
+func marshalAttributes(attrs []Attribute, tag int) ([]byte, error) {
+	var encoded [][]byte
+	for _, attr := range attrs {
+		value, err := asn1.Marshal(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		data, err := asn1.Marshal(attribute{
+			Type:   attr.Type,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: value},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, data)
+	}
+
+	// The elements of DER sets are sorted by their encodings.
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	raw := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(encoded, nil)}
+	if tag >= 0 {
+		raw.Class, raw.Tag = asn1.ClassContextSpecific, tag
+	}
+	return asn1.Marshal(raw)
+}
+
+// parseAttributes parses the set of attributes `raw`.
+func parseAttributes(raw asn1.RawValue) ([]Attribute, error) {
+	var attrs []Attribute
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var attr attribute
+		var err error
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, err
+		}
+		var value asn1.RawValue
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &value); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, Attribute{Type: attr.Type, Value: value})
+	}
+	return attrs, nil
+}
+
+// findAttribute returns the attribute of type `oid` in `attrs`.
+func findAttribute(attrs []Attribute, oid asn1.ObjectIdentifier) (Attribute, bool) {
+	for _, attr := range attrs {
+		if attr.Type.Equal(oid) {
+			return attr, true
+		}
+	}
+	return Attribute{}, false
+}
+
+// explicitContent returns the explicitly tagged [0] content `data`, which the asn1 package does
+// not wrap for raw values.
+func explicitContent(data []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: data}
+}
+
+// contentInfo is the ASN.1 structure of the CMS content info.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+// signedData is the ASN.1 structure of the CMS signed data.
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// encapContentInfo is the ASN.1 structure of the encapsulated content.
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional"`
+}
+
+// signerInfo is the ASN.1 structure of the signer information.
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// issuerAndSerial is the ASN.1 structure identifying certificates by issuer and serial number.
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// essCertIDv2 is the ASN.1 structure identifying the signing certificate (RFC 5035).
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+// issuerSerial is the ASN.1 structure of the issuer serial of the ESS certificate identifiers.
+type issuerSerial struct {
+	Issuer       []asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// signingCertificateV2 is the ASN.1 structure of the signing certificate v2 attribute.
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// MessageImprint is the imprint of the data time-stamped by a time-stamp token.
+type MessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// Accuracy is the accuracy of the time of a time-stamp token.
+type Accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// TSTInfo is the information of a time-stamp token (RFC 3161).
+type TSTInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint MessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       Accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+// errNoSigner is returned for signed data without signer.
+var errNoSigner = errors.New("cms: no signer")
@@ -0,0 +1,158 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package cms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// makeTestCertificate returns a self-signed certificate of the key `signer`.
+func makeTestCertificate(t *testing.T, signer crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "CMS Test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: []byte{1, 2, 3, 4},
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(data)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestSignVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name   string
+		signer crypto.Signer
+		hash   crypto.Hash
+		pss    bool
+	}{
+		{"rsa", rsaKey, crypto.SHA256, false},
+		{"rsa-pss", rsaKey, crypto.SHA512, true},
+		{"ecdsa", ecKey, crypto.SHA384, false},
+		{"ed25519", edKey, crypto.SHA512, false},
+	}
+
+	content := []byte("signed content")
+	for _, tcase := range testcases {
+		cert := makeTestCertificate(t, tcase.signer)
+		h := tcase.hash.New()
+		h.Write(content)
+
+		sd, err := NewSignedData(cert, nil, tcase.hash, h.Sum(nil))
+		require.NoError(t, err, tcase.name)
+		require.NoError(t, sd.AddSigningCertificateV2(), tcase.name)
+		require.NoError(t, sd.Sign(tcase.signer, tcase.pss), tcase.name)
+		require.NoError(t, sd.AddUnsignedAttribute(OIDAttributeTimestamp, asn1.RawValue{FullBytes: []byte{5, 0}}))
+		data, err := sd.Marshal()
+		require.NoError(t, err, tcase.name)
+
+		// Signature contents are padded with zeros.
+		parsed, err := Parse(append(data, make([]byte, 64)...))
+		require.NoError(t, err, tcase.name)
+		require.Equal(t, cert.Raw, parsed.Certificate.Raw)
+		require.True(t, parsed.HasSigningCertificateV2(), tcase.name)
+		require.NoError(t, parsed.Verify(content), tcase.name)
+		require.Error(t, parsed.Verify([]byte("modified content")), tcase.name)
+		token, ok := parsed.TimestampToken()
+		require.True(t, ok)
+		require.Equal(t, []byte{5, 0}, token)
+	}
+}
+
+func TestExternalSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := makeTestCertificate(t, key)
+	digest := sha256.Sum256([]byte("content"))
+
+	sd, err := NewSignedData(cert, nil, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	attrsDigest, err := sd.SignedAttributesDigest()
+	require.NoError(t, err)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, attrsDigest)
+	require.NoError(t, err)
+	require.NoError(t, sd.SetSignature(signature, false))
+
+	data, err := sd.Marshal()
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	require.NoError(t, parsed.VerifyDigest(digest[:]))
+
+	_, err = NewSignedData(cert, nil, crypto.SHA256, digest[:4])
+	require.Error(t, err)
+}
+
+func TestContentTypeAttribute(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := makeTestCertificate(t, key)
+	content := []byte{0x30, 0}
+	digest := sha256.Sum256(content)
+
+	// The content type attribute follows the encapsulated content type.
+	sd, err := NewSignedData(cert, nil, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	sd.ContentType = OIDTSTInfo
+	sd.Content = content
+	require.NoError(t, sd.Sign(key, false))
+	data, err := sd.Marshal()
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, OIDTSTInfo, parsed.ContentType)
+	value, ok := parsed.SignedAttribute(OIDAttributeContentType)
+	require.True(t, ok)
+	var contentType asn1.ObjectIdentifier
+	_, err = asn1.Unmarshal(value.FullBytes, &contentType)
+	require.NoError(t, err)
+	require.Equal(t, OIDTSTInfo, contentType)
+	require.NoError(t, parsed.VerifyDigest(digest[:]))
+
+	// A content type attribute differing from the encapsulated content type is rejected.
+	parsed.ContentType = OIDData
+	require.EqualError(t, parsed.VerifyDigest(digest[:]), "cms: content type attribute mismatch")
+}
+
+func TestBERToDER(t *testing.T) {
+	// SEQUENCE of indefinite length containing a constructed OCTET STRING and an INTEGER.
+	ber := []byte{
+		0x30, 0x80,
+		0x24, 0x80, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c', 0x00, 0x00,
+		0x02, 0x01, 0x07,
+		0x00, 0x00,
+		0xff,
+	}
+	der, err := berToDER(ber)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x30, 0x08, 0x04, 0x03, 'a', 'b', 'c', 0x02, 0x01, 0x07}, der)
+	require.Equal(t, []byte{0x81, 0x80}, encodeLength(128))
+}
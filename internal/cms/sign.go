@@ -0,0 +1,384 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package cms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// SignedData is the signed data of a single signer, with detached content.
+type SignedData struct {
+	// Certificate is the certificate of the signer.
+	Certificate *x509.Certificate
+
+	// Chain contains the certificates embedded in the signed data, in addition to Certificate.
+	Chain []*x509.Certificate
+
+	// Hash is the digest algorithm of the signer.
+	Hash crypto.Hash
+
+	// ContentType is the type of the encapsulated content, OIDData if nil.
+	ContentType asn1.ObjectIdentifier
+
+	// Content is the encapsulated content, if not detached.
+	Content []byte
+
+	signedAttrs    []Attribute
+	rawSignedAttrs []byte
+	unsignedAttrs  []Attribute
+	sigAlg         pkix.AlgorithmIdentifier
+	signature      []byte
+	sid            asn1.RawValue
+	crls           asn1.RawValue
+}
+
+// NewSignedData returns the signed data of the content whose digest, computed with `hash`, is
+// `digest`. The content type and message digest signed attributes are added to the signed data,
+// the content type attribute being encoded with the ContentType of the signed data.
+func NewSignedData(certificate *x509.Certificate, chain []*x509.Certificate, hash crypto.Hash,
+	digest []byte) (*SignedData, error) {
+	if certificate == nil {
+		return nil, errors.New("cms: certificate required")
+	}
+	if _, err := HashOID(hash); err != nil {
+		return nil, err
+	}
+	if len(digest) != hash.Size() {
+		return nil, fmt.Errorf("cms: invalid digest length %d", len(digest))
+	}
+
+	sd := &SignedData{
+		Certificate: certificate,
+		Chain:       chain,
+		Hash:        hash,
+	}
+	if err := sd.AddSignedAttribute(OIDAttributeContentType, sd.contentType()); err != nil {
+		return nil, err
+	}
+	if err := sd.AddSignedAttribute(OIDAttributeMessageDigest, digest); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// AddSignedAttribute adds the signed attribute of type `oid` with the value `value`.
+func (sd *SignedData) AddSignedAttribute(oid asn1.ObjectIdentifier, value interface{}) error {
+	attr, err := NewAttribute(oid, value)
+	if err != nil {
+		return err
+	}
+	sd.signedAttrs = setAttribute(sd.signedAttrs, attr)
+	sd.rawSignedAttrs = nil
+	return nil
+}
+
+// AddUnsignedAttribute adds the unsigned attribute of type `oid` with the value `value`.
+func (sd *SignedData) AddUnsignedAttribute(oid asn1.ObjectIdentifier, value interface{}) error {
+	attr, err := NewAttribute(oid, value)
+	if err != nil {
+		return err
+	}
+	sd.unsignedAttrs = setAttribute(sd.unsignedAttrs, attr)
+	return nil
+}
+
+// setAttribute replaces the attribute of the type of `attr` in `attrs`, or appends it.
+func setAttribute(attrs []Attribute, attr Attribute) []Attribute {
+	for i := range attrs {
+		if attrs[i].Type.Equal(attr.Type) {
+			attrs[i] = attr
+			return attrs
+		}
+	}
+	return append(attrs, attr)
+}
+
+// AddSigningCertificateV2 adds the signing certificate v2 signed attribute (RFC 5035), which
+// binds the certificate of the signer to the signature, as required by CAdES.
+func (sd *SignedData) AddSigningCertificateV2() error {
+	h := crypto.SHA256.New()
+	h.Write(sd.Certificate.Raw)
+
+	// SHA-256 is the default hash algorithm of the ESS certificate identifiers and is omitted.
+	cert := essCertIDv2{
+		CertHash: h.Sum(nil),
+		IssuerSerial: issuerSerial{
+			Issuer: []asn1.RawValue{{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        4,
+				IsCompound: true,
+				Bytes:      sd.Certificate.RawIssuer,
+			}},
+			SerialNumber: sd.Certificate.SerialNumber,
+		},
+	}
+	return sd.AddSignedAttribute(OIDAttributeSigningCertV2, signingCertificateV2{Certs: []essCertIDv2{cert}})
+}
+
+// SignedAttributesData returns the DER encoding of the signed attributes, which is the data to
+// be signed by the signer.
+func (sd *SignedData) SignedAttributesData() ([]byte, error) {
+	if sd.rawSignedAttrs != nil {
+		// The signed attributes are signed with the universal SET tag.
+		data := append([]byte{}, sd.rawSignedAttrs...)
+		data[0] = 0x31
+		return data, nil
+	}
+	attrs, err := sd.signedAttributes()
+	if err != nil {
+		return nil, err
+	}
+	return marshalAttributes(attrs, -1)
+}
+
+// signedAttributes returns the signed attributes of `sd`. Unless they were parsed, the content
+// type attribute is set to the encapsulated content type, which may be set after the attribute is
+// added (RFC 5652 11.1).
+func (sd *SignedData) signedAttributes() ([]Attribute, error) {
+	if sd.rawSignedAttrs != nil {
+		return sd.signedAttrs, nil
+	}
+	if _, ok := findAttribute(sd.signedAttrs, OIDAttributeContentType); !ok {
+		return sd.signedAttrs, nil
+	}
+	attr, err := NewAttribute(OIDAttributeContentType, sd.contentType())
+	if err != nil {
+		return nil, err
+	}
+	return setAttribute(append([]Attribute{}, sd.signedAttrs...), attr), nil
+}
+
+// contentType returns the type of the encapsulated content.
+func (sd *SignedData) contentType() asn1.ObjectIdentifier {
+	if sd.ContentType == nil {
+		return OIDData
+	}
+	return sd.ContentType
+}
+
+// SignedAttributesDigest returns the digest of the signed attributes with the digest algorithm
+// of the signer.
+func (sd *SignedData) SignedAttributesDigest() ([]byte, error) {
+	data, err := sd.SignedAttributesData()
+	if err != nil {
+		return nil, err
+	}
+	h := sd.Hash.New()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Sign signs the signed attributes with `signer`, whose public key must be the one of the
+// certificate. RSA keys produce RSASSA-PSS signatures if `pss` is true, PKCS #1 v1.5 signatures
+// otherwise.
+func (sd *SignedData) Sign(signer crypto.Signer, pss bool) error {
+	sigAlg, err := SignatureAlgorithm(signer.Public(), sd.Hash, pss)
+	if err != nil {
+		return err
+	}
+	data, err := sd.SignedAttributesData()
+	if err != nil {
+		return err
+	}
+
+	var opts crypto.SignerOpts = sd.Hash
+	input := data
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		// Ed25519 signs the data itself.
+		opts = crypto.Hash(0)
+	default:
+		h := sd.Hash.New()
+		h.Write(data)
+		input = h.Sum(nil)
+		if pss {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sd.Hash}
+		}
+	}
+
+	signature, err := signer.Sign(rand.Reader, input, opts)
+	if err != nil {
+		return err
+	}
+	sd.sigAlg = sigAlg
+	sd.signature = signature
+	return nil
+}
+
+// SetSignature sets the signature of the signed attributes computed externally, with the
+// signature algorithm of the public key of the certificate.
+func (sd *SignedData) SetSignature(signature []byte, pss bool) error {
+	sigAlg, err := SignatureAlgorithm(sd.Certificate.PublicKey, sd.Hash, pss)
+	if err != nil {
+		return err
+	}
+	sd.sigAlg = sigAlg
+	sd.signature = signature
+	return nil
+}
+
+// Signature returns the signature value of the signer.
+func (sd *SignedData) Signature() []byte {
+	return sd.signature
+}
+
+// SignatureAlgorithm returns the algorithm identifier of the signatures made with the key
+// `pub`, of digests computed with `hash`.
+func SignatureAlgorithm(pub crypto.PublicKey, hash crypto.Hash, pss bool) (pkix.AlgorithmIdentifier, error) {
+	hashOID, err := HashOID(hash)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		if !pss {
+			return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureRSA, Parameters: asn1.NullRawValue}, nil
+		}
+		hashAlg := pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue}
+		mgfParams, err := asn1.Marshal(hashAlg)
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, err
+		}
+		params, err := asn1.Marshal(pssParameters{
+			Hash:         hashAlg,
+			MGF:          pkix.AlgorithmIdentifier{Algorithm: OIDMGF1, Parameters: asn1.RawValue{FullBytes: mgfParams}},
+			SaltLength:   hash.Size(),
+			TrailerField: 1,
+		})
+		if err != nil {
+			return pkix.AlgorithmIdentifier{}, err
+		}
+		return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureRSAPSS, Parameters: asn1.RawValue{FullBytes: params}}, nil
+	case *ecdsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureECDSA256}, nil
+		case crypto.SHA384:
+			return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureECDSA384}, nil
+		case crypto.SHA512:
+			return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureECDSA512}, nil
+		}
+	case ed25519.PublicKey:
+		if hash == crypto.SHA512 {
+			return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureEd25519}, nil
+		}
+	default:
+		return pkix.AlgorithmIdentifier{}, fmt.Errorf("cms: unsupported public key %T", pub)
+	}
+	return pkix.AlgorithmIdentifier{}, fmt.Errorf("cms: unsupported digest algorithm %v for %T", hash, pub)
+}
+
+// pssParameters is the ASN.1 structure of the RSASSA-PSS parameters.
+type pssParameters struct {
+	Hash         pkix.AlgorithmIdentifier `asn1:"explicit,tag:0"`
+	MGF          pkix.AlgorithmIdentifier `asn1:"explicit,tag:1"`
+	SaltLength   int                      `asn1:"explicit,tag:2"`
+	TrailerField int                      `asn1:"optional,explicit,tag:3,default:1"`
+}
+
+// Marshal returns the DER encoding of the signed data as a CMS content info.
+func (sd *SignedData) Marshal() ([]byte, error) {
+	if sd.signature == nil {
+		return nil, errors.New("cms: signed data not signed")
+	}
+	hashOID, err := HashOID(sd.Hash)
+	if err != nil {
+		return nil, err
+	}
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue}
+
+	sid := sd.sid
+	if sid.FullBytes == nil {
+		data, err := asn1.Marshal(issuerAndSerial{
+			Issuer:       asn1.RawValue{FullBytes: sd.Certificate.RawIssuer},
+			SerialNumber: sd.Certificate.SerialNumber,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sid = asn1.RawValue{FullBytes: data}
+	}
+
+	signedAttrs := sd.rawSignedAttrs
+	if signedAttrs == nil {
+		attrs, err := sd.signedAttributes()
+		if err != nil {
+			return nil, err
+		}
+		if signedAttrs, err = marshalAttributes(attrs, 0); err != nil {
+			return nil, err
+		}
+	}
+	si := signerInfo{
+		Version:            1,
+		SID:                sid,
+		DigestAlgorithm:    digestAlg,
+		SignedAttrs:        asn1.RawValue{FullBytes: signedAttrs},
+		SignatureAlgorithm: sd.sigAlg,
+		Signature:          sd.signature,
+	}
+	if len(sd.unsignedAttrs) > 0 {
+		unsignedAttrs, err := marshalAttributes(sd.unsignedAttrs, 1)
+		if err != nil {
+			return nil, err
+		}
+		si.UnsignedAttrs = asn1.RawValue{FullBytes: unsignedAttrs}
+	}
+	siData, err := asn1.Marshal(si)
+	if err != nil {
+		return nil, err
+	}
+	algData, err := asn1.Marshal(digestAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Embed the signer certificate, followed by the chain, without duplicates.
+	var rawCerts []byte
+	seen := map[string]bool{}
+	for _, cert := range append([]*x509.Certificate{sd.Certificate}, sd.Chain...) {
+		if cert == nil || seen[string(cert.Raw)] {
+			continue
+		}
+		seen[string(cert.Raw)] = true
+		rawCerts = append(rawCerts, cert.Raw...)
+	}
+
+	encap := encapContentInfo{EContentType: sd.contentType()}
+	if sd.Content != nil {
+		content, err := asn1.Marshal(sd.Content)
+		if err != nil {
+			return nil, err
+		}
+		encap.EContent = explicitContent(content)
+	}
+
+	data, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: algData},
+		EncapContentInfo: encap,
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: rawCerts},
+		CRLs:             sd.crls,
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: siData},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: OIDSignedData,
+		Content:     explicitContent(data),
+	})
+}
@@ -0,0 +1,529 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Parse parses the CMS signed data `data`. Trailing bytes, such as the zero padding of the
+// signature contents of PDF files, are ignored and BER encoded data is supported.
+func Parse(data []byte) (*SignedData, error) {
+	sd, err := parse(data)
+	if err == nil {
+		return sd, nil
+	}
+	der, berErr := berToDER(data)
+	if berErr != nil {
+		return nil, err
+	}
+	return parse(der)
+}
+
+// parse parses the DER encoded signed data `data`.
+func parse(data []byte) (*SignedData, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+	if !ci.ContentType.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("cms: unsupported content type %v", ci.ContentType)
+	}
+
+	signed, err := parseSignedData(ci.Content.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	if len(signed.Certificates.Bytes) > 0 {
+		if certs, err = x509.ParseCertificates(signed.Certificates.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	if len(signed.SignerInfos.Bytes) == 0 {
+		return nil, errNoSigner
+	}
+	si, err := parseSignerInfo(signed.SignerInfos.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := HashFromOID(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	sd := &SignedData{
+		Hash:        hash,
+		ContentType: signed.EncapContentInfo.EContentType,
+		sigAlg:      si.SignatureAlgorithm,
+		signature:   si.Signature,
+		sid:         si.SID,
+		crls:        signed.CRLs,
+	}
+	if len(signed.EncapContentInfo.EContent.Bytes) > 0 {
+		if _, err := asn1.Unmarshal(signed.EncapContentInfo.EContent.Bytes, &sd.Content); err != nil {
+			return nil, err
+		}
+	}
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		if sd.signedAttrs, err = parseAttributes(si.SignedAttrs); err != nil {
+			return nil, err
+		}
+		sd.rawSignedAttrs = si.SignedAttrs.FullBytes
+	}
+	if len(si.UnsignedAttrs.FullBytes) > 0 {
+		if sd.unsignedAttrs, err = parseAttributes(si.UnsignedAttrs); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cert := range certs {
+		if sd.Certificate == nil && matchSignerID(si.SID, cert) {
+			sd.Certificate = cert
+			continue
+		}
+		sd.Chain = append(sd.Chain, cert)
+	}
+	if sd.Certificate == nil {
+		return nil, errors.New("cms: signer certificate not found")
+	}
+	return sd, nil
+}
+
+// sequenceElements returns the elements of the DER encoded SEQUENCE `data`.
+func sequenceElements(data []byte) ([]asn1.RawValue, error) {
+	var seq asn1.RawValue
+	if _, err := asn1.Unmarshal(data, &seq); err != nil {
+		return nil, err
+	}
+	if seq.Class != asn1.ClassUniversal || seq.Tag != asn1.TagSequence {
+		return nil, errors.New("cms: sequence expected")
+	}
+	var elements []asn1.RawValue
+	for rest := seq.Bytes; len(rest) > 0; {
+		var elem asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &elem); err != nil {
+			return nil, err
+		}
+		elements = append(elements, elem)
+	}
+	return elements, nil
+}
+
+// isContextTag returns true if `elem` has the tag `tag` in the context specific class.
+func isContextTag(elem asn1.RawValue, tag int) bool {
+	return elem.Class == asn1.ClassContextSpecific && elem.Tag == tag
+}
+
+// parseSignedData parses the signed data `data`. The optional fields are matched by tag, which
+// the asn1 package does not do for raw values.
+func parseSignedData(data []byte) (*signedData, error) {
+	elements, err := sequenceElements(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) < 4 {
+		return nil, errors.New("cms: invalid signed data")
+	}
+
+	var signed signedData
+	if _, err := asn1.Unmarshal(elements[0].FullBytes, &signed.Version); err != nil {
+		return nil, err
+	}
+	signed.DigestAlgorithms = elements[1]
+	if _, err := asn1.Unmarshal(elements[2].FullBytes, &signed.EncapContentInfo); err != nil {
+		return nil, err
+	}
+	for _, elem := range elements[3:] {
+		switch {
+		case isContextTag(elem, 0):
+			signed.Certificates = elem
+		case isContextTag(elem, 1):
+			signed.CRLs = elem
+		default:
+			signed.SignerInfos = elem
+		}
+	}
+	return &signed, nil
+}
+
+// parseSignerInfo parses the first signer information of the set of signer infos `data`.
+func parseSignerInfo(data []byte) (*signerInfo, error) {
+	elements, err := sequenceElements(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) < 5 {
+		return nil, errors.New("cms: invalid signer info")
+	}
+
+	var si signerInfo
+	if _, err := asn1.Unmarshal(elements[0].FullBytes, &si.Version); err != nil {
+		return nil, err
+	}
+	si.SID = elements[1]
+	if _, err := asn1.Unmarshal(elements[2].FullBytes, &si.DigestAlgorithm); err != nil {
+		return nil, err
+	}
+	elements = elements[3:]
+	if isContextTag(elements[0], 0) {
+		si.SignedAttrs = elements[0]
+		elements = elements[1:]
+	}
+	if len(elements) < 2 {
+		return nil, errors.New("cms: invalid signer info")
+	}
+	if _, err := asn1.Unmarshal(elements[0].FullBytes, &si.SignatureAlgorithm); err != nil {
+		return nil, err
+	}
+	if _, err := asn1.Unmarshal(elements[1].FullBytes, &si.Signature); err != nil {
+		return nil, err
+	}
+	if len(elements) > 2 && isContextTag(elements[2], 1) {
+		si.UnsignedAttrs = elements[2]
+	}
+	return &si, nil
+}
+
+// matchSignerID returns true if `cert` is the certificate identified by the signer identifier
+// `sid`, either by issuer and serial number or by subject key identifier.
+func matchSignerID(sid asn1.RawValue, cert *x509.Certificate) bool {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		return len(cert.SubjectKeyId) > 0 && bytes.Equal(sid.Bytes, cert.SubjectKeyId)
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return false
+	}
+	return bytes.Equal(ias.Issuer.FullBytes, cert.RawIssuer) && ias.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+// Certificates returns the certificates embedded in the signed data, starting with the
+// certificate of the signer.
+func (sd *SignedData) Certificates() []*x509.Certificate {
+	return append([]*x509.Certificate{sd.Certificate}, sd.Chain...)
+}
+
+// SignedAttribute returns the value of the signed attribute of type `oid`.
+func (sd *SignedData) SignedAttribute(oid asn1.ObjectIdentifier) (asn1.RawValue, bool) {
+	attrs, err := sd.signedAttributes()
+	if err != nil {
+		return asn1.RawValue{}, false
+	}
+	attr, ok := findAttribute(attrs, oid)
+	return attr.Value, ok
+}
+
+// UnsignedAttribute returns the value of the unsigned attribute of type `oid`.
+func (sd *SignedData) UnsignedAttribute(oid asn1.ObjectIdentifier) (asn1.RawValue, bool) {
+	attr, ok := findAttribute(sd.unsignedAttrs, oid)
+	return attr.Value, ok
+}
+
+// MessageDigest returns the value of the message digest signed attribute.
+func (sd *SignedData) MessageDigest() ([]byte, error) {
+	value, ok := sd.SignedAttribute(OIDAttributeMessageDigest)
+	if !ok {
+		return nil, errors.New("cms: message digest attribute not found")
+	}
+	var digest []byte
+	if _, err := asn1.Unmarshal(value.FullBytes, &digest); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// SigningTime returns the value of the signing time signed attribute, if present.
+func (sd *SignedData) SigningTime() (time.Time, bool) {
+	value, ok := sd.SignedAttribute(OIDAttributeSigningTime)
+	if !ok {
+		return time.Time{}, false
+	}
+	var t time.Time
+	if _, err := asn1.Unmarshal(value.FullBytes, &t); err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// HasSigningCertificateV2 returns true if the signed data has a signing certificate v2
+// attribute referencing the certificate of the signer.
+func (sd *SignedData) HasSigningCertificateV2() bool {
+	value, ok := sd.SignedAttribute(OIDAttributeSigningCertV2)
+	if !ok {
+		return false
+	}
+	var attr signingCertificateV2
+	if _, err := asn1.Unmarshal(value.FullBytes, &attr); err != nil || len(attr.Certs) == 0 {
+		return false
+	}
+
+	hash := crypto.SHA256
+	if alg := attr.Certs[0].HashAlgorithm.Algorithm; len(alg) > 0 {
+		var err error
+		if hash, err = HashFromOID(alg); err != nil {
+			return false
+		}
+	}
+	h := hash.New()
+	h.Write(sd.Certificate.Raw)
+	return bytes.Equal(h.Sum(nil), attr.Certs[0].CertHash)
+}
+
+// TimestampToken returns the time-stamp token of the signature, stored in the signature
+// timestamp unsigned attribute, if present.
+func (sd *SignedData) TimestampToken() ([]byte, bool) {
+	value, ok := sd.UnsignedAttribute(OIDAttributeTimestamp)
+	if !ok {
+		return nil, false
+	}
+	return value.FullBytes, true
+}
+
+// Verify verifies the signature of the detached content `content`, or of the encapsulated
+// content if `content` is nil.
+func (sd *SignedData) Verify(content []byte) error {
+	if content == nil {
+		content = sd.Content
+	}
+	h := sd.Hash.New()
+	h.Write(content)
+	if len(sd.signedAttrs) == 0 {
+		return sd.verifySignature(content, h.Sum(nil))
+	}
+	return sd.VerifyDigest(h.Sum(nil))
+}
+
+// VerifyDigest verifies the signature of the content whose digest is `digest`. The signed data
+// must have signed attributes.
+func (sd *SignedData) VerifyDigest(digest []byte) error {
+	if len(sd.signedAttrs) == 0 {
+		return errors.New("cms: signed attributes required")
+	}
+	messageDigest, err := sd.MessageDigest()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(messageDigest, digest) {
+		return errors.New("cms: message digest mismatch")
+	}
+	value, ok := sd.SignedAttribute(OIDAttributeContentType)
+	if !ok {
+		return errors.New("cms: content type attribute not found")
+	}
+	// The content type attribute must be the type of the encapsulated content (RFC 5652 11.1).
+	var contentType asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(value.FullBytes, &contentType); err != nil {
+		return err
+	}
+	if !contentType.Equal(sd.contentType()) {
+		return errors.New("cms: content type attribute mismatch")
+	}
+
+	data, err := sd.SignedAttributesData()
+	if err != nil {
+		return err
+	}
+	h := sd.Hash.New()
+	h.Write(data)
+	return sd.verifySignature(data, h.Sum(nil))
+}
+
+// verifySignature verifies the signature of `data`, whose digest is `digest`, with the public
+// key of the certificate of the signer.
+func (sd *SignedData) verifySignature(data, digest []byte) error {
+	alg := sd.sigAlg.Algorithm
+	switch pub := sd.Certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if alg.Equal(OIDSignatureRSAPSS) {
+			return rsa.VerifyPSS(pub, sd.Hash, digest, sd.signature, &rsa.PSSOptions{
+				SaltLength: rsa.PSSSaltLengthAuto,
+			})
+		}
+		hash := sd.Hash
+		switch {
+		case alg.Equal(OIDSignatureRSA):
+		case alg.Equal(OIDSignatureRSASHA1):
+			hash = crypto.SHA1
+		case alg.Equal(OIDSignatureRSASHA256):
+			hash = crypto.SHA256
+		case alg.Equal(OIDSignatureRSASHA384):
+			hash = crypto.SHA384
+		case alg.Equal(OIDSignatureRSASHA512):
+			hash = crypto.SHA512
+		default:
+			return fmt.Errorf("cms: unsupported signature algorithm %v", alg)
+		}
+		if hash != sd.Hash {
+			h := hash.New()
+			h.Write(data)
+			digest = h.Sum(nil)
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, sd.signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, sd.signature) {
+			return errors.New("cms: invalid ECDSA signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, data, sd.signature) {
+			return errors.New("cms: invalid Ed25519 signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("cms: unsupported public key %T", sd.Certificate.PublicKey)
+}
+
+// ParseTimestampToken parses the time-stamp token `data` and returns its signed data and its
+// time-stamp information. The signature of the token is verified.
+func ParseTimestampToken(data []byte) (*SignedData, *TSTInfo, error) {
+	sd, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sd.ContentType.Equal(OIDTSTInfo) {
+		return nil, nil, fmt.Errorf("cms: unexpected time-stamp token content type %v", sd.ContentType)
+	}
+	var info TSTInfo
+	if _, err := asn1.Unmarshal(sd.Content, &info); err != nil {
+		return nil, nil, err
+	}
+	if err := sd.Verify(nil); err != nil {
+		return nil, nil, err
+	}
+	return sd, &info, nil
+}
+
+// VerifyImprint verifies that the time-stamp information is the one of `data`.
+func (info *TSTInfo) VerifyImprint(data []byte) error {
+	hash, err := HashFromOID(info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), info.MessageImprint.HashedMessage) {
+		return errors.New("cms: time-stamp imprint mismatch")
+	}
+	return nil
+}
+
+// berToDER converts the BER encoded `data` to DER, replacing the indefinite lengths with
+// definite ones and merging the constructed strings. Trailing bytes are dropped.
+func berToDER(data []byte) ([]byte, error) {
+	der, _, err := convertBER(data)
+	return der, err
+}
+
+// convertBER converts the first BER element of `data` and returns the remaining bytes.
+func convertBER(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("cms: truncated BER data")
+	}
+	tagLen := 1
+	if data[0]&0x1f == 0x1f {
+		for tagLen < len(data) && data[tagLen]&0x80 != 0 {
+			tagLen++
+		}
+		tagLen++
+	}
+	if tagLen >= len(data) {
+		return nil, nil, errors.New("cms: truncated BER tag")
+	}
+	tag := data[:tagLen]
+	constructed := data[0]&0x20 != 0
+	rest := data[tagLen:]
+
+	// Read the length.
+	length := -1
+	if rest[0] < 0x80 {
+		length = int(rest[0])
+		rest = rest[1:]
+	} else if rest[0] > 0x80 {
+		n := int(rest[0] & 0x7f)
+		if n > 4 || len(rest) < n+1 {
+			return nil, nil, errors.New("cms: invalid BER length")
+		}
+		length = 0
+		for _, b := range rest[1 : n+1] {
+			length = length<<8 | int(b)
+		}
+		rest = rest[n+1:]
+	} else {
+		rest = rest[1:]
+	}
+	if length > len(rest) {
+		return nil, nil, errors.New("cms: truncated BER data")
+	}
+
+	var content []byte
+	if !constructed {
+		if length < 0 {
+			return nil, nil, errors.New("cms: indefinite length of primitive BER element")
+		}
+		content, rest = rest[:length], rest[length:]
+		return append(append(append([]byte{}, tag...), encodeLength(len(content))...), content...), rest, nil
+	}
+
+	// Convert the elements of constructed values.
+	var elements [][]byte
+	body := rest
+	if length >= 0 {
+		body, rest = rest[:length], rest[length:]
+	}
+	for {
+		if length < 0 {
+			if len(body) >= 2 && body[0] == 0 && body[1] == 0 {
+				rest = body[2:]
+				break
+			}
+		} else if len(body) == 0 {
+			break
+		}
+		elem, remaining, err := convertBER(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		elements = append(elements, elem)
+		body = remaining
+	}
+
+	// Constructed octet strings are merged into primitive ones.
+	if tag[0] == 0x24 {
+		for _, elem := range elements {
+			var s []byte
+			if _, err := asn1.Unmarshal(elem, &s); err != nil {
+				return nil, nil, err
+			}
+			content = append(content, s...)
+		}
+		return append(append([]byte{0x04}, encodeLength(len(content))...), content...), rest, nil
+	}
+	content = bytes.Join(elements, nil)
+	return append(append(append([]byte{}, tag...), encodeLength(len(content))...), content...), rest, nil
+}
+
+// encodeLength returns the DER encoding of the length `n`.
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
@@ -82,6 +82,15 @@ func (a *PdfAction) ToPdfObject() core.PdfObject {
 	return container
 }
 
+// actionToPdfObject returns the PDF object of the action `action` including the entries of
+// its context, the type-dependent subaction.
+func actionToPdfObject(action *PdfAction) core.PdfObject {
+	if ctx := action.GetContext(); ctx != nil {
+		return ctx.ToPdfObject()
+	}
+	return action.ToPdfObject()
+}
+
 // String implements interface PdfObject.
 func (a *PdfAction) String() string {
 	obj, ok := a.ToPdfObject().(*core.PdfIndirectObject)
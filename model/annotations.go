@@ -677,6 +677,26 @@ func NewPdfAnnotationWidget() *PdfAnnotationWidget {
 	return annotationWidget
 }
 
+// NewPdfAnnotationFromObject loads a PDF annotation model from the annotation dictionary `obj`,
+// contained in an indirect object or direct. Used for loading the annotations of data which is
+// not part of a PDF document, e.g. FDF files.
+func NewPdfAnnotationFromObject(obj core.PdfObject) (*PdfAnnotation, error) {
+	container, ok := obj.(*core.PdfIndirectObject)
+	if !ok {
+		d, isDict := obj.(*core.PdfObjectDictionary)
+		if !isDict {
+			return nil, fmt.Errorf("annotation not a dictionary (%T)", obj)
+		}
+		container = core.MakeIndirectObject(d)
+	}
+
+	r := &PdfReader{
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+	}
+	return r.newPdfAnnotationFromIndirectObject(container)
+}
+
 // Used for PDF parsing.  Loads a PDF annotation model from a PDF dictionary.
 // Loads the common PDF annotation dictionary, and anything needed for the annotation subtype.
 func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndirectObject) (*PdfAnnotation, error) {
@@ -1088,6 +1108,7 @@ func (r *PdfReader) newPdfAnnotationLinkFromDict(d *core.PdfObjectDictionary) (*
 	annot.PA = d.Get("PA")
 	annot.QuadPoints = d.Get("QuadPoints")
 	annot.BS = d.Get("BS")
+	annot.reader = r
 
 	return &annot, nil
 }
@@ -1099,8 +1120,11 @@ func (r *PdfReader) loadAction(obj core.PdfObject) (*PdfAction, error) {
 			return nil, err
 		}
 		return actionObj, nil
+	} else if dict, isDict := core.GetDict(obj); isDict {
+		// Actions are often direct objects. Wrap them into an indirect object.
+		return r.newPdfActionFromIndirectObject(core.MakeIndirectObject(dict))
 	} else if !core.IsNullObject(obj) {
-		return nil, errors.New("action should point to an indirect object")
+		return nil, errors.New("action should be a dictionary")
 	}
 	return nil, nil
 }
@@ -26,6 +26,18 @@ type PdfAppender struct {
 	Reader   *PdfReader
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
+	dss      *DSS
+	outlines *PdfOutlineTreeNode
+
+	// Document-level scripts and open action of the new revision, and whether the scripts of
+	// the catalog are removed.
+	javaScripts   []documentScript
+	openAction    *PdfAction
+	removeScripts bool
+
+	// Certification signature and FieldMDP signature references of the new revision.
+	certification *PdfSignature
+	fieldMDPRefs  []*core.PdfObjectDictionary
 
 	xrefs          core.XrefTable
 	xrefOffset     int64
@@ -45,6 +57,18 @@ type PdfAppender struct {
 
 	prevRevisionSize int64
 	written          bool
+
+	// Signatures applied in the revisions following the new revision.
+	queue []*queuedSignature
+}
+
+// queuedSignature is a signature queued by the appender, applied in its own revision either to
+// a new signature field or to an existing one.
+type queuedSignature struct {
+	pageNum   int
+	field     *PdfFieldSignature
+	name      string
+	signature *PdfSignature
 }
 
 func getPageResources(p *PdfPage) map[core.PdfObjectName]core.PdfObject {
@@ -466,6 +490,10 @@ func (a *PdfAppender) ReplacePage(pageNum int, page *PdfPage) {
 // Sign signs a specific page with a digital signature.
 // The signature field parameter must have a valid signature dictionary
 // specified by its V field.
+// A certification signature (see PdfSignature.SetCertification) can only be applied to a
+// document which is not signed. The fields locked by the signature field (see
+// PdfFieldSignature.SetLock) are made read-only, and the signature is checked against the
+// seed values of the field.
 func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	if field == nil {
 		return errors.New("signature field cannot be nil")
@@ -475,6 +503,9 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	if signature == nil {
 		return errors.New("signature dictionary cannot be nil")
 	}
+	if err := a.checkSignaturePermissions(field); err != nil {
+		return err
+	}
 
 	// Get a copy of the selected page.
 	pageIndex := pageNum - 1
@@ -500,6 +531,13 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	}
 	acroForm.SigFlags = core.MakeInteger(3)
 
+	if field.Lock != nil {
+		a.lockFields(acroForm, field)
+	}
+	if signature.GetCertification() != DocMDPPermissionNone {
+		a.certification = signature
+	}
+
 	fields := append(acroForm.AllFields(), field.PdfField)
 	acroForm.Fields = &fields
 	a.ReplaceAcroForm(acroForm)
@@ -511,6 +549,167 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	return nil
 }
 
+// SignField signs the existing unsigned signature field with the fully qualified name `name`
+// with `signature`. The field keeps its widget annotations and their appearance. As with Sign,
+// the fields locked by the field are made read-only and the signature is checked against the
+// seed values of the field.
+func (a *PdfAppender) SignField(name string, signature *PdfSignature) error {
+	if signature == nil {
+		return errors.New("signature dictionary cannot be nil")
+	}
+	if a.Reader.AcroForm == nil {
+		return errors.New("document has no form")
+	}
+
+	var field *PdfFieldSignature
+	for _, sigField := range a.Reader.AcroForm.signatureFields() {
+		if !sigField.IsTerminal() {
+			continue
+		}
+		if fullName, err := sigField.FullName(); err == nil && fullName == name {
+			field = sigField
+			break
+		}
+	}
+	if field == nil {
+		return fmt.Errorf("signature field %s not found", name)
+	}
+	if field.V != nil {
+		return fmt.Errorf("signature field %s already signed", name)
+	}
+
+	field.V = signature
+	if err := a.checkSignaturePermissions(field); err != nil {
+		field.V = nil
+		return err
+	}
+
+	if a.acroForm == nil || a.acroForm == a.roReader.AcroForm {
+		a.acroForm = a.Reader.AcroForm
+	}
+	acroForm := a.acroForm
+	acroForm.SigFlags = core.MakeInteger(3)
+	if field.Lock != nil {
+		a.lockFields(acroForm, field)
+	}
+	if signature.GetCertification() != DocMDPPermissionNone {
+		a.certification = signature
+	}
+	a.ReplaceAcroForm(acroForm)
+
+	// The field is updated even if not part of a form replaced previously.
+	a.updateObjectsDeep(field.ToPdfObject(), nil)
+	return nil
+}
+
+// QueueSignature queues the signature of the new signature field `field` on page `pageNum`,
+// as for Sign. The signature is applied when the appender is written, in its own incremental
+// revision following the revision of the appender. Queued signatures, including document
+// timestamps, are applied in order, each one covering the previous revisions.
+func (a *PdfAppender) QueueSignature(pageNum int, field *PdfFieldSignature) error {
+	if field == nil {
+		return errors.New("signature field cannot be nil")
+	}
+	if field.V == nil {
+		return errors.New("signature dictionary cannot be nil")
+	}
+	a.queue = append(a.queue, &queuedSignature{pageNum: pageNum, field: field})
+	return nil
+}
+
+// QueueFieldSignature queues the signature of the existing unsigned signature field with the
+// fully qualified name `name`, as for SignField. The signature is applied when the appender is
+// written, in its own incremental revision (see QueueSignature).
+func (a *PdfAppender) QueueFieldSignature(name string, signature *PdfSignature) error {
+	if signature == nil {
+		return errors.New("signature dictionary cannot be nil")
+	}
+	a.queue = append(a.queue, &queuedSignature{name: name, signature: signature})
+	return nil
+}
+
+// apply applies the queued signature to the document `data` and returns the document with the
+// new revision.
+func (q *queuedSignature) apply(data []byte) ([]byte, error) {
+	reader, err := NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	appender, err := NewPdfAppender(reader)
+	if err != nil {
+		return nil, err
+	}
+	if q.field != nil {
+		err = appender.Sign(q.pageNum, q.field)
+	} else {
+		err = appender.SignField(q.name, q.signature)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := appender.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkSignaturePermissions returns an error if the signature of `field` cannot be applied to
+// the document, according to the certification of the document and to the seed values of
+// the field.
+func (a *PdfAppender) checkSignaturePermissions(field *PdfFieldSignature) error {
+	signature := field.V
+	if a.Reader.docMDPPermission() == DocMDPPermissionNoChanges {
+		return errors.New("document certified without permission of changes")
+	}
+	if signature.GetCertification() != DocMDPPermissionNone {
+		if a.Reader.docMDPPermission() != DocMDPPermissionNone || a.certification != nil {
+			return errors.New("document already certified")
+		}
+		if a.Reader.AcroForm != nil {
+			for _, sigField := range a.Reader.AcroForm.signatureFields() {
+				if sigField.V != nil {
+					return errors.New("certification signature of a signed document")
+				}
+			}
+		}
+	}
+	if sv := field.GetSeedValue(); sv != nil {
+		if err := sv.check(signature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockFields adds the FieldMDP transform of the lock of the signature field `field` to its
+// signature, and makes the locked fields of `acroForm` read-only.
+func (a *PdfAppender) lockFields(acroForm *PdfAcroForm, field *PdfFieldSignature) {
+	dict, ok := core.GetDict(field.Lock)
+	if !ok {
+		return
+	}
+	lock := newFieldLock(dict)
+	if lock == nil {
+		return
+	}
+	a.fieldMDPRefs = append(a.fieldMDPRefs, field.V.addFieldMDP(lock))
+
+	for _, f := range acroForm.AllFields() {
+		if !f.IsTerminal() {
+			continue
+		}
+		name, err := f.FullName()
+		if err != nil || !lock.locks(name) {
+			continue
+		}
+		if flags := f.Flags(); !flags.Has(FieldFlagReadOnly) {
+			f.SetFlag(flags.Set(FieldFlagReadOnly))
+		}
+	}
+}
+
 // ReplaceAcroForm replaces the acrobat form. It appends a new form to the Pdf which
 // replaces the original AcroForm.
 func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
@@ -520,12 +719,51 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
-// Write writes the Appender output to io.Writer.
+// SetDSS sets the document security store of the document, written in the new revision.
+// The store returned by PdfReader.GetDSS on the reader of the appender can be completed with
+// the validation material of the signatures. A document timestamp signed in the same revision
+// covers the validation material (PAdES B-LTA level).
+func (a *PdfAppender) SetDSS(dss *DSS) {
+	a.dss = dss
+}
+
+// SetOutlineTree sets the outline tree of the document, written in the new revision. The
+// outline returned by PdfReader.GetOutlines on the reader of the appender can be edited and
+// written back with Outline.ToOutlineTree.
+func (a *PdfAppender) SetOutlineTree(outlineTree *PdfOutlineTreeNode) {
+	a.outlines = outlineTree
+}
+
+// Write writes the Appender output to io.Writer, followed by the revisions of the queued
+// signatures, if any.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
 	if a.written {
 		return errors.New("appender write can only be invoked once")
 	}
+	if len(a.queue) == 0 {
+		return a.write(w)
+	}
+
+	var buf bytes.Buffer
+	if err := a.write(&buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	for i, q := range a.queue {
+		var err error
+		if data, err = q.apply(data); err != nil {
+			return fmt.Errorf("queued signature %d: %v", i+1, err)
+		}
+	}
+	a.written = true
+
+	_, err := w.Write(data)
+	return err
+}
+
+// write writes the new revision of the appender to `w`.
+func (a *PdfAppender) write(w io.Writer) error {
 
 	writer := NewPdfWriter()
 
@@ -560,6 +798,10 @@ func (a *PdfAppender) Write(w io.Writer) error {
 
 	// Add the keys which are not set.
 	for _, key := range catalog.Keys() {
+		if key == "NeedsRendering" && a.acroForm != nil && !a.acroForm.HasXFA() {
+			// Only relevant for XFA forms.
+			continue
+		}
 		if writer.catalog.Get(key) == nil {
 			obj := catalog.Get(key)
 			writer.catalog.Set(key, obj)
@@ -569,6 +811,42 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		writer.catalog.Set("AcroForm", a.acroForm.ToPdfObject())
 		a.updateObjectsDeep(a.acroForm.ToPdfObject(), nil)
 	}
+	if a.outlines != nil {
+		outlines := a.outlines.ToPdfObject()
+		writer.catalog.Set("Outlines", outlines)
+		a.updateObjectsDeep(outlines, nil)
+	}
+	if a.dss != nil {
+		writer.catalog.Set("DSS", a.dss.ToPdfObject())
+		a.updateObjectsDeep(a.dss.ToPdfObject(), nil)
+	}
+	if a.removeScripts {
+		removeCatalogScripts(writer.catalog)
+	}
+	if len(a.javaScripts) > 0 {
+		names := makeJavaScriptNames(writer.catalog.Get("Names"), a.javaScripts, !a.removeScripts)
+		writer.catalog.Set("Names", names)
+		a.updateObjectsDeep(names, nil)
+	}
+	if a.openAction != nil {
+		openAction := actionToPdfObject(a.openAction)
+		writer.catalog.Set("OpenAction", openAction)
+		a.updateObjectsDeep(openAction, nil)
+	}
+	if a.certification != nil {
+		perms := core.MakeDict()
+		if oldPerms, ok := core.GetDict(catalog.Get("Perms")); ok {
+			for _, key := range oldPerms.Keys() {
+				perms.Set(key, oldPerms.Get(key))
+			}
+		}
+		perms.Set("DocMDP", a.certification.ToPdfObject())
+		writer.catalog.Set("Perms", perms)
+	}
+	for _, ref := range a.fieldMDPRefs {
+		// The FieldMDP transforms apply to the catalog of the document.
+		ref.Set("Data", writer.root)
+	}
 
 	a.addNewObject(writer.infoObj)
 	a.addNewObject(writer.root)
@@ -0,0 +1,333 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// DestinationMode represents the magnification mode of an explicit destination.
+// See section 12.3.2.2 "Explicit Destinations" (page 374).
+type DestinationMode string
+
+// Destination magnification modes (Table 151 - p. 374).
+const (
+	DestinationModeXYZ   DestinationMode = "XYZ"   // [page /XYZ left top zoom]
+	DestinationModeFit   DestinationMode = "Fit"   // [page /Fit]
+	DestinationModeFitH  DestinationMode = "FitH"  // [page /FitH top]
+	DestinationModeFitV  DestinationMode = "FitV"  // [page /FitV left]
+	DestinationModeFitR  DestinationMode = "FitR"  // [page /FitR left bottom right top]
+	DestinationModeFitB  DestinationMode = "FitB"  // [page /FitB]
+	DestinationModeFitBH DestinationMode = "FitBH" // [page /FitBH top]
+	DestinationModeFitBV DestinationMode = "FitBV" // [page /FitBV left]
+)
+
+// Destination represents a PDF destination (section 12.3.2 p. 373).
+// A destination is either explicit, pointing to a page with a magnification
+// mode, or named, in which case it is resolved through the names tree or the
+// Dests dictionary of the document using PdfReader.ResolveDestination.
+type Destination struct {
+	// Name is the name of named destinations. Empty for explicit destinations.
+	Name string
+
+	// PageObj is the page object of the destination. It is nil for named
+	// destinations and for destinations to pages of other documents.
+	PageObj *core.PdfIndirectObject
+
+	// Page is the zero-based index of the destination page. It is -1 if the
+	// page is not known.
+	Page int64
+
+	// Mode is the magnification mode of explicit destinations.
+	Mode DestinationMode
+
+	// Left, Bottom, Right, Top and Zoom are the parameters of the magnification
+	// mode. Parameters not used by the mode are ignored and null parameters are
+	// read as 0.
+	Left   float64
+	Bottom float64
+	Right  float64
+	Top    float64
+	Zoom   float64
+
+	// isName specifies whether the name of the destination is a PDF name, as
+	// used by the Dests dictionary, instead of a string.
+	isName bool
+}
+
+// NewDestination returns a new explicit destination to the page at the
+// zero-based index `page`, with the magnification mode `mode`.
+func NewDestination(page int64, mode DestinationMode) *Destination {
+	return &Destination{
+		Page: page,
+		Mode: mode,
+	}
+}
+
+// NewNamedDestination returns a new destination to the named destination `name`.
+func NewNamedDestination(name string) *Destination {
+	return &Destination{
+		Name: name,
+		Page: -1,
+	}
+}
+
+// IsNamed returns true if the destination is a named destination.
+func (d *Destination) IsNamed() bool {
+	return d.Name != ""
+}
+
+// String returns a string representation of the destination.
+func (d *Destination) String() string {
+	if d.IsNamed() {
+		return fmt.Sprintf("named destination %q", d.Name)
+	}
+	return fmt.Sprintf("page %d /%s", d.Page, d.Mode)
+}
+
+// ToPdfObject returns a PDF object representation of the destination.
+func (d *Destination) ToPdfObject() core.PdfObject {
+	if d.IsNamed() {
+		if d.isName {
+			return core.MakeName(d.Name)
+		}
+		return core.MakeString(d.Name)
+	}
+	if d.PageObj == nil && d.Page < 0 {
+		return core.MakeNull()
+	}
+
+	dest := core.MakeArray()
+	if d.PageObj != nil {
+		dest.Append(d.PageObj)
+	} else {
+		// Page of another document.
+		dest.Append(core.MakeInteger(d.Page))
+	}
+
+	mode := d.Mode
+	switch mode {
+	case DestinationModeXYZ, DestinationModeFitH, DestinationModeFitV, DestinationModeFitR,
+		DestinationModeFitB, DestinationModeFitBH, DestinationModeFitBV:
+	default:
+		mode = DestinationModeFit
+	}
+	dest.Append(core.MakeName(string(mode)))
+
+	switch mode {
+	case DestinationModeFit, DestinationModeFitB:
+	case DestinationModeFitH, DestinationModeFitBH:
+		dest.Append(core.MakeFloat(d.Top))
+	case DestinationModeFitV, DestinationModeFitBV:
+		dest.Append(core.MakeFloat(d.Left))
+	case DestinationModeFitR:
+		dest.Append(core.MakeFloat(d.Left), core.MakeFloat(d.Bottom),
+			core.MakeFloat(d.Right), core.MakeFloat(d.Top))
+	case DestinationModeXYZ:
+		dest.Append(core.MakeFloat(d.Left), core.MakeFloat(d.Top), core.MakeFloat(d.Zoom))
+	}
+	return dest
+}
+
+// newDestinationFromPdfObject creates a destination from the PDF object `obj`,
+// which is either a name, a string, an explicit destination array or a
+// dictionary with a D entry. The page of explicit destinations is identified
+// using the reader `r`, which may be nil.
+func newDestinationFromPdfObject(obj core.PdfObject, r *PdfReader) (*Destination, error) {
+	obj = core.TraceToDirectObject(obj)
+	switch t := obj.(type) {
+	case *core.PdfObjectName:
+		dest := NewNamedDestination(t.String())
+		dest.isName = true
+		return dest, nil
+	case *core.PdfObjectString:
+		return NewNamedDestination(t.Str()), nil
+	case *core.PdfObjectDictionary:
+		// Values of the Dests dictionary and of the names tree may be
+		// dictionaries containing the destination in their D entry.
+		if t.Get("D") == nil {
+			return nil, errors.New("destination dictionary missing D entry")
+		}
+		return newDestinationFromPdfObject(t.Get("D"), r)
+	case *core.PdfObjectArray:
+	default:
+		return nil, fmt.Errorf("invalid destination type: %T", obj)
+	}
+
+	destArr := obj.(*core.PdfObjectArray)
+	if destArr.Len() < 2 {
+		return nil, fmt.Errorf("invalid destination array length: %d", destArr.Len())
+	}
+
+	dest := &Destination{Page: -1, Mode: DestinationModeFit}
+	pageObj := destArr.Get(0)
+	if pageInd, ok := core.GetIndirect(pageObj); ok {
+		dest.PageObj = pageInd
+		if r != nil {
+			if _, pageNum, err := r.PageFromIndirectObject(pageInd); err == nil {
+				dest.Page = int64(pageNum - 1)
+			}
+		}
+	} else if pageIdx, ok := core.GetIntVal(pageObj); ok {
+		dest.Page = int64(pageIdx)
+	} else {
+		return nil, fmt.Errorf("invalid destination page: %T", pageObj)
+	}
+
+	mode, ok := core.GetNameVal(destArr.Get(1))
+	if !ok {
+		common.Log.Debug("invalid destination magnification mode: %v", destArr.Get(1))
+		return dest, nil
+	}
+
+	param := func(i int) float64 {
+		if i >= destArr.Len() {
+			return 0
+		}
+		val, _ := core.GetNumberAsFloat(core.TraceToDirectObject(destArr.Get(i)))
+		return val
+	}
+
+	dest.Mode = DestinationMode(mode)
+	switch dest.Mode {
+	case DestinationModeFit, DestinationModeFitB:
+	case DestinationModeFitH, DestinationModeFitBH:
+		dest.Top = param(2)
+	case DestinationModeFitV, DestinationModeFitBV:
+		dest.Left = param(2)
+	case DestinationModeFitR:
+		dest.Left, dest.Bottom, dest.Right, dest.Top = param(2), param(3), param(4), param(5)
+	case DestinationModeXYZ:
+		dest.Left, dest.Top, dest.Zoom = param(2), param(3), param(4)
+	default:
+		common.Log.Debug("unsupported destination magnification mode: %s", mode)
+		dest.Mode = DestinationModeFit
+	}
+	return dest, nil
+}
+
+// ResolveDestination returns the explicit destination `dest` points to.
+// Named destinations are looked up in the Dests names tree and in the Dests
+// dictionary of the document catalog. An error is returned if the named
+// destination is not defined or if the destination page is not part of the
+// document, which allows detecting broken internal links.
+func (r *PdfReader) ResolveDestination(dest *Destination) (*Destination, error) {
+	if dest == nil {
+		return nil, errors.New("destination cannot be nil")
+	}
+
+	resolved := *dest
+	if dest.IsNamed() {
+		obj := r.lookupNamedDestination(dest.Name)
+		if obj == nil {
+			return nil, fmt.Errorf("named destination %q not found", dest.Name)
+		}
+		explicit, err := newDestinationFromPdfObject(obj, r)
+		if err != nil {
+			return nil, fmt.Errorf("named destination %q: %v", dest.Name, err)
+		}
+		if explicit.IsNamed() {
+			return nil, fmt.Errorf("named destination %q points to another name", dest.Name)
+		}
+		resolved = *explicit
+		resolved.Name = dest.Name
+		resolved.isName = dest.isName
+	}
+
+	if resolved.PageObj == nil {
+		// Page indices are only valid for destinations in other documents,
+		// but are accepted for the pages of the current document.
+		if resolved.Page < 0 || resolved.Page >= int64(len(r.PageList)) {
+			return nil, fmt.Errorf("destination page %d not found", resolved.Page)
+		}
+		resolved.PageObj = r.PageList[resolved.Page].GetPageAsIndirectObject()
+		return &resolved, nil
+	}
+
+	_, pageNum, err := r.PageFromIndirectObject(resolved.PageObj)
+	if err != nil {
+		return nil, fmt.Errorf("destination page object %d not found", resolved.PageObj.ObjectNumber)
+	}
+	resolved.Page = int64(pageNum - 1)
+	return &resolved, nil
+}
+
+// lookupNamedDestination returns the value of the named destination `name`
+// from the Dests names tree or the Dests dictionary of the catalog. Returns
+// nil if the destination is not defined.
+func (r *PdfReader) lookupNamedDestination(name string) core.PdfObject {
+	if names, ok := core.GetDict(r.catalog.Get("Names")); ok {
+		if tree, ok := core.GetDict(names.Get("Dests")); ok {
+			if obj := lookupNameTree(tree, name, map[*core.PdfObjectDictionary]bool{}); obj != nil {
+				return obj
+			}
+		}
+	}
+	if dests, ok := core.GetDict(r.catalog.Get("Dests")); ok {
+		if obj := dests.Get(core.PdfObjectName(name)); obj != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// lookupNameTree returns the value of the key `key` in the name tree node
+// `node` and its descendants (section 7.9.6 p. 88). Returns nil if the key
+// is not found.
+func lookupNameTree(node *core.PdfObjectDictionary, key string, visited map[*core.PdfObjectDictionary]bool) core.PdfObject {
+	if visited[node] {
+		return nil
+	}
+	visited[node] = true
+
+	if names, ok := core.GetArray(node.Get("Names")); ok {
+		for i := 0; i+1 < names.Len(); i += 2 {
+			if k, ok := core.GetString(names.Get(i)); ok && k.Str() == key {
+				return names.Get(i + 1)
+			}
+		}
+	}
+
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		return nil
+	}
+	for _, kid := range kids.Elements() {
+		kidDict, ok := core.GetDict(kid)
+		if !ok {
+			continue
+		}
+		if limits, ok := core.GetArray(kidDict.Get("Limits")); ok && limits.Len() == 2 {
+			first, ok1 := core.GetString(limits.Get(0))
+			last, ok2 := core.GetString(limits.Get(1))
+			if ok1 && ok2 && (key < first.Str() || key > last.Str()) {
+				continue
+			}
+		}
+		if obj := lookupNameTree(kidDict, key, visited); obj != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// GetDestination returns the destination of the go to action.
+// Named destinations can be resolved using PdfReader.ResolveDestination.
+func (gotoAct *PdfActionGoTo) GetDestination() (*Destination, error) {
+	if gotoAct.D == nil {
+		return nil, errors.New("go to action missing destination")
+	}
+	return newDestinationFromPdfObject(gotoAct.D, nil)
+}
+
+// SetDestination sets the destination of the go to action.
+func (gotoAct *PdfActionGoTo) SetDestination(dest *Destination) {
+	gotoAct.D = dest.ToPdfObject()
+}
@@ -0,0 +1,347 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+)
+
+// VRI represents the validation related information of a signature, stored in the document
+// security store (ETSI EN 319 142-1, 5.4.2.3).
+type VRI struct {
+	// Cert contains the certificates used to validate the signature.
+	Cert []*core.PdfObjectStream
+
+	// OCSP contains the OCSP responses used to validate the signature.
+	OCSP []*core.PdfObjectStream
+
+	// CRL contains the certificate revocation lists used to validate the signature.
+	CRL []*core.PdfObjectStream
+
+	// TU is the date at which the validation information has been created.
+	TU *core.PdfObjectString
+
+	// TS is the time-stamp token of the validation information.
+	TS *core.PdfObjectStream
+}
+
+// DSS represents the document security store of a PDF document, which contains the validation
+// material of its signatures for their long term validation (PAdES B-LT level).
+// (ETSI EN 319 142-1, 5.4.2.2).
+type DSS struct {
+	container *core.PdfIndirectObject
+
+	// Certs contains all the certificates of the document security store.
+	Certs []*core.PdfObjectStream
+
+	// OCSPs contains all the OCSP responses of the document security store.
+	OCSPs []*core.PdfObjectStream
+
+	// CRLs contains all the certificate revocation lists of the document security store.
+	CRLs []*core.PdfObjectStream
+
+	// VRI maps the validation related information of the signatures by their key, which is the
+	// upper case hexadecimal SHA-1 digest of their contents (see SignatureVRIKey).
+	VRI map[string]*VRI
+
+	// Streams of the document security store, mapped by their decoded data.
+	streams map[string]*core.PdfObjectStream
+}
+
+// NewDSS returns a new empty document security store.
+func NewDSS() *DSS {
+	return &DSS{
+		container: core.MakeIndirectObject(core.MakeDict()),
+		VRI:       map[string]*VRI{},
+		streams:   map[string]*core.PdfObjectStream{},
+	}
+}
+
+// GetDSS returns the document security store of the document, or nil if the document has none.
+// Validation material can be added to the returned store, which can then be written with
+// PdfAppender.SetDSS.
+func (r *PdfReader) GetDSS() (*DSS, error) {
+	obj := core.ResolveReference(r.catalog.Get("DSS"))
+	if obj == nil {
+		return nil, nil
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Invalid DSS object type: %T", obj)
+		return nil, ErrTypeCheck
+	}
+
+	dss := NewDSS()
+	if ind, ok := obj.(*core.PdfIndirectObject); ok {
+		dss.container = ind
+	} else {
+		dss.container = core.MakeIndirectObject(dict)
+	}
+
+	var err error
+	if dss.Certs, err = dss.loadStreams(dict.Get("Certs")); err != nil {
+		return nil, err
+	}
+	if dss.OCSPs, err = dss.loadStreams(dict.Get("OCSPs")); err != nil {
+		return nil, err
+	}
+	if dss.CRLs, err = dss.loadStreams(dict.Get("CRLs")); err != nil {
+		return nil, err
+	}
+
+	if vriDict, ok := core.GetDict(dict.Get("VRI")); ok {
+		for _, key := range vriDict.Keys() {
+			d, ok := core.GetDict(vriDict.Get(key))
+			if !ok {
+				common.Log.Debug("ERROR: Invalid VRI entry %s", key)
+				return nil, ErrTypeCheck
+			}
+			vri := &VRI{}
+			if vri.Cert, err = dss.loadStreams(d.Get("Cert")); err != nil {
+				return nil, err
+			}
+			if vri.OCSP, err = dss.loadStreams(d.Get("OCSP")); err != nil {
+				return nil, err
+			}
+			if vri.CRL, err = dss.loadStreams(d.Get("CRL")); err != nil {
+				return nil, err
+			}
+			vri.TU, _ = core.GetString(d.Get("TU"))
+			vri.TS, _ = core.GetStream(d.Get("TS"))
+			dss.VRI[strings.ToUpper(string(key))] = vri
+		}
+	}
+	return dss, nil
+}
+
+// loadStreams loads the array of streams `obj`.
+func (d *DSS) loadStreams(obj core.PdfObject) ([]*core.PdfObjectStream, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Invalid DSS stream array type: %T", obj)
+		return nil, ErrTypeCheck
+	}
+
+	var streams []*core.PdfObjectStream
+	for _, obj := range arr.Elements() {
+		stream, ok := core.GetStream(obj)
+		if !ok {
+			common.Log.Debug("ERROR: Invalid DSS stream type: %T", obj)
+			return nil, ErrTypeCheck
+		}
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := d.streams[string(data)]; !ok {
+			d.streams[string(data)] = stream
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// addStreams adds the streams of `data`, which are not already in the document security store,
+// to `streams`. The streams of `data` are returned.
+func (d *DSS) addStreams(streams *[]*core.PdfObjectStream, data [][]byte) ([]*core.PdfObjectStream, error) {
+	var added []*core.PdfObjectStream
+	for _, b := range data {
+		stream, ok := d.streams[string(b)]
+		if !ok {
+			var err error
+			stream, err = core.MakeStream(b, core.NewFlateEncoder())
+			if err != nil {
+				return nil, err
+			}
+			d.streams[string(b)] = stream
+		}
+		if !containsStream(*streams, stream) {
+			*streams = append(*streams, stream)
+		}
+		if !containsStream(added, stream) {
+			added = append(added, stream)
+		}
+	}
+	return added, nil
+}
+
+// containsStream returns true if `stream` is in `streams`.
+func containsStream(streams []*core.PdfObjectStream, stream *core.PdfObjectStream) bool {
+	for _, s := range streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCerts adds the DER encoded certificates `certs` to the document security store and returns
+// their streams.
+func (d *DSS) AddCerts(certs [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.Certs, certs)
+}
+
+// AddOCSPs adds the DER encoded OCSP responses `ocsps` to the document security store and
+// returns their streams.
+func (d *DSS) AddOCSPs(ocsps [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.OCSPs, ocsps)
+}
+
+// AddCRLs adds the DER encoded certificate revocation lists `crls` to the document security
+// store and returns their streams.
+func (d *DSS) AddCRLs(crls [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.CRLs, crls)
+}
+
+// AddValidationData adds the validation material of the signature `sig` to the document
+// security store, and records it in the validation related information of the signature.
+// The certificates `certs` are those of the chain of the signer certificate. If no certificates
+// are specified, the certificates embedded in the signature are used. The `ocsps` and `crls`
+// parameters are the DER encoded OCSP responses and certificate revocation lists, fetched by
+// the caller.
+func (d *DSS) AddValidationData(sig *PdfSignature, certs []*x509.Certificate, ocsps, crls [][]byte) error {
+	key, err := SignatureVRIKey(sig)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		signedData, err := cms.Parse(sig.Contents.Bytes())
+		if err != nil {
+			return err
+		}
+		certs = signedData.Certificates()
+	}
+	var certData [][]byte
+	for _, cert := range certs {
+		certData = append(certData, cert.Raw)
+	}
+
+	vri := d.VRI[key]
+	if vri == nil {
+		vri = &VRI{}
+		d.VRI[key] = vri
+	}
+	if vri.Cert, err = d.appendStreams(vri.Cert, d.AddCerts, certData); err != nil {
+		return err
+	}
+	if vri.OCSP, err = d.appendStreams(vri.OCSP, d.AddOCSPs, ocsps); err != nil {
+		return err
+	}
+	if vri.CRL, err = d.appendStreams(vri.CRL, d.AddCRLs, crls); err != nil {
+		return err
+	}
+
+	date, err := NewPdfDateFromTime(time.Now())
+	if err != nil {
+		return err
+	}
+	vri.TU = date.ToPdfObject().(*core.PdfObjectString)
+	return nil
+}
+
+// appendStreams adds `data` to the document security store with `add` and appends the new
+// streams to `streams`.
+func (d *DSS) appendStreams(streams []*core.PdfObjectStream,
+	add func([][]byte) ([]*core.PdfObjectStream, error), data [][]byte) ([]*core.PdfObjectStream, error) {
+	added, err := add(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, stream := range added {
+		if !containsStream(streams, stream) {
+			streams = append(streams, stream)
+		}
+	}
+	return streams, nil
+}
+
+// SignatureVRIKey returns the key of the validation related information of the signature `sig`
+// in the document security store, which is the upper case hexadecimal SHA-1 digest of its
+// contents. The padding of document timestamp tokens is not part of the digest.
+func SignatureVRIKey(sig *PdfSignature) (string, error) {
+	if sig == nil || sig.Contents == nil {
+		return "", errors.New("signature contents not set")
+	}
+	data := sig.Contents.Bytes()
+	if sig.SubFilter != nil && *sig.SubFilter == "ETSI.RFC3161" {
+		var token asn1.RawValue
+		if _, err := asn1.Unmarshal(data, &token); err != nil {
+			return "", err
+		}
+		data = token.FullBytes
+	}
+	sum := sha1.Sum(data)
+	return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (d *DSS) GetContainingPdfObject() core.PdfObject {
+	return d.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (d *DSS) ToPdfObject() core.PdfObject {
+	dict := d.container.PdfObject.(*core.PdfObjectDictionary)
+	dict.Set("Type", core.MakeName("DSS"))
+	setStreams(dict, "Certs", d.Certs)
+	setStreams(dict, "OCSPs", d.OCSPs)
+	setStreams(dict, "CRLs", d.CRLs)
+
+	if len(d.VRI) == 0 {
+		dict.Remove("VRI")
+		return d.container
+	}
+	keys := make([]string, 0, len(d.VRI))
+	for key := range d.VRI {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vriDict := core.MakeDict()
+	for _, key := range keys {
+		vri := d.VRI[key]
+		entry := core.MakeDict()
+		entry.Set("Type", core.MakeName("VRI"))
+		setStreams(entry, "Cert", vri.Cert)
+		setStreams(entry, "OCSP", vri.OCSP)
+		setStreams(entry, "CRL", vri.CRL)
+		entry.SetIfNotNil("TU", vri.TU)
+		if vri.TS != nil {
+			entry.Set("TS", vri.TS)
+		}
+		vriDict.Set(core.PdfObjectName(key), entry)
+	}
+	dict.Set("VRI", vriDict)
+	return d.container
+}
+
+// setStreams sets the `key` entry of `dict` to the array of `streams`, or removes it if there
+// are no streams.
+func setStreams(dict *core.PdfObjectDictionary, key core.PdfObjectName, streams []*core.PdfObjectStream) {
+	if len(streams) == 0 {
+		dict.Remove(key)
+		return
+	}
+	arr := core.MakeArray()
+	for _, stream := range streams {
+		arr.Append(stream)
+	}
+	dict.Set(key, arr)
+}
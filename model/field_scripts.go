@@ -0,0 +1,746 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// The JavaScript of the field actions is not executed. The calls to the standard Acrobat form
+// functions (AF*) used for formatting and calculating field values are interpreted instead.
+// See "Acrobat Forms JavaScript Object Specification" for the definitions of the functions.
+
+// afCall is a call to an Acrobat form function found in the JavaScript of a field action.
+// The arguments are float64, string, bool or []string (arrays) values.
+type afCall struct {
+	name string
+	args []interface{}
+}
+
+// afCallRegexp matches the beginning of the calls to the Acrobat form functions.
+var afCallRegexp = regexp.MustCompile(`\b(AF[A-Za-z]+_[A-Za-z]+)\s*\(`)
+
+// parseAFCall returns the first call to an Acrobat form function in the JavaScript `js`.
+// Returns nil if not found or if the arguments cannot be parsed.
+func parseAFCall(js string) *afCall {
+	loc := afCallRegexp.FindStringSubmatchIndex(js)
+	if loc == nil {
+		return nil
+	}
+	call := &afCall{name: js[loc[2]:loc[3]]}
+	p := &afArgParser{s: js, pos: loc[1]}
+	args, ok := p.parseList(')')
+	if !ok {
+		common.Log.Debug("ERROR: invalid arguments of %s: %q", call.name, js)
+		return nil
+	}
+	call.args = args
+	return call
+}
+
+// afArgParser parses the literal arguments of Acrobat form function calls.
+type afArgParser struct {
+	s   string
+	pos int
+}
+
+// parseList parses comma separated values up to the `end` delimiter.
+func (p *afArgParser) parseList(end byte) ([]interface{}, bool) {
+	var values []interface{}
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return nil, false
+		}
+		if p.s[p.pos] == end {
+			p.pos++
+			return values, true
+		}
+		if len(values) > 0 {
+			if p.s[p.pos] != ',' {
+				return nil, false
+			}
+			p.pos++
+			p.skipSpaces()
+		}
+		val, ok := p.parseValue()
+		if !ok {
+			return nil, false
+		}
+		values = append(values, val)
+	}
+}
+
+// parseValue parses a number, string, boolean or array literal.
+func (p *afArgParser) parseValue() (interface{}, bool) {
+	if p.pos >= len(p.s) {
+		return nil, false
+	}
+	rest := p.s[p.pos:]
+	switch c := rest[0]; {
+	case c == '"' || c == '\'':
+		return p.parseString(c)
+	case c == '[':
+		p.pos++
+		return p.parseArray(']')
+	case strings.HasPrefix(rest, "new Array("):
+		p.pos += len("new Array(")
+		return p.parseArray(')')
+	case strings.HasPrefix(rest, "true"):
+		p.pos += 4
+		return true, true
+	case strings.HasPrefix(rest, "false"):
+		p.pos += 5
+		return false, true
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	val, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return nil, false
+	}
+	return val, true
+}
+
+// parseString parses a string literal delimited by `quote`.
+func (p *afArgParser) parseString(quote byte) (interface{}, bool) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), true
+		case c == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			switch c = p.s[p.pos]; c {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 < len(p.s) {
+					if r, err := strconv.ParseUint(p.s[p.pos+1:p.pos+5], 16, 16); err == nil {
+						b.WriteRune(rune(r))
+						p.pos += 4
+						continue
+					}
+				}
+				b.WriteByte(c)
+			default:
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, false
+}
+
+// parseArray parses the string elements of an array up to the `end` delimiter.
+func (p *afArgParser) parseArray(end byte) (interface{}, bool) {
+	values, ok := p.parseList(end)
+	if !ok {
+		return nil, false
+	}
+	var strs []string
+	for _, val := range values {
+		str, ok := val.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, str)
+	}
+	return strs, true
+}
+
+func (p *afArgParser) skipSpaces() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// intArg returns the integer argument at index `i`, or `def` if not specified.
+func (c *afCall) intArg(i, def int) int {
+	if i < len(c.args) {
+		switch t := c.args[i].(type) {
+		case float64:
+			return int(t)
+		case string:
+			if val, err := strconv.Atoi(t); err == nil {
+				return val
+			}
+		}
+	}
+	return def
+}
+
+// stringArg returns the string argument at index `i`, or an empty string if not specified.
+func (c *afCall) stringArg(i int) string {
+	if i < len(c.args) {
+		if str, ok := c.args[i].(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// boolArg returns the boolean argument at index `i`, or `def` if not specified.
+func (c *afCall) boolArg(i int, def bool) bool {
+	if i < len(c.args) {
+		switch t := c.args[i].(type) {
+		case bool:
+			return t
+		case float64:
+			return t != 0
+		}
+	}
+	return def
+}
+
+// stringsArg returns the array argument at index `i`. Comma separated lists in strings are
+// split into their elements.
+func (c *afCall) stringsArg(i int) []string {
+	if i >= len(c.args) {
+		return nil
+	}
+	switch t := c.args[i].(type) {
+	case []string:
+		return t
+	case string:
+		var strs []string
+		for _, s := range strings.Split(t, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// fieldActionCall returns the call to an Acrobat form function in the JavaScript action of the
+// additional actions (AA) of field `f` for the trigger event `key`: K (keystroke), F (format),
+// V (validate) or C (calculate). Returns nil if not found.
+func fieldActionCall(f *PdfField, key core.PdfObjectName) *afCall {
+	aa, ok := core.GetDict(f.AA)
+	if !ok {
+		return nil
+	}
+	action, ok := core.GetDict(aa.Get(key))
+	if !ok {
+		return nil
+	}
+	if s, ok := core.GetName(action.Get("S")); !ok || string(*s) != string(ActionTypeJavaScript) {
+		return nil
+	}
+
+	js, err := javaScriptCode(action.Get("JS"))
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode JavaScript: %v", err)
+		return nil
+	}
+	return parseAFCall(js)
+}
+
+// fieldValueText returns the text of the value of field `f`.
+func fieldValueText(f *PdfField) string {
+	switch t := core.TraceToDirectObject(f.V).(type) {
+	case *core.PdfObjectString:
+		return t.Decoded()
+	case *core.PdfObjectName:
+		return t.String()
+	case *core.PdfObjectInteger, *core.PdfObjectFloat:
+		val, _ := core.GetNumberAsFloat(t)
+		return formatAFResult(val)
+	}
+	return ""
+}
+
+// FormattedValue returns the value of field `f` as displayed by viewers, formatted by the format
+// action of the field (AA F). The supported formats are the number, percent, date, time and
+// special formats of the Acrobat forms (AFNumber_Format, AFPercent_Format, AFDate_Format,
+// AFDate_FormatEx, AFTime_Format, AFTime_FormatEx and AFSpecial_Format). The value is returned
+// unchanged if the field has no supported format, or if the value does not match the format.
+// The colors of the negative number styles are not applied.
+func (f *PdfField) FormattedValue() string {
+	text := fieldValueText(f)
+	if text == "" {
+		return text
+	}
+	call := fieldActionCall(f, "F")
+	if call == nil {
+		return text
+	}
+
+	switch call.name {
+	case "AFNumber_Format":
+		sepStyle := call.intArg(1, 0)
+		val, ok := parseAFNumber(text, sepStyle)
+		if !ok {
+			return text
+		}
+		return formatAFNumber(val, call.intArg(0, 2), sepStyle, call.intArg(2, 0),
+			call.stringArg(4), call.boolArg(5, true))
+	case "AFPercent_Format":
+		sepStyle := call.intArg(1, 0)
+		val, ok := parseAFNumber(text, sepStyle)
+		if !ok {
+			return text
+		}
+		num := formatAFNumber(val*100, call.intArg(0, 2), sepStyle, 0, "", false)
+		if call.boolArg(2, false) {
+			return "%" + num
+		}
+		return num + "%"
+	case "AFDate_Format", "AFDate_FormatEx", "AFTime_Format", "AFTime_FormatEx":
+		format := afDateFormat(call)
+		if format == "" {
+			return text
+		}
+		t, ok := parseAFDate(text, format)
+		if !ok {
+			return text
+		}
+		return formatAFDate(t, format)
+	case "AFSpecial_Format":
+		return formatAFSpecial(text, call.intArg(0, 0))
+	}
+
+	common.Log.Debug("Unsupported field format %s of field %s", call.name, f.PartialName())
+	return text
+}
+
+// parseAFNumber parses the number `s` written in the separator style `sepStyle` of the Acrobat
+// number formats. Currency symbols and spaces are ignored.
+func parseAFNumber(s string, sepStyle int) (float64, bool) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = s[1 : len(s)-1]
+	}
+	decimalComma := sepStyle == 2 || sepStyle == 3
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '-' && b.Len() == 0:
+			neg = !neg
+		case r == '.':
+			if !decimalComma {
+				b.WriteRune('.')
+			}
+		case r == ',':
+			if decimalComma {
+				b.WriteRune('.')
+			}
+		case unicode.IsLetter(r):
+			return 0, false
+		}
+	}
+	if b.Len() == 0 {
+		return 0, false
+	}
+	val, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		val = -val
+	}
+	return val, true
+}
+
+// formatAFNumber formats `val` as AFNumber_Format with `nDec` decimals, the separator style
+// `sepStyle`, the negative style `negStyle` and the currency symbol `currency`. The separator
+// styles 0 to 4 format numbers as 1,234.56, 1234.56, 1.234,56, 1234,56 and 1'234.56. The
+// negative styles 0 and 1 prefix negative numbers with a minus sign, 2 and 3 use parentheses.
+func formatAFNumber(val float64, nDec, sepStyle, negStyle int, currency string, prepend bool) string {
+	if nDec < 0 {
+		nDec = 0
+	}
+	// Round half away from zero.
+	scale := math.Pow(10, float64(nDec))
+	digits := strconv.FormatFloat(math.Round(math.Abs(val)*scale)/scale, 'f', nDec, 64)
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+
+	group, decimal := "", "."
+	switch sepStyle {
+	case 0:
+		group = ","
+	case 2:
+		group, decimal = ".", ","
+	case 3:
+		decimal = ","
+	case 4:
+		group = "'"
+	}
+
+	var b strings.Builder
+	for i, r := range intPart {
+		if group != "" && i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(r)
+	}
+	if fracPart != "" {
+		b.WriteString(decimal)
+		b.WriteString(fracPart)
+	}
+
+	num := b.String()
+	if prepend {
+		num = currency + num
+	} else {
+		num += currency
+	}
+
+	if val < 0 && strings.Trim(digits, "0.") != "" {
+		if negStyle == 2 || negStyle == 3 {
+			return "(" + num + ")"
+		}
+		return "-" + num
+	}
+	return num
+}
+
+// formatAFResult formats the result `val` of a calculation as the value of a field.
+func formatAFResult(val float64) string {
+	// Round off the floating point errors, e.g. of 0.1+0.2.
+	val = math.Round(val*1e10) / 1e10
+	return strconv.FormatFloat(val, 'f', -1, 64)
+}
+
+// afDateFormats are the date formats of AFDate_Format, by index.
+var afDateFormats = []string{
+	"m/d", "m/d/yy", "mm/dd/yy", "mm/yy", "d-mmm", "d-mmm-yy", "dd-mmm-yy", "yy-mm-dd",
+	"mmm-yy", "mmmm-yy", "mmm d, yyyy", "mmmm d, yyyy", "m/d/yy h:MM tt", "m/d/yy HH:MM",
+}
+
+// afTimeFormats are the time formats of AFTime_Format, by index.
+var afTimeFormats = []string{"HH:MM", "h:MM tt", "HH:MM:ss", "h:MM:ss tt"}
+
+// afDateFormat returns the date or time format of the format function `call`.
+func afDateFormat(call *afCall) string {
+	switch call.name {
+	case "AFDate_FormatEx", "AFTime_FormatEx":
+		return call.stringArg(0)
+	case "AFDate_Format":
+		if i := call.intArg(0, 0); i >= 0 && i < len(afDateFormats) {
+			return afDateFormats[i]
+		}
+	case "AFTime_Format":
+		if i := call.intArg(0, 0); i >= 0 && i < len(afTimeFormats) {
+			return afTimeFormats[i]
+		}
+	}
+	return ""
+}
+
+// afDateTokens are the tokens of the date formats, longest first.
+var afDateTokens = []string{
+	"mmmm", "mmm", "mm", "m", "dddd", "ddd", "dd", "d", "yyyy", "yy",
+	"HH", "H", "hh", "h", "MM", "M", "ss", "s", "tt",
+}
+
+// afDateLayouts maps the tokens of the date formats to the elements of Go time layouts.
+var afDateLayouts = map[string]string{
+	"mmmm": "January", "mmm": "Jan", "mm": "01", "m": "1",
+	"dddd": "Monday", "ddd": "Mon", "dd": "02", "d": "2",
+	"yyyy": "2006", "yy": "06",
+	"HH": "15", "H": "15", "hh": "03", "h": "3",
+	"MM": "04", "M": "4", "ss": "05", "s": "5", "tt": "PM",
+}
+
+// splitAFDateFormat splits the date format `format` into tokens and literal strings. The
+// literal strings are returned with a false token flag.
+func splitAFDateFormat(format string) (parts []string, tokens []bool) {
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, literal.String())
+			tokens = append(tokens, false)
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); {
+		if format[i] == '\\' && i+1 < len(format) {
+			literal.WriteByte(format[i+1])
+			i += 2
+			continue
+		}
+		var token string
+		for _, t := range afDateTokens {
+			if strings.HasPrefix(format[i:], t) {
+				token = t
+				break
+			}
+		}
+		if token == "" {
+			literal.WriteByte(format[i])
+			i++
+			continue
+		}
+		flush()
+		parts = append(parts, token)
+		tokens = append(tokens, true)
+		i += len(token)
+	}
+	flush()
+	return parts, tokens
+}
+
+// afDateFallbackLayouts are the layouts of the dates and times which do not match the format
+// of the fields.
+var afDateFallbackLayouts = []string{
+	"2006-01-02", "2006-01-02T15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05",
+	"2006-01-02 15:04", "2006/01/02", "01/02/2006", "1/2/2006", "1/2/06", "January 2, 2006",
+	"Jan 2, 2006", "2 January 2006", "2 Jan 2006", "15:04:05", "15:04", "3:04:05 PM", "3:04 PM",
+}
+
+// parseAFDate parses the date or time `s` with the date format `format`, or with common date
+// layouts and PDF date strings if not matching the format.
+func parseAFDate(s, format string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+
+	var layout strings.Builder
+	parts, tokens := splitAFDateFormat(format)
+	for i, part := range parts {
+		if tokens[i] {
+			layout.WriteString(afDateLayouts[part])
+		} else {
+			layout.WriteString(part)
+		}
+	}
+	// The uppercase value matches lowercase am/pm markers.
+	layouts := append([]string{layout.String()}, afDateFallbackLayouts...)
+	for _, l := range layouts {
+		for _, v := range []string{s, strings.ToUpper(s)} {
+			if t, err := time.Parse(l, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	if strings.HasPrefix(s, "D:") {
+		if date, err := NewPdfDate(s); err == nil {
+			return date.ToGoTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatAFDate formats the time `t` with the date format `format`.
+func formatAFDate(t time.Time, format string) string {
+	var b strings.Builder
+	parts, tokens := splitAFDateFormat(format)
+	for i, part := range parts {
+		if !tokens[i] {
+			b.WriteString(part)
+			continue
+		}
+		switch part {
+		case "H":
+			b.WriteString(strconv.Itoa(t.Hour()))
+		case "tt":
+			if t.Hour() < 12 {
+				b.WriteString("am")
+			} else {
+				b.WriteString("pm")
+			}
+		default:
+			b.WriteString(t.Format(afDateLayouts[part]))
+		}
+	}
+	return b.String()
+}
+
+// afSpecialFormats are the digit masks of the special formats of AFSpecial_Format, by index:
+// zip code, zip+4 code, phone number and social security number.
+var afSpecialFormats = []string{"99999", "99999-9999", "(999) 999-9999", "999-99-9999"}
+
+// formatAFSpecial formats the digits of `s` with the special format at index `psf`. Phone
+// numbers can have 7 digits. Returns `s` if the number of digits does not match the format.
+func formatAFSpecial(s string, psf int) string {
+	if psf < 0 || psf >= len(afSpecialFormats) {
+		return s
+	}
+	var digits []rune
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+
+	mask := afSpecialFormats[psf]
+	if psf == 2 && len(digits) == 7 {
+		mask = "999-9999"
+	}
+	if len(digits) != strings.Count(mask, "9") {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range mask {
+		if r == '9' {
+			b.WriteRune(digits[0])
+			digits = digits[1:]
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fieldNumber returns the numeric value of field `f`, parsed with the separator style of its
+// number or percent format. Returns 0 for empty or non numeric values.
+func fieldNumber(f *PdfField) float64 {
+	sepStyle := 0
+	if call := fieldActionCall(f, "F"); call != nil {
+		switch call.name {
+		case "AFNumber_Format", "AFPercent_Format":
+			sepStyle = call.intArg(1, 0)
+		}
+	}
+	val, _ := parseAFNumber(fieldValueText(f), sepStyle)
+	return val
+}
+
+// Calculate computes the values of the calculated fields of the form, in the calculation order
+// (CO), with the calculation actions of the fields (AA C). The supported calculations are the
+// simple calculations of the Acrobat forms (AFSimple_Calculate): sum (SUM), product (PRD),
+// average (AVG), minimum (MIN) and maximum (MAX) of the values of other fields. A field name
+// refers to all the terminal fields of its hierarchy. Returns the fields whose values were set.
+func (form *PdfAcroForm) Calculate() []*PdfField {
+	if form == nil || form.CO == nil {
+		return nil
+	}
+
+	fields := form.AllFields()
+	byObject := map[core.PdfObject]*PdfField{}
+	byName := map[string][]*PdfField{}
+	for _, f := range fields {
+		byObject[f.GetContainingPdfObject()] = f
+		if len(f.Kids) > 0 {
+			continue
+		}
+		name, err := f.FullName()
+		if err != nil {
+			continue
+		}
+		// Register the terminal field under its full name and the names of its ancestors.
+		for {
+			byName[name] = append(byName[name], f)
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+
+	var calculated []*PdfField
+	for _, obj := range form.CO.Elements() {
+		f, ok := byObject[obj]
+		if !ok {
+			f, ok = byObject[core.ResolveReference(obj)]
+		}
+		if !ok {
+			common.Log.Debug("ERROR: calculated field not found: %v", obj)
+			continue
+		}
+		call := fieldActionCall(f, "C")
+		if call == nil {
+			continue
+		}
+		if call.name != "AFSimple_Calculate" {
+			common.Log.Debug("Unsupported calculation %s of field %s", call.name, f.PartialName())
+			continue
+		}
+
+		var values []float64
+		for _, name := range call.stringsArg(1) {
+			operands, ok := byName[name]
+			if !ok {
+				common.Log.Debug("ERROR: field %s of calculation not found", name)
+				continue
+			}
+			for _, operand := range operands {
+				values = append(values, fieldNumber(operand))
+			}
+		}
+
+		val, ok := afSimpleCalculate(call.stringArg(0), values)
+		if !ok {
+			common.Log.Debug("ERROR: invalid calculation %q of field %s", call.stringArg(0),
+				f.PartialName())
+			continue
+		}
+		if err := fillFieldValue(f, core.MakeString(formatAFResult(val))); err != nil {
+			common.Log.Debug("ERROR: unable to set calculated value: %v", err)
+			continue
+		}
+		calculated = append(calculated, f)
+	}
+	return calculated
+}
+
+// afSimpleCalculate applies the operation `op` of AFSimple_Calculate to `values`.
+func afSimpleCalculate(op string, values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, true
+	}
+	switch strings.ToUpper(op) {
+	case "SUM":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum, true
+	case "PRD":
+		prd := 1.0
+		for _, v := range values {
+			prd *= v
+		}
+		return prd, true
+	case "AVG":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values)), true
+	case "MIN":
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min, true
+	case "MAX":
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max, true
+	}
+	return 0, false
+}
@@ -0,0 +1,145 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// makeScriptTextField returns a text field named `name` with the JavaScript actions `actions`,
+// mapped by trigger event.
+func makeScriptTextField(name string, actions map[string]string) *PdfField {
+	field := NewPdfField()
+	text := &PdfFieldText{PdfField: field}
+	field.SetContext(text)
+	field.T = core.MakeString(name)
+
+	aa := core.MakeDict()
+	for key, js := range actions {
+		action := core.MakeDict()
+		action.Set("S", core.MakeName("JavaScript"))
+		action.Set("JS", core.MakeString(js))
+		aa.Set(core.PdfObjectName(key), action)
+	}
+	field.AA = aa
+	return field
+}
+
+type testFieldValues map[string]core.PdfObject
+
+func (v testFieldValues) FieldValues() (map[string]core.PdfObject, error) {
+	return v, nil
+}
+
+func TestParseAFCall(t *testing.T) {
+	call := parseAFCall(`AFNumber_Keystroke(2, 0, 0, 0, "", true);` + "\n" +
+		`AFSimple_Calculate("SUM", new Array ("a", 'b.c'));`)
+	require.NotNil(t, call)
+	require.Equal(t, "AFNumber_Keystroke", call.name)
+	require.Equal(t, []interface{}{2.0, 0.0, 0.0, 0.0, "", true}, call.args)
+
+	call = parseAFCall(`AFSimple_Calculate("SUM", ["a", "b.c"]);`)
+	require.NotNil(t, call)
+	require.Equal(t, []string{"a", "b.c"}, call.stringsArg(1))
+
+	call = parseAFCall(`AFSimple_Calculate("AVG", "a, b ,c");`)
+	require.Equal(t, []string{"a", "b", "c"}, call.stringsArg(1))
+
+	require.Nil(t, parseAFCall(`event.value = a + b;`))
+	require.Nil(t, parseAFCall(`AFNumber_Format(2, x);`))
+}
+
+func TestFieldFormattedValue(t *testing.T) {
+	testcases := []struct {
+		format   string
+		value    string
+		expected string
+	}{
+		{`AFNumber_Format(2, 0, 0, 0, "$", true);`, "1234567.891", "$1,234,567.89"},
+		{`AFNumber_Format(2, 0, 0, 0, "$", true);`, "-12.5", "-$12.50"},
+		{`AFNumber_Format(0, 1, 2, 0, " EUR", false);`, "-1234.5", "(1235 EUR)"},
+		{`AFNumber_Format(2, 2, 0, 0, "", false);`, "1234,5", "1.234,50"},
+		{`AFNumber_Format(1, 4, 0, 0, "", false);`, "9876543.21", "9'876'543.2"},
+		{`AFNumber_Format(2, 0, 0, 0, "", false);`, "abc", "abc"},
+		{`AFPercent_Format(1, 0);`, "0.1234", "12.3%"},
+		{`AFDate_FormatEx("dd mmmm yyyy");`, "2020-03-07", "07 March 2020"},
+		{`AFDate_FormatEx("mm/dd/yyyy");`, "3/7/2020", "03/07/2020"},
+		{`AFDate_FormatEx("ddd, d-mmm-yy");`, "D:20200307120000Z", "Sat, 7-Mar-20"},
+		{`AFDate_Format(2);`, "March 7, 2020", "03/07/20"},
+		{`AFTime_Format(1);`, "14:05", "2:05 pm"},
+		{`AFTime_FormatEx("HH:MM:ss");`, "2:05:09 pm", "14:05:09"},
+		{`AFSpecial_Format(2);`, "5551234567", "(555) 123-4567"},
+		{`AFSpecial_Format(3);`, "123 45 6789", "123-45-6789"},
+		{`AFSpecial_Format(0);`, "123", "123"},
+	}
+
+	for _, tcase := range testcases {
+		field := makeScriptTextField("field", map[string]string{"F": tcase.format})
+		field.V = core.MakeString(tcase.value)
+		require.Equal(t, tcase.expected, field.FormattedValue(), tcase.format)
+	}
+
+	field := makeScriptTextField("field", nil)
+	field.V = core.MakeString("1234")
+	require.Equal(t, "1234", field.FormattedValue())
+}
+
+func TestFormCalculate(t *testing.T) {
+	price := makeScriptTextField("price", map[string]string{
+		"F": `AFNumber_Format(2, 2, 0, 0, "", false);`,
+	})
+	qty := makeScriptTextField("qty", nil)
+	subtotal := makeScriptTextField("subtotal", map[string]string{
+		"C": `AFSimple_Calculate("PRD", new Array("price", "qty"));`,
+	})
+
+	items := NewPdfField()
+	items.SetContext(&PdfFieldText{PdfField: items})
+	items.T = core.MakeString("items")
+	for _, name := range []string{"a", "b", "c"} {
+		kid := makeScriptTextField(name, nil)
+		kid.Parent = items
+		items.Kids = append(items.Kids, kid)
+	}
+
+	total := makeScriptTextField("total", map[string]string{
+		"F": `AFNumber_Format(2, 0, 0, 0, "$", true);`,
+		"C": `AFSimple_Calculate("SUM", "subtotal, items");`,
+	})
+	max := makeScriptTextField("max", map[string]string{
+		"C": `AFSimple_Calculate("MAX", new Array("items.a", "items.b", "items.c"));`,
+	})
+	avg := makeScriptTextField("avg", map[string]string{
+		"C": `AFSimple_Calculate("AVG", new Array("items"));`,
+	})
+
+	form := NewPdfAcroForm()
+	form.Fields = &[]*PdfField{price, qty, subtotal, items, total, max, avg}
+	form.CO = core.MakeArray(subtotal.ToPdfObject(), total.ToPdfObject(), max.ToPdfObject(),
+		avg.ToPdfObject())
+
+	err := form.Fill(testFieldValues{
+		"price":   core.MakeString("1,5"),
+		"qty":     core.MakeString("3"),
+		"items.a": core.MakeString("0.1"),
+		"items.b": core.MakeString("0.2"),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "4.5", fieldValueText(subtotal))
+	require.Equal(t, "4.8", fieldValueText(total))
+	require.Equal(t, "$4.80", total.FormattedValue())
+	require.Equal(t, "0.2", fieldValueText(max))
+	require.Equal(t, "0.1", fieldValueText(avg))
+
+	// Forms without calculation order.
+	form.CO = nil
+	require.Empty(t, form.Calculate())
+}
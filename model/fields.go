@@ -527,7 +527,7 @@ func (f *PdfField) Flags() FieldFlag {
 	var flags FieldFlag
 	found, err := f.inherit(func(node *PdfField) bool {
 		if node.Ff != nil {
-			flags = FieldFlag(*f.Ff)
+			flags = FieldFlag(*node.Ff)
 			return true
 		}
 		return false
@@ -296,7 +296,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 		// In the case of not yet supported fonts, we attempt to return enough information in the
 		// font for the caller to see some font properties.
 		// TODO(peterwilliams97): Add support for these fonts and remove this special error handling.
-		if err == ErrType3FontNotSupported || err == ErrType1CFontNotSupported {
+		if err == ErrType1CFontNotSupported {
 			simplefont, err2 := newSimpleFontFromPdfObject(d, base, nil)
 			if err2 != nil {
 				common.Log.Debug("ERROR: While loading simple font: font=%s err=%v", base, err2)
@@ -321,7 +321,14 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 			return nil, err
 		}
 		font.context = type0font
-	case "Type1", "Type3", "MMType1", "TrueType":
+	case "Type3":
+		type3font, err := newPdfFontType3FromPdfObject(d, base)
+		if err != nil {
+			common.Log.Debug("ERROR: While loading Type3 font. font=%s err=%v", base, err)
+			return nil, err
+		}
+		font.context = type3font
+	case "Type1", "MMType1", "TrueType":
 		var simplefont *pdfFontSimple
 		fnt, builtin := fonts.NewStdFontByName(fonts.StdFontName(base.basefont))
 		if builtin {
@@ -623,6 +630,10 @@ func (font *PdfFont) GetCharMetrics(code textencoding.CharCode) (CharMetrics, bo
 		if m, ok := t.GetCharMetrics(code); ok {
 			return m, ok
 		}
+	case *pdfFontType3:
+		if m, ok := t.GetCharMetrics(code); ok {
+			return m, ok
+		}
 	case *pdfCIDFontType0:
 		if m, ok := t.GetCharMetrics(code); ok {
 			return m, ok
@@ -787,13 +798,9 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 		font.name = name
 	}
 
-	if subtype == "Type3" {
-		common.Log.Debug("ERROR: Type 3 font not supported. d=%s", d)
-		return d, font, ErrType3FontNotSupported
-	}
-
 	basefont, ok := core.GetNameVal(d.Get("BaseFont"))
-	if !ok {
+	if !ok && subtype != "Type3" {
+		// BaseFont is optional for Type 3 fonts.
 		common.Log.Debug("ERROR: Font Incompatibility. BaseFont (Required) missing")
 		return d, font, ErrRequiredAttributeMissing
 	}
@@ -0,0 +1,289 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+
+	"github.com/loxiouve/unipdf/v3/internal/textencoding"
+	"github.com/loxiouve/unipdf/v3/model/internal/fonts"
+)
+
+// pdfFontType3 implements pdfFont
+var _ pdfFont = (*pdfFontType3)(nil)
+
+// pdfFontType3 represents a Type 3 font.
+//
+// 9.6.5 Type 3 Fonts (page 258)
+// Type 3 fonts differ from the other fonts supported by PDF. A Type 3 font dictionary defines the
+// font; font dictionaries for other fonts simply contain information about the font and refer to a
+// separate font program for the actual glyph descriptions. In Type 3 fonts, glyphs shall be
+// defined by streams of PDF graphics operators. These streams shall be associated with glyph names.
+// A separate encoding entry shall map character codes to the appropriate glyph names for the
+// glyphs.
+type pdfFontType3 struct {
+	fontCommon
+	container *core.PdfIndirectObject
+
+	// These fields are specific to Type 3 fonts.
+
+	FontBBox   core.PdfObject
+	FontMatrix core.PdfObject
+	CharProcs  core.PdfObject
+	Encoding   core.PdfObject
+	FirstChar  core.PdfObject
+	LastChar   core.PdfObject
+	Widths     core.PdfObject
+	Resources  core.PdfObject
+
+	// fontMatrix maps glyph space to text space.
+	fontMatrix []float64
+	// charWidths are the widths of the glyphs, in glyph space.
+	charWidths map[textencoding.CharCode]float64
+	// differences maps the character codes to the names of the glyph procedures.
+	differences map[textencoding.CharCode]textencoding.GlyphName
+	// encoder is the encoder specified by the /Encoding entry in the font dict.
+	encoder textencoding.TextEncoder
+	// resources are the resources used by the glyph procedures.
+	resources *PdfPageResources
+}
+
+// defaultFontMatrix is the glyph space to text space mapping of the fonts other than Type 3
+// fonts, 1000 glyph space units being 1 unit of text space.
+var defaultFontMatrix = []float64{0.001, 0, 0, 0.001, 0, 0}
+
+// baseFields returns the fields of `font` that are common to all PDF fonts.
+func (font *pdfFontType3) baseFields() *fontCommon {
+	return &font.fontCommon
+}
+
+func (font *pdfFontType3) getFontDescriptor() *PdfFontDescriptor {
+	return font.fontDescriptor
+}
+
+// Encoder returns the font's text encoder.
+func (font *pdfFontType3) Encoder() textencoding.TextEncoder {
+	return font.encoder
+}
+
+// GetRuneMetrics returns the character metrics for the rune.
+// A bool flag is returned to indicate whether or not the entry was found.
+func (font *pdfFontType3) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
+	if font.encoder == nil {
+		common.Log.Debug("No encoder for fonts=%s", font)
+		return fonts.CharMetrics{}, false
+	}
+	code, found := font.encoder.RuneToCharcode(r)
+	if !found {
+		return fonts.CharMetrics{}, false
+	}
+	return font.GetCharMetrics(code)
+}
+
+// GetCharMetrics returns the character metrics for the specified character code. The widths of
+// Type 3 fonts are in glyph space and are converted to thousandths of text space units, as for the
+// other fonts, using the font matrix.
+func (font *pdfFontType3) GetCharMetrics(code textencoding.CharCode) (fonts.CharMetrics, bool) {
+	width, ok := font.charWidths[code]
+	if !ok {
+		return fonts.CharMetrics{}, false
+	}
+	return fonts.CharMetrics{Wx: width * font.fontMatrix[0] * 1000}, true
+}
+
+// charProc returns the glyph procedure of the character code `code`.
+func (font *pdfFontType3) charProc(code textencoding.CharCode) (*core.PdfObjectStream, bool) {
+	glyph, ok := font.differences[code]
+	if !ok {
+		if font.encoder == nil {
+			return nil, false
+		}
+		r, ok := font.encoder.CharcodeToRune(code)
+		if !ok {
+			return nil, false
+		}
+		if glyph, ok = textencoding.RuneToGlyph(r); !ok {
+			return nil, false
+		}
+	}
+	procs, ok := core.GetDict(font.CharProcs)
+	if !ok {
+		return nil, false
+	}
+	return core.GetStream(procs.Get(core.PdfObjectName(glyph)))
+}
+
+// newPdfFontType3FromPdfObject creates a pdfFontType3 from dictionary `d`. Elements of `d` that
+// are already parsed are contained in `base`.
+// An error is returned if there is a problem with loading.
+func newPdfFontType3FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon) (*pdfFontType3, error) {
+	font := &pdfFontType3{
+		fontCommon: *base,
+		charWidths: make(map[textencoding.CharCode]float64),
+	}
+
+	font.FontBBox = d.Get("FontBBox")
+	font.FontMatrix = d.Get("FontMatrix")
+	arr, ok := core.GetArray(font.FontMatrix)
+	if !ok {
+		common.Log.Debug("ERROR: Type 3 font FontMatrix (Required) missing")
+		return nil, ErrRequiredAttributeMissing
+	}
+	matrix, err := arr.ToFloat64Array()
+	if err != nil || len(matrix) != 6 {
+		common.Log.Debug("ERROR: Invalid Type 3 font FontMatrix: %s", arr)
+		return nil, core.ErrRangeError
+	}
+	font.fontMatrix = matrix
+
+	font.CharProcs = d.Get("CharProcs")
+	if _, ok := core.GetDict(font.CharProcs); !ok {
+		common.Log.Debug("ERROR: Type 3 font CharProcs (Required) missing")
+		return nil, ErrRequiredAttributeMissing
+	}
+
+	font.FirstChar = d.Get("FirstChar")
+	font.LastChar = d.Get("LastChar")
+	font.Widths = d.Get("Widths")
+	firstChar, _ := core.GetIntVal(font.FirstChar)
+	if arr, ok := core.GetArray(font.Widths); ok {
+		widths, err := arr.ToFloat64Array()
+		if err != nil {
+			common.Log.Debug("ERROR: converting widths to array")
+			return nil, err
+		}
+		for i, w := range widths {
+			font.charWidths[textencoding.CharCode(firstChar+i)] = w
+		}
+	}
+
+	font.Encoding = core.TraceToDirectObject(d.Get("Encoding"))
+	if err := font.addEncoding(); err != nil {
+		return nil, err
+	}
+
+	font.Resources = d.Get("Resources")
+	if resDict, ok := core.GetDict(font.Resources); ok {
+		resources, err := NewPdfPageResourcesFromDict(resDict)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid Type 3 font resources: %v", err)
+			return nil, err
+		}
+		font.resources = resources
+	}
+	return font, nil
+}
+
+// addEncoding sets the encoder of `font` from its Encoding dictionary. Type 3 fonts have no built-in
+// encoding, so the codes are mapped to the glyph names of the Differences array, on top of the
+// BaseEncoding if one is specified.
+func (font *pdfFontType3) addEncoding() error {
+	if name, ok := font.Encoding.(*core.PdfObjectName); ok {
+		// Not conforming but seen in the wild.
+		encoder, err := textencoding.NewSimpleTextEncoder(name.String(), nil)
+		if err != nil {
+			return err
+		}
+		font.encoder = encoder
+		return nil
+	}
+	encoding, ok := font.Encoding.(*core.PdfObjectDictionary)
+	if !ok {
+		common.Log.Debug("ERROR: Type 3 font Encoding must be a dictionary (%T)", font.Encoding)
+		return core.ErrTypeError
+	}
+	if diffList, ok := core.GetArray(encoding.Get("Differences")); ok {
+		differences, err := textencoding.FromFontDifferences(diffList)
+		if err != nil {
+			return err
+		}
+		font.differences = differences
+	}
+
+	var (
+		encoder textencoding.SimpleEncoder
+		err     error
+	)
+	if baseName, ok := core.GetNameVal(encoding.Get("BaseEncoding")); ok {
+		encoder, err = textencoding.NewSimpleTextEncoder(baseName, font.differences)
+	} else if len(font.differences) > 0 {
+		encoder, err = textencoding.NewCustomSimpleTextEncoder(font.differences, nil)
+	} else {
+		encoder, err = textencoding.NewSimpleTextEncoder("StandardEncoding", nil)
+	}
+	if err != nil {
+		return err
+	}
+	font.encoder = encoder
+	return nil
+}
+
+// ToPdfObject converts the pdfFontType3 to its PDF representation for outputting.
+func (font *pdfFontType3) ToPdfObject() core.PdfObject {
+	if font.container == nil {
+		font.container = &core.PdfIndirectObject{}
+	}
+	d := font.baseFields().asPdfObjectDictionary("Type3")
+	if font.basefont == "" {
+		// BaseFont is optional for Type 3 fonts.
+		d.Remove("BaseFont")
+	}
+	if font.name != "" {
+		d.Set("Name", core.MakeName(font.name))
+	}
+	font.container.PdfObject = d
+
+	d.Set("FontBBox", font.FontBBox)
+	d.Set("FontMatrix", font.FontMatrix)
+	d.Set("CharProcs", font.CharProcs)
+	d.Set("Encoding", font.Encoding)
+	if font.FirstChar != nil {
+		d.Set("FirstChar", font.FirstChar)
+	}
+	if font.LastChar != nil {
+		d.Set("LastChar", font.LastChar)
+	}
+	if font.Widths != nil {
+		d.Set("Widths", font.Widths)
+	}
+	if font.Resources != nil {
+		d.Set("Resources", font.Resources)
+	}
+	return font.container
+}
+
+// FontMatrix returns the matrix mapping the glyph space of the font to text space, as an array of
+// 6 numbers. The font matrix of Type 3 fonts is specified by their FontMatrix entry, the glyph
+// space of the other fonts being 1000 units per text space unit.
+func (font *PdfFont) FontMatrix() []float64 {
+	if t3, ok := font.context.(*pdfFontType3); ok {
+		return t3.fontMatrix
+	}
+	return defaultFontMatrix
+}
+
+// GetCharProc returns the glyph procedure of the character code `code` for Type 3 fonts, that is
+// the content stream describing the glyph in glyph space. The first operator of glyph procedures is
+// either d0, for glyphs specifying their own colors, or d1, for glyphs painted with the current
+// color. The returned bool is false if the font is not a Type 3 font or if the glyph is not defined.
+func (font *PdfFont) GetCharProc(code textencoding.CharCode) (*core.PdfObjectStream, bool) {
+	t3, ok := font.context.(*pdfFontType3)
+	if !ok {
+		return nil, false
+	}
+	return t3.charProc(code)
+}
+
+// GetCharProcResources returns the resources used by the glyph procedures of Type 3 fonts.
+// Returns nil if the font is not a Type 3 font or if the glyph procedures use the resources of the
+// page they are painted on.
+func (font *PdfFont) GetCharProcResources() *PdfPageResources {
+	if t3, ok := font.context.(*pdfFontType3); ok {
+		return t3.resources
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+
+	"github.com/loxiouve/unipdf/v3/internal/testutils"
+	"github.com/loxiouve/unipdf/v3/internal/textencoding"
+)
+
+// type3FontObjects is a Type 3 font, the glyph named g66 being mapped to Unicode by the ToUnicode
+// CMap.
+const type3FontObjects = `
+1 0 obj
+<< /Type /Font /Subtype /Type3 /Name /T3 /FontBBox [0 0 100 100] /FontMatrix [0.01 0 0 0.01 0 0]
+   /CharProcs 2 0 R /Encoding << /Differences [65 /A /g66] >> /FirstChar 65 /LastChar 66
+   /Widths [50 100] /ToUnicode 5 0 R /Resources << /ProcSet [/PDF] >> >>
+endobj
+2 0 obj
+<< /A 3 0 R /g66 4 0 R >>
+endobj
+3 0 obj
+<< /Length 35 >>
+stream
+50 0 0 0 50 100 d1
+0 0 50 100 re f
+endstream
+endobj
+4 0 obj
+<< /Length 34 >>
+stream
+100 0 d0
+1 0 0 rg 0 0 100 100 re f
+endstream
+endobj
+5 0 obj
+<< /Length 178 >>
+stream
+/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+1 beginbfchar
+<42> <0042>
+endbfchar
+endcmap
+end
+end
+endstream
+endobj
+`
+
+func TestType3Font(t *testing.T) {
+	objects, err := testutils.ParseIndirectObjects(type3FontObjects)
+	require.NoError(t, err)
+
+	font, err := model.NewPdfFontFromPdfObject(objects[1])
+	require.NoError(t, err)
+	require.Equal(t, "Type3", font.Subtype())
+	require.Equal(t, "", font.BaseFont())
+	require.Equal(t, []float64{0.01, 0, 0, 0.01, 0, 0}, font.FontMatrix())
+
+	// Widths are converted from glyph space to thousandths of text space units.
+	metrics, ok := font.GetCharMetrics(65)
+	require.True(t, ok)
+	require.InDelta(t, 500, metrics.Wx, 1e-9)
+	metrics, ok = font.GetCharMetrics(66)
+	require.True(t, ok)
+	require.InDelta(t, 1000, metrics.Wx, 1e-9)
+	metrics, ok = font.GetRuneMetrics('A')
+	require.True(t, ok)
+	require.InDelta(t, 500, metrics.Wx, 1e-9)
+
+	// Text is mapped through the ToUnicode CMap and the Encoding.
+	text, _, numMisses := font.CharcodeBytesToUnicode([]byte("AB"))
+	require.Equal(t, "AB", text)
+	require.Zero(t, numMisses)
+
+	// Glyph procedures.
+	proc, ok := font.GetCharProc(textencoding.CharCode('A'))
+	require.True(t, ok)
+	content, err := core.DecodeStream(proc)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "d1")
+	_, ok = font.GetCharProc(textencoding.CharCode('C'))
+	require.False(t, ok)
+	require.NotNil(t, font.GetCharProcResources())
+
+	// Other fonts have no glyph procedures.
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	require.Equal(t, []float64{0.001, 0, 0, 0.001, 0, 0}, helvetica.FontMatrix())
+	_, ok = helvetica.GetCharProc(textencoding.CharCode('A'))
+	require.False(t, ok)
+
+	// Output dictionary.
+	d, ok := core.GetDict(font.ToPdfObject())
+	require.True(t, ok)
+	require.Equal(t, "Type3", d.Get("Subtype").String())
+	require.Nil(t, d.Get("BaseFont"))
+	for _, key := range []core.PdfObjectName{"FontBBox", "FontMatrix", "CharProcs", "Encoding",
+		"Widths", "ToUnicode", "Resources"} {
+		require.NotNil(t, d.Get(key), key)
+	}
+
+	font, err = model.NewPdfFontFromPdfObject(d)
+	require.NoError(t, err)
+	text, _, _ = font.CharcodeBytesToUnicode([]byte("BA"))
+	require.Equal(t, "BA", text)
+}
@@ -251,8 +251,9 @@ func (form *PdfAcroForm) FillWithAppearance(provider FieldValueProvider, appGen
 	return form.fill(provider, appGen)
 }
 
-// fill populates `form` with values provided by `provider`. If `appGen` is
-// not nil, field appearances are also generated.
+// fill populates `form` with values provided by `provider`, then computes the
+// calculated fields in the calculation order of the form. If `appGen` is not
+// nil, the appearances of the filled and calculated fields are also generated.
 func (form *PdfAcroForm) fill(provider FieldValueProvider, appGen FieldAppearanceGenerator) error {
 	if form == nil {
 		return nil
@@ -262,6 +263,7 @@ func (form *PdfAcroForm) fill(provider FieldValueProvider, appGen FieldAppearanc
 		return err
 	}
 
+	var filled []*PdfField
 	for _, field := range form.AllFields() {
 		// Try finding the field in the provider field map using its partial
 		// name. If not found, try finding it by its full name.
@@ -281,12 +283,21 @@ func (form *PdfAcroForm) fill(provider FieldValueProvider, appGen FieldAppearanc
 		if err := fillFieldValue(field, valObj); err != nil {
 			return err
 		}
+		filled = append(filled, field)
+	}
 
-		// Generate field appearance based on the specified settings.
-		if appGen == nil {
-			continue
+	// Compute the values of the calculated fields, which depend on the filled values.
+	for _, field := range form.Calculate() {
+		if !containsField(filled, field) {
+			filled = append(filled, field)
 		}
+	}
 
+	// Generate field appearance based on the specified settings.
+	if appGen == nil {
+		return nil
+	}
+	for _, field := range filled {
 		for _, annot := range field.Annotations {
 			// appGen generates the appearance based on the form/field/annotation and other settings
 			// depending on the implementation (for example may only generate appearance if none set).
@@ -303,6 +314,16 @@ func (form *PdfAcroForm) fill(provider FieldValueProvider, appGen FieldAppearanc
 	return nil
 }
 
+// containsField returns true if `fields` contains `field`.
+func containsField(fields []*PdfField, field *PdfField) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 // fillFieldValue populates form field `f` with value represented by `v`.
 func fillFieldValue(f *PdfField, val core.PdfObject) error {
 	switch f.GetContext().(type) {
@@ -323,12 +344,12 @@ func fillFieldValue(f *PdfField, val core.PdfObject) error {
 		case *core.PdfObjectName:
 			if len(val.String()) > 0 {
 				f.V = val
-				setFieldAnnotAS(f, val)
+				setButtonAnnotAS(f, val)
 			}
 		case *core.PdfObjectString:
 			if len(val.String()) > 0 {
 				f.V = core.MakeName(val.String())
-				setFieldAnnotAS(f, f.V)
+				setButtonAnnotAS(f, f.V)
 			}
 		default:
 			common.Log.Debug("ERROR: UNEXPECTED %s -> %v", f.PartialName(), val)
@@ -358,6 +379,25 @@ func fillFieldValue(f *PdfField, val core.PdfObject) error {
 	return nil
 }
 
+// setButtonAnnotAS sets the appearance state of the widgets of the button field `f` to `val`.
+// The buttons of radio button groups whose on state is not `val` are turned off.
+func setButtonAnnotAS(f *PdfField, val core.PdfObject) {
+	if !f.Flags().Has(FieldFlagRadio) {
+		setFieldAnnotAS(f, val)
+		return
+	}
+	for _, wa := range f.Annotations {
+		state := val
+		if apDict, ok := core.GetDict(wa.AP); ok {
+			if nDict, ok := core.GetDict(apDict.Get("N")); ok && nDict.Get(core.PdfObjectName(val.String())) == nil {
+				state = core.MakeName("Off")
+			}
+		}
+		wa.AS = state
+		wa.ToPdfObject()
+	}
+}
+
 // setFieldAnnotAS sets the appearance stream of the field annotations to `val`.
 func setFieldAnnotAS(f *PdfField, val core.PdfObject) {
 	for _, wa := range f.Annotations {
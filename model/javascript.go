@@ -0,0 +1,471 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// ScriptSource represents the location of a script in a PDF document.
+type ScriptSource string
+
+// Script locations.
+const (
+	// ScriptSourceDocument is a document-level script of the JavaScript names tree.
+	ScriptSourceDocument ScriptSource = "Document"
+	// ScriptSourceOpenAction is a script of the open action of the document.
+	ScriptSourceOpenAction ScriptSource = "OpenAction"
+	// ScriptSourceDocumentAction is a script of the additional actions of the document
+	// (WC, WS, DS, WP and DP triggers).
+	ScriptSourceDocumentAction ScriptSource = "DocumentAction"
+	// ScriptSourcePage is a script of the additional actions of a page (O and C triggers).
+	ScriptSourcePage ScriptSource = "Page"
+	// ScriptSourceAnnotation is a script of the action or the additional actions of a link,
+	// screen or widget annotation.
+	ScriptSourceAnnotation ScriptSource = "Annotation"
+	// ScriptSourceField is a script of the additional actions of a form field
+	// (K, F, V and C triggers).
+	ScriptSourceField ScriptSource = "Field"
+	// ScriptSourceOutline is a script of the action of an outline item.
+	ScriptSourceOutline ScriptSource = "Outline"
+)
+
+// PdfScript represents a JavaScript script of a PDF document along with its location.
+// The scripts of chained actions (Next entries) are reported with the location of the
+// first action of the chain.
+type PdfScript struct {
+	Source ScriptSource
+
+	// Name is the name of document-level scripts and the title of outline item scripts.
+	Name string
+
+	// Trigger is the key of the trigger event in additional actions dictionaries (AA),
+	// e.g. O for the opening of a page or K for a field keystroke.
+	Trigger string
+
+	// PageNumber is the number of the page of page and annotation scripts, 0 otherwise.
+	PageNumber int
+
+	// Field is the full name of the field of field scripts and widget annotation scripts.
+	Field string
+
+	// JS is the JavaScript code.
+	JS string
+
+	// remove removes the action containing the script from the model, nil for the catalog
+	// scripts.
+	remove func()
+}
+
+// GetJavaScript returns the JavaScript code of the action, stored either in a text string or
+// in a stream.
+func (javaScriptAct *PdfActionJavaScript) GetJavaScript() (string, error) {
+	return javaScriptCode(javaScriptAct.JS)
+}
+
+// SetJavaScript sets the JavaScript code of the action.
+func (javaScriptAct *PdfActionJavaScript) SetJavaScript(js string) {
+	javaScriptAct.JS = makeJavaScriptString(js)
+}
+
+// javaScriptCode returns the JavaScript code of the JS entry `obj` of a JavaScript action.
+func javaScriptCode(obj core.PdfObject) (string, error) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectString:
+		return t.Decoded(), nil
+	case *core.PdfObjectStream:
+		data, err := core.DecodeStream(t)
+		if err != nil {
+			return "", err
+		}
+		return core.MakeStringFromBytes(data).Decoded(), nil
+	case nil, *core.PdfObjectNull:
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid JavaScript type: %T", obj)
+}
+
+// makeJavaScriptString returns the text string of the JavaScript code `js`.
+func makeJavaScriptString(js string) *core.PdfObjectString {
+	for _, r := range js {
+		if r > 0x7f {
+			return core.MakeEncodedString(js, true)
+		}
+	}
+	return core.MakeString(js)
+}
+
+// newJavaScriptAction returns a new JavaScript action of the code `js`.
+func newJavaScriptAction(js string) *PdfAction {
+	action := NewPdfActionJavaScript()
+	action.SetJavaScript(js)
+	return action.PdfAction
+}
+
+// actionScripts returns the JavaScript code of the action `obj` and of its chained actions.
+func actionScripts(obj core.PdfObject, visited map[*core.PdfObjectDictionary]bool) []string {
+	action, ok := core.GetDict(obj)
+	if !ok || visited[action] {
+		return nil
+	}
+	visited[action] = true
+
+	var scripts []string
+	if s, ok := core.GetNameVal(action.Get("S")); ok && s == string(ActionTypeJavaScript) {
+		js, err := javaScriptCode(action.Get("JS"))
+		if err != nil {
+			common.Log.Debug("ERROR: unable to decode JavaScript: %v", err)
+		} else {
+			scripts = append(scripts, js)
+		}
+	}
+
+	switch next := core.TraceToDirectObject(action.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		scripts = append(scripts, actionScripts(next, visited)...)
+	case *core.PdfObjectArray:
+		for _, obj := range next.Elements() {
+			scripts = append(scripts, actionScripts(obj, visited)...)
+		}
+	}
+	return scripts
+}
+
+// scriptCollector collects the scripts of a document.
+type scriptCollector struct {
+	// scripts are the scripts of the document, each action being reported once.
+	scripts []*PdfScript
+
+	// removals are the scripts of all the actions, including the actions referenced more
+	// than once, used to remove the scripts from the model.
+	removals []*PdfScript
+	seen     map[*core.PdfObjectDictionary]bool
+}
+
+// addAction adds the scripts of the action `obj`, located as described by `loc`. The action
+// is removed from the model with `remove`.
+func (c *scriptCollector) addAction(obj core.PdfObject, loc PdfScript, remove func()) {
+	action, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	scripts := actionScripts(action, map[*core.PdfObjectDictionary]bool{})
+	if len(scripts) == 0 {
+		return
+	}
+	loc.remove = remove
+	c.removals = append(c.removals, &loc)
+	if c.seen[action] {
+		return
+	}
+	c.seen[action] = true
+
+	for _, js := range scripts {
+		script := loc
+		script.JS = js
+		c.scripts = append(c.scripts, &script)
+	}
+}
+
+// addTriggers adds the scripts of the additional actions dictionary `obj`, located as
+// described by `loc`.
+func (c *scriptCollector) addTriggers(obj core.PdfObject, loc PdfScript) {
+	aa, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	for _, key := range aa.Keys() {
+		key := key
+		trigger := loc
+		trigger.Trigger = string(key)
+		c.addAction(aa.Get(key), trigger, func() { aa.Remove(key) })
+	}
+}
+
+// addOutlineItems adds the scripts of the actions of the outline items of `node` and of their
+// descendants.
+func (c *scriptCollector) addOutlineItems(node *PdfOutlineTreeNode, visited map[*PdfOutlineTreeNode]bool) {
+	if node == nil {
+		return
+	}
+	for child := node.First; child != nil && !visited[child]; {
+		visited[child] = true
+		item, ok := child.context.(*PdfOutlineItem)
+		if !ok {
+			return
+		}
+		var title string
+		if item.Title != nil {
+			title = item.Title.Decoded()
+		}
+		c.addAction(item.A, PdfScript{Source: ScriptSourceOutline, Name: title}, func() {
+			item.A = nil
+			if dict, ok := core.GetDict(item.primitive); ok {
+				dict.Remove("A")
+			}
+		})
+		c.addOutlineItems(child, visited)
+		child = item.Next
+	}
+}
+
+// collectScripts returns the collected scripts of the document.
+func (r *PdfReader) collectScripts() (*scriptCollector, error) {
+	c := &scriptCollector{seen: map[*core.PdfObjectDictionary]bool{}}
+
+	// Catalog.
+	if names, ok := core.GetDict(r.catalog.Get("Names")); ok {
+		if tree, ok := core.GetDict(names.Get("JavaScript")); ok {
+			for _, entry := range nameTreeEntries(tree, map[*core.PdfObjectDictionary]bool{}) {
+				c.addAction(entry.value, PdfScript{Source: ScriptSourceDocument, Name: entry.key}, nil)
+			}
+		}
+	}
+	c.addAction(r.catalog.Get("OpenAction"), PdfScript{Source: ScriptSourceOpenAction}, nil)
+	c.addTriggers(r.catalog.Get("AA"), PdfScript{Source: ScriptSourceDocumentAction})
+
+	// Form fields.
+	if r.AcroForm != nil {
+		for _, field := range r.AcroForm.AllFields() {
+			name, _ := field.FullName()
+			c.addTriggers(field.AA, PdfScript{Source: ScriptSourceField, Field: name})
+		}
+	}
+
+	// Outline items.
+	c.addOutlineItems(r.outlineTree, map[*PdfOutlineTreeNode]bool{})
+
+	// Pages and annotations.
+	for i, page := range r.PageList {
+		c.addTriggers(page.AA, PdfScript{Source: ScriptSourcePage, PageNumber: i + 1})
+
+		annotations, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annotation := range annotations {
+			loc := PdfScript{Source: ScriptSourceAnnotation, PageNumber: i + 1}
+			switch t := annotation.GetContext().(type) {
+			case *PdfAnnotationLink:
+				c.addAction(t.A, loc, func() { t.SetAction(nil) })
+			case *PdfAnnotationScreen:
+				c.addAction(t.A, loc, func() { t.A = nil })
+				c.addTriggers(t.AA, loc)
+			case *PdfAnnotationWidget:
+				if t.parent != nil {
+					loc.Field, _ = t.parent.FullName()
+				}
+				c.addAction(t.A, loc, func() { t.A = nil })
+				c.addTriggers(t.AA, loc)
+			}
+		}
+	}
+	return c, nil
+}
+
+// GetScripts returns the JavaScript scripts of the document: the document-level scripts, the
+// scripts of the open action and of the additional actions of the document, the scripts of
+// the additional actions of the form fields and pages, the scripts of the actions of the outline
+// items and the scripts of the actions of the link, screen and widget annotations.
+func (r *PdfReader) GetScripts() ([]*PdfScript, error) {
+	c, err := r.collectScripts()
+	if err != nil {
+		return nil, err
+	}
+	return c.scripts, nil
+}
+
+// GetOpenAction returns the open action of the document (OpenAction entry of the catalog),
+// which is either an action or a destination. Both are nil if the document has no open
+// action.
+func (r *PdfReader) GetOpenAction() (*PdfAction, *Destination, error) {
+	obj := r.catalog.Get("OpenAction")
+	switch core.TraceToDirectObject(obj).(type) {
+	case nil, *core.PdfObjectNull:
+		return nil, nil, nil
+	case *core.PdfObjectDictionary:
+		action, err := r.loadAction(obj)
+		return action, nil, err
+	}
+	dest, err := newDestinationFromPdfObject(obj, r)
+	return nil, dest, err
+}
+
+// nameTreeEntry is a key and value pair of a name tree.
+type nameTreeEntry struct {
+	key   string
+	value core.PdfObject
+}
+
+// nameTreeEntries returns the entries of the name tree node `node` and its descendants.
+func nameTreeEntries(node *core.PdfObjectDictionary, visited map[*core.PdfObjectDictionary]bool) []nameTreeEntry {
+	if visited[node] {
+		return nil
+	}
+	visited[node] = true
+
+	var entries []nameTreeEntry
+	if names, ok := core.GetArray(node.Get("Names")); ok {
+		for i := 0; i+1 < names.Len(); i += 2 {
+			if key, ok := core.GetString(names.Get(i)); ok {
+				entries = append(entries, nameTreeEntry{key: key.Str(), value: names.Get(i + 1)})
+			}
+		}
+	}
+	if kids, ok := core.GetArray(node.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			if kidDict, ok := core.GetDict(kid); ok {
+				entries = append(entries, nameTreeEntries(kidDict, visited)...)
+			}
+		}
+	}
+	return entries
+}
+
+// documentScript is a document-level script added by a writer or an appender.
+type documentScript struct {
+	name   string
+	action *PdfAction
+}
+
+// makeJavaScriptNames returns a copy of the Names dictionary `names`, which may be nil, with
+// a JavaScript names tree containing the scripts `scripts`. The scripts of the JavaScript names
+// tree of `names` are kept if `keep` is true.
+func makeJavaScriptNames(names core.PdfObject, scripts []documentScript, keep bool) *core.PdfObjectDictionary {
+	namesCopy := core.MakeDict()
+	var entries []nameTreeEntry
+	if namesDict, ok := core.GetDict(names); ok {
+		for _, key := range namesDict.Keys() {
+			if key != "JavaScript" {
+				namesCopy.Set(key, namesDict.Get(key))
+			}
+		}
+		if tree, ok := core.GetDict(namesDict.Get("JavaScript")); ok && keep {
+			entries = nameTreeEntries(tree, map[*core.PdfObjectDictionary]bool{})
+		}
+	}
+
+	for _, script := range scripts {
+		entries = append(entries, nameTreeEntry{key: script.name, value: actionToPdfObject(script.action)})
+	}
+	if len(entries) == 0 {
+		return namesCopy
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	arr := core.MakeArray()
+	for _, entry := range entries {
+		arr.Append(core.MakeString(entry.key), entry.value)
+	}
+	tree := core.MakeDict()
+	tree.Set("Names", arr)
+	namesCopy.Set("JavaScript", tree)
+	return namesCopy
+}
+
+// removeCatalogScripts removes the scripts of the catalog entries of `catalog`: the JavaScript
+// names tree, the open action if it contains a script and the document additional actions
+// containing scripts. The Names and AA dictionaries are replaced by copies.
+func removeCatalogScripts(catalog *core.PdfObjectDictionary) {
+	if names, ok := core.GetDict(catalog.Get("Names")); ok && names.Get("JavaScript") != nil {
+		catalog.Set("Names", makeJavaScriptNames(names, nil, false))
+	}
+	if len(actionScripts(catalog.Get("OpenAction"), map[*core.PdfObjectDictionary]bool{})) > 0 {
+		catalog.Remove("OpenAction")
+	}
+	if aa, ok := core.GetDict(catalog.Get("AA")); ok {
+		aaCopy := core.MakeDict()
+		for _, key := range aa.Keys() {
+			if len(actionScripts(aa.Get(key), map[*core.PdfObjectDictionary]bool{})) == 0 {
+				aaCopy.Set(key, aa.Get(key))
+			}
+		}
+		if len(aaCopy.Keys()) == 0 {
+			catalog.Remove("AA")
+		} else {
+			catalog.Set("AA", aaCopy)
+		}
+	}
+}
+
+// AddJavaScript adds the document-level script `js` named `name`, executed by viewers when
+// the document is opened.
+func (w *PdfWriter) AddJavaScript(name, js string) {
+	w.javaScripts = append(w.javaScripts, documentScript{name: name, action: newJavaScriptAction(js)})
+}
+
+// SetOpenAction sets the action performed when the document is opened, e.g. a JavaScript
+// action printing the document.
+func (w *PdfWriter) SetOpenAction(action *PdfAction) error {
+	if action == nil {
+		return errors.New("open action cannot be nil")
+	}
+	obj := actionToPdfObject(action)
+	w.catalog.Set("OpenAction", obj)
+	return w.addObjects(obj)
+}
+
+// AddJavaScript adds the document-level script `js` named `name` in the new revision.
+func (a *PdfAppender) AddJavaScript(name, js string) {
+	a.javaScripts = append(a.javaScripts, documentScript{name: name, action: newJavaScriptAction(js)})
+}
+
+// SetOpenAction sets the action performed when the document is opened, written in the new
+// revision.
+func (a *PdfAppender) SetOpenAction(action *PdfAction) error {
+	if action == nil {
+		return errors.New("open action cannot be nil")
+	}
+	a.openAction = action
+	return nil
+}
+
+// RemoveScripts removes the JavaScript scripts returned by PdfReader.GetScripts from the
+// document in the new revision. Actions chained to scripts are removed along with them.
+// The scripts added with AddJavaScript are kept.
+func (a *PdfAppender) RemoveScripts() error {
+	c, err := a.Reader.collectScripts()
+	if err != nil {
+		return err
+	}
+
+	pages := map[int]bool{}
+	formChanged, outlinesChanged := false, false
+	for _, script := range c.removals {
+		switch script.Source {
+		case ScriptSourceDocument, ScriptSourceOpenAction, ScriptSourceDocumentAction:
+			// Removed from the catalog of the new revision.
+			a.removeScripts = true
+			continue
+		case ScriptSourceField:
+			formChanged = true
+		case ScriptSourceOutline:
+			outlinesChanged = true
+		default:
+			pages[script.PageNumber] = true
+		}
+		script.remove()
+	}
+
+	for i, page := range a.Reader.PageList {
+		if pages[i+1] {
+			a.UpdatePage(page)
+		}
+	}
+	if formChanged {
+		a.ReplaceAcroForm(a.Reader.AcroForm)
+	}
+	if outlinesChanged && a.outlines == nil {
+		// Outline trees set with SetOutlineTree replace the outline of the reader.
+		a.SetOutlineTree(a.Reader.GetOutlineTree())
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// newTestJavaScriptAction returns a new JavaScript action of the code `js`.
+func newTestJavaScriptAction(js string) *model.PdfActionJavaScript {
+	action := model.NewPdfActionJavaScript()
+	action.SetJavaScript(js)
+	return action
+}
+
+// scriptLocations returns the locations and the code of the scripts of the PDF file `path`.
+func scriptLocations(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	scripts, err := reader.GetScripts()
+	require.NoError(t, err)
+
+	var locations []string
+	for _, script := range scripts {
+		locations = append(locations, fmt.Sprintf("%s:%s:%s:%d:%s:%s", script.Source, script.Name,
+			script.Trigger, script.PageNumber, script.Field, script.JS))
+	}
+	return locations
+}
+
+func TestScripts(t *testing.T) {
+	path := tempFile("scripts.pdf")
+	updateSignedFile(t, testPdfAcroFormFile1, path, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		appender.AddJavaScript("init", "console.println('init');")
+		require.NoError(t, appender.SetOpenAction(newTestJavaScriptAction("this.print({bUI: true});").PdfAction))
+		require.Error(t, appender.SetOpenAction(nil))
+
+		// Outline items with a script and with a script chained to a URI action.
+		outline := model.NewOutline()
+		printItem := model.NewOutlineItem("Print", model.NewOutlineDest(0, 0, 0))
+		printItem.Action = newTestJavaScriptAction("this.print();").PdfAction
+		linkItem := model.NewOutlineItem("Link", model.NewOutlineDest(0, 0, 0))
+		linkItem.SetURIAction("https://example.com")
+		linkItem.Action.Next = newTestJavaScriptAction("app.alert('outline');").ToPdfObject()
+		printItem.Add(linkItem)
+		outline.Add(model.NewOutlineItem("Start", model.NewOutlineDest(0, 0, 0)))
+		outline.Add(printItem)
+		appender.SetOutlineTree(outline.ToOutlineTree())
+
+		page := reader.PageList[0]
+		aa := core.MakeDict()
+		aa.Set("O", newTestJavaScriptAction("app.alert('page');").ToPdfObject())
+		page.AA = aa
+
+		uriAction := model.NewPdfActionURI()
+		uriAction.URI = core.MakeString("https://example.com")
+		uriAction.Next = newTestJavaScriptAction("app.alert('link');").ToPdfObject()
+		link := model.NewPdfAnnotationLink()
+		link.Rect = core.MakeArrayFromFloats([]float64{0, 0, 10, 10})
+		link.SetAction(uriAction.PdfAction)
+		page.AddAnnotation(link.PdfAnnotation)
+		appender.UpdatePage(page)
+
+		for _, field := range reader.AcroForm.AllFields() {
+			if name, _ := field.FullName(); name == "Given Name Text Box" {
+				aa := core.MakeDict()
+				aa.Set("K", newTestJavaScriptAction("AFSpecial_Keystroke(0);").ToPdfObject())
+				field.AA = aa
+			}
+		}
+		appender.ReplaceAcroForm(reader.AcroForm)
+	})
+
+	require.Equal(t, []string{
+		"Document:init::0::console.println('init');",
+		"OpenAction:::0::this.print({bUI: true});",
+		"Field::K:0:Given Name Text Box:AFSpecial_Keystroke(0);",
+		"Outline:Print::0::this.print();",
+		"Outline:Link::0::app.alert('outline');",
+		"Page::O:1::app.alert('page');",
+		"Annotation:::1::app.alert('link');",
+	}, scriptLocations(t, path))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	action, dest, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	require.Nil(t, dest)
+	jsAction, ok := action.GetContext().(*model.PdfActionJavaScript)
+	require.True(t, ok)
+	js, err := jsAction.GetJavaScript()
+	require.NoError(t, err)
+	require.Equal(t, "this.print({bUI: true});", js)
+
+	// Removal of the scripts, keeping the scripts added in the same revision.
+	removed := tempFile("scripts-removed.pdf")
+	updateSignedFile(t, path, removed, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		require.NoError(t, appender.RemoveScripts())
+		appender.AddJavaScript("kept", "var kept = true;")
+	})
+	require.Equal(t, []string{"Document:kept::0::var kept = true;"}, scriptLocations(t, removed))
+
+	f, err = os.Open(removed)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err = model.NewPdfReader(f)
+	require.NoError(t, err)
+	action, dest, err = reader.GetOpenAction()
+	require.NoError(t, err)
+	require.Nil(t, action)
+	require.Nil(t, dest)
+	require.NotNil(t, reader.AcroForm)
+	require.NotEmpty(t, reader.AcroForm.AllFields())
+
+	// The outline items are kept without their scripts.
+	outline, err := reader.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 2)
+	require.Equal(t, "Print", outline.Entries[1].Title)
+	require.Len(t, outline.Entries[1].Entries, 1)
+}
+
+func TestWriterScripts(t *testing.T) {
+	f, err := os.Open("./testdata/pages3.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+
+	writer := model.NewPdfWriter()
+	for _, page := range reader.PageList {
+		require.NoError(t, writer.AddPage(page))
+	}
+	writer.AddJavaScript("b", "var b;")
+	writer.AddJavaScript("a", "var a = 'été';")
+	require.NoError(t, writer.SetOpenAction(newTestJavaScriptAction("this.print();").PdfAction))
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	scripts, err := reader.GetScripts()
+	require.NoError(t, err)
+	require.Len(t, scripts, 3)
+	require.Equal(t, "a", scripts[0].Name)
+	require.Equal(t, "var a = 'été';", scripts[0].JS)
+	require.Equal(t, "b", scripts[1].Name)
+	require.Equal(t, model.ScriptSourceOpenAction, scripts[2].Source)
+	require.Equal(t, "this.print();", scripts[2].JS)
+}
@@ -0,0 +1,139 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// PdfLink represents a link annotation of a page along with its target.
+type PdfLink struct {
+	// Annotation is the link annotation.
+	Annotation *PdfAnnotationLink
+
+	// Action is the action of the link. It is nil for links to destinations.
+	Action *PdfAction
+
+	// URI is the URI of links with URI actions.
+	URI string
+
+	// Destination is the target of links to destinations of the document,
+	// either through the Dest entry of the annotation or a go to action.
+	// Named destinations are resolved to explicit destinations if possible.
+	Destination *Destination
+
+	// Err is the error encountered while resolving the destination of the
+	// link. A non-nil error indicates a broken internal link.
+	Err error
+}
+
+// IsInternal returns true if the link points to a destination of the document.
+func (l *PdfLink) IsInternal() bool {
+	return l.Destination != nil
+}
+
+// IsBroken returns true if the destination of the link could not be resolved.
+func (l *PdfLink) IsBroken() bool {
+	return l.Err != nil
+}
+
+// SetURI replaces the URI of a link with an URI action. The change is written
+// along with the page of the link, e.g. with PdfAppender.UpdatePage.
+func (l *PdfLink) SetURI(uri string) error {
+	if l.Action == nil {
+		return errors.New("link has no action")
+	}
+	uriAction, ok := l.Action.GetContext().(*PdfActionURI)
+	if !ok {
+		return fmt.Errorf("link action is not an URI action: %T", l.Action.GetContext())
+	}
+	uriAction.URI = core.MakeString(uri)
+	l.Annotation.SetAction(l.Action)
+	l.URI = uri
+	return nil
+}
+
+// GetDestination returns the destination of the link annotation, specified by
+// either the Dest entry of the annotation or a go to action. Returns nil if the
+// link does not point to a destination of the document. Named destinations can
+// be resolved using PdfReader.ResolveDestination.
+func (link *PdfAnnotationLink) GetDestination() (*Destination, error) {
+	if link.Dest != nil && !core.IsNullObject(link.Dest) {
+		return newDestinationFromPdfObject(link.Dest, link.reader)
+	}
+
+	action, err := link.GetAction()
+	if err != nil || action == nil {
+		return nil, err
+	}
+	gotoAction, ok := action.GetContext().(*PdfActionGoTo)
+	if !ok {
+		return nil, nil
+	}
+	return newDestinationFromPdfObject(gotoAction.D, link.reader)
+}
+
+// SetDestination sets the destination of the link annotation, replacing its
+// action if any.
+func (link *PdfAnnotationLink) SetDestination(dest *Destination) {
+	link.SetAction(nil)
+	link.Dest = dest.ToPdfObject()
+}
+
+// GetLinks returns the link annotations of the page `pageNumber` along with
+// their targets. Internal destinations are resolved, the resolution errors
+// being reported in the Err field of the links.
+func (r *PdfReader) GetLinks(pageNumber int) ([]*PdfLink, error) {
+	page, err := r.GetPage(pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*PdfLink
+	for _, annotation := range annotations {
+		linkAnnot, ok := annotation.GetContext().(*PdfAnnotationLink)
+		if !ok {
+			continue
+		}
+		if linkAnnot.reader == nil {
+			linkAnnot.reader = r
+		}
+
+		link := &PdfLink{Annotation: linkAnnot}
+		link.Action, err = linkAnnot.GetAction()
+		if err != nil {
+			link.Err = err
+			links = append(links, link)
+			continue
+		}
+		if link.Action != nil {
+			if uriAction, ok := link.Action.GetContext().(*PdfActionURI); ok {
+				link.URI, _ = core.GetStringVal(uriAction.URI)
+			}
+		}
+
+		dest, err := linkAnnot.GetDestination()
+		if err != nil {
+			link.Err = err
+		} else if dest != nil {
+			link.Destination = dest
+			if resolved, err := r.ResolveDestination(dest); err == nil {
+				link.Destination = resolved
+			} else {
+				link.Err = err
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
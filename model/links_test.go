@@ -0,0 +1,178 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// newTestLink returns a new link annotation with the rectangle `rect`.
+func newTestLink(rect []float64) *model.PdfAnnotationLink {
+	link := model.NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats(rect)
+	return link
+}
+
+func TestDestinations(t *testing.T) {
+	pageObj := core.MakeIndirectObject(core.MakeDict())
+	dests := map[model.DestinationMode][]float64{
+		model.DestinationModeXYZ:   {10, 20, 1.5},
+		model.DestinationModeFit:   nil,
+		model.DestinationModeFitH:  {30},
+		model.DestinationModeFitV:  {40},
+		model.DestinationModeFitR:  {10, 20, 30, 40},
+		model.DestinationModeFitB:  nil,
+		model.DestinationModeFitBH: {50},
+		model.DestinationModeFitBV: {60},
+	}
+	for mode, params := range dests {
+		dest := model.NewDestination(0, mode)
+		dest.PageObj = pageObj
+		dest.Left, dest.Bottom, dest.Right, dest.Top, dest.Zoom = 10, 20, 30, 40, 1.5
+		switch mode {
+		case model.DestinationModeXYZ:
+			dest.Top = 20
+		case model.DestinationModeFitH:
+			dest.Top = 30
+		case model.DestinationModeFitV:
+			dest.Left = 40
+		case model.DestinationModeFitBH:
+			dest.Top = 50
+		case model.DestinationModeFitBV:
+			dest.Left = 60
+		}
+
+		arr, ok := core.GetArray(dest.ToPdfObject())
+		require.True(t, ok)
+		require.Equal(t, pageObj, arr.Get(0))
+		require.Equal(t, string(mode), arr.Get(1).(*core.PdfObjectName).String())
+		values, err := core.MakeArray(arr.Elements()[2:]...).ToFloat64Array()
+		require.NoError(t, err)
+		require.Equal(t, len(params), len(values), mode)
+		for i := range params {
+			require.InDelta(t, params[i], values[i], 1e-6, mode)
+		}
+
+		// Same destination parsed from a go to action.
+		action := model.NewPdfActionGoTo()
+		action.SetDestination(dest)
+		parsed, err := action.GetDestination()
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed.Mode)
+		require.Equal(t, arr.String(), parsed.ToPdfObject().String())
+	}
+
+	named := model.NewNamedDestination("chapter1")
+	require.True(t, named.IsNamed())
+	require.Equal(t, "(chapter1)", named.ToPdfObject().WriteString())
+}
+
+func TestGetLinks(t *testing.T) {
+	f, err := os.Open("./testdata/pages3.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+
+	// Links of the first page.
+	writer := model.NewPdfWriter()
+	page := reader.PageList[0]
+	uriLink := newTestLink([]float64{0, 0, 10, 10})
+	uriAction := model.NewPdfActionURI()
+	uriAction.URI = core.MakeString("https://example.com/?utm_source=test")
+	uriLink.SetAction(uriAction.PdfAction)
+	page.AddAnnotation(uriLink.PdfAnnotation)
+
+	explicitLink := newTestLink([]float64{10, 0, 20, 10})
+	dest := model.NewDestination(1, model.DestinationModeFitR)
+	dest.PageObj = reader.PageList[1].GetPageAsIndirectObject()
+	dest.Left, dest.Bottom, dest.Right, dest.Top = 10, 20, 100, 200
+	explicitLink.SetDestination(dest)
+	page.AddAnnotation(explicitLink.PdfAnnotation)
+
+	namedLink := newTestLink([]float64{20, 0, 30, 10})
+	gotoAction := model.NewPdfActionGoTo()
+	gotoAction.SetDestination(model.NewNamedDestination("chapter3"))
+	namedLink.SetAction(gotoAction.PdfAction)
+	page.AddAnnotation(namedLink.PdfAnnotation)
+
+	brokenLink := newTestLink([]float64{30, 0, 40, 10})
+	brokenLink.SetDestination(model.NewNamedDestination("missing"))
+	page.AddAnnotation(brokenLink.PdfAnnotation)
+
+	for _, p := range reader.PageList {
+		require.NoError(t, writer.AddPage(p))
+	}
+
+	// Names tree with an intermediate node.
+	chapter3 := model.NewDestination(2, model.DestinationModeXYZ)
+	chapter3.PageObj = reader.PageList[2].GetPageAsIndirectObject()
+	chapter3.Top = 500
+	leaf := core.MakeDict()
+	leaf.Set("Limits", core.MakeArray(core.MakeString("chapter1"), core.MakeString("chapter3")))
+	leaf.Set("Names", core.MakeArray(core.MakeString("chapter3"), chapter3.ToPdfObject()))
+	tree := core.MakeDict()
+	tree.Set("Kids", core.MakeArray(core.MakeIndirectObject(leaf)))
+	names := core.MakeDict()
+	names.Set("Dests", tree)
+	require.NoError(t, writer.SetNamedDestinations(names))
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	links, err := reader.GetLinks(1)
+	require.NoError(t, err)
+	require.Len(t, links, 4)
+
+	require.Equal(t, "https://example.com/?utm_source=test", links[0].URI)
+	require.False(t, links[0].IsInternal())
+	require.False(t, links[0].IsBroken())
+
+	require.True(t, links[1].IsInternal())
+	require.NoError(t, links[1].Err)
+	require.Nil(t, links[1].Action)
+	require.Equal(t, int64(1), links[1].Destination.Page)
+	require.Equal(t, model.DestinationModeFitR, links[1].Destination.Mode)
+	require.Equal(t, []float64{10, 20, 100, 200}, []float64{links[1].Destination.Left,
+		links[1].Destination.Bottom, links[1].Destination.Right, links[1].Destination.Top})
+
+	require.True(t, links[2].IsInternal())
+	require.NoError(t, links[2].Err)
+	require.Equal(t, "chapter3", links[2].Destination.Name)
+	require.Equal(t, int64(2), links[2].Destination.Page)
+	require.Equal(t, model.DestinationModeXYZ, links[2].Destination.Mode)
+	require.Equal(t, float64(500), links[2].Destination.Top)
+
+	require.True(t, links[3].IsBroken())
+	require.Equal(t, "missing", links[3].Destination.Name)
+
+	// Only URI links can be rewritten.
+	require.Error(t, links[1].SetURI("https://example.com"))
+	require.NoError(t, links[0].SetURI("https://example.com/"))
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.UpdatePage(reader.PageList[0])
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	links, err = reader.GetLinks(1)
+	require.NoError(t, err)
+	require.Len(t, links, 4)
+	require.Equal(t, "https://example.com/", links[0].URI)
+	require.Equal(t, "chapter3", links[2].Destination.Name)
+	require.NoError(t, links[2].Err)
+}
@@ -158,7 +158,8 @@ func (od OutlineDest) ToPdfObject() core.PdfObject {
 }
 
 // Outline represents a PDF outline dictionary (Table 152 - p. 376).
-// Currently, the Outline object can only be used to construct PDF outlines.
+// Outlines can be built from scratch or read from existing documents with
+// PdfReader.GetOutlines, edited and written back with PdfAppender.SetOutlineTree.
 type Outline struct {
 	Entries []*OutlineItem `json:"entries,omitempty"`
 }
@@ -189,6 +190,64 @@ func (o *Outline) Items() []*OutlineItem {
 	return o.Entries
 }
 
+// Remove removes the outline item `item`, along with its children, from the
+// outline. The item may be at any level of the outline. Returns false if the
+// item is not found.
+func (o *Outline) Remove(item *OutlineItem) bool {
+	return removeOutlineItem(&o.Entries, item)
+}
+
+// Move moves the outline item `item`, along with its children, to the
+// children of `parent` at the specified index. The item is moved to the top
+// level of the outline if `parent` is nil.
+func (o *Outline) Move(item, parent *OutlineItem, index uint) error {
+	if item == nil {
+		return errors.New("outline item cannot be nil")
+	}
+	if parent == item || (parent != nil && item.contains(parent)) {
+		return errors.New("outline item cannot be moved to its descendants")
+	}
+	if parent != nil && !containsOutlineItem(o.Entries, parent) {
+		return errors.New("parent outline item not found")
+	}
+	if !o.Remove(item) {
+		return errors.New("outline item not found")
+	}
+
+	if parent == nil {
+		o.Insert(index, item)
+	} else {
+		parent.Insert(index, item)
+	}
+	return nil
+}
+
+// removeOutlineItem removes `item` from the outline items `entries` or from
+// their descendants.
+func removeOutlineItem(entries *[]*OutlineItem, item *OutlineItem) bool {
+	for i, entry := range *entries {
+		if entry == item {
+			*entries = append((*entries)[:i], (*entries)[i+1:]...)
+			return true
+		}
+		if removeOutlineItem(&entry.Entries, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsOutlineItem returns true if `item` is one of the outline items
+// `entries` or of their descendants.
+func containsOutlineItem(entries []*OutlineItem, item *OutlineItem) bool {
+	for _, entry := range entries {
+		if entry == item || entry.contains(item) {
+			return true
+		}
+	}
+	return false
+}
+
 // ToPdfOutline returns a low level PdfOutline object, based on the current
 // instance.
 func (o *Outline) ToPdfOutline() *PdfOutline {
@@ -238,11 +297,37 @@ func (o *Outline) ToPdfObject() core.PdfObject {
 	return o.ToPdfOutline().ToPdfObject()
 }
 
+// OutlineItemStyle represents the style flags of an outline item title
+// (Table 154 - p. 377).
+type OutlineItemStyle int
+
+const (
+	// OutlineItemStyleItalic displays the outline item title in italic.
+	OutlineItemStyleItalic OutlineItemStyle = 1 << iota
+
+	// OutlineItemStyleBold displays the outline item title in bold.
+	OutlineItemStyleBold
+)
+
 // OutlineItem represents a PDF outline item dictionary (Table 153 - pp. 376 - 377).
 type OutlineItem struct {
 	Title   string         `json:"title"`
 	Dest    OutlineDest    `json:"dest"`
 	Entries []*OutlineItem `json:"entries,omitempty"`
+
+	// Color is the color of the outline item title.
+	Color *PdfColorDeviceRGB `json:"color,omitempty"`
+
+	// Style contains the style flags of the outline item title.
+	Style OutlineItemStyle `json:"style,omitempty"`
+
+	// Closed specifies whether the children of the outline item are hidden
+	// when the outline is displayed.
+	Closed bool `json:"closed,omitempty"`
+
+	// Action is the action performed when the outline item is activated,
+	// used instead of the destination of the item if not nil.
+	Action *PdfAction `json:"-"`
 }
 
 // NewOutlineItem returns a new outline item instance.
@@ -274,13 +359,56 @@ func (oi *OutlineItem) Items() []*OutlineItem {
 	return oi.Entries
 }
 
+// contains returns true if `item` is a descendant of the outline item.
+func (oi *OutlineItem) contains(item *OutlineItem) bool {
+	return containsOutlineItem(oi.Entries, item)
+}
+
+// SetURIAction sets the action of the outline item to the resolution of the
+// specified URI.
+func (oi *OutlineItem) SetURIAction(uri string) {
+	action := NewPdfActionURI()
+	action.URI = core.MakeString(uri)
+	oi.Action = action.PdfAction
+}
+
+// SetNamedDestAction sets the action of the outline item to go to the named
+// destination `name` of the document.
+func (oi *OutlineItem) SetNamedDestAction(name string) {
+	action := NewPdfActionGoTo()
+	action.D = core.MakeString(name)
+	oi.Action = action.PdfAction
+}
+
+// SetLaunchAction sets the action of the outline item to launch the
+// application or open the document specified by the file specification
+// string `file`.
+func (oi *OutlineItem) SetLaunchAction(file string) {
+	filespec := NewPdfFilespec()
+	filespec.F = core.MakeString(file)
+	action := NewPdfActionLaunch()
+	action.F = filespec
+	oi.Action = action.PdfAction
+}
+
 // ToPdfOutlineItem returns a low level PdfOutlineItem object,
-// based on the current instance.
+// based on the current instance, along with the number of its visible
+// descendants. The count of the item is negative if the item is closed.
 func (oi *OutlineItem) ToPdfOutlineItem() (*PdfOutlineItem, int64) {
 	// Create outline item.
 	currItem := NewPdfOutlineItem()
 	currItem.Title = core.MakeEncodedString(oi.Title, true)
-	currItem.Dest = oi.Dest.ToPdfObject()
+	if oi.Action != nil {
+		currItem.A = actionToPdfObject(oi.Action)
+	} else {
+		currItem.Dest = oi.Dest.ToPdfObject()
+	}
+	if oi.Color != nil {
+		currItem.C = core.MakeArrayFromFloats(oi.Color[:])
+	}
+	if oi.Style != 0 {
+		currItem.F = core.MakeInteger(int64(oi.Style))
+	}
 
 	// Create outline items.
 	var outlineItems []*PdfOutlineItem
@@ -308,7 +436,13 @@ func (oi *OutlineItem) ToPdfOutlineItem() (*PdfOutlineItem, int64) {
 	if lenOutlineItems > 0 {
 		currItem.First = &outlineItems[0].PdfOutlineTreeNode
 		currItem.Last = &outlineItems[lenOutlineItems-1].PdfOutlineTreeNode
-		currItem.Count = &lenDescendants
+
+		count := lenDescendants
+		if oi.Closed {
+			count = -count
+			lenDescendants = 0
+		}
+		currItem.Count = &count
 	}
 
 	return currItem, lenDescendants
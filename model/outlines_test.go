@@ -13,6 +13,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
 )
 
 func TestGetOutlines(t *testing.T) {
@@ -71,3 +73,153 @@ func TestGetOutlines(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, srcJson, dstJson)
 }
+
+func TestEditOutlines(t *testing.T) {
+	f, err := os.Open(`./testdata/pages3.pdf`)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewPdfReader(f)
+	require.NoError(t, err)
+
+	writer := NewPdfWriter()
+	for _, page := range reader.PageList {
+		require.NoError(t, writer.AddPage(page))
+	}
+	srcOutline := NewOutline()
+	for i, title := range []string{"A", "B", "C"} {
+		item := NewOutlineItem(title, NewOutlineDest(int64(i), 0, 0))
+		srcOutline.Add(item)
+		if title == "A" {
+			item.Add(NewOutlineItem("A.1", NewOutlineDest(0, 0, 100)))
+			item.Add(NewOutlineItem("A.2", NewOutlineDest(0, 0, 200)))
+		}
+	}
+	writer.AddOutlineTree(srcOutline.ToOutlineTree())
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	// Edit the outline of the document.
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err := reader.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 3)
+	a, b, c := outline.Entries[0], outline.Entries[1], outline.Entries[2]
+	a1, a2 := a.Entries[0], a.Entries[1]
+
+	require.True(t, outline.Remove(c))
+	require.False(t, outline.Remove(c))
+	require.NoError(t, outline.Move(a2, nil, 0))
+	require.Error(t, outline.Move(a, a1, 0))
+	require.Error(t, outline.Move(c, b, 0))
+
+	a.Closed = true
+	a1.SetURIAction("https://example.com")
+	b.Title = "Chapter B"
+	b.Color = NewPdfColorDeviceRGB(1, 0, 0)
+	b.Style = OutlineItemStyleBold | OutlineItemStyleItalic
+	b.SetNamedDestAction("chapter-b")
+	launch := NewOutlineItem("Notes", OutlineDest{})
+	launch.SetLaunchAction("notes.txt")
+	b.Add(launch)
+
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.SetOutlineTree(outline.ToOutlineTree())
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+
+	// Check the edited outline.
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err = reader.GetOutlines()
+	require.NoError(t, err)
+	var titles []string
+	for _, item := range outline.Entries {
+		titles = append(titles, item.Title)
+	}
+	require.Equal(t, []string{"A.2", "A", "Chapter B"}, titles)
+	a2, a, b = outline.Entries[0], outline.Entries[1], outline.Entries[2]
+	require.Equal(t, int64(0), a2.Dest.Page)
+	require.Equal(t, 200.0, a2.Dest.Y)
+	require.True(t, a.Closed)
+	require.False(t, b.Closed)
+	require.Equal(t, NewPdfColorDeviceRGB(1, 0, 0), b.Color)
+	require.Equal(t, OutlineItemStyleBold|OutlineItemStyleItalic, b.Style)
+
+	uri, ok := a.Entries[0].Action.GetContext().(*PdfActionURI)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com", uri.URI.String())
+	gotoAction, ok := b.Action.GetContext().(*PdfActionGoTo)
+	require.True(t, ok)
+	require.Equal(t, "chapter-b", gotoAction.D.String())
+	require.Len(t, b.Entries, 1)
+	launchAction, ok := b.Entries[0].Action.GetContext().(*PdfActionLaunch)
+	require.True(t, ok)
+	require.Equal(t, "notes.txt", launchAction.F.F.String())
+
+	// The counts only include the visible items.
+	tree := reader.GetOutlineTree()
+	root, ok := tree.GetContext().(*PdfOutline)
+	require.True(t, ok)
+	require.Equal(t, int64(4), *root.Count)
+	item, ok := root.First.GetContext().(*PdfOutlineItem)
+	require.True(t, ok)
+	require.Nil(t, item.Count)
+	item, ok = item.Next.GetContext().(*PdfOutlineItem)
+	require.True(t, ok)
+	require.Equal(t, int64(-1), *item.Count)
+	item, ok = item.Next.GetContext().(*PdfOutlineItem)
+	require.True(t, ok)
+	require.Equal(t, int64(1), *item.Count)
+}
+
+func TestOutlinesNamedDestinations(t *testing.T) {
+	f, err := os.Open(`./testdata/pages3.pdf`)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewPdfReader(f)
+	require.NoError(t, err)
+
+	writer := NewPdfWriter()
+	for _, page := range reader.PageList {
+		require.NoError(t, writer.AddPage(page))
+	}
+	srcOutline := NewOutline()
+	srcOutline.Add(NewOutlineItem("Intro", NewOutlineDest(0, 0, 0)))
+	srcOutline.Add(NewOutlineItem("Chapter", NewOutlineDest(1, 0, 0)))
+	tree := srcOutline.ToPdfOutline()
+	intro := tree.First.GetContext().(*PdfOutlineItem)
+	intro.Dest = core.MakeName("intro")
+	chapter := intro.Next.GetContext().(*PdfOutlineItem)
+	chapter.Dest = core.MakeString("chapter")
+	writer.AddOutlineTree(&tree.PdfOutlineTreeNode)
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	// Write the outline read from the document back.
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err := reader.GetOutlines()
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.SetOutlineTree(outline.ToOutlineTree())
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+
+	// The named destinations are kept.
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err = reader.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 2)
+	var names []core.PdfObject
+	for _, item := range outline.Entries {
+		require.NotNil(t, item.Action, item.Title)
+		gotoAction, ok := item.Action.GetContext().(*PdfActionGoTo)
+		require.True(t, ok)
+		names = append(names, gotoAction.D)
+	}
+	require.Equal(t, []core.PdfObject{core.MakeName("intro"), core.MakeString("chapter")}, names)
+}
@@ -0,0 +1,308 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// testPKI contains the certificates and keys of a test public key infrastructure.
+type testPKI struct {
+	caCert   *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+	leafCert *x509.Certificate
+	leafKey  *ecdsa.PrivateKey
+	tsaCert  *x509.Certificate
+	tsaKey   *ecdsa.PrivateKey
+}
+
+// newTestCertificate returns a new certificate named `name`, issued by `parent` (self-signed if
+// nil), and its key.
+func newTestCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serial
+	template.Subject = pkix.Name{CommonName: name, Organization: []string{"UniPDF Test"}}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(24 * time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(data)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// newTestPKI returns a root certificate authority issuing a signer and a timestamp authority.
+func newTestPKI(t *testing.T) *testPKI {
+	pki := &testPKI{}
+	pki.caCert, pki.caKey = newTestCertificate(t, "Test Root CA", nil, nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	})
+	pki.leafCert, pki.leafKey = newTestCertificate(t, "Test Signer", pki.caCert, pki.caKey, &x509.Certificate{
+		KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	})
+	pki.tsaCert, pki.tsaKey = newTestCertificate(t, "Test TSA", pki.caCert, pki.caKey, &x509.Certificate{
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	return pki
+}
+
+// newTestTSA starts a timestamp server signing the time-stamp tokens with the TSA certificate of
+// `pki`.
+func newTestTSA(t *testing.T, pki *testPKI) *httptest.Server {
+	return httptest.NewServer(sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey))
+}
+
+// signPAdES signs the first page of the PDF file `inputPath` with `handler` and writes the
+// signed document to `outputPath`.
+func signPAdES(t *testing.T, inputPath, outputPath string, handler model.SignatureHandler) {
+	require.NoError(t, signField(t, inputPath, outputPath, handler, nil))
+}
+
+// signField signs the first page of the PDF file `inputPath` with `handler`, after applying
+// `configure` to the signature and its field if not nil, and writes the signed document to
+// `outputPath`. The error of the signature is returned.
+func signField(t *testing.T, inputPath, outputPath string, handler model.SignatureHandler,
+	configure func(sig *model.PdfSignature, field *model.PdfFieldSignature)) error {
+	f, err := os.Open(inputPath)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	signature := model.NewPdfSignature(handler)
+	signature.SetName("Test PAdES")
+	signature.SetReason("TestPAdES")
+	signature.SetDate(time.Now(), "")
+	require.NoError(t, signature.Initialize())
+
+	sigField := model.NewPdfFieldSignature(signature)
+	sigField.T = core.MakeString("PAdES Signature")
+	sigField.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0),
+		core.MakeInteger(0))
+	if configure != nil {
+		configure(signature, sigField)
+	}
+	if err := appender.Sign(1, sigField); err != nil {
+		return err
+	}
+	return appender.WriteToFile(outputPath)
+}
+
+// validatePAdES validates the signatures of the PDF file `path`.
+func validatePAdES(t *testing.T, path string) []model.SignatureValidationResult {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	handler, err := sighandler.NewEtsiPAdES(nil, nil, nil)
+	require.NoError(t, err)
+	handler2, err := sighandler.NewDocTimeStamp("", 0)
+	require.NoError(t, err)
+	res, err := reader.ValidateSignatures([]model.SignatureHandler{handler, handler2})
+	require.NoError(t, err)
+	for _, item := range res {
+		require.True(t, item.IsSigned)
+		require.True(t, item.IsVerified, item.Errors)
+	}
+	return res
+}
+
+func TestAppenderSignPAdES(t *testing.T) {
+	pki := newTestPKI(t)
+	tsa := newTestTSA(t, pki)
+	defer tsa.Close()
+
+	// B-B level.
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	signPAdES(t, testPdfFile1, tempFile("appender-sign-pades-b-b.pdf"), handler)
+	res := validatePAdES(t, tempFile("appender-sign-pades-b-b.pdf"))
+	require.Len(t, res, 1)
+	require.True(t, res[0].GeneralizedTime.IsZero())
+
+	// B-T level.
+	handler, err = sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain:              []*x509.Certificate{pki.caCert},
+		HashAlgorithm:      crypto.SHA384,
+		TimestampServerURL: tsa.URL,
+	})
+	require.NoError(t, err)
+	signPAdES(t, testPdfFile1, tempFile("appender-sign-pades-b-t.pdf"), handler)
+	res = validatePAdES(t, tempFile("appender-sign-pades-b-t.pdf"))
+	require.Len(t, res, 1)
+	require.False(t, res[0].GeneralizedTime.IsZero())
+
+	// Modified documents are not verified.
+	data, err := ioutil.ReadFile(tempFile("appender-sign-pades-b-t.pdf"))
+	require.NoError(t, err)
+	data = bytes.Replace(data, []byte("/Reason (TestPAdES)"), []byte("/Reason (TestPADES)"), 1)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	res, err = reader.ValidateSignatures([]model.SignatureHandler{handler})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.False(t, res[0].IsVerified)
+
+	// Reserved signature size too small.
+	handler, err = sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		SignatureSize: 64,
+	})
+	require.NoError(t, err)
+	f, err := os.Open(testPdfFile1)
+	require.NoError(t, err)
+	defer f.Close()
+	pdfReader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(pdfReader)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(handler)
+	require.NoError(t, signature.Initialize())
+	sigField := model.NewPdfFieldSignature(signature)
+	sigField.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0),
+		core.MakeInteger(0))
+	require.NoError(t, appender.Sign(1, sigField))
+	require.Error(t, appender.Write(ioutil.Discard))
+}
+
+func TestAppenderDSS(t *testing.T) {
+	pki := newTestPKI(t)
+	tsa := newTestTSA(t, pki)
+	defer tsa.Close()
+
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain:              []*x509.Certificate{pki.caCert},
+		TimestampServerURL: tsa.URL,
+	})
+	require.NoError(t, err)
+	signPAdES(t, testPdfFile1, tempFile("appender-pades-b-t.pdf"), handler)
+
+	// Validation material fetched by the caller.
+	ocspResp, err := ocsp.CreateResponse(pki.caCert, pki.caCert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: pki.leafCert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}, pki.caKey)
+	require.NoError(t, err)
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, pki.caCert, pki.caKey)
+	require.NoError(t, err)
+
+	// B-LT level: add the document security store, then B-LTA level: add a document timestamp
+	// covering it in the same revision.
+	f, err := os.Open(tempFile("appender-pades-b-t.pdf"))
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	dss, err := reader.GetDSS()
+	require.NoError(t, err)
+	require.Nil(t, dss)
+	dss = model.NewDSS()
+
+	var sig *model.PdfSignature
+	for _, field := range reader.AcroForm.AllFields() {
+		if sigField, ok := field.GetContext().(*model.PdfFieldSignature); ok && sigField.V != nil {
+			sig = sigField.V
+		}
+	}
+	require.NotNil(t, sig)
+	require.NoError(t, dss.AddValidationData(sig, nil, [][]byte{ocspResp}, [][]byte{crl}))
+	// Adding the same data again does not duplicate it.
+	require.NoError(t, dss.AddValidationData(sig, []*x509.Certificate{pki.leafCert}, nil, [][]byte{crl}))
+	appender.SetDSS(dss)
+
+	tsHandler, err := sighandler.NewDocTimeStamp(tsa.URL, crypto.SHA256)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(tsHandler)
+	require.NoError(t, signature.Initialize())
+	sigField := model.NewPdfFieldSignature(signature)
+	sigField.T = core.MakeString("Document Timestamp")
+	sigField.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0),
+		core.MakeInteger(0))
+	require.NoError(t, appender.Sign(1, sigField))
+	require.NoError(t, appender.WriteToFile(tempFile("appender-pades-b-lta.pdf")))
+
+	res := validatePAdES(t, tempFile("appender-pades-b-lta.pdf"))
+	require.Len(t, res, 2)
+
+	// The validation material and the document timestamp are allowed after the signature.
+	require.False(t, res[0].CoversWholeDocument)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.True(t, res[1].CoversWholeDocument)
+
+	// Check the document security store.
+	data, err := ioutil.ReadFile(tempFile("appender-pades-b-lta.pdf"))
+	require.NoError(t, err)
+	reader, err = model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	dss, err = reader.GetDSS()
+	require.NoError(t, err)
+	require.NotNil(t, dss)
+	require.Len(t, dss.Certs, 2)
+	require.Len(t, dss.OCSPs, 1)
+	require.Len(t, dss.CRLs, 1)
+
+	key, err := model.SignatureVRIKey(sig)
+	require.NoError(t, err)
+	require.Len(t, dss.VRI, 1)
+	vri := dss.VRI[key]
+	require.NotNil(t, vri)
+	require.Len(t, vri.Cert, 2)
+	require.Len(t, vri.OCSP, 1)
+	require.Len(t, vri.CRL, 1)
+	require.NotNil(t, vri.TU)
+
+	certData, err := core.DecodeStream(dss.Certs[0])
+	require.NoError(t, err)
+	require.Equal(t, pki.leafCert.Raw, certData)
+	ocspData, err := core.DecodeStream(dss.OCSPs[0])
+	require.NoError(t, err)
+	_, err = ocsp.ParseResponse(ocspData, pki.caCert)
+	require.NoError(t, err)
+}
@@ -16,6 +16,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/loxiouve/unipdf/v3/common"
@@ -378,6 +379,239 @@ func (p *PdfPage) GetMediaBox() (*PdfRectangle, error) {
 		return p.MediaBox, nil
 	}
 
+	rect, err := p.getParentBox(PdfPageBoxMedia)
+	if err != nil {
+		return nil, err
+	}
+	if rect == nil {
+		return nil, errors.New("media box not defined")
+	}
+
+	return rect, nil
+}
+
+// PdfPageBox represents a page boundary box (section 14.11.2 "Page Boundaries").
+type PdfPageBox int
+
+// Page boundary boxes.
+const (
+	PdfPageBoxMedia PdfPageBox = iota
+	PdfPageBoxCrop
+	PdfPageBoxBleed
+	PdfPageBoxTrim
+	PdfPageBoxArt
+)
+
+// String returns the name of the page dictionary entry of the box.
+func (box PdfPageBox) String() string {
+	switch box {
+	case PdfPageBoxMedia:
+		return "MediaBox"
+	case PdfPageBoxCrop:
+		return "CropBox"
+	case PdfPageBoxBleed:
+		return "BleedBox"
+	case PdfPageBoxTrim:
+		return "TrimBox"
+	case PdfPageBoxArt:
+		return "ArtBox"
+	}
+	return fmt.Sprintf("PdfPageBox(%d)", int(box))
+}
+
+// GetBox returns the effective value of the specified page boundary box.
+// Inheritance from the parent page tree nodes is applied for the MediaBox
+// and the CropBox. Boxes which are not defined get their default values:
+// the CropBox defaults to the MediaBox, while the BleedBox, TrimBox and
+// ArtBox default to the CropBox. All boxes are reduced to their intersection
+// with the MediaBox. The returned rectangle is expressed in default user
+// space units and does not take the page rotation into account.
+func (p *PdfPage) GetBox(box PdfPageBox) (*PdfRectangle, error) {
+	mediaBox, err := p.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	mbox := normalizeRectangle(*mediaBox)
+	if box == PdfPageBoxMedia {
+		return &mbox, nil
+	}
+
+	var rect *PdfRectangle
+	switch box {
+	case PdfPageBoxCrop:
+		rect = p.CropBox
+		if rect == nil {
+			if rect, err = p.getParentBox(PdfPageBoxCrop); err != nil {
+				return nil, err
+			}
+		}
+	case PdfPageBoxBleed:
+		rect = p.BleedBox
+	case PdfPageBoxTrim:
+		rect = p.TrimBox
+	case PdfPageBoxArt:
+		rect = p.ArtBox
+	default:
+		return nil, fmt.Errorf("invalid page box: %s", box)
+	}
+
+	if rect == nil {
+		if box == PdfPageBoxCrop {
+			return &mbox, nil
+		}
+		return p.GetBox(PdfPageBoxCrop)
+	}
+
+	clipped, ok := intersectRectangles(normalizeRectangle(*rect), mbox)
+	if !ok {
+		common.Log.Debug("WARN: %s outside of MediaBox. Using MediaBox", box)
+		return &mbox, nil
+	}
+	return &clipped, nil
+}
+
+// GetCropBox returns the effective CropBox of the page, which is the visible
+// region of the page. Defaults to the MediaBox.
+func (p *PdfPage) GetCropBox() (*PdfRectangle, error) {
+	return p.GetBox(PdfPageBoxCrop)
+}
+
+// GetBleedBox returns the effective BleedBox of the page, which is the region
+// to which the page contents are clipped in a production environment.
+// Defaults to the CropBox.
+func (p *PdfPage) GetBleedBox() (*PdfRectangle, error) {
+	return p.GetBox(PdfPageBoxBleed)
+}
+
+// GetTrimBox returns the effective TrimBox of the page, which represents the
+// intended dimensions of the finished page after trimming.
+// Defaults to the CropBox.
+func (p *PdfPage) GetTrimBox() (*PdfRectangle, error) {
+	return p.GetBox(PdfPageBoxTrim)
+}
+
+// GetArtBox returns the effective ArtBox of the page, which represents the
+// extent of the meaningful content of the page. Defaults to the CropBox.
+func (p *PdfPage) GetArtBox() (*PdfRectangle, error) {
+	return p.GetBox(PdfPageBoxArt)
+}
+
+// GetRotatedBox returns the effective value of the specified page boundary
+// box, as displayed after applying the page rotation. The rotated MediaBox
+// keeps the lower left corner of the unrotated MediaBox, while its width and
+// height are swapped for rotations of 90 and 270 degrees.
+func (p *PdfPage) GetRotatedBox(box PdfPageBox) (*PdfRectangle, error) {
+	rect, err := p.GetBox(box)
+	if err != nil {
+		return nil, err
+	}
+	mbox, err := p.GetBox(PdfPageBoxMedia)
+	if err != nil {
+		return nil, err
+	}
+	rotate, err := p.GetRotate()
+	if err != nil {
+		return nil, err
+	}
+
+	// Transform the corners of the box clockwise around the MediaBox.
+	transform := func(x, y float64) (float64, float64) {
+		switch rotate {
+		case 90:
+			return mbox.Llx + y - mbox.Lly, mbox.Lly + mbox.Urx - x
+		case 180:
+			return mbox.Llx + mbox.Urx - x, mbox.Lly + mbox.Ury - y
+		case 270:
+			return mbox.Llx + mbox.Ury - y, mbox.Lly + x - mbox.Llx
+		}
+		return x, y
+	}
+
+	x1, y1 := transform(rect.Llx, rect.Lly)
+	x2, y2 := transform(rect.Urx, rect.Ury)
+	rotated := normalizeRectangle(PdfRectangle{Llx: x1, Lly: y1, Urx: x2, Ury: y2})
+	return &rotated, nil
+}
+
+// SetBox sets the specified page boundary box. Setting a nil rectangle
+// removes the box from the page, so that its default value applies.
+func (p *PdfPage) SetBox(box PdfPageBox, rect *PdfRectangle) error {
+	if rect != nil {
+		r := normalizeRectangle(*rect)
+		if r.Width() == 0 || r.Height() == 0 {
+			return fmt.Errorf("invalid %s: zero area", box)
+		}
+		rect = &r
+	}
+
+	switch box {
+	case PdfPageBoxMedia:
+		p.MediaBox = rect
+	case PdfPageBoxCrop:
+		p.CropBox = rect
+	case PdfPageBoxBleed:
+		p.BleedBox = rect
+	case PdfPageBoxTrim:
+		p.TrimBox = rect
+	case PdfPageBoxArt:
+		p.ArtBox = rect
+	default:
+		return fmt.Errorf("invalid page box: %s", box)
+	}
+
+	return nil
+}
+
+// GetRotate returns the inheritable rotation of the page in degrees. The
+// returned value is normalized to one of 0, 90, 180 or 270.
+func (p *PdfPage) GetRotate() (int64, error) {
+	rotate := p.Rotate
+	if rotate == nil {
+		node := p.Parent
+		for node != nil {
+			dict, ok := core.GetDict(node)
+			if !ok {
+				return 0, errors.New("invalid parent objects dictionary")
+			}
+
+			if obj := dict.Get("Rotate"); obj != nil {
+				val, ok := core.GetIntVal(obj)
+				if !ok {
+					return 0, errors.New("invalid page Rotate object")
+				}
+				r := int64(val)
+				rotate = &r
+				break
+			}
+
+			node = dict.Get("Parent")
+		}
+	}
+	if rotate == nil {
+		return 0, nil
+	}
+
+	if *rotate%90 != 0 {
+		common.Log.Debug("ERROR: page rotation not a multiple of 90: %d", *rotate)
+		return 0, errors.New("invalid page rotation")
+	}
+	return ((*rotate % 360) + 360) % 360, nil
+}
+
+// SetRotate sets the rotation of the page in degrees. The angle must be a
+// multiple of 90.
+func (p *PdfPage) SetRotate(angle int64) error {
+	if angle%90 != 0 {
+		return errors.New("page rotation must be a multiple of 90")
+	}
+	angle = ((angle % 360) + 360) % 360
+	p.Rotate = &angle
+	return nil
+}
+
+// getParentBox searches for the specified inheritable box in the parent
+// nodes of the page. Returns nil if the box is not found.
+func (p *PdfPage) getParentBox(box PdfPageBox) (*PdfRectangle, error) {
 	node := p.Parent
 	for node != nil {
 		dict, ok := core.GetDict(node)
@@ -385,24 +619,45 @@ func (p *PdfPage) GetMediaBox() (*PdfRectangle, error) {
 			return nil, errors.New("invalid parent objects dictionary")
 		}
 
-		if obj := dict.Get("MediaBox"); obj != nil {
+		if obj := dict.Get(core.PdfObjectName(box.String())); obj != nil {
 			arr, ok := core.GetArray(obj)
 			if !ok {
-				return nil, errors.New("invalid media box")
-			}
-			rect, err := NewPdfRectangle(*arr)
-
-			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("invalid %s", box)
 			}
-
-			return rect, nil
+			return NewPdfRectangle(*arr)
 		}
 
 		node = dict.Get("Parent")
 	}
 
-	return nil, errors.New("media box not defined")
+	return nil, nil
+}
+
+// normalizeRectangle returns a copy of `rect` having its lower left corner
+// below and to the left of its upper right corner.
+func normalizeRectangle(rect PdfRectangle) PdfRectangle {
+	if rect.Llx > rect.Urx {
+		rect.Llx, rect.Urx = rect.Urx, rect.Llx
+	}
+	if rect.Lly > rect.Ury {
+		rect.Lly, rect.Ury = rect.Ury, rect.Lly
+	}
+	return rect
+}
+
+// intersectRectangles returns the intersection of the normalized rectangles
+// `r1` and `r2`. The returned flag is false if they do not overlap.
+func intersectRectangles(r1, r2 PdfRectangle) (PdfRectangle, bool) {
+	r := PdfRectangle{
+		Llx: math.Max(r1.Llx, r2.Llx),
+		Lly: math.Max(r1.Lly, r2.Lly),
+		Urx: math.Min(r1.Urx, r2.Urx),
+		Ury: math.Min(r1.Ury, r2.Ury),
+	}
+	if r.Llx >= r.Urx || r.Lly >= r.Ury {
+		return PdfRectangle{}, false
+	}
+	return r, true
 }
 
 // getParentResources searches for page resources in the parent nodes of the page.
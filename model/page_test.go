@@ -9,6 +9,8 @@ import (
 	"io"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/loxiouve/unipdf/v3/common"
 	"github.com/loxiouve/unipdf/v3/core"
 )
@@ -382,3 +384,55 @@ func TestRect(t *testing.T) {
 		return
 	}
 }
+
+func TestPageBoxes(t *testing.T) {
+	parent := core.MakeDict()
+	parent.Set("MediaBox", core.MakeArrayFromIntegers([]int{0, 0, 600, 800}))
+	parent.Set("CropBox", core.MakeArrayFromIntegers([]int{10, 10, 590, 790}))
+	parent.Set("Rotate", core.MakeInteger(-90))
+
+	page := NewPdfPage()
+	page.Parent = parent
+	page.TrimBox = &PdfRectangle{Llx: 650, Lly: 20, Urx: 20, Ury: 700}
+
+	mbox, err := page.GetBox(PdfPageBoxMedia)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 0, Lly: 0, Urx: 600, Ury: 800}, *mbox)
+
+	// CropBox is inherited.
+	cbox, err := page.GetCropBox()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 10, Lly: 10, Urx: 590, Ury: 790}, *cbox)
+
+	// BleedBox defaults to the CropBox.
+	bbox, err := page.GetBleedBox()
+	require.NoError(t, err)
+	require.Equal(t, *cbox, *bbox)
+
+	// TrimBox is normalized and reduced to the MediaBox.
+	tbox, err := page.GetTrimBox()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 20, Lly: 20, Urx: 600, Ury: 700}, *tbox)
+
+	rotate, err := page.GetRotate()
+	require.NoError(t, err)
+	require.Equal(t, int64(270), rotate)
+
+	rbox, err := page.GetRotatedBox(PdfPageBoxMedia)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 0, Lly: 0, Urx: 800, Ury: 600}, *rbox)
+
+	require.NoError(t, page.SetRotate(90))
+	rbox, err = page.GetRotatedBox(PdfPageBoxTrim)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 20, Lly: 0, Urx: 700, Ury: 580}, *rbox)
+
+	// Setting the CropBox on the page overrides the inherited value.
+	require.NoError(t, page.SetBox(PdfPageBoxCrop, &PdfRectangle{Llx: 50, Lly: 50, Urx: 100, Ury: 100}))
+	bbox, err = page.GetBleedBox()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Llx: 50, Lly: 50, Urx: 100, Ury: 100}, *bbox)
+
+	require.Error(t, page.SetBox(PdfPageBoxArt, &PdfRectangle{Llx: 50, Lly: 50, Urx: 50, Ury: 100}))
+	require.Error(t, page.SetRotate(45))
+}
@@ -440,7 +440,8 @@ func (r *PdfReader) GetOutlinesFlattened() ([]*PdfOutlineTreeNode, []string, err
 }
 
 // GetOutlines returns a high-level Outline object, based on the outline tree
-// of the reader.
+// of the reader. The named destinations of the outline items are returned as
+// go to actions.
 func (r *PdfReader) GetOutlines() (*Outline, error) {
 	if r == nil {
 		return nil, errors.New("cannot create outline from nil reader")
@@ -466,12 +467,29 @@ func (r *PdfReader) GetOutlines() (*Outline, error) {
 		if item, ok := node.context.(*PdfOutlineItem); ok {
 			// Search for outline destination object.
 			destObj := item.Dest
+			var action *PdfAction
 			if (destObj == nil || core.IsNullObject(destObj)) && item.A != nil {
+				destObj = nil
 				if actionDict, ok := core.GetDict(item.A); ok {
-					destObj, _ = core.GetArray(actionDict.Get("D"))
+					if destArr, ok := core.GetArray(actionDict.Get("D")); ok {
+						destObj = destArr
+					}
+				}
+				if destObj == nil {
+					// Keep the actions other than go to explicit destinations.
+					action = r.loadOutlineAction(item.A)
 				}
 			}
 
+			// Named destinations are kept as go to actions.
+			switch name := core.TraceToDirectObject(destObj).(type) {
+			case *core.PdfObjectName, *core.PdfObjectString:
+				gotoAction := NewPdfActionGoTo()
+				gotoAction.D = name
+				action = gotoAction.PdfAction
+				destObj = nil
+			}
+
 			// Parse outline destination object.
 			var dest OutlineDest
 			if destObj != nil && !core.IsNullObject(destObj) {
@@ -483,6 +501,18 @@ func (r *PdfReader) GetOutlines() (*Outline, error) {
 			}
 
 			entry = NewOutlineItem(item.Title.Decoded(), dest)
+			entry.Action = action
+			if item.Count != nil && *item.Count < 0 {
+				entry.Closed = true
+			}
+			if colorArr, ok := core.GetArray(item.C); ok && colorArr.Len() == 3 {
+				if values, err := colorArr.ToFloat64Array(); err == nil {
+					entry.Color = NewPdfColorDeviceRGB(values[0], values[1], values[2])
+				}
+			}
+			if flags, ok := core.GetIntVal(item.F); ok {
+				entry.Style = OutlineItemStyle(flags)
+			}
 			*entries = append(*entries, entry)
 
 			// Traverse next node.
@@ -507,6 +537,17 @@ func (r *PdfReader) GetOutlines() (*Outline, error) {
 	return outline, nil
 }
 
+// loadOutlineAction loads the action `obj` of an outline item. Returns nil if
+// the action is invalid.
+func (r *PdfReader) loadOutlineAction(obj core.PdfObject) *PdfAction {
+	action, err := r.loadAction(obj)
+	if err != nil {
+		common.Log.Debug("WARN: could not load outline action: %v", err)
+		return nil
+	}
+	return action
+}
+
 // AcroFormRepairOptions contains options for rebuilding the AcroForm.
 type AcroFormRepairOptions struct {
 }
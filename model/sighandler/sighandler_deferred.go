@@ -0,0 +1,242 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// DeferredOptions contains the options of the deferred signature handler.
+type DeferredOptions struct {
+	// SubFilter is the signature sub filter: ETSI.CAdES.detached (default) or
+	// adbe.pkcs7.detached.
+	SubFilter string
+
+	// Chain contains the certificates of the chain of the signer certificate, embedded in the
+	// signature containers built by the handler.
+	Chain []*x509.Certificate
+
+	// HashAlgorithm is the digest algorithm of the signature: crypto.SHA256, crypto.SHA384 or
+	// crypto.SHA512. By default crypto.SHA256, or crypto.SHA512 for Ed25519 keys.
+	HashAlgorithm crypto.Hash
+
+	// PSS selects RSASSA-PSS signatures for RSA keys, instead of PKCS #1 v1.5 signatures.
+	PSS bool
+
+	// TimestampClient is the client requesting the signature timestamps of the signature
+	// containers built by the handler. No timestamp is added if nil.
+	TimestampClient TimestampClient
+
+	// SignatureSize is the size reserved for the signature contents, in bytes. 8192 by default.
+	// The reserved size cannot be changed once the document is written, and must be large
+	// enough for the signature container injected afterwards.
+	SignatureSize int
+}
+
+// DeferredSigner is a signature handler for the two-phase signing of documents with external
+// signers, such as remote signing services.
+//
+// In the first phase, the document is signed by an appender with the handler, which does not
+// compute any signature: the signature contents are reserved and left empty. The digest of the
+// signed byte range, available through Digest once the document is written, is signed
+// externally. The external signer either produces the whole signature container (CMS
+// SignedData) of the digest, or only signs the signed attributes of the container built by the
+// handler, whose digest is available through SignedAttributesDigest. In the latter case, the
+// container is built by Container from the signature value.
+//
+// In the second phase, the signature container is written into the reserved contents of the
+// document with model.InjectSignature, without changing the signed bytes of the document.
+//
+// A DeferredSigner is used to sign one signature field of one document.
+type DeferredSigner struct {
+	certificate *x509.Certificate
+	opts        DeferredOptions
+	digest      []byte
+	sig         *model.PdfSignature
+}
+
+// NewDeferredSigner creates a new deferred signature handler for the signer `certificate`. The
+// certificate may be nil if the signature container is produced externally, and the opts
+// parameter may be nil for the default options.
+func NewDeferredSigner(certificate *x509.Certificate, opts *DeferredOptions) (*DeferredSigner, error) {
+	handler := &DeferredSigner{
+		certificate: certificate,
+	}
+	if opts != nil {
+		handler.opts = *opts
+	}
+
+	switch handler.opts.SubFilter {
+	case "":
+		handler.opts.SubFilter = "ETSI.CAdES.detached"
+	case "ETSI.CAdES.detached", "adbe.pkcs7.detached":
+	default:
+		return nil, fmt.Errorf("unsupported signature sub filter %s", handler.opts.SubFilter)
+	}
+
+	var pub crypto.PublicKey
+	if certificate != nil {
+		pub = certificate.PublicKey
+	}
+	hash, err := signerHashAlgorithm(pub, handler.opts.HashAlgorithm, handler.opts.PSS)
+	if err != nil {
+		return nil, err
+	}
+	handler.opts.HashAlgorithm = hash
+	if handler.opts.SignatureSize <= 0 {
+		handler.opts.SignatureSize = 8192
+	}
+	return handler, nil
+}
+
+// InitSignature initialises the PdfSignature.
+func (a *DeferredSigner) InitSignature(sig *model.PdfSignature) error {
+	if a.sig != nil {
+		return errors.New("deferred signer already used")
+	}
+
+	// The handler is not copied as it keeps the state of the signature.
+	sig.Handler = a
+	sig.Filter = core.MakeName("Adobe.PPKLite")
+	sig.SubFilter = core.MakeName(a.opts.SubFilter)
+	sig.Contents = core.MakeHexString(string(make([]byte, a.opts.SignatureSize)))
+	return nil
+}
+
+// NewDigest creates a new digest.
+func (a *DeferredSigner) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
+	return bytes.NewBuffer(nil), nil
+}
+
+// Validate is not supported by the deferred signature handler, the signatures are validated
+// by the handler of their sub filter.
+func (a *DeferredSigner) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	return model.SignatureValidationResult{}, errors.New("deferred signer cannot validate signatures")
+}
+
+// Sign computes the digest of the signed byte range. The signature contents are left empty.
+func (a *DeferredSigner) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	buffer := digest.(*bytes.Buffer)
+	h := a.opts.HashAlgorithm.New()
+	h.Write(buffer.Bytes())
+	a.digest = h.Sum(nil)
+	a.sig = sig
+	return nil
+}
+
+// IsApplicable returns false, the deferred signature handler is not used for validation.
+func (a *DeferredSigner) IsApplicable(sig *model.PdfSignature) bool {
+	return false
+}
+
+// Digest returns the digest of the signed byte range of the document, computed with the hash
+// algorithm of the handler. It is nil until the document is written.
+func (a *DeferredSigner) Digest() []byte {
+	return a.digest
+}
+
+// SetDigest sets the digest of the signed byte range of a document written previously, which
+// allows building the signature container with another handler, for example after a restart.
+// The options of the handler must be the same as the ones of the handler of the first phase.
+func (a *DeferredSigner) SetDigest(digest []byte) error {
+	if len(digest) != a.opts.HashAlgorithm.Size() {
+		return fmt.Errorf("invalid digest length %d", len(digest))
+	}
+	a.digest = digest
+	return nil
+}
+
+// ByteRange returns the signed byte range of the document, which locates the reserved
+// signature contents. It is nil until the document is written.
+func (a *DeferredSigner) ByteRange() []int64 {
+	if a.sig == nil || a.sig.ByteRange == nil {
+		return nil
+	}
+	byteRange, err := a.sig.ByteRange.ToInt64Slice()
+	if err != nil {
+		return nil
+	}
+	return byteRange
+}
+
+// HashAlgorithm returns the digest algorithm of the signature.
+func (a *DeferredSigner) HashAlgorithm() crypto.Hash {
+	return a.opts.HashAlgorithm
+}
+
+// signedData returns the signature container of the digest, without signature value.
+func (a *DeferredSigner) signedData() (*cms.SignedData, error) {
+	if a.certificate == nil {
+		return nil, errors.New("certificate must not be nil")
+	}
+	if a.digest == nil {
+		return nil, errors.New("document digest not available")
+	}
+	signedData, err := cms.NewSignedData(a.certificate, a.opts.Chain, a.opts.HashAlgorithm, a.digest)
+	if err != nil {
+		return nil, err
+	}
+	if a.opts.SubFilter == "ETSI.CAdES.detached" {
+		if err := signedData.AddSigningCertificateV2(); err != nil {
+			return nil, err
+		}
+	}
+	return signedData, nil
+}
+
+// SignedAttributes returns the DER encoding of the signed attributes of the signature
+// container, which is the data to be signed by signers requiring the whole message, such as
+// Ed25519 signers. The signed attributes are deterministic: they only depend on the
+// certificate, the options and the document digest.
+func (a *DeferredSigner) SignedAttributes() ([]byte, error) {
+	signedData, err := a.signedData()
+	if err != nil {
+		return nil, err
+	}
+	return signedData.SignedAttributesData()
+}
+
+// SignedAttributesDigest returns the digest of the signed attributes of the signature
+// container, which is the digest to be signed by the external signer.
+func (a *DeferredSigner) SignedAttributesDigest() ([]byte, error) {
+	signedData, err := a.signedData()
+	if err != nil {
+		return nil, err
+	}
+	return signedData.SignedAttributesDigest()
+}
+
+// Container returns the signature container with the signature value `signature` of the
+// signed attributes, computed externally, to be injected in the document. The signature
+// timestamp is added to the container if the handler has a timestamp client.
+func (a *DeferredSigner) Container(signature []byte) ([]byte, error) {
+	signedData, err := a.signedData()
+	if err != nil {
+		return nil, err
+	}
+	if err := signedData.SetSignature(signature, a.opts.PSS); err != nil {
+		return nil, err
+	}
+	if err := addSignatureTimestamp(signedData, a.opts.TimestampClient); err != nil {
+		return nil, err
+	}
+	container, err := signedData.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if len(container) > a.opts.SignatureSize {
+		return nil, fmt.Errorf("signature size %d exceeds the reserved size %d", len(container), a.opts.SignatureSize)
+	}
+	return container, nil
+}
@@ -0,0 +1,155 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// PAdESOptions contains the options of the PAdES signature handlers.
+type PAdESOptions struct {
+	// Chain contains the certificates of the chain of the signer certificate, embedded in the
+	// signature.
+	Chain []*x509.Certificate
+
+	// HashAlgorithm is the digest algorithm of the signature: crypto.SHA256, crypto.SHA384 or
+	// crypto.SHA512. By default crypto.SHA256, or crypto.SHA512 for Ed25519 keys.
+	HashAlgorithm crypto.Hash
+
+	// PSS selects RSASSA-PSS signatures for RSA keys, instead of PKCS #1 v1.5 signatures.
+	PSS bool
+
+	// TimestampServerURL is the URL of the timestamp server used to add a signature timestamp
+	// to the signature (PAdES B-T level). No timestamp is added if empty (PAdES B-B level).
+	TimestampServerURL string
+
+	// TimestampClient is the client requesting the signature timestamps, used instead of an
+	// HTTP client of the timestamp server URL if set.
+	TimestampClient TimestampClient
+
+	// TimestampRoots contains the trusted roots of the certificate chains of the TSAs, which
+	// are verified on validation. The roots of the system are used if empty.
+	TimestampRoots []*x509.Certificate
+
+	// SignatureSize is the size reserved for the signature contents, in bytes. By default 8192,
+	// or 16384 if a signature timestamp is added.
+	SignatureSize int
+}
+
+// etsiPAdES is the ETSI.CAdES.detached signature handler.
+type etsiPAdES struct {
+	signer      crypto.Signer
+	certificate *x509.Certificate
+	opts        PAdESOptions
+}
+
+// NewEtsiPAdES creates a new Adobe.PPKLite ETSI.CAdES.detached signature handler, which produces
+// PAdES baseline signatures of the B-B level, or of the B-T level if a timestamp server URL is
+// specified in the options. The `signer` parameter is the private key of the `certificate`,
+// of one of the key types supported by NewAdobePKCS7DetachedSigner.
+// The signer and certificate parameters may be nil for the signature validation, and the opts
+// parameter may be nil for the default options.
+func NewEtsiPAdES(signer crypto.Signer, certificate *x509.Certificate, opts *PAdESOptions) (model.SignatureHandler, error) {
+	handler := &etsiPAdES{
+		signer:      signer,
+		certificate: certificate,
+	}
+	if opts != nil {
+		handler.opts = *opts
+	}
+
+	var pub crypto.PublicKey
+	if signer != nil {
+		pub = signer.Public()
+	}
+	hash, err := signerHashAlgorithm(pub, handler.opts.HashAlgorithm, handler.opts.PSS)
+	if err != nil {
+		return nil, err
+	}
+	handler.opts.HashAlgorithm = hash
+	if handler.opts.TimestampClient == nil && handler.opts.TimestampServerURL != "" {
+		handler.opts.TimestampClient = NewHTTPTimestampClient(handler.opts.TimestampServerURL)
+	}
+	if handler.opts.SignatureSize <= 0 {
+		handler.opts.SignatureSize = 8192
+		if handler.opts.TimestampClient != nil {
+			handler.opts.SignatureSize = 16384
+		}
+	}
+	return handler, nil
+}
+
+// InitSignature initialises the PdfSignature.
+func (a *etsiPAdES) InitSignature(sig *model.PdfSignature) error {
+	if a.certificate == nil {
+		return errors.New("certificate must not be nil")
+	}
+	if a.signer == nil {
+		return errors.New("signer must not be nil")
+	}
+
+	handler := *a
+	sig.Handler = &handler
+	sig.Filter = core.MakeName("Adobe.PPKLite")
+	sig.SubFilter = core.MakeName("ETSI.CAdES.detached")
+
+	// The signature is computed once the document is written.
+	sig.Contents = core.MakeHexString(string(make([]byte, a.opts.SignatureSize)))
+	return nil
+}
+
+// NewDigest creates a new digest.
+func (a *etsiPAdES) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
+	return bytes.NewBuffer(nil), nil
+}
+
+// Validate validates PdfSignature.
+func (a *etsiPAdES) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	return validateCMS(sig, digest, true, a.opts.TimestampRoots)
+}
+
+// Sign sets the Contents fields.
+func (a *etsiPAdES) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	buffer := digest.(*bytes.Buffer)
+	h := a.opts.HashAlgorithm.New()
+	h.Write(buffer.Bytes())
+
+	signedData, err := cms.NewSignedData(a.certificate, a.opts.Chain, a.opts.HashAlgorithm, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if err := signedData.AddSigningCertificateV2(); err != nil {
+		return err
+	}
+	if err := signedData.Sign(a.signer, a.opts.PSS); err != nil {
+		return err
+	}
+
+	if err := addSignatureTimestamp(signedData, a.opts.TimestampClient); err != nil {
+		return err
+	}
+
+	signature, err := signedData.Marshal()
+	if err != nil {
+		return err
+	}
+	return setSignatureContents(sig, signature, a.opts.SignatureSize)
+}
+
+// IsApplicable returns true if the signature handler is applicable for the PdfSignature.
+func (a *etsiPAdES) IsApplicable(sig *model.PdfSignature) bool {
+	if sig == nil || sig.Filter == nil || sig.SubFilter == nil {
+		return false
+	}
+	return (*sig.Filter == "Adobe.PPKMS" || *sig.Filter == "Adobe.PPKLite") && *sig.SubFilter == "ETSI.CAdES.detached"
+}
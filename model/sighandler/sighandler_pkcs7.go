@@ -9,12 +9,14 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 
 	"github.com/unidoc/pkcs7"
 
 	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
 	"github.com/loxiouve/unipdf/v3/model"
 )
 
@@ -61,7 +63,6 @@ func (a *adobePKCS7Detached) InitSignature(sig *model.PdfSignature) error {
 	sig.Handler = &handler
 	sig.Filter = core.MakeName("Adobe.PPKLite")
 	sig.SubFilter = core.MakeName("adbe.pkcs7.detached")
-	sig.Reference = nil
 
 	digest, err := handler.NewDigest(sig)
 	if err != nil {
@@ -109,6 +110,8 @@ func (a *adobePKCS7Detached) NewDigest(sig *model.PdfSignature) (model.Hasher, e
 }
 
 // Validate validates PdfSignature.
+// The signatures of algorithms not supported by the pkcs7 package, such as RSASSA-PSS and
+// Ed25519, are validated as the signatures of NewAdobePKCS7DetachedSigner.
 func (a *adobePKCS7Detached) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
 	signed := sig.Contents.Bytes()
 	p7, err := pkcs7.Parse(signed)
@@ -119,6 +122,9 @@ func (a *adobePKCS7Detached) Validate(sig *model.PdfSignature, digest model.Hash
 	buffer := digest.(*bytes.Buffer)
 	p7.Content = buffer.Bytes()
 	if err = p7.Verify(); err != nil {
+		if len(p7.Signers) == 1 && isUnsupportedPKCS7Algorithm(p7.Signers[0].DigestEncryptionAlgorithm.Algorithm) {
+			return validateCMS(sig, digest, false, nil)
+		}
 		return model.SignatureValidationResult{}, err
 	}
 
@@ -128,6 +134,12 @@ func (a *adobePKCS7Detached) Validate(sig *model.PdfSignature, digest model.Hash
 	}, nil
 }
 
+// isUnsupportedPKCS7Algorithm returns true if the signature algorithm `oid` of a signer info is
+// not supported by the pkcs7 package.
+func isUnsupportedPKCS7Algorithm(oid asn1.ObjectIdentifier) bool {
+	return oid.Equal(cms.OIDSignatureRSAPSS) || oid.Equal(cms.OIDSignatureEd25519)
+}
+
 // Sign sets the Contents fields.
 func (a *adobePKCS7Detached) Sign(sig *model.PdfSignature, digest model.Hasher) error {
 	if a.emptySignature {
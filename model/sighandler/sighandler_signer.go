@@ -0,0 +1,263 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// SignerOptions contains the options of the signature handlers based on a crypto.Signer.
+type SignerOptions struct {
+	// Chain contains the certificates of the chain of the signer certificate, embedded in the
+	// signature.
+	Chain []*x509.Certificate
+
+	// HashAlgorithm is the digest algorithm of the signature: crypto.SHA256, crypto.SHA384 or
+	// crypto.SHA512. By default crypto.SHA256, or crypto.SHA512 for Ed25519 keys, which only
+	// support SHA-512.
+	HashAlgorithm crypto.Hash
+
+	// PSS selects RSASSA-PSS signatures for RSA keys, instead of PKCS #1 v1.5 signatures.
+	PSS bool
+
+	// TimestampClient is the client requesting the signature timestamps. No timestamp is added
+	// to the signatures if nil.
+	TimestampClient TimestampClient
+
+	// TimestampRoots contains the trusted roots of the certificate chains of the TSAs, which
+	// are verified on validation. The roots of the system are used if empty.
+	TimestampRoots []*x509.Certificate
+
+	// SignatureSize is the size reserved for the signature contents, in bytes. By default 8192,
+	// or 16384 if a signature timestamp is added.
+	SignatureSize int
+}
+
+// signerHashAlgorithm returns the digest algorithm of the signatures made with the public key
+// `pub` of a signer, which may be nil for the signature validation. An error is returned if
+// `hash` is not allowed, or if the key type is not supported.
+func signerHashAlgorithm(pub crypto.PublicKey, hash crypto.Hash, pss bool) (crypto.Hash, error) {
+	if hash == 0 {
+		hash = crypto.SHA256
+		if _, ok := pub.(ed25519.PublicKey); ok {
+			hash = crypto.SHA512
+		}
+	}
+	switch hash {
+	case crypto.SHA256, crypto.SHA384, crypto.SHA512:
+	default:
+		return 0, fmt.Errorf("digest algorithm %v not allowed", hash)
+	}
+	if pub == nil {
+		return hash, nil
+	}
+
+	if _, ok := pub.(*rsa.PublicKey); !ok && pss {
+		return 0, errors.New("RSA-PSS requires an RSA key")
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return 0, fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		// The message digest of Ed25519 signatures is SHA-512 (RFC 8419).
+		if hash != crypto.SHA512 {
+			return 0, errors.New("Ed25519 signatures require the SHA-512 digest algorithm")
+		}
+	default:
+		return 0, fmt.Errorf("unsupported signer key type %T", pub)
+	}
+	return hash, nil
+}
+
+// adobePKCS7Signer is the adbe.pkcs7.detached signature handler based on a crypto.Signer.
+type adobePKCS7Signer struct {
+	signer      crypto.Signer
+	certificate *x509.Certificate
+	opts        SignerOptions
+}
+
+// NewAdobePKCS7DetachedSigner creates a new Adobe.PPKLite adbe.pkcs7.detached signature
+// handler, which signs with `signer`, the private key of the `certificate`. The signer may be
+// backed by a hardware security module or a smart card, the key never being exported.
+// RSA (PKCS #1 v1.5 or RSASSA-PSS), ECDSA (P-256, P-384 and P-521) and Ed25519 keys are
+// supported. The signer and certificate parameters may be nil for the signature validation,
+// and the opts parameter may be nil for the default options.
+func NewAdobePKCS7DetachedSigner(signer crypto.Signer, certificate *x509.Certificate, opts *SignerOptions) (model.SignatureHandler, error) {
+	handler := &adobePKCS7Signer{
+		signer:      signer,
+		certificate: certificate,
+	}
+	if opts != nil {
+		handler.opts = *opts
+	}
+
+	var pub crypto.PublicKey
+	if signer != nil {
+		pub = signer.Public()
+	}
+	hash, err := signerHashAlgorithm(pub, handler.opts.HashAlgorithm, handler.opts.PSS)
+	if err != nil {
+		return nil, err
+	}
+	handler.opts.HashAlgorithm = hash
+	if handler.opts.SignatureSize <= 0 {
+		handler.opts.SignatureSize = 8192
+		if handler.opts.TimestampClient != nil {
+			handler.opts.SignatureSize = 16384
+		}
+	}
+	return handler, nil
+}
+
+// InitSignature initialises the PdfSignature.
+func (a *adobePKCS7Signer) InitSignature(sig *model.PdfSignature) error {
+	if a.certificate == nil {
+		return errors.New("certificate must not be nil")
+	}
+	if a.signer == nil {
+		return errors.New("signer must not be nil")
+	}
+
+	handler := *a
+	sig.Handler = &handler
+	sig.Filter = core.MakeName("Adobe.PPKLite")
+	sig.SubFilter = core.MakeName("adbe.pkcs7.detached")
+
+	// The signature is computed once the document is written.
+	sig.Contents = core.MakeHexString(string(make([]byte, a.opts.SignatureSize)))
+	return nil
+}
+
+// NewDigest creates a new digest.
+func (a *adobePKCS7Signer) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
+	return bytes.NewBuffer(nil), nil
+}
+
+// Validate validates PdfSignature.
+func (a *adobePKCS7Signer) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	return validateCMS(sig, digest, false, a.opts.TimestampRoots)
+}
+
+// Sign sets the Contents fields.
+func (a *adobePKCS7Signer) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	buffer := digest.(*bytes.Buffer)
+	h := a.opts.HashAlgorithm.New()
+	h.Write(buffer.Bytes())
+
+	signedData, err := cms.NewSignedData(a.certificate, a.opts.Chain, a.opts.HashAlgorithm, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if err := signedData.Sign(a.signer, a.opts.PSS); err != nil {
+		return err
+	}
+	if err := addSignatureTimestamp(signedData, a.opts.TimestampClient); err != nil {
+		return err
+	}
+	signature, err := signedData.Marshal()
+	if err != nil {
+		return err
+	}
+	return setSignatureContents(sig, signature, a.opts.SignatureSize)
+}
+
+// IsApplicable returns true if the signature handler is applicable for the PdfSignature.
+func (a *adobePKCS7Signer) IsApplicable(sig *model.PdfSignature) bool {
+	if sig == nil || sig.Filter == nil || sig.SubFilter == nil {
+		return false
+	}
+	return (*sig.Filter == "Adobe.PPKMS" || *sig.Filter == "Adobe.PPKLite") && *sig.SubFilter == "adbe.pkcs7.detached"
+}
+
+// setSignatureContents sets the contents of `sig` to `signature`, padded to the reserved size
+// `size`.
+func setSignatureContents(sig *model.PdfSignature, signature []byte, size int) error {
+	if len(signature) > size {
+		return fmt.Errorf("signature size %d exceeds the reserved size %d", len(signature), size)
+	}
+	data := make([]byte, size)
+	copy(data, signature)
+	sig.Contents = core.MakeHexString(string(data))
+	return nil
+}
+
+// addSignatureTimestamp adds the signature timestamp, the time-stamp token of the signature
+// value requested with `client`, to the unsigned attributes of `signedData`. No timestamp is
+// added if the client is nil.
+func addSignatureTimestamp(signedData *cms.SignedData, client TimestampClient) error {
+	if client == nil {
+		return nil
+	}
+	h := signedData.Hash.New()
+	h.Write(signedData.Signature())
+	token, err := client.Timestamp(signedData.Hash, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	return signedData.AddUnsignedAttribute(cms.OIDAttributeTimestamp, asn1.RawValue{FullBytes: token})
+}
+
+// validateCMS validates the CMS signature of `sig`, computed over the data of `digest`.
+// The signing certificate attribute is required if `requireSigningCertificate` is true.
+// The signature timestamp, if any, is validated as well, and the certificate chain of its TSA
+// is verified with the trusted roots `timestampRoots`, or the roots of the system if empty.
+func validateCMS(sig *model.PdfSignature, digest model.Hasher, requireSigningCertificate bool,
+	timestampRoots []*x509.Certificate) (model.SignatureValidationResult, error) {
+	signedData, err := cms.Parse(sig.Contents.Bytes())
+	if err != nil {
+		return model.SignatureValidationResult{}, err
+	}
+
+	res := model.SignatureValidationResult{IsSigned: true}
+	buffer := digest.(*bytes.Buffer)
+	if err := signedData.Verify(buffer.Bytes()); err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		return res, nil
+	}
+	if requireSigningCertificate && !signedData.HasSigningCertificateV2() {
+		res.Errors = append(res.Errors, "signing certificate attribute not found")
+		return res, nil
+	}
+	res.IsVerified = true
+
+	// Signature timestamp.
+	if token, ok := signedData.TimestampToken(); ok {
+		_, info, err := cms.ParseTimestampToken(token)
+		if err == nil {
+			err = info.VerifyImprint(signedData.Signature())
+		}
+		if err != nil {
+			res.IsVerified = false
+			res.Errors = append(res.Errors, fmt.Sprintf("invalid signature timestamp: %v", err))
+			return res, nil
+		}
+		res.GeneralizedTime = info.GenTime
+		if err := verifyTimestampCertificate(token, timestampRoots); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("timestamp certificate chain: %v", err))
+		} else {
+			res.IsTimestampTrusted = true
+		}
+	}
+	return res, nil
+}
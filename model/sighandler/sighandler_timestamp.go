@@ -14,36 +14,71 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"time"
 
 	"github.com/loxiouve/unipdf/v3/core"
 	"github.com/loxiouve/unipdf/v3/model"
 	"github.com/unidoc/pkcs7"
-	"github.com/unidoc/timestamp"
 )
 
 // docTimeStamp DocTimeStamp signature handler.
 type docTimeStamp struct {
-	timestampServerURL string
-	hashAlgorithm      crypto.Hash
+	client         TimestampClient
+	hashAlgorithm  crypto.Hash
+	timestampRoots []*x509.Certificate
+
+	// Size of the signature contents, reserved on initialization.
+	signatureSize int
+}
+
+// DocTimeStampOpts contains the options of the DocTimeStamp signature handler.
+type DocTimeStampOpts struct {
+	// Client is the client requesting the time-stamp tokens. It may be nil for the signature
+	// validation.
+	Client TimestampClient
+
+	// HashAlgorithm is the digest algorithm of the time-stamp requests: crypto.SHA1,
+	// crypto.SHA256, crypto.SHA384 or crypto.SHA512.
+	HashAlgorithm crypto.Hash
+
+	// TimestampRoots contains the trusted roots of the certificate chains of the TSAs, which
+	// are verified on validation. The roots of the system are used if empty.
+	TimestampRoots []*x509.Certificate
 }
 
 // NewDocTimeStamp creates a new DocTimeStamp signature handler.
 // The timestampServerURL parameter can be empty string for the signature validation.
 // The hashAlgorithm parameter can be crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512.
 func NewDocTimeStamp(timestampServerURL string, hashAlgorithm crypto.Hash) (model.SignatureHandler, error) {
+	opts := &DocTimeStampOpts{HashAlgorithm: hashAlgorithm}
+	if timestampServerURL != "" {
+		opts.Client = NewHTTPTimestampClient(timestampServerURL)
+	}
+	return NewDocTimeStampWithOpts(opts)
+}
+
+// NewDocTimeStampWithOpts creates a new DocTimeStamp signature handler with the options
+// `opts`, which requests the time-stamp tokens with the timestamp client of the options.
+func NewDocTimeStampWithOpts(opts *DocTimeStampOpts) (model.SignatureHandler, error) {
+	if opts == nil {
+		opts = &DocTimeStampOpts{}
+	}
 	return &docTimeStamp{
-		timestampServerURL: timestampServerURL,
-		hashAlgorithm:      hashAlgorithm,
+		client:         opts.Client,
+		hashAlgorithm:  opts.HashAlgorithm,
+		timestampRoots: opts.TimestampRoots,
 	}, nil
 }
 
 // InitSignature initialises the PdfSignature.
 func (a *docTimeStamp) InitSignature(sig *model.PdfSignature) error {
+	if a.client == nil {
+		return errors.New("timestamp client must not be nil")
+	}
+
 	handler := *a
 	sig.Handler = &handler
+	sig.Type = core.MakeName("DocTimeStamp")
 	sig.Filter = core.MakeName("Adobe.PPKLite")
 	sig.SubFilter = core.MakeName("ETSI.RFC3161")
 	sig.Reference = nil
@@ -52,7 +87,14 @@ func (a *docTimeStamp) InitSignature(sig *model.PdfSignature) error {
 		return err
 	}
 	digest.Write([]byte("calculate the Contents field size"))
-	return handler.Sign(sig, digest)
+	if err := handler.Sign(sig, digest); err != nil {
+		return err
+	}
+
+	// The size of the tokens varies slightly, so that some space is reserved for the final one.
+	handler.signatureSize = len(sig.Contents.Bytes()) + 1024
+	sig.Contents = core.MakeHexString(string(make([]byte, handler.signatureSize)))
+	return nil
 }
 
 func (a *docTimeStamp) getCertificate(sig *model.PdfSignature) (*x509.Certificate, error) {
@@ -148,6 +190,11 @@ func (a *docTimeStamp) Validate(sig *model.PdfSignature, digest model.Hasher) (m
 		IsVerified:      bytes.Equal(sm, tsInfo.MessageImprint.HashedMessage),
 		GeneralizedTime: tsInfo.GeneralizedTime,
 	}
+	if err := verifyTimestampCertificate(signed, a.timestampRoots); err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("timestamp certificate chain: %v", err))
+	} else {
+		res.IsTimestampTrusted = true
+	}
 	return res, nil
 }
 
@@ -160,45 +207,21 @@ func (a *docTimeStamp) Sign(sig *model.PdfSignature, digest model.Hasher) error
 		return err
 	}
 
-	s := h.Sum(nil)
-	r := timestamp.Request{
-		HashAlgorithm:   a.hashAlgorithm,
-		HashedMessage:   s,
-		Certificates:    true,
-		Extensions:      nil,
-		ExtraExtensions: nil,
-	}
-	data, err := r.Marshal()
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(a.timestampServerURL, "application/timestamp-query", bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	token, err := a.client.Timestamp(a.hashAlgorithm, h.Sum(nil))
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
-	}
-
-	var ci struct {
-		Version asn1.RawValue
-		Content asn1.RawValue
-	}
-
-	_, err = asn1.Unmarshal(body, &ci)
-	if err != nil {
-		return err
+	if a.signatureSize > 0 {
+		if len(token) > a.signatureSize {
+			return fmt.Errorf("timestamp token size %d exceeds the reserved size %d", len(token), a.signatureSize)
+		}
+		data := make([]byte, a.signatureSize)
+		copy(data, token)
+		token = data
 	}
 
-	sig.Contents = core.MakeHexString(string(ci.Content.FullBytes))
+	sig.Contents = core.MakeHexString(string(token))
 	return nil
 }
 
@@ -0,0 +1,284 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+	"github.com/unidoc/timestamp"
+)
+
+// TimestampClient requests RFC 3161 time-stamp tokens from a time stamping authority (TSA).
+type TimestampClient interface {
+	// Timestamp returns the DER encoded time-stamp token of `digest`, computed with `hash`.
+	Timestamp(hash crypto.Hash, digest []byte) ([]byte, error)
+}
+
+// HTTPTimestampClient is a TimestampClient requesting the time-stamp tokens over HTTP
+// (RFC 3161 section 3.4).
+type HTTPTimestampClient struct {
+	// URL is the URL of the timestamp server.
+	URL string
+
+	// HTTPClient is the client sending the requests, which may be configured with a proxy, a
+	// TLS configuration or a timeout. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+
+	// Header contains additional headers of the requests, such as authorization headers.
+	Header http.Header
+
+	// Username and Password are the credentials of the HTTP basic authentication, used if the
+	// username is not empty.
+	Username string
+	Password string
+
+	// Policy is the TSA policy requested for the tokens, if not empty.
+	Policy asn1.ObjectIdentifier
+
+	// Retries is the number of times a request is retried after a network error or a server
+	// error, waiting RetryDelay between the attempts.
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// NewHTTPTimestampClient creates a new HTTP timestamp client for the timestamp server at `url`.
+func NewHTTPTimestampClient(url string) *HTTPTimestampClient {
+	return &HTTPTimestampClient{URL: url}
+}
+
+// Timestamp returns the DER encoded time-stamp token of `digest`, computed with `hash`. The
+// token is checked to be the one of the request.
+func (c *HTTPTimestampClient) Timestamp(hash crypto.Hash, digest []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req := timestamp.Request{
+		HashAlgorithm: hash,
+		HashedMessage: digest,
+		Certificates:  true,
+		TSAPolicyOID:  c.Policy,
+		Nonce:         nonce,
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, retry, err := c.post(data)
+		if err == nil {
+			return parseTimestampResponse(body, hash, digest, nonce)
+		}
+		if !retry || attempt >= c.Retries {
+			return nil, err
+		}
+		common.Log.Debug("Timestamp request failed, retrying: %v", err)
+		time.Sleep(c.RetryDelay)
+	}
+}
+
+// post posts the time-stamp request `data` and returns the response body. On error, it also
+// returns whether the request may be retried.
+func (c *HTTPTimestampClient) post(data []byte) ([]byte, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= 500, fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
+	}
+	return body, false, nil
+}
+
+// timestampResponse is a time-stamp response (RFC 3161 section 2.4.2).
+type timestampResponse struct {
+	Status struct {
+		Status       int
+		StatusString []asn1.RawValue `asn1:"optional"`
+		FailInfo     asn1.BitString  `asn1:"optional"`
+	}
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// parseTimestampResponse returns the time-stamp token of the time-stamp response `data`, after
+// checking that it is the token of `digest`, computed with `hash`, with the nonce `nonce` if
+// not nil.
+func parseTimestampResponse(data []byte, hash crypto.Hash, digest []byte, nonce *big.Int) ([]byte, error) {
+	var resp timestampResponse
+	if _, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	// The status is either granted (0) or granted with modifications (1).
+	if status := resp.Status.Status; status != 0 && status != 1 {
+		return nil, fmt.Errorf("time-stamp request rejected (status %d)", status)
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("time-stamp token not found in response")
+	}
+
+	token := resp.TimeStampToken.FullBytes
+	_, info, err := cms.ParseTimestampToken(token)
+	if err != nil {
+		return nil, err
+	}
+	tokenHash, err := cms.HashFromOID(info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if tokenHash != hash || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, errors.New("time-stamp token imprint mismatch")
+	}
+	if nonce != nil && (info.Nonce == nil || info.Nonce.Cmp(nonce) != 0) {
+		return nil, errors.New("time-stamp token nonce mismatch")
+	}
+	return token, nil
+}
+
+// LocalTimestampAuthority is an in-process time stamping authority, which issues time-stamp
+// tokens signed with a local key. It is a stand-in for remote TSAs in tests and offline
+// setups: it implements TimestampClient, as well as http.Handler to serve time-stamp requests
+// over HTTP.
+type LocalTimestampAuthority struct {
+	// Certificate is the certificate of the TSA, whose extended key usage must be time
+	// stamping, and Signer its private key.
+	Certificate *x509.Certificate
+	Signer      crypto.Signer
+
+	// Policy is the TSA policy of the tokens. 1.2.3.4.1 by default.
+	Policy asn1.ObjectIdentifier
+
+	// Now returns the time of the tokens. time.Now by default.
+	Now func() time.Time
+}
+
+// NewLocalTimestampAuthority creates a new in-process time stamping authority issuing tokens
+// signed by `signer`, the private key of the TSA `certificate`.
+func NewLocalTimestampAuthority(certificate *x509.Certificate, signer crypto.Signer) *LocalTimestampAuthority {
+	return &LocalTimestampAuthority{
+		Certificate: certificate,
+		Signer:      signer,
+	}
+}
+
+// respond returns the time-stamp response of `digest`, computed with `hash`.
+func (tsa *LocalTimestampAuthority) respond(hash crypto.Hash, digest []byte, nonce *big.Int,
+	certificates bool) ([]byte, error) {
+	if tsa.Certificate == nil || tsa.Signer == nil {
+		return nil, errors.New("TSA certificate and signer required")
+	}
+	policy := tsa.Policy
+	if len(policy) == 0 {
+		policy = asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+	}
+	now := time.Now
+	if tsa.Now != nil {
+		now = tsa.Now
+	}
+
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     hash,
+		HashedMessage:     digest,
+		Time:              now(),
+		Nonce:             nonce,
+		Policy:            policy,
+		AddTSACertificate: certificates,
+	}
+	return ts.CreateResponse(tsa.Certificate, tsa.Signer)
+}
+
+// Timestamp returns the DER encoded time-stamp token of `digest`, computed with `hash`.
+func (tsa *LocalTimestampAuthority) Timestamp(hash crypto.Hash, digest []byte) ([]byte, error) {
+	data, err := tsa.respond(hash, digest, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return parseTimestampResponse(data, hash, digest, nil)
+}
+
+// ServeHTTP serves the time-stamp requests posted over HTTP.
+func (tsa *LocalTimestampAuthority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := timestamp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := tsa.respond(req.HashAlgorithm, req.HashedMessage, req.Nonce, req.Certificates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(resp)
+}
+
+// verifyTimestampCertificate verifies the certificate chain of the TSA of the time-stamp
+// token `token` at the time of the token, with the trusted roots `roots`, or the roots of the
+// system if empty.
+func verifyTimestampCertificate(token []byte, roots []*x509.Certificate) error {
+	signedData, info, err := cms.ParseTimestampToken(token)
+	if err != nil {
+		return err
+	}
+
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	if len(roots) > 0 {
+		opts.Roots = x509.NewCertPool()
+		for _, cert := range roots {
+			opts.Roots.AddCert(cert)
+		}
+	}
+	for _, cert := range signedData.Chain {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = signedData.Certificate.Verify(opts)
+	return err
+}
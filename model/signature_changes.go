@@ -0,0 +1,835 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// SignatureChangeType represents the type of a change made to a document after a signature.
+// The types are ordered by severity.
+type SignatureChangeType int
+
+const (
+	// SignatureChangeValidationData is the addition of validation material (document security
+	// store) or of a document timestamp.
+	SignatureChangeValidationData SignatureChangeType = iota
+
+	// SignatureChangeSignature is the addition of a signature.
+	SignatureChangeSignature
+
+	// SignatureChangeFormFill is the modification of the value of a form field.
+	SignatureChangeFormFill
+
+	// SignatureChangeAnnotation is the addition, modification or removal of an annotation or
+	// of a form field.
+	SignatureChangeAnnotation
+
+	// SignatureChangePageContent is the modification of the content of a page, or the
+	// addition or removal of pages.
+	SignatureChangePageContent
+
+	// SignatureChangeOther is any other change of the document.
+	SignatureChangeOther
+)
+
+// String returns a string representation of the change type.
+func (t SignatureChangeType) String() string {
+	switch t {
+	case SignatureChangeValidationData:
+		return "validation data added"
+	case SignatureChangeSignature:
+		return "signature added"
+	case SignatureChangeFormFill:
+		return "form filled"
+	case SignatureChangeAnnotation:
+		return "annotation changed"
+	case SignatureChangePageContent:
+		return "page content changed"
+	}
+	return "other change"
+}
+
+// SignatureChange represents an object of a document which has been added or modified after
+// a signature.
+type SignatureChange struct {
+	// Type is the type of the change.
+	Type SignatureChangeType
+
+	// ObjectNumber is the number of the added or modified object.
+	ObjectNumber int64
+
+	// Page is the number of the page concerned by the change (starting from 1), or 0 if the
+	// change does not concern a specific page.
+	Page int
+
+	// Field is the fully qualified name of the form field concerned by the change, if any.
+	Field string
+
+	// Allowed is true if the change is permitted by the DocMDP permissions of the document and
+	// by the FieldMDP locks of the signature.
+	Allowed bool
+}
+
+// String returns a string representation of the change.
+func (c SignatureChange) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("%s (object %d", c.Type, c.ObjectNumber))
+	if c.Page > 0 {
+		buf.WriteString(fmt.Sprintf(", page %d", c.Page))
+	}
+	if c.Field != "" {
+		buf.WriteString(fmt.Sprintf(", field %s", c.Field))
+	}
+	buf.WriteString(")")
+	if !c.Allowed {
+		buf.WriteString(": not allowed")
+	}
+	return buf.String()
+}
+
+// fieldLock represents the fields locked by a FieldMDP transform or by the lock dictionary of
+// a signature field (section 12.8.2.4 and table 233 PDF32000_2008).
+type fieldLock struct {
+	action string
+	fields []string
+}
+
+// newFieldLock returns the field lock of the transform parameters or lock dictionary `dict`.
+func newFieldLock(dict *core.PdfObjectDictionary) *fieldLock {
+	action, ok := core.GetNameVal(dict.Get("Action"))
+	if !ok {
+		return nil
+	}
+	lock := &fieldLock{action: action}
+	if arr, ok := core.GetArray(dict.Get("Fields")); ok {
+		for _, obj := range arr.Elements() {
+			if s, ok := core.GetString(obj); ok {
+				lock.fields = append(lock.fields, s.Decoded())
+			}
+		}
+	}
+	return lock
+}
+
+// locks returns true if the field `name` is locked.
+func (l *fieldLock) locks(name string) bool {
+	var listed bool
+	for _, field := range l.fields {
+		if name == field || strings.HasPrefix(name, field+".") {
+			listed = true
+			break
+		}
+	}
+	switch l.action {
+	case "All":
+		return true
+	case "Include":
+		return listed
+	case "Exclude":
+		return !listed
+	}
+	return false
+}
+
+// signatureTransformParams returns the transform parameters of the signature reference
+// dictionaries of `sig` with the transform method `method`.
+func signatureTransformParams(sig *core.PdfObjectDictionary, method string) []*core.PdfObjectDictionary {
+	refs, ok := core.GetArray(sig.Get("Reference"))
+	if !ok {
+		return nil
+	}
+	var params []*core.PdfObjectDictionary
+	for _, obj := range refs.Elements() {
+		ref, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		if name, ok := core.GetNameVal(ref.Get("TransformMethod")); !ok || name != method {
+			continue
+		}
+		dict, ok := core.GetDict(ref.Get("TransformParams"))
+		if !ok {
+			dict = core.MakeDict()
+		}
+		params = append(params, dict)
+	}
+	return params
+}
+
+// docMDPPermission returns the access permission of the certification signature of the
+// document, or DocMDPPermissionNone if the document is not certified.
+func (r *PdfReader) docMDPPermission() DocMDPPermission {
+	perms, ok := core.GetDict(r.catalog.Get("Perms"))
+	if !ok {
+		return DocMDPPermissionNone
+	}
+	sig, ok := core.GetDict(perms.Get("DocMDP"))
+	if !ok {
+		return DocMDPPermissionNone
+	}
+	return sigDocMDPPermission(sig)
+}
+
+// sigDocMDPPermission returns the access permission of the DocMDP transform of the signature
+// dictionary `sig`, or DocMDPPermissionNone if it is not a certification signature.
+func sigDocMDPPermission(sig *core.PdfObjectDictionary) DocMDPPermission {
+	params := signatureTransformParams(sig, "DocMDP")
+	if len(params) == 0 {
+		return DocMDPPermissionNone
+	}
+	p, ok := core.GetIntVal(params[0].Get("P"))
+	if !ok || p < 1 || p > 3 {
+		return DocMDPPermissionFillForms
+	}
+	return DocMDPPermission(p)
+}
+
+// changeContext represents the type of the changes of the objects reached while walking the
+// document, with the page and field they belong to.
+type changeContext struct {
+	kind  SignatureChangeType
+	page  int
+	field string
+}
+
+// Roles of the dictionaries of a document, which determine how their changes are classified.
+const (
+	roleNone = iota
+	roleCatalog
+	rolePages
+	rolePage
+	roleAcroForm
+	roleField
+	roleAnnot
+	roleSig
+	roleDSS
+)
+
+// changeAnalyzer finds the objects of a document which have been added or modified after a
+// signed revision, and classifies the changes.
+type changeAnalyzer struct {
+	r        *PdfReader // Complete document.
+	revision *PdfReader // Signed revision.
+
+	catalog  int64
+	acroForm int64
+	pages    map[int64]int
+	visited  map[int64]changeContext
+}
+
+// readAll returns the data of the document.
+func (r *PdfReader) readAll() ([]byte, error) {
+	if _, err := r.rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r.rs)
+}
+
+// signatureCoverage returns true if the byte range of `sig` covers the whole document `data`.
+// Otherwise, it returns the length of the signed revision.
+func signatureCoverage(sig *PdfSignature, data []byte) (bool, int64, error) {
+	if sig.ByteRange == nil || sig.ByteRange.Len() < 2 {
+		return false, 0, ErrTypeCheck
+	}
+	n := sig.ByteRange.Len()
+	start, err := core.GetNumberAsInt64(sig.ByteRange.Get(n - 2))
+	if err != nil {
+		return false, 0, err
+	}
+	length, err := core.GetNumberAsInt64(sig.ByteRange.Get(n - 1))
+	if err != nil {
+		return false, 0, err
+	}
+	end := start + length
+	if end <= 0 || end > int64(len(data)) {
+		return false, 0, fmt.Errorf("invalid signature byte range end %d", end)
+	}
+	if len(bytes.TrimSpace(data[end:])) == 0 {
+		return true, end, nil
+	}
+	return false, end, nil
+}
+
+// analyzeSignatureChanges determines whether the signature `sig` of the field `field` covers
+// the whole document and, if not, classifies the changes made after the signed revision in
+// `result`. The `revisions` map caches the readers of the signed revisions by their length.
+func (r *PdfReader) analyzeSignatureChanges(sig *PdfSignature, field *PdfField, data []byte,
+	revisions map[int64]*PdfReader, result *SignatureValidationResult) error {
+	result.DocMDPPermission = r.docMDPPermission()
+	covers, end, err := signatureCoverage(sig, data)
+	if err != nil {
+		return err
+	}
+	result.CoversWholeDocument = covers
+	if covers {
+		return nil
+	}
+
+	revision, ok := revisions[end]
+	if !ok {
+		revision, err = NewPdfReader(bytes.NewReader(data[:end]))
+		if err != nil {
+			return err
+		}
+		if encrypted, _ := revision.IsEncrypted(); encrypted {
+			return fmt.Errorf("changes of encrypted documents not supported")
+		}
+		revisions[end] = revision
+	}
+
+	analyzer := &changeAnalyzer{
+		r:        r,
+		revision: revision,
+		pages:    map[int64]int{},
+		visited:  map[int64]changeContext{},
+	}
+	changes, err := analyzer.analyze()
+	if err != nil {
+		return err
+	}
+
+	var locks []*fieldLock
+	if sig.container != nil {
+		if dict, ok := core.GetDict(sig.container); ok {
+			for _, params := range signatureTransformParams(dict, "FieldMDP") {
+				if lock := newFieldLock(params); lock != nil {
+					locks = append(locks, lock)
+				}
+			}
+		}
+	}
+	if field != nil {
+		if dict, ok := core.GetDict(field.container); ok {
+			if lockDict, ok := core.GetDict(dict.Get("Lock")); ok {
+				if lock := newFieldLock(lockDict); lock != nil {
+					locks = append(locks, lock)
+				}
+			}
+		}
+	}
+
+	var disallowed int
+	for i := range changes {
+		changes[i].Allowed = changeAllowed(changes[i], result.DocMDPPermission, locks)
+		if !changes[i].Allowed {
+			disallowed++
+		}
+	}
+	result.Changes = changes
+	if disallowed > 0 {
+		result.Errors = append(result.Errors,
+			fmt.Sprintf("document modified after signing: %d disallowed changes", disallowed))
+	}
+	return nil
+}
+
+// changeAllowed returns true if `change` is permitted by the DocMDP access permission `p`
+// and by the field locks `locks`.
+func changeAllowed(change SignatureChange, p DocMDPPermission, locks []*fieldLock) bool {
+	switch change.Type {
+	case SignatureChangeValidationData:
+		return true
+	case SignatureChangeSignature:
+		return p != DocMDPPermissionNoChanges
+	case SignatureChangeFormFill:
+		if p == DocMDPPermissionNoChanges {
+			return false
+		}
+		for _, lock := range locks {
+			if lock.locks(change.Field) {
+				return false
+			}
+		}
+		return true
+	case SignatureChangeAnnotation:
+		return p == DocMDPPermissionNone || p == DocMDPPermissionAnnotate
+	}
+	return false
+}
+
+// objectNumber returns the object number of `obj`, or 0 if it is a direct object.
+func objectNumber(obj core.PdfObject) int64 {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		return t.ObjectNumber
+	case *core.PdfIndirectObject:
+		return t.ObjectNumber
+	case *core.PdfObjectStream:
+		return t.ObjectNumber
+	}
+	return 0
+}
+
+// analyze walks the complete document from its catalog and returns the changes of the objects
+// which have been added or modified after the signed revision.
+func (a *changeAnalyzer) analyze() ([]SignatureChange, error) {
+	trailer, err := a.r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	root := trailer.Get("Root")
+	a.catalog = objectNumber(root)
+	a.acroForm = objectNumber(a.r.catalog.Get("AcroForm"))
+	for i, page := range a.r.PageList {
+		if page.primitive != nil {
+			a.pages[page.primitive.ObjectNumber] = i + 1
+		}
+	}
+
+	a.walk(root, changeContext{kind: SignatureChangeOther})
+
+	nums := make([]int64, 0, len(a.visited))
+	for n := range a.visited {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	var changes []SignatureChange
+	for _, n := range nums {
+		ctx, changed := a.classify(n, a.visited[n])
+		if !changed {
+			continue
+		}
+		changes = append(changes, SignatureChange{
+			Type:         ctx.kind,
+			ObjectNumber: n,
+			Page:         ctx.page,
+			Field:        ctx.field,
+		})
+	}
+	return changes, nil
+}
+
+// lookup returns the object number `n` of the reader `r`, or nil if not found.
+func lookup(r *PdfReader, n int64) core.PdfObject {
+	obj, err := r.GetIndirectObjectByNumber(int(n))
+	if err != nil {
+		return nil
+	}
+	if _, isNull := obj.(*core.PdfObjectNull); isNull {
+		// Undefined object.
+		return nil
+	}
+	return obj
+}
+
+// objectDict returns the dictionary of the indirect or stream object `obj`, if any.
+func objectDict(obj core.PdfObject) (*core.PdfObjectDictionary, bool) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return core.GetDict(t.PdfObject)
+	case *core.PdfObjectStream:
+		return t.PdfObjectDictionary, true
+	}
+	return core.GetDict(obj)
+}
+
+// walk walks the object `obj` and the objects it refers to in the context `ctx`, recording the
+// context of the numbered objects. Objects already visited in a context of a higher severity
+// are not walked again.
+func (a *changeAnalyzer) walk(obj core.PdfObject, ctx changeContext) {
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		obj = lookup(a.r, ref.ObjectNumber)
+		if obj == nil {
+			return
+		}
+	}
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if prev, ok := a.visited[t.ObjectNumber]; ok && prev.kind >= ctx.kind {
+			return
+		}
+		a.visited[t.ObjectNumber] = ctx
+		if dict, ok := core.GetDict(t.PdfObject); ok {
+			a.walkDict(t.ObjectNumber, dict, ctx)
+		} else {
+			a.walk(t.PdfObject, ctx)
+		}
+	case *core.PdfObjectStream:
+		if prev, ok := a.visited[t.ObjectNumber]; ok && prev.kind >= ctx.kind {
+			return
+		}
+		a.visited[t.ObjectNumber] = ctx
+		a.walkDict(t.ObjectNumber, t.PdfObjectDictionary, ctx)
+	case *core.PdfObjectDictionary:
+		a.walkDict(0, t, ctx)
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			a.walk(elem, ctx)
+		}
+	}
+}
+
+// walkDict walks the entries of the dictionary `dict` of the object number `n`. The context of
+// the entries depends on the role of the dictionary.
+func (a *changeAnalyzer) walkDict(n int64, dict *core.PdfObjectDictionary, ctx changeContext) {
+	role := a.role(n, dict)
+	for _, key := range dict.Keys() {
+		child := ctx
+		switch role {
+		case roleCatalog:
+			switch key {
+			case "AcroForm":
+				child = changeContext{kind: SignatureChangeFormFill}
+			case "DSS":
+				child = changeContext{kind: SignatureChangeValidationData}
+			case "Pages":
+				child = changeContext{kind: SignatureChangePageContent}
+			default:
+				child = changeContext{kind: SignatureChangeOther}
+			}
+		case rolePages:
+			if key == "Parent" {
+				continue
+			}
+			child = changeContext{kind: SignatureChangePageContent}
+		case rolePage:
+			switch key {
+			case "Parent":
+				continue
+			case "Annots":
+				child = changeContext{kind: SignatureChangeAnnotation, page: a.pages[n]}
+			default:
+				child = changeContext{kind: SignatureChangePageContent, page: a.pages[n]}
+			}
+		case roleAcroForm:
+			child = changeContext{kind: SignatureChangeFormFill}
+		case roleField:
+			if key == "Parent" || key == "P" {
+				continue
+			}
+			child = a.fieldContext(dict)
+		case roleAnnot:
+			if key == "Parent" || key == "P" {
+				continue
+			}
+			child = changeContext{kind: SignatureChangeAnnotation, page: a.annotPage(dict, ctx)}
+		case roleSig:
+			if key == "Reference" {
+				continue
+			}
+			child = changeContext{kind: sigChangeType(dict)}
+		case roleDSS:
+			child = changeContext{kind: SignatureChangeValidationData}
+		}
+		a.walk(dict.Get(key), child)
+	}
+}
+
+// role returns the role of the dictionary `dict` of the object number `n`.
+func (a *changeAnalyzer) role(n int64, dict *core.PdfObjectDictionary) int {
+	if n != 0 && n == a.catalog {
+		return roleCatalog
+	}
+	if n != 0 && n == a.acroForm {
+		return roleAcroForm
+	}
+	switch name, _ := core.GetNameVal(dict.Get("Type")); name {
+	case "Pages":
+		return rolePages
+	case "Page":
+		return rolePage
+	case "Sig", "DocTimeStamp":
+		return roleSig
+	case "DSS":
+		return roleDSS
+	}
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	if dict.Get("FT") != nil || subtype == "Widget" || (dict.Get("T") != nil && dict.Get("Parent") != nil) {
+		return roleField
+	}
+	if subtype != "" && dict.Get("Rect") != nil {
+		return roleAnnot
+	}
+	return roleNone
+}
+
+// sigChangeType returns the change type of the addition of the signature dictionary `dict`.
+func sigChangeType(dict *core.PdfObjectDictionary) SignatureChangeType {
+	if name, _ := core.GetNameVal(dict.Get("Type")); name == "DocTimeStamp" {
+		return SignatureChangeValidationData
+	}
+	if subFilter, _ := core.GetNameVal(dict.Get("SubFilter")); subFilter == "ETSI.RFC3161" {
+		return SignatureChangeValidationData
+	}
+	return SignatureChangeSignature
+}
+
+// fieldContext returns the change context of the field or widget annotation `dict`: the
+// signature of signature fields, or the form filling of the other fields.
+func (a *changeAnalyzer) fieldContext(dict *core.PdfObjectDictionary) changeContext {
+	var names []string
+	var ft string
+	var v core.PdfObject
+	for d, depth := dict, 0; d != nil && depth < 32; depth++ {
+		if t, ok := core.GetString(d.Get("T")); ok {
+			names = append([]string{t.Decoded()}, names...)
+		}
+		if ft == "" {
+			ft, _ = core.GetNameVal(d.Get("FT"))
+		}
+		if v == nil {
+			v = d.Get("V")
+		}
+		d, _ = core.GetDict(d.Get("Parent"))
+	}
+
+	ctx := changeContext{
+		kind:  SignatureChangeFormFill,
+		field: strings.Join(names, "."),
+		page:  a.pages[objectNumber(dict.Get("P"))],
+	}
+	if ft == "Sig" {
+		ctx.kind = SignatureChangeSignature
+		if sig, ok := core.GetDict(v); ok {
+			ctx.kind = sigChangeType(sig)
+		}
+	}
+	return ctx
+}
+
+// annotPage returns the number of the page of the annotation `dict`, or the page of `ctx`.
+func (a *changeAnalyzer) annotPage(dict *core.PdfObjectDictionary, ctx changeContext) int {
+	if page, ok := a.pages[objectNumber(dict.Get("P"))]; ok {
+		return page
+	}
+	return ctx.page
+}
+
+// classify returns the change context of the object number `n`, visited in the context `ctx`,
+// and true if it has been added or modified after the signed revision.
+func (a *changeAnalyzer) classify(n int64, ctx changeContext) (changeContext, bool) {
+	obj := lookup(a.r, n)
+	dict, _ := objectDict(obj)
+	role := roleNone
+	if dict != nil {
+		role = a.role(n, dict)
+	}
+
+	var old core.PdfObject
+	if role == roleCatalog {
+		// The catalog of incremental updates is commonly a new object.
+		if trailer, err := a.revision.GetTrailer(); err == nil {
+			old = core.ResolveReference(trailer.Get("Root"))
+		}
+	} else {
+		old = lookup(a.revision, n)
+	}
+	if old == nil {
+		// Added object.
+		switch role {
+		case roleField:
+			fieldCtx := a.fieldContext(dict)
+			if fieldCtx.kind == SignatureChangeFormFill {
+				fieldCtx.kind = SignatureChangeAnnotation
+			}
+			return fieldCtx, true
+		case roleAnnot:
+			return changeContext{kind: SignatureChangeAnnotation, page: a.annotPage(dict, ctx)}, true
+		case roleSig:
+			return changeContext{kind: sigChangeType(dict)}, true
+		case roleDSS:
+			return changeContext{kind: SignatureChangeValidationData}, true
+		}
+		return ctx, true
+	}
+	if sameObject(old, obj) {
+		return ctx, false
+	}
+
+	oldDict, _ := objectDict(old)
+	if dict == nil || oldDict == nil {
+		return ctx, true
+	}
+	keys := changedKeys(oldDict, dict)
+
+	switch role {
+	case roleCatalog:
+		ctx = changeContext{kind: SignatureChangeValidationData}
+		for _, key := range keys {
+			kind := SignatureChangeOther
+			switch key {
+			case "AcroForm":
+				kind = SignatureChangeFormFill
+			case "DSS":
+				kind = SignatureChangeValidationData
+			}
+			ctx.kind = maxChangeType(ctx.kind, kind)
+		}
+	case rolePages:
+		ctx = changeContext{kind: SignatureChangePageContent}
+	case rolePage:
+		ctx = changeContext{kind: SignatureChangeValidationData, page: a.pages[n]}
+		for _, key := range keys {
+			kind := SignatureChangePageContent
+			switch {
+			case key == "Annots":
+				kind = a.arrayChangeType(oldDict.Get(key), dict.Get(key))
+			case isInheritedAttribute(oldDict, dict, key):
+				// Inherited attributes copied to the page by the update.
+				continue
+			}
+			ctx.kind = maxChangeType(ctx.kind, kind)
+		}
+	case roleAcroForm:
+		ctx = changeContext{kind: SignatureChangeValidationData}
+		for _, key := range keys {
+			kind := SignatureChangeFormFill
+			switch key {
+			case "Fields":
+				kind = a.arrayChangeType(oldDict.Get(key), dict.Get(key))
+			case "SigFlags":
+				kind = SignatureChangeSignature
+			}
+			ctx.kind = maxChangeType(ctx.kind, kind)
+		}
+	case roleField:
+		fieldCtx := a.fieldContext(dict)
+		ctx = fieldCtx
+		ctx.kind = SignatureChangeValidationData
+		for _, key := range keys {
+			kind := SignatureChangeAnnotation
+			switch key {
+			case "V", "AS", "AP":
+				kind = fieldCtx.kind
+			case "Kids":
+				kind = a.arrayChangeType(oldDict.Get(key), dict.Get(key))
+			}
+			ctx.kind = maxChangeType(ctx.kind, kind)
+		}
+	case roleAnnot:
+		ctx = changeContext{kind: SignatureChangeAnnotation, page: a.annotPage(dict, ctx)}
+	case roleSig:
+		// Signed signature dictionaries must not be modified.
+		ctx = changeContext{kind: SignatureChangeOther}
+	case roleDSS:
+		ctx = changeContext{kind: SignatureChangeValidationData}
+	}
+	return ctx, true
+}
+
+// arrayChangeType returns the change type of the modification of an array of fields or
+// annotations from `old` to `obj`: the addition of signature fields is a signature, any other
+// addition or removal is an annotation change.
+func (a *changeAnalyzer) arrayChangeType(old, obj core.PdfObject) SignatureChangeType {
+	oldArr, _ := core.GetArray(core.ResolveReference(old))
+	arr, _ := core.GetArray(core.ResolveReference(obj))
+	if arr == nil {
+		return SignatureChangeAnnotation
+	}
+
+	oldNums := map[int64]bool{}
+	if oldArr != nil {
+		for _, elem := range oldArr.Elements() {
+			oldNums[objectNumber(elem)] = true
+		}
+	}
+	nums := map[int64]bool{}
+	kind := SignatureChangeValidationData
+	for _, elem := range arr.Elements() {
+		n := objectNumber(elem)
+		nums[n] = true
+		if n != 0 && oldNums[n] {
+			continue
+		}
+		dict, ok := core.GetDict(elem)
+		if !ok || a.role(n, dict) != roleField {
+			return SignatureChangeAnnotation
+		}
+		fieldCtx := a.fieldContext(dict)
+		if fieldCtx.kind == SignatureChangeFormFill {
+			return SignatureChangeAnnotation
+		}
+		kind = maxChangeType(kind, fieldCtx.kind)
+	}
+	for n := range oldNums {
+		if !nums[n] {
+			return SignatureChangeAnnotation
+		}
+	}
+	return kind
+}
+
+// isInheritedAttribute returns true if the entry `key` of the page `dict` is an inheritable
+// page attribute absent from the page `oldDict`, whose value is the one inherited by `oldDict`
+// from its ancestors.
+func isInheritedAttribute(oldDict, dict *core.PdfObjectDictionary, key core.PdfObjectName) bool {
+	switch key {
+	case "Resources", "MediaBox", "CropBox", "Rotate":
+	default:
+		return false
+	}
+	value := dict.Get(key)
+	if value == nil || oldDict.Get(key) != nil {
+		return false
+	}
+
+	parent, _ := core.GetDict(oldDict.Get("Parent"))
+	for depth := 0; parent != nil && depth < 32; depth++ {
+		if inherited := parent.Get(key); inherited != nil {
+			return core.TraceToDirectObject(inherited).WriteString() ==
+				core.TraceToDirectObject(value).WriteString()
+		}
+		parent, _ = core.GetDict(parent.Get("Parent"))
+	}
+	return false
+}
+
+// maxChangeType returns the most severe of the change types `a` and `b`.
+func maxChangeType(a, b SignatureChangeType) SignatureChangeType {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// sameObject returns true if the indirect or stream objects `a` and `b` are identical, regardless
+// of the order of the entries of their dictionaries.
+func sameObject(a, b core.PdfObject) bool {
+	switch t := a.(type) {
+	case *core.PdfIndirectObject:
+		u, ok := b.(*core.PdfIndirectObject)
+		if !ok {
+			return false
+		}
+		dict, isDict := core.GetDict(t.PdfObject)
+		otherDict, otherIsDict := core.GetDict(u.PdfObject)
+		if isDict && otherIsDict {
+			return len(changedKeys(dict, otherDict)) == 0
+		}
+		return t.PdfObject.WriteString() == u.PdfObject.WriteString()
+	case *core.PdfObjectStream:
+		u, ok := b.(*core.PdfObjectStream)
+		return ok && len(changedKeys(t.PdfObjectDictionary, u.PdfObjectDictionary)) == 0 &&
+			bytes.Equal(t.Stream, u.Stream)
+	}
+	return a.WriteString() == b.WriteString()
+}
+
+// changedKeys returns the keys of the entries which differ between the dictionaries `a` and `b`.
+func changedKeys(a, b *core.PdfObjectDictionary) []core.PdfObjectName {
+	var keys []core.PdfObjectName
+	for _, key := range b.Keys() {
+		if oldVal := a.Get(key); oldVal == nil || oldVal.WriteString() != b.Get(key).WriteString() {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range a.Keys() {
+		if b.Get(key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// updateSignedFile applies `update` to the PDF file `inputPath` with an appender and writes
+// the incremental update to `outputPath`.
+func updateSignedFile(t *testing.T, inputPath, outputPath string,
+	update func(reader *model.PdfReader, appender *model.PdfAppender)) {
+	f, err := os.Open(inputPath)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	update(reader, appender)
+	require.NoError(t, appender.WriteToFile(outputPath))
+}
+
+// changeTypes returns the types of the changes of `res`.
+func changeTypes(res model.SignatureValidationResult) map[model.SignatureChangeType]bool {
+	types := map[model.SignatureChangeType]bool{}
+	for _, change := range res.Changes {
+		types[change.Type] = true
+	}
+	return types
+}
+
+// fillTextField sets the value of the field `name` of the PDF file `inputPath` to `value` and
+// writes the incremental update to `outputPath`.
+func fillTextField(t *testing.T, inputPath, outputPath, name, value string) {
+	updateSignedFile(t, inputPath, outputPath, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		for _, field := range reader.AcroForm.AllFields() {
+			if fullName, _ := field.FullName(); fullName == name {
+				dict := field.GetContainingPdfObject().(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+				dict.Set("V", core.MakeString(value))
+				appender.UpdateObject(field.GetContainingPdfObject())
+			}
+		}
+	})
+}
+
+func TestSignatureChanges(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+	signed := tempFile("changes-signed.pdf")
+	signPAdES(t, testPdfAcroFormFile1, signed, handler)
+
+	// Not modified after signing.
+	res := validatePAdES(t, signed)
+	require.Len(t, res, 1)
+	require.True(t, res[0].CoversWholeDocument)
+	require.Empty(t, res[0].Changes)
+	require.Equal(t, model.DocMDPPermissionNone, res[0].DocMDPPermission)
+
+	// Second signature.
+	path := tempFile("changes-signature.pdf")
+	signPAdES(t, signed, path, handler)
+	res = validatePAdES(t, path)
+	require.Len(t, res, 2)
+	require.False(t, res[0].CoversWholeDocument)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.Equal(t, map[model.SignatureChangeType]bool{model.SignatureChangeSignature: true}, changeTypes(res[0]))
+	require.True(t, res[1].CoversWholeDocument)
+	require.Empty(t, res[1].Changes)
+
+	// Form filling.
+	path = tempFile("changes-form-fill.pdf")
+	fillTextField(t, signed, path, "Given Name Text Box", "John")
+	res = validatePAdES(t, path)
+	require.False(t, res[0].CoversWholeDocument)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.Len(t, res[0].Changes, 1)
+	require.Equal(t, model.SignatureChangeFormFill, res[0].Changes[0].Type)
+	require.Equal(t, "Given Name Text Box", res[0].Changes[0].Field)
+
+	// Annotation.
+	path = tempFile("changes-annotation.pdf")
+	updateSignedFile(t, signed, path, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page := reader.PageList[0]
+		annotation := model.NewPdfAnnotationSquare()
+		annotation.Rect = core.MakeArrayFromFloats([]float64{50, 50, 150, 250})
+		page.AddAnnotation(annotation.PdfAnnotation)
+		appender.UpdatePage(page)
+	})
+	res = validatePAdES(t, path)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.Equal(t, map[model.SignatureChangeType]bool{model.SignatureChangeAnnotation: true}, changeTypes(res[0]))
+	for _, change := range res[0].Changes {
+		require.Equal(t, 1, change.Page)
+	}
+
+	// Page content.
+	path = tempFile("changes-page-content.pdf")
+	updateSignedFile(t, signed, path, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page := reader.PageList[0]
+		require.NoError(t, page.AppendContentStream("BT /F1 12 Tf 100 100 Td (Altered) Tj ET"))
+		appender.UpdatePage(page)
+	})
+	res = validatePAdES(t, path)
+	require.True(t, res[0].HasDisallowedChanges())
+	require.True(t, changeTypes(res[0])[model.SignatureChangePageContent], res[0].String())
+	require.NotEmpty(t, res[0].Errors)
+	for _, change := range res[0].Changes {
+		if change.Type == model.SignatureChangePageContent {
+			require.Equal(t, 1, change.Page)
+			require.False(t, change.Allowed)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// InjectSignature writes the signature `contents`, produced externally, into the contents
+// reserved for the signature of the byte range `byteRange` in the document `rws`. Only the
+// reserved contents are written, the other bytes of the document are left unchanged, so that
+// the signed byte range remains identical. The reserved contents must be empty (filled with
+// zeros), as written by a deferred signature handler.
+func InjectSignature(rws io.ReadWriteSeeker, byteRange []int64, contents []byte) error {
+	if len(byteRange) != 4 {
+		return fmt.Errorf("invalid signature byte range %v", byteRange)
+	}
+	start, end := byteRange[0]+byteRange[1], byteRange[2]
+	if byteRange[0] != 0 || start >= end-1 {
+		return fmt.Errorf("invalid signature byte range %v", byteRange)
+	}
+	size, err := rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if byteRange[2]+byteRange[3] != size {
+		return errors.New("signature byte range does not cover the whole document")
+	}
+
+	// The reserved contents are a hexadecimal string filled with zeros.
+	reserved := make([]byte, end-start)
+	if _, err := rws.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(rws, reserved); err != nil {
+		return err
+	}
+	if reserved[0] != '<' || reserved[len(reserved)-1] != '>' {
+		return errors.New("signature contents not found at the byte range gap")
+	}
+	placeholder := reserved[1 : len(reserved)-1]
+	if len(bytes.Trim(placeholder, "0")) != 0 {
+		return errors.New("signature contents already set")
+	}
+	if hex.EncodedLen(len(contents)) > len(placeholder) {
+		return fmt.Errorf("signature size %d exceeds the reserved size %d", len(contents), len(placeholder)/2)
+	}
+
+	data := make([]byte, hex.EncodedLen(len(contents)))
+	hex.Encode(data, contents)
+	if _, err := rws.Seek(start+1, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = rws.Write(data)
+	return err
+}
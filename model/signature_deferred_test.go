@@ -0,0 +1,107 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// injectSignature injects the signature `contents` into the PDF file `path`.
+func injectSignature(t *testing.T, path string, byteRange []int64, contents []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	return model.InjectSignature(f, byteRange, contents)
+}
+
+func TestDeferredSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	opts := &sighandler.DeferredOptions{Chain: []*x509.Certificate{pki.caCert}}
+	handler, err := sighandler.NewDeferredSigner(pki.leafCert, opts)
+	require.NoError(t, err)
+	require.Nil(t, handler.Digest())
+
+	// First phase: the document is written with empty signature contents.
+	path := tempFile("deferred-pades.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+	require.Len(t, handler.Digest(), crypto.SHA256.Size())
+	byteRange := handler.ByteRange()
+	require.Len(t, byteRange, 4)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), byteRange[2]+byteRange[3])
+
+	// The signed attributes are signed externally.
+	digest, err := handler.SignedAttributesDigest()
+	require.NoError(t, err)
+	signature, err := pki.leafKey.Sign(rand.Reader, digest, crypto.SHA256)
+	require.NoError(t, err)
+	container, err := handler.Container(signature)
+	require.NoError(t, err)
+
+	// Second phase.
+	require.NoError(t, injectSignature(t, path, byteRange, container))
+	res := validatePAdES(t, path)
+	require.Len(t, res, 1)
+	require.True(t, res[0].CoversWholeDocument)
+	require.Empty(t, res[0].Changes)
+
+	// The contents can be injected only once.
+	require.Error(t, injectSignature(t, path, byteRange, container))
+	require.Error(t, handler.InitSignature(model.NewPdfSignature(handler)))
+}
+
+func TestDeferredSignatureRestart(t *testing.T) {
+	pki := newTestPKI(t)
+	opts := &sighandler.DeferredOptions{
+		SubFilter:     "adbe.pkcs7.detached",
+		HashAlgorithm: crypto.SHA384,
+		SignatureSize: 4096,
+	}
+	handler, err := sighandler.NewDeferredSigner(nil, opts)
+	require.NoError(t, err)
+	path := tempFile("deferred-pkcs7.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+	digest, byteRange := handler.Digest(), handler.ByteRange()
+	_, err = handler.SignedAttributesDigest()
+	require.Error(t, err)
+
+	// The container is built by another handler from the saved digest.
+	handler, err = sighandler.NewDeferredSigner(pki.leafCert, opts)
+	require.NoError(t, err)
+	require.Error(t, handler.SetDigest(digest[:8]))
+	require.NoError(t, handler.SetDigest(digest))
+	data, err := handler.SignedAttributesDigest()
+	require.NoError(t, err)
+	signature, err := pki.leafKey.Sign(rand.Reader, data, crypto.SHA384)
+	require.NoError(t, err)
+	container, err := handler.Container(signature)
+	require.NoError(t, err)
+
+	// Contents exceeding the reserved size.
+	require.Error(t, injectSignature(t, path, byteRange, make([]byte, 4097)))
+	require.Error(t, injectSignature(t, path, []int64{0, byteRange[1] + 1, byteRange[2], byteRange[3]}, container))
+
+	require.NoError(t, injectSignature(t, path, byteRange, container))
+	validator, err := sighandler.NewAdobePKCS7DetachedSigner(nil, nil, nil)
+	require.NoError(t, err)
+	res := validateSignatures(t, path, validator)
+	require.Len(t, res, 1)
+	require.True(t, res[0].IsVerified, res[0].Errors)
+	require.True(t, res[0].CoversWholeDocument)
+
+	_, err = sighandler.NewDeferredSigner(nil, &sighandler.DeferredOptions{SubFilter: "adbe.x509.rsa_sha1"})
+	require.Error(t, err)
+}
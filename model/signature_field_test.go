@@ -0,0 +1,169 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"crypto"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// createSignatureTemplate writes to `outputPath` the PDF file `inputPath` with an unsigned
+// signature field named `name` on the first page, restricted to the signature reasons
+// `reasons` if not empty.
+func createSignatureTemplate(t *testing.T, inputPath, outputPath, name string, reasons ...string) {
+	updateSignedFile(t, inputPath, outputPath, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page := reader.PageList[0]
+		field := model.NewPdfFieldSignature(nil)
+		field.T = core.MakeString(name)
+		field.Rect = core.MakeArrayFromFloats([]float64{100, 100, 300, 150})
+		field.P = page.ToPdfObject()
+		if len(reasons) > 0 {
+			field.SetSeedValue(&model.PdfSignatureSeedValue{Reasons: reasons, Ff: model.SeedValueFlagReasons})
+		}
+		page.AddAnnotation(field.PdfAnnotationWidget.PdfAnnotation)
+
+		acroForm := reader.AcroForm
+		if acroForm == nil {
+			acroForm = model.NewPdfAcroForm()
+		}
+		fields := append(acroForm.AllFields(), field.PdfField)
+		acroForm.Fields = &fields
+		appender.ReplaceAcroForm(acroForm)
+		appender.UpdatePage(page)
+	})
+}
+
+// newTestSignature returns an initialized signature of `handler` with the reason `reason`.
+func newTestSignature(t *testing.T, handler model.SignatureHandler, reason string) *model.PdfSignature {
+	signature := model.NewPdfSignature(handler)
+	signature.SetName("Test Signer")
+	signature.SetReason(reason)
+	signature.SetDate(time.Now(), "")
+	require.NoError(t, signature.Initialize())
+	return signature
+}
+
+// newTestAppender returns an appender of the PDF file `path`.
+func newTestAppender(t *testing.T, path string) *model.PdfAppender {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	return appender
+}
+
+// readSignatureFields returns the signature fields of the PDF file `path`.
+func readSignatureFields(t *testing.T, path string) []*model.PdfFieldSignature {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	require.NotNil(t, reader.AcroForm)
+
+	var fields []*model.PdfFieldSignature
+	for _, field := range reader.AcroForm.AllFields() {
+		if sigField, ok := field.GetContext().(*model.PdfFieldSignature); ok {
+			fields = append(fields, sigField)
+		}
+	}
+	return fields
+}
+
+func TestAppenderSignField(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+	template := tempFile("sign-field-template.pdf")
+	createSignatureTemplate(t, testPdfFile1, template, "Approval", "Approved")
+	fields := readSignatureFields(t, template)
+	require.Len(t, fields, 1)
+	require.Nil(t, fields[0].V)
+
+	// Unknown field.
+	appender := newTestAppender(t, template)
+	require.Error(t, appender.SignField("Unknown", newTestSignature(t, handler, "Approved")))
+
+	// Reason not allowed by the seed value of the field.
+	require.Error(t, appender.SignField("Approval", newTestSignature(t, handler, "Rejected")))
+
+	// The field is signed in place, keeping its widget.
+	require.NoError(t, appender.SignField("Approval", newTestSignature(t, handler, "Approved")))
+	path := tempFile("sign-field.pdf")
+	require.NoError(t, appender.WriteToFile(path))
+
+	res := validatePAdES(t, path)
+	require.Len(t, res, 1)
+	require.True(t, res[0].CoversWholeDocument)
+	fields = readSignatureFields(t, path)
+	require.Len(t, fields, 1)
+	require.NotNil(t, fields[0].V)
+	require.Len(t, fields[0].Annotations, 1)
+	rect, ok := core.GetArray(fields[0].Annotations[0].Rect)
+	require.True(t, ok)
+	values, err := rect.ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{100, 100, 300, 150}, values)
+
+	// Signed fields cannot be signed again.
+	appender = newTestAppender(t, path)
+	require.Error(t, appender.SignField("Approval", newTestSignature(t, handler, "Approved")))
+}
+
+func TestAppenderQueueSignatures(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+	tsHandler, err := sighandler.NewDocTimeStampWithOpts(&sighandler.DocTimeStampOpts{
+		Client:        sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey),
+		HashAlgorithm: crypto.SHA256,
+	})
+	require.NoError(t, err)
+	template := tempFile("queue-template.pdf")
+	createSignatureTemplate(t, testPdfFile1, template, "Approval")
+
+	// Signature of the pre-placed field in the appender revision, followed by a new signature
+	// field and a document timestamp in their own revisions.
+	appender := newTestAppender(t, template)
+	require.NoError(t, appender.SignField("Approval", newTestSignature(t, handler, "Approval")))
+	field := model.NewPdfFieldSignature(newTestSignature(t, handler, "Review"))
+	field.T = core.MakeString("Review")
+	field.Rect = core.MakeArrayFromFloats([]float64{0, 0, 0, 0})
+	require.NoError(t, appender.QueueSignature(1, field))
+	timestamp := model.NewPdfFieldSignature(newTestSignature(t, tsHandler, ""))
+	timestamp.T = core.MakeString("Timestamp")
+	timestamp.Rect = core.MakeArrayFromFloats([]float64{0, 0, 0, 0})
+	require.NoError(t, appender.QueueSignature(1, timestamp))
+	path := tempFile("queue-signatures.pdf")
+	require.NoError(t, appender.WriteToFile(path))
+	require.Error(t, appender.WriteToFile(tempFile("queue-signatures-again.pdf")))
+
+	res := validatePAdES(t, path)
+	require.Len(t, res, 3)
+	for i := 0; i < 2; i++ {
+		require.False(t, res[i].CoversWholeDocument)
+		require.False(t, res[i].HasDisallowedChanges(), res[i].String())
+	}
+	require.True(t, res[2].CoversWholeDocument)
+	require.False(t, res[2].GeneralizedTime.IsZero())
+	require.Len(t, readSignatureFields(t, path), 3)
+
+	// Errors of the queued signatures are returned by Write.
+	appender = newTestAppender(t, template)
+	require.NoError(t, appender.QueueFieldSignature("Unknown", newTestSignature(t, handler, "Approval")))
+	require.Error(t, appender.WriteToFile(tempFile("queue-signatures-error.pdf")))
+}
@@ -45,12 +45,33 @@ type SignatureValidationResult struct {
 	Location    string
 	ContactInfo string
 
-	// TODO(gunnsth): Add more fields such as ability to access the certificate information (name, CN, etc).
-	// TODO: Also add flags to indicate whether the signature covers the entire file, or the entire portion of
-	// a revision (if incremental updates used).
+	// CoversWholeDocument is true if the byte range of the signature covers the whole document,
+	// i.e. if the document has not been updated after the signature.
+	CoversWholeDocument bool
+
+	// DocMDPPermission is the access permission of the certification signature of the document,
+	// or DocMDPPermissionNone if the document is not certified.
+	DocMDPPermission DocMDPPermission
+
+	// Changes contains the objects added or modified after the signature, if it does not cover
+	// the whole document, with the type of the changes and whether they are allowed.
+	Changes []SignatureChange
 
 	// GeneralizedTime is the time at which the time-stamp token has been created by the TSA (RFC 3161).
 	GeneralizedTime time.Time
+
+	// IsTimestampTrusted is true if the certificate chain of the TSA of the time-stamp token
+	// leads to a trusted root.
+	IsTimestampTrusted bool
+
+	// Certificates contains the details of the certificate chain of the signer, starting with
+	// the signer certificate. Only set when validated with a validation policy.
+	Certificates []SignatureCertificate
+
+	// RevocationStatus is the revocation status of the certificate chain of the signer: revoked
+	// if any certificate is revoked, good if the status of all the certificates is known to be
+	// good. Only set when validated with a validation policy.
+	RevocationStatus RevocationStatus
 }
 
 func (v SignatureValidationResult) String() string {
@@ -94,12 +115,48 @@ func (v SignatureValidationResult) String() string {
 	}
 	if !v.GeneralizedTime.IsZero() {
 		buf.WriteString(fmt.Sprintf("GeneralizedTime: %s\n", v.GeneralizedTime.String()))
+		if v.IsTimestampTrusted {
+			buf.WriteString("Timestamp: TSA certificate is trusted\n")
+		} else {
+			buf.WriteString("Timestamp: Untrusted TSA certificate\n")
+		}
+	}
+	if len(v.Certificates) > 0 {
+		buf.WriteString(fmt.Sprintf("Signer: %s\n", v.Certificates[0].Subject))
+		buf.WriteString(fmt.Sprintf("Issuer: %s\n", v.Certificates[0].Issuer))
+		buf.WriteString(fmt.Sprintf("Revocation status: %s\n", v.RevocationStatus))
+	}
+	if v.CoversWholeDocument {
+		buf.WriteString("Coverage: Signature covers the whole document\n")
+	} else if v.IsSigned {
+		buf.WriteString(fmt.Sprintf("Coverage: Document modified after signing (%d changes)\n", len(v.Changes)))
+		for _, change := range v.Changes {
+			buf.WriteString(fmt.Sprintf("  %s\n", change))
+		}
 	}
 	return buf.String()
 }
 
+// HasDisallowedChanges returns true if the document has been modified after the signature in a
+// way which is not permitted by the DocMDP and FieldMDP permissions.
+func (v SignatureValidationResult) HasDisallowedChanges() bool {
+	for _, change := range v.Changes {
+		if !change.Allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateSignatures validates digital signatures in the document.
+// The certificates of the signers are not verified, see ValidateSignaturesWithPolicy.
 func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]SignatureValidationResult, error) {
+	return r.validateSignatures(handlers, nil)
+}
+
+// validateSignatures validates digital signatures in the document. The certificates of the
+// signers are verified according to `policy`, if not nil.
+func (r *PdfReader) validateSignatures(handlers []SignatureHandler, policy *SignatureValidationPolicy) ([]SignatureValidationResult, error) {
 	if r.AcroForm == nil {
 		return nil, nil
 	}
@@ -118,7 +175,7 @@ func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]Signature
 			continue
 		}
 		if d, found := core.GetDict(f.V); found {
-			if name, ok := core.GetNameVal(d.Get("Type")); ok && name == "Sig" {
+			if name, ok := core.GetNameVal(d.Get("Type")); ok && (name == "Sig" || name == "DocTimeStamp") {
 				ind, found := core.GetIndirect(f.V)
 				if !found {
 					common.Log.Debug("ERROR: Signature container is nil")
@@ -148,6 +205,17 @@ func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]Signature
 		}
 	}
 
+	var verifier *certificateVerifier
+	if policy != nil {
+		var err error
+		if verifier, err = r.newCertificateVerifier(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	revisions := map[int64]*PdfReader{}
+
 	var results []SignatureValidationResult
 	for _, pair := range pairs {
 		defaultResult := SignatureValidationResult{
@@ -189,6 +257,18 @@ func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]Signature
 		if err != nil {
 			return nil, err
 		}
+		if verifier != nil {
+			verifier.verify(pair.sig, &result)
+		}
+		if data == nil {
+			if data, err = r.readAll(); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.analyzeSignatureChanges(pair.sig, pair.field, data, revisions, &result); err != nil {
+			common.Log.Debug("ERROR: Unable to analyze the changes after the signature: %v", err)
+			result.Errors = append(result.Errors, fmt.Sprintf("unable to analyze the changes after signing: %v", err))
+		}
 
 		result.Name = pair.sig.Name.Decoded()
 		result.Reason = pair.sig.Reason.Decoded()
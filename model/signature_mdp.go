@@ -0,0 +1,334 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// DocMDPPermission represents the access permissions granted by a certification signature
+// (section 12.8.2.2, table 254 PDF32000_2008).
+type DocMDPPermission int
+
+const (
+	// DocMDPPermissionNone indicates that the document is not certified.
+	DocMDPPermissionNone DocMDPPermission = 0
+
+	// DocMDPPermissionNoChanges does not permit any change to the document.
+	DocMDPPermissionNoChanges DocMDPPermission = 1
+
+	// DocMDPPermissionFillForms permits filling in forms, instantiating page templates and
+	// signing.
+	DocMDPPermissionFillForms DocMDPPermission = 2
+
+	// DocMDPPermissionAnnotate permits the changes of DocMDPPermissionFillForms, as well as the
+	// creation, deletion and modification of annotations.
+	DocMDPPermissionAnnotate DocMDPPermission = 3
+)
+
+// FieldLockAction represents the fields locked by a signature field (section 12.7.4.5,
+// table 233 PDF32000_2008).
+type FieldLockAction string
+
+const (
+	// FieldLockAll locks all the fields of the document.
+	FieldLockAll FieldLockAction = "All"
+
+	// FieldLockInclude locks the specified fields.
+	FieldLockInclude FieldLockAction = "Include"
+
+	// FieldLockExclude locks all the fields of the document, except the specified fields.
+	FieldLockExclude FieldLockAction = "Exclude"
+)
+
+// newSignatureReference returns a signature reference dictionary of the transform method
+// `method` with the transform parameters `params` (section 12.8.1, table 253 PDF32000_2008).
+func newSignatureReference(method string, params *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	params.Set("Type", core.MakeName("TransformParams"))
+	params.Set("V", core.MakeName("1.2"))
+
+	ref := core.MakeDict()
+	ref.Set("Type", core.MakeName("SigRef"))
+	ref.Set("TransformMethod", core.MakeName(method))
+	ref.Set("TransformParams", params)
+	return ref
+}
+
+// SetCertification makes the signature a certification signature, which grants the access
+// permissions `permission` to the document. The document must not be signed before a
+// certification signature.
+func (sig *PdfSignature) SetCertification(permission DocMDPPermission) error {
+	if permission < DocMDPPermissionNoChanges || permission > DocMDPPermissionAnnotate {
+		return fmt.Errorf("invalid DocMDP permission %d", permission)
+	}
+
+	params := core.MakeDict()
+	params.Set("P", core.MakeInteger(int64(permission)))
+	refs := core.MakeArray(newSignatureReference("DocMDP", params))
+	if sig.Reference != nil {
+		for _, obj := range sig.Reference.Elements() {
+			if ref, ok := core.GetDict(obj); ok {
+				if method, _ := core.GetNameVal(ref.Get("TransformMethod")); method == "DocMDP" {
+					continue
+				}
+			}
+			refs.Append(obj)
+		}
+	}
+	sig.Reference = refs
+	return nil
+}
+
+// GetCertification returns the access permissions granted by the signature, or
+// DocMDPPermissionNone if it is not a certification signature.
+func (sig *PdfSignature) GetCertification() DocMDPPermission {
+	if sig.Reference == nil {
+		return DocMDPPermissionNone
+	}
+	dict := core.MakeDict()
+	dict.Set("Reference", sig.Reference)
+	return sigDocMDPPermission(dict)
+}
+
+// addFieldMDP adds a FieldMDP transform locking the fields of `lock` to the signature.
+func (sig *PdfSignature) addFieldMDP(lock *fieldLock) *core.PdfObjectDictionary {
+	params := core.MakeDict()
+	params.Set("Action", core.MakeName(lock.action))
+	if lock.action != string(FieldLockAll) {
+		fields := core.MakeArray()
+		for _, name := range lock.fields {
+			fields.Append(core.MakeString(name))
+		}
+		params.Set("Fields", fields)
+	}
+
+	ref := newSignatureReference("FieldMDP", params)
+	if sig.Reference == nil {
+		sig.Reference = core.MakeArray()
+	}
+	sig.Reference.Append(ref)
+	return ref
+}
+
+// SetLock sets the fields locked when the signature field is signed. The `fields` parameter
+// contains the fully qualified names of the fields, ignored with the FieldLockAll action.
+func (sig *PdfFieldSignature) SetLock(action FieldLockAction, fields ...string) error {
+	switch action {
+	case FieldLockAll, FieldLockInclude, FieldLockExclude:
+	default:
+		return fmt.Errorf("invalid field lock action %s", action)
+	}
+
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("SigFieldLock"))
+	dict.Set("Action", core.MakeName(string(action)))
+	if action != FieldLockAll {
+		arr := core.MakeArray()
+		for _, name := range fields {
+			arr.Append(core.MakeString(name))
+		}
+		dict.Set("Fields", arr)
+	}
+	sig.Lock = core.MakeIndirectObject(dict)
+	return nil
+}
+
+// SeedValueFlag represents the flags of a signature field seed value dictionary, indicating
+// the seed values which are required constraints (section 12.7.4.5, table 234 PDF32000_2008).
+type SeedValueFlag int
+
+const (
+	// SeedValueFlagFilter requires the Filter seed value.
+	SeedValueFlagFilter SeedValueFlag = 1
+
+	// SeedValueFlagSubFilter requires one of the SubFilter seed values.
+	SeedValueFlagSubFilter SeedValueFlag = 1 << 1
+
+	// SeedValueFlagV requires the V seed value.
+	SeedValueFlagV SeedValueFlag = 1 << 2
+
+	// SeedValueFlagReasons requires one of the Reasons seed values.
+	SeedValueFlagReasons SeedValueFlag = 1 << 3
+
+	// SeedValueFlagLegalAttestation requires the LegalAttestation seed values.
+	SeedValueFlagLegalAttestation SeedValueFlag = 1 << 4
+
+	// SeedValueFlagAddRevInfo requires the AddRevInfo seed value.
+	SeedValueFlagAddRevInfo SeedValueFlag = 1 << 5
+
+	// SeedValueFlagDigestMethod requires one of the DigestMethod seed values.
+	SeedValueFlagDigestMethod SeedValueFlag = 1 << 6
+)
+
+// PdfSignatureSeedValue represents the seed value dictionary of a signature field, which
+// constrains the signatures of the field (section 12.7.4.5, table 234 PDF32000_2008).
+type PdfSignatureSeedValue struct {
+	// Ff indicates the seed values which are required constraints. The other seed values are
+	// suggestions.
+	Ff SeedValueFlag
+
+	// Filter is the signature handler to be used to sign the field.
+	Filter string
+
+	// SubFilter contains the acceptable encodings of the signature.
+	SubFilter []string
+
+	// DigestMethod contains the acceptable digest algorithms of the signature.
+	DigestMethod []string
+
+	// Reasons contains the acceptable reasons of the signature. A single "." reason requires
+	// the signature to have no reason.
+	Reasons []string
+
+	// MDP is the access permission of the signature: the signature must be a certification
+	// signature with the given permissions, or an approval signature if
+	// DocMDPPermissionNone. The permission is not constrained if nil.
+	MDP *DocMDPPermission
+}
+
+// newPdfSignatureSeedValueFromDict loads a seed value dictionary.
+func newPdfSignatureSeedValueFromDict(dict *core.PdfObjectDictionary) *PdfSignatureSeedValue {
+	names := func(key core.PdfObjectName) []string {
+		var values []string
+		if arr, ok := core.GetArray(dict.Get(key)); ok {
+			for _, obj := range arr.Elements() {
+				if name, ok := core.GetNameVal(obj); ok {
+					values = append(values, name)
+				} else if s, ok := core.GetString(obj); ok {
+					values = append(values, s.Decoded())
+				}
+			}
+		}
+		return values
+	}
+
+	sv := &PdfSignatureSeedValue{
+		SubFilter:    names("SubFilter"),
+		DigestMethod: names("DigestMethod"),
+		Reasons:      names("Reasons"),
+	}
+	if ff, ok := core.GetIntVal(dict.Get("Ff")); ok {
+		sv.Ff = SeedValueFlag(ff)
+	}
+	sv.Filter, _ = core.GetNameVal(dict.Get("Filter"))
+	if mdp, ok := core.GetDict(dict.Get("MDP")); ok {
+		if p, ok := core.GetIntVal(mdp.Get("P")); ok {
+			permission := DocMDPPermission(p)
+			sv.MDP = &permission
+		}
+	}
+	return sv
+}
+
+// ToPdfObject returns the seed value dictionary.
+func (sv *PdfSignatureSeedValue) ToPdfObject() core.PdfObject {
+	nameArray := func(values []string) *core.PdfObjectArray {
+		arr := core.MakeArray()
+		for _, value := range values {
+			arr.Append(core.MakeName(value))
+		}
+		return arr
+	}
+
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("SV"))
+	if sv.Ff != 0 {
+		dict.Set("Ff", core.MakeInteger(int64(sv.Ff)))
+	}
+	if sv.Filter != "" {
+		dict.Set("Filter", core.MakeName(sv.Filter))
+	}
+	if len(sv.SubFilter) > 0 {
+		dict.Set("SubFilter", nameArray(sv.SubFilter))
+	}
+	if len(sv.DigestMethod) > 0 {
+		dict.Set("DigestMethod", nameArray(sv.DigestMethod))
+	}
+	if len(sv.Reasons) > 0 {
+		reasons := core.MakeArray()
+		for _, reason := range sv.Reasons {
+			reasons.Append(core.MakeString(reason))
+		}
+		dict.Set("Reasons", reasons)
+	}
+	if sv.MDP != nil {
+		mdp := core.MakeDict()
+		mdp.Set("P", core.MakeInteger(int64(*sv.MDP)))
+		dict.Set("MDP", mdp)
+	}
+	return dict
+}
+
+// SetSeedValue sets the seed value dictionary of the signature field.
+func (sig *PdfFieldSignature) SetSeedValue(sv *PdfSignatureSeedValue) {
+	if sv == nil {
+		sig.SV = nil
+		return
+	}
+	sig.SV = core.MakeIndirectObject(sv.ToPdfObject())
+}
+
+// GetSeedValue returns the seed value dictionary of the signature field, or nil if not set.
+func (sig *PdfFieldSignature) GetSeedValue() *PdfSignatureSeedValue {
+	if sig.SV == nil {
+		return nil
+	}
+	dict, ok := core.GetDict(sig.SV)
+	if !ok {
+		return nil
+	}
+	return newPdfSignatureSeedValueFromDict(dict)
+}
+
+// check returns an error if the signature `sig` does not satisfy the required constraints of
+// the seed values. The digest method, which is only known by the signature handler, is not
+// checked.
+func (sv *PdfSignatureSeedValue) check(sig *PdfSignature) error {
+	if sv.Ff&SeedValueFlagFilter != 0 && sv.Filter != "" {
+		if sig.Filter == nil || string(*sig.Filter) != sv.Filter {
+			return fmt.Errorf("signature filter must be %s", sv.Filter)
+		}
+	}
+	if sv.Ff&SeedValueFlagSubFilter != 0 && len(sv.SubFilter) > 0 {
+		var subFilter string
+		if sig.SubFilter != nil {
+			subFilter = string(*sig.SubFilter)
+		}
+		if !containsString(sv.SubFilter, subFilter) {
+			return fmt.Errorf("signature sub filter must be one of %v", sv.SubFilter)
+		}
+	}
+	if sv.Ff&SeedValueFlagReasons != 0 && len(sv.Reasons) > 0 {
+		reason := sig.Reason.Decoded()
+		if len(sv.Reasons) == 1 && sv.Reasons[0] == "." {
+			if reason != "" {
+				return errors.New("signature reason not allowed")
+			}
+		} else if !containsString(sv.Reasons, reason) {
+			return fmt.Errorf("signature reason must be one of %v", sv.Reasons)
+		}
+	}
+	if sv.MDP != nil && sig.GetCertification() != *sv.MDP {
+		if *sv.MDP == DocMDPPermissionNone {
+			return errors.New("signature must be an approval signature")
+		}
+		return fmt.Errorf("signature must be a certification signature with permission %d", *sv.MDP)
+	}
+	return nil
+}
+
+// containsString returns true if `values` contains `s`.
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// setTextFieldFlags sets the flags of the field `name` of the PDF file `inputPath` to `flags` and
+// writes the incremental update to `outputPath`.
+func setTextFieldFlags(t *testing.T, inputPath, outputPath, name string, flags model.FieldFlag) {
+	updateSignedFile(t, inputPath, outputPath, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		for _, field := range reader.AcroForm.AllFields() {
+			if fullName, _ := field.FullName(); fullName == name {
+				dict := field.GetContainingPdfObject().(*core.PdfIndirectObject).PdfObject.(*core.PdfObjectDictionary)
+				dict.Set("Ff", core.MakeInteger(int64(flags)))
+				appender.UpdateObject(field.GetContainingPdfObject())
+			}
+		}
+	})
+}
+
+func TestCertificationSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+
+	certified := tempFile("mdp-certified.pdf")
+	err = signField(t, testPdfAcroFormFile1, certified, handler, func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+		require.NoError(t, sig.SetCertification(model.DocMDPPermissionFillForms))
+		require.Equal(t, model.DocMDPPermissionFillForms, sig.GetCertification())
+	})
+	require.NoError(t, err)
+
+	res := validatePAdES(t, certified)
+	require.Len(t, res, 1)
+	require.Equal(t, model.DocMDPPermissionFillForms, res[0].DocMDPPermission)
+	require.True(t, res[0].CoversWholeDocument)
+
+	// Form filling is allowed.
+	path := tempFile("mdp-certified-fill.pdf")
+	fillTextField(t, certified, path, "Given Name Text Box", "John")
+	res = validatePAdES(t, path)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.Len(t, res[0].Changes, 1)
+
+	// Annotations are not allowed.
+	path = tempFile("mdp-certified-annotation.pdf")
+	updateSignedFile(t, certified, path, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page := reader.PageList[0]
+		annotation := model.NewPdfAnnotationSquare()
+		annotation.Rect = core.MakeArrayFromFloats([]float64{50, 50, 150, 250})
+		page.AddAnnotation(annotation.PdfAnnotation)
+		appender.UpdatePage(page)
+	})
+	res = validatePAdES(t, path)
+	require.True(t, res[0].HasDisallowedChanges())
+	require.True(t, changeTypes(res[0])[model.SignatureChangeAnnotation])
+
+	// Field flags cannot be changed, e.g. to make a read-only field editable.
+	readOnly := tempFile("mdp-read-only.pdf")
+	setTextFieldFlags(t, testPdfAcroFormFile1, readOnly, "Given Name Text Box", model.FieldFlagReadOnly)
+	readOnlyCertified := tempFile("mdp-read-only-certified.pdf")
+	err = signField(t, readOnly, readOnlyCertified, handler, func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+		require.NoError(t, sig.SetCertification(model.DocMDPPermissionFillForms))
+	})
+	require.NoError(t, err)
+	path = tempFile("mdp-certified-editable.pdf")
+	setTextFieldFlags(t, readOnlyCertified, path, "Given Name Text Box", model.FieldFlagClear)
+	res = validatePAdES(t, path)
+	require.True(t, res[0].HasDisallowedChanges(), res[0].String())
+	require.False(t, changeTypes(res[0])[model.SignatureChangeFormFill], res[0].String())
+
+	// Approval signature of the certified document, which cannot be certified again.
+	path = tempFile("mdp-certified-approval.pdf")
+	require.NoError(t, signField(t, certified, path, handler, nil))
+	res = validatePAdES(t, path)
+	require.Len(t, res, 2)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+	err = signField(t, certified, tempFile("mdp-certified-twice.pdf"), handler, func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+		require.NoError(t, sig.SetCertification(model.DocMDPPermissionAnnotate))
+	})
+	require.Error(t, err)
+
+	// No changes are permitted.
+	locked := tempFile("mdp-certified-no-changes.pdf")
+	err = signField(t, testPdfAcroFormFile1, locked, handler, func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+		require.NoError(t, sig.SetCertification(model.DocMDPPermissionNoChanges))
+	})
+	require.NoError(t, err)
+	require.Error(t, signField(t, locked, tempFile("mdp-no-changes-approval.pdf"), handler, nil))
+	path = tempFile("mdp-no-changes-fill.pdf")
+	fillTextField(t, locked, path, "Given Name Text Box", "John")
+	res = validatePAdES(t, path)
+	require.Equal(t, model.DocMDPPermissionNoChanges, res[0].DocMDPPermission)
+	require.True(t, res[0].HasDisallowedChanges())
+
+	// A signed document cannot be certified.
+	err = signField(t, tempFile("mdp-certified-approval.pdf"), tempFile("mdp-signed-certified.pdf"), handler,
+		func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+			require.NoError(t, sig.SetCertification(model.DocMDPPermissionFillForms))
+		})
+	require.Error(t, err)
+	require.Error(t, model.NewPdfSignature(handler).SetCertification(4))
+}
+
+func TestSignatureFieldLock(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+
+	signed := tempFile("mdp-lock.pdf")
+	err = signField(t, testPdfAcroFormFile1, signed, handler, func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+		require.NoError(t, field.SetLock(model.FieldLockInclude, "Given Name Text Box"))
+	})
+	require.NoError(t, err)
+
+	// The locked field is read-only.
+	f, err := os.Open(signed)
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	for _, field := range reader.AcroForm.AllFields() {
+		name, err := field.FullName()
+		require.NoError(t, err)
+		switch name {
+		case "Given Name Text Box":
+			require.True(t, field.Flags().Has(model.FieldFlagReadOnly))
+		case "Family Name Text Box":
+			require.False(t, field.Flags().Has(model.FieldFlagReadOnly))
+		}
+	}
+
+	// Filling the locked field is not allowed, unlike the other fields.
+	path := tempFile("mdp-lock-fill-locked.pdf")
+	fillTextField(t, signed, path, "Given Name Text Box", "John")
+	res := validatePAdES(t, path)
+	require.True(t, res[0].HasDisallowedChanges())
+	require.Equal(t, "Given Name Text Box", res[0].Changes[0].Field)
+
+	path = tempFile("mdp-lock-fill-other.pdf")
+	fillTextField(t, signed, path, "Family Name Text Box", "Doe")
+	res = validatePAdES(t, path)
+	require.False(t, res[0].HasDisallowedChanges(), res[0].String())
+
+	require.Error(t, model.NewPdfFieldSignature(nil).SetLock("None"))
+}
+
+func TestSignatureSeedValue(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+
+	approval := model.DocMDPPermissionNone
+	tests := []struct {
+		sv    *model.PdfSignatureSeedValue
+		valid bool
+	}{
+		{&model.PdfSignatureSeedValue{SubFilter: []string{"ETSI.CAdES.detached"}, Ff: model.SeedValueFlagSubFilter}, true},
+		{&model.PdfSignatureSeedValue{SubFilter: []string{"adbe.pkcs7.detached"}, Ff: model.SeedValueFlagSubFilter}, false},
+		{&model.PdfSignatureSeedValue{SubFilter: []string{"adbe.pkcs7.detached"}}, true},
+		{&model.PdfSignatureSeedValue{Filter: "Adobe.PPKLite", Ff: model.SeedValueFlagFilter}, true},
+		{&model.PdfSignatureSeedValue{Reasons: []string{"TestPAdES", "Approval"}, Ff: model.SeedValueFlagReasons}, true},
+		{&model.PdfSignatureSeedValue{Reasons: []string{"."}, Ff: model.SeedValueFlagReasons}, false},
+		{&model.PdfSignatureSeedValue{MDP: &approval}, true},
+	}
+	for i, test := range tests {
+		err := signField(t, testPdfAcroFormFile1, tempFile("mdp-seed-value.pdf"), handler,
+			func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+				field.SetSeedValue(test.sv)
+				require.Equal(t, test.sv, field.GetSeedValue())
+			})
+		if test.valid {
+			require.NoError(t, err, i)
+		} else {
+			require.Error(t, err, i)
+		}
+	}
+
+	// Certification required by the seed value.
+	certify := model.DocMDPPermissionFillForms
+	sv := &model.PdfSignatureSeedValue{MDP: &certify}
+	err = signField(t, testPdfAcroFormFile1, tempFile("mdp-seed-value.pdf"), handler,
+		func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+			field.SetSeedValue(sv)
+		})
+	require.Error(t, err)
+	err = signField(t, testPdfAcroFormFile1, tempFile("mdp-seed-value.pdf"), handler,
+		func(sig *model.PdfSignature, field *model.PdfFieldSignature) {
+			field.SetSeedValue(sv)
+			require.NoError(t, sig.SetCertification(model.DocMDPPermissionFillForms))
+		})
+	require.NoError(t, err)
+}
@@ -0,0 +1,437 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/cms"
+)
+
+// RevocationStatus represents the revocation status of a certificate.
+type RevocationStatus int
+
+// Revocation statuses.
+const (
+	// RevocationStatusUnknown is the status of the certificates without revocation data.
+	RevocationStatusUnknown RevocationStatus = iota
+	RevocationStatusGood
+	RevocationStatusRevoked
+)
+
+// String returns a string representation of the revocation status.
+func (s RevocationStatus) String() string {
+	switch s {
+	case RevocationStatusGood:
+		return "good"
+	case RevocationStatusRevoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
+// SignatureCertificate contains the details of a certificate of the chain of a signature.
+type SignatureCertificate struct {
+	Certificate  *x509.Certificate
+	Subject      string
+	Issuer       string
+	SerialNumber *big.Int
+	NotBefore    time.Time
+	NotAfter     time.Time
+	KeyUsage     x509.KeyUsage
+	ExtKeyUsage  []x509.ExtKeyUsage
+
+	// RevocationStatus is the status of the certificate at the validation time, according to the
+	// available revocation data. The status of trusted roots is not checked.
+	RevocationStatus RevocationStatus
+
+	// RevocationTime is the time at which the certificate has been revoked, if revoked.
+	RevocationTime time.Time
+}
+
+// newSignatureCertificate returns the details of `cert`.
+func newSignatureCertificate(cert *x509.Certificate) SignatureCertificate {
+	return SignatureCertificate{
+		Certificate:  cert,
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		KeyUsage:     cert.KeyUsage,
+		ExtKeyUsage:  cert.ExtKeyUsage,
+	}
+}
+
+// SignatureValidationPolicy defines how the certificates of the signatures are verified by
+// PdfReader.ValidateSignaturesWithPolicy.
+type SignatureValidationPolicy struct {
+	// TrustedRoots contains the trusted root certificates. The roots of the system are used if
+	// empty.
+	TrustedRoots []*x509.Certificate
+
+	// Intermediates contains intermediate certificates used to build the certificate chains, in
+	// addition to the certificates embedded in the signatures and in the document security store.
+	Intermediates []*x509.Certificate
+
+	// ValidationTime is the time at which the certificates are verified. If zero, the time of
+	// the signature timestamp is used for timestamped signatures whose TSA certificate chain leads
+	// to a trusted root, the current time otherwise.
+	ValidationTime time.Time
+
+	// OCSPResponses and CRLs contain DER encoded OCSP responses and certificate revocation
+	// lists, used in addition to those of the document security store.
+	OCSPResponses [][]byte
+	CRLs          [][]byte
+
+	// OCSPResponder, if set, is called to get the DER encoded OCSP response of the certificates
+	// whose status cannot be determined by the available revocation data.
+	OCSPResponder func(cert, issuer *x509.Certificate) ([]byte, error)
+
+	// CRLFetcher, if set, is called to get the DER encoded certificate revocation list of the
+	// certificates whose status cannot be determined by the available revocation data and the
+	// OCSP responder.
+	CRLFetcher func(cert, issuer *x509.Certificate) ([]byte, error)
+
+	// RequireRevocationStatus specifies whether the signatures are only trusted if the status
+	// of all the certificates of their chain, except the root, is known to be good.
+	RequireRevocationStatus bool
+}
+
+// ValidateSignaturesWithPolicy validates digital signatures in the document, like
+// ValidateSignatures, and verifies the certificates of the signers according to `policy`.
+// The signatures are trusted if the chain of their certificate leads to a trusted root at the
+// validation time, and none of its certificates is revoked.
+func (r *PdfReader) ValidateSignaturesWithPolicy(handlers []SignatureHandler,
+	policy *SignatureValidationPolicy) ([]SignatureValidationResult, error) {
+	if policy == nil {
+		return nil, errors.New("validation policy cannot be nil")
+	}
+	return r.validateSignatures(handlers, policy)
+}
+
+// certificateVerifier verifies the certificates of signatures according to a validation policy.
+type certificateVerifier struct {
+	policy        *SignatureValidationPolicy
+	roots         *x509.CertPool
+	intermediates []*x509.Certificate
+	ocsps         [][]byte
+	crls          []*x509.RevocationList
+}
+
+// newCertificateVerifier returns a verifier of the certificates of the signatures of the
+// document, with the validation material of its document security store.
+func (r *PdfReader) newCertificateVerifier(policy *SignatureValidationPolicy) (*certificateVerifier, error) {
+	v := &certificateVerifier{
+		policy:        policy,
+		intermediates: append([]*x509.Certificate{}, policy.Intermediates...),
+		ocsps:         append([][]byte{}, policy.OCSPResponses...),
+	}
+	if len(policy.TrustedRoots) > 0 {
+		v.roots = x509.NewCertPool()
+		for _, cert := range policy.TrustedRoots {
+			v.roots.AddCert(cert)
+		}
+	}
+	crls := append([][]byte{}, policy.CRLs...)
+
+	dss, err := r.GetDSS()
+	if err != nil {
+		return nil, err
+	}
+	if dss != nil {
+		decode := func(streams []*core.PdfObjectStream) [][]byte {
+			var data [][]byte
+			for _, stream := range streams {
+				b, err := core.DecodeStream(stream)
+				if err != nil {
+					common.Log.Debug("ERROR: Invalid DSS stream: %v", err)
+					continue
+				}
+				data = append(data, b)
+			}
+			return data
+		}
+		for _, b := range decode(dss.Certs) {
+			cert, err := x509.ParseCertificate(b)
+			if err != nil {
+				common.Log.Debug("ERROR: Invalid DSS certificate: %v", err)
+				continue
+			}
+			v.intermediates = append(v.intermediates, cert)
+		}
+		v.ocsps = append(v.ocsps, decode(dss.OCSPs)...)
+		crls = append(crls, decode(dss.CRLs)...)
+	}
+
+	for _, b := range crls {
+		crl, err := x509.ParseRevocationList(b)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid CRL: %v", err)
+			continue
+		}
+		v.crls = append(v.crls, crl)
+	}
+	return v, nil
+}
+
+// signatureCertificates returns the certificate of the signer of `sig` and the other
+// certificates embedded in the signature.
+func signatureCertificates(sig *PdfSignature) (*x509.Certificate, []*x509.Certificate, error) {
+	if sig.SubFilter != nil && *sig.SubFilter == "adbe.x509.rsa_sha1" {
+		var certData []byte
+		switch certObj := sig.Cert.(type) {
+		case *core.PdfObjectString:
+			certData = certObj.Bytes()
+		case *core.PdfObjectArray:
+			for _, obj := range certObj.Elements() {
+				if certStr, ok := core.GetString(obj); ok {
+					certData = append(certData, certStr.Bytes()...)
+				}
+			}
+		}
+		certs, err := x509.ParseCertificates(certData)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(certs) == 0 {
+			return nil, nil, errors.New("no signature certificates found")
+		}
+		return certs[0], certs[1:], nil
+	}
+
+	signedData, err := cms.Parse(sig.Contents.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return signedData.Certificate, signedData.Chain, nil
+}
+
+// verify verifies the certificates of the signature `sig` and updates `result` with their
+// details, their revocation status and whether the signature is trusted.
+func (v *certificateVerifier) verify(sig *PdfSignature, result *SignatureValidationResult) {
+	result.IsTrusted = false
+	signer, embedded, err := signatureCertificates(sig)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("signature certificates: %v", err))
+		return
+	}
+
+	// The time of the signature timestamp is only used if its TSA is trusted by the policy, as
+	// a backdated timestamp would otherwise make expired or revoked certificates valid.
+	validationTime := v.policy.ValidationTime
+	if validationTime.IsZero() && !result.GeneralizedTime.IsZero() {
+		if t, err := v.timestampTime(sig); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("timestamp not trusted: %v", err))
+		} else {
+			validationTime = t
+		}
+	}
+	if validationTime.IsZero() {
+		validationTime = time.Now()
+	}
+
+	candidates := append(append([]*x509.Certificate{}, embedded...), v.intermediates...)
+	intermediates := x509.NewCertPool()
+	for _, cert := range candidates {
+		intermediates.AddCert(cert)
+	}
+	chains, err := signer.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   validationTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+
+	chain := []*x509.Certificate{signer}
+	trusted := err == nil && len(chains) > 0
+	if trusted {
+		chain = chains[0]
+	} else {
+		result.Errors = append(result.Errors, fmt.Sprintf("certificate chain: %v", err))
+		// Complete the chain with the available issuers, for the certificate details.
+		for issuer := findIssuer(signer, candidates); issuer != nil && len(chain) < 10; issuer = findIssuer(issuer, candidates) {
+			if issuer == chain[len(chain)-1] {
+				break
+			}
+			chain = append(chain, issuer)
+		}
+	}
+
+	result.Certificates = nil
+	result.RevocationStatus = RevocationStatusGood
+	for i, cert := range chain {
+		details := newSignatureCertificate(cert)
+		last := i == len(chain)-1
+		if trusted && last {
+			// Trusted roots are not checked.
+			result.Certificates = append(result.Certificates, details)
+			continue
+		}
+
+		var issuer *x509.Certificate
+		if !last {
+			issuer = chain[i+1]
+		}
+		if issuer != nil {
+			details.RevocationStatus, details.RevocationTime = v.revocationStatus(cert, issuer, validationTime)
+		}
+		switch details.RevocationStatus {
+		case RevocationStatusRevoked:
+			result.RevocationStatus = RevocationStatusRevoked
+			result.Errors = append(result.Errors, fmt.Sprintf("certificate %s revoked at %s",
+				details.Subject, details.RevocationTime))
+		case RevocationStatusUnknown:
+			if result.RevocationStatus == RevocationStatusGood {
+				result.RevocationStatus = RevocationStatusUnknown
+			}
+		}
+		result.Certificates = append(result.Certificates, details)
+	}
+
+	switch {
+	case !trusted:
+	case result.RevocationStatus == RevocationStatusRevoked:
+	case result.RevocationStatus == RevocationStatusUnknown && v.policy.RequireRevocationStatus:
+		result.Errors = append(result.Errors, "certificate revocation status unknown")
+	default:
+		result.IsTrusted = true
+	}
+}
+
+// timestampTime returns the time of the time-stamp token of `sig`, which is the signature itself
+// for document timestamps. An error is returned if the certificate chain of the TSA does not lead
+// to a trusted root of the policy at that time.
+func (v *certificateVerifier) timestampTime(sig *PdfSignature) (time.Time, error) {
+	token := sig.Contents.Bytes()
+	if sig.SubFilter == nil || *sig.SubFilter != "ETSI.RFC3161" {
+		signedData, err := cms.Parse(token)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var ok bool
+		if token, ok = signedData.TimestampToken(); !ok {
+			return time.Time{}, errors.New("time-stamp token not found")
+		}
+	}
+	signedData, info, err := cms.ParseTimestampToken(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range append(append([]*x509.Certificate{}, signedData.Chain...), v.intermediates...) {
+		intermediates.AddCert(cert)
+	}
+	_, err = signedData.Certificate.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.GenTime, nil
+}
+
+// findIssuer returns the issuer of `cert` among `candidates`, or nil if not found.
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if bytes.Equal(cert.RawIssuer, candidate.RawSubject) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// revocationStatus returns the revocation status of `cert`, issued by `issuer`, at the time `t`
+// and its revocation time if revoked. The OCSP responses are checked first, then the CRLs, and
+// then the responders of the policy.
+func (v *certificateVerifier) revocationStatus(cert, issuer *x509.Certificate, t time.Time) (RevocationStatus, time.Time) {
+	for _, data := range v.ocsps {
+		if status, revokedAt, ok := ocspStatus(data, cert, issuer, t); ok {
+			return status, revokedAt
+		}
+	}
+	for _, crl := range v.crls {
+		if status, revokedAt, ok := crlStatus(crl, cert, issuer, t); ok {
+			return status, revokedAt
+		}
+	}
+
+	if v.policy.OCSPResponder != nil {
+		data, err := v.policy.OCSPResponder(cert, issuer)
+		if err != nil {
+			common.Log.Debug("ERROR: OCSP responder: %v", err)
+		} else if status, revokedAt, ok := ocspStatus(data, cert, issuer, t); ok {
+			return status, revokedAt
+		}
+	}
+	if v.policy.CRLFetcher != nil {
+		data, err := v.policy.CRLFetcher(cert, issuer)
+		if err != nil {
+			common.Log.Debug("ERROR: CRL fetcher: %v", err)
+		} else if crl, err := x509.ParseRevocationList(data); err != nil {
+			common.Log.Debug("ERROR: Invalid CRL: %v", err)
+		} else if status, revokedAt, ok := crlStatus(crl, cert, issuer, t); ok {
+			return status, revokedAt
+		}
+	}
+	return RevocationStatusUnknown, time.Time{}
+}
+
+// ocspStatus returns the status of `cert` at the time `t` according to the OCSP response
+// `data`, if it is a valid response for `cert` signed by `issuer` or by its delegated responder.
+// The good status is only reported by responses current at the time `t`, that is produced
+// between their ThisUpdate and NextUpdate times.
+func ocspStatus(data []byte, cert, issuer *x509.Certificate, t time.Time) (RevocationStatus, time.Time, bool) {
+	resp, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return RevocationStatusUnknown, time.Time{}, false
+	}
+	if resp.Status == ocsp.Revoked && !resp.RevokedAt.After(t) {
+		// Revocations are final, later responses also apply.
+		return RevocationStatusRevoked, resp.RevokedAt, true
+	}
+	if resp.ThisUpdate.After(t) || (!resp.NextUpdate.IsZero() && resp.NextUpdate.Before(t)) {
+		common.Log.Debug("OCSP response not current at %s (%s - %s)", t, resp.ThisUpdate, resp.NextUpdate)
+		return RevocationStatusUnknown, time.Time{}, false
+	}
+	switch resp.Status {
+	case ocsp.Good, ocsp.Revoked:
+		return RevocationStatusGood, time.Time{}, true
+	}
+	return RevocationStatusUnknown, time.Time{}, false
+}
+
+// crlStatus returns the status of `cert` at the time `t` according to `crl`, if it is a valid
+// certificate revocation list of `issuer`. As for OCSP responses, the good status is only
+// reported by lists current at the time `t`.
+func crlStatus(crl *x509.RevocationList, cert, issuer *x509.Certificate, t time.Time) (RevocationStatus, time.Time, bool) {
+	if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) || crl.CheckSignatureFrom(issuer) != nil {
+		return RevocationStatusUnknown, time.Time{}, false
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 && !entry.RevocationTime.After(t) {
+			return RevocationStatusRevoked, entry.RevocationTime, true
+		}
+	}
+	if crl.ThisUpdate.After(t) || (!crl.NextUpdate.IsZero() && crl.NextUpdate.Before(t)) {
+		common.Log.Debug("CRL not current at %s (%s - %s)", t, crl.ThisUpdate, crl.NextUpdate)
+		return RevocationStatusUnknown, time.Time{}, false
+	}
+	return RevocationStatusGood, time.Time{}, true
+}
@@ -0,0 +1,349 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// validateWithPolicy validates the signatures of the PDF file `path` with `policy`.
+func validateWithPolicy(t *testing.T, path string, policy *model.SignatureValidationPolicy) []model.SignatureValidationResult {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	handler, err := sighandler.NewEtsiPAdES(nil, nil, nil)
+	require.NoError(t, err)
+	handler2, err := sighandler.NewDocTimeStamp("", 0)
+	require.NoError(t, err)
+	res, err := reader.ValidateSignaturesWithPolicy([]model.SignatureHandler{handler, handler2}, policy)
+	require.NoError(t, err)
+	require.NotEmpty(t, res)
+	return res
+}
+
+// newTestOCSPResponse returns an OCSP response of the status `status` of `cert`, signed by the
+// CA of `pki`.
+func newTestOCSPResponse(t *testing.T, pki *testPKI, cert *x509.Certificate, status int) []byte {
+	resp, err := ocsp.CreateResponse(pki.caCert, pki.caCert, ocsp.Response{
+		Status:           status,
+		SerialNumber:     cert.SerialNumber,
+		ThisUpdate:       time.Now().Add(-time.Minute),
+		NextUpdate:       time.Now().Add(time.Hour),
+		RevokedAt:        time.Now().Add(-time.Minute),
+		RevocationReason: ocsp.KeyCompromise,
+	}, pki.caKey)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestValidateSignaturesWithPolicy(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	path := tempFile("validate-policy.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+
+	// Trusted chain without revocation data.
+	res := validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots: []*x509.Certificate{pki.caCert},
+	})
+	require.True(t, res[0].IsVerified)
+	require.True(t, res[0].IsTrusted, res[0].Errors)
+	require.Equal(t, model.RevocationStatusUnknown, res[0].RevocationStatus)
+	require.Len(t, res[0].Certificates, 2)
+	signer := res[0].Certificates[0]
+	require.Equal(t, pki.leafCert.Subject.String(), signer.Subject)
+	require.Equal(t, pki.caCert.Subject.String(), signer.Issuer)
+	require.Equal(t, pki.leafCert.SerialNumber, signer.SerialNumber)
+	require.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment, signer.KeyUsage)
+	require.Equal(t, pki.leafCert.NotAfter, signer.NotAfter)
+
+	// Revocation status required.
+	res = validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots:            []*x509.Certificate{pki.caCert},
+		RequireRevocationStatus: true,
+	})
+	require.False(t, res[0].IsTrusted)
+
+	// Untrusted issuer.
+	otherPKI := newTestPKI(t)
+	res = validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots: []*x509.Certificate{otherPKI.caCert},
+	})
+	require.True(t, res[0].IsVerified)
+	require.False(t, res[0].IsTrusted)
+	require.NotEmpty(t, res[0].Errors)
+	require.Len(t, res[0].Certificates, 2)
+
+	// Expired certificate at the validation time.
+	res = validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots:   []*x509.Certificate{pki.caCert},
+		ValidationTime: time.Now().Add(48 * time.Hour),
+	})
+	require.False(t, res[0].IsTrusted)
+
+	// Good status from an OCSP response provided by the caller.
+	res = validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots:            []*x509.Certificate{pki.caCert},
+		OCSPResponses:           [][]byte{newTestOCSPResponse(t, pki, pki.leafCert, ocsp.Good)},
+		RequireRevocationStatus: true,
+	})
+	require.True(t, res[0].IsTrusted, res[0].Errors)
+	require.Equal(t, model.RevocationStatusGood, res[0].RevocationStatus)
+	require.Equal(t, model.RevocationStatusGood, res[0].Certificates[0].RevocationStatus)
+
+	// Revoked status from an OCSP responder.
+	res = validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots: []*x509.Certificate{pki.caCert},
+		OCSPResponder: func(cert, issuer *x509.Certificate) ([]byte, error) {
+			require.Equal(t, pki.caCert.Raw, issuer.Raw)
+			return newTestOCSPResponse(t, pki, cert, ocsp.Revoked), nil
+		},
+	})
+	require.False(t, res[0].IsTrusted)
+	require.Equal(t, model.RevocationStatusRevoked, res[0].RevocationStatus)
+	require.False(t, res[0].Certificates[0].RevocationTime.IsZero())
+
+	// Revoked status from a CRL fetcher, after the failure of the OCSP responder.
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: time.Now().Add(-20 * time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{
+			SerialNumber:   pki.leafCert.SerialNumber,
+			RevocationTime: time.Now().Add(-time.Minute),
+		}},
+	}, pki.caCert, pki.caKey)
+	require.NoError(t, err)
+	policy := &model.SignatureValidationPolicy{
+		TrustedRoots: []*x509.Certificate{pki.caCert},
+		OCSPResponder: func(cert, issuer *x509.Certificate) ([]byte, error) {
+			return nil, errors.New("responder unavailable")
+		},
+		CRLFetcher: func(cert, issuer *x509.Certificate) ([]byte, error) {
+			return crl, nil
+		},
+	}
+	res = validateWithPolicy(t, path, policy)
+	require.False(t, res[0].IsTrusted)
+	require.Equal(t, model.RevocationStatusRevoked, res[0].RevocationStatus)
+
+	// Not yet revoked at the validation time.
+	policy.ValidationTime = time.Now().Add(-10 * time.Minute)
+	res = validateWithPolicy(t, path, policy)
+	require.True(t, res[0].IsTrusted, res[0].Errors)
+	require.Equal(t, model.RevocationStatusGood, res[0].RevocationStatus)
+}
+
+func TestCRLFreshness(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	path := tempFile("validate-crl-freshness.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+
+	validate := func(thisUpdate, nextUpdate time.Time, revoked bool) model.SignatureValidationResult {
+		template := &x509.RevocationList{
+			Number:     big.NewInt(3),
+			ThisUpdate: thisUpdate,
+			NextUpdate: nextUpdate,
+		}
+		if revoked {
+			template.RevokedCertificateEntries = []x509.RevocationListEntry{{
+				SerialNumber:   pki.leafCert.SerialNumber,
+				RevocationTime: time.Now().Add(-time.Minute),
+			}}
+		}
+		crl, err := x509.CreateRevocationList(rand.Reader, template, pki.caCert, pki.caKey)
+		require.NoError(t, err)
+		res := validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+			TrustedRoots:            []*x509.Certificate{pki.caCert},
+			CRLs:                    [][]byte{crl},
+			RequireRevocationStatus: true,
+		})
+		return res[0]
+	}
+
+	// Current list.
+	res := validate(time.Now().Add(-time.Minute), time.Now().Add(time.Hour), false)
+	require.True(t, res.IsTrusted, res.Errors)
+	require.Equal(t, model.RevocationStatusGood, res.RevocationStatus)
+
+	// Stale lists, or lists issued after the validation time, do not report good statuses.
+	res = validate(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), false)
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusUnknown, res.RevocationStatus)
+	res = validate(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour), false)
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusUnknown, res.RevocationStatus)
+
+	// Revocations are final.
+	res = validate(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Second), true)
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusRevoked, res.RevocationStatus)
+}
+
+func TestValidateSignaturesTimestampTime(t *testing.T) {
+	pki := newTestPKI(t)
+	otherPKI := newTestPKI(t)
+
+	// Signer certificate expired since 10 minutes.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	data, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "Expired Signer"},
+		NotBefore:    time.Now().Add(-50 * time.Minute),
+		NotAfter:     time.Now().Add(-10 * time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, pki.caCert, key.Public(), pki.caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(data)
+	require.NoError(t, err)
+
+	// signBackdated signs with the expired certificate, timestamped 30 minutes ago by `tsaPKI`.
+	signBackdated := func(tsaPKI *testPKI, path string) {
+		tsa := sighandler.NewLocalTimestampAuthority(tsaPKI.tsaCert, tsaPKI.tsaKey)
+		tsa.Now = func() time.Time {
+			return time.Now().Add(-30 * time.Minute)
+		}
+		handler, err := sighandler.NewEtsiPAdES(key, cert, &sighandler.PAdESOptions{
+			Chain:           []*x509.Certificate{pki.caCert, tsaPKI.caCert},
+			TimestampClient: tsa,
+		})
+		require.NoError(t, err)
+		signPAdES(t, testPdfFile1, path, handler)
+	}
+	policy := &model.SignatureValidationPolicy{
+		TrustedRoots: []*x509.Certificate{pki.caCert},
+	}
+
+	// The certificate was valid at the time of a timestamp issued by a trusted TSA.
+	path := tempFile("validate-timestamp-trusted.pdf")
+	signBackdated(pki, path)
+	res := validateWithPolicy(t, path, policy)
+	require.True(t, res[0].IsVerified, res[0].Errors)
+	require.False(t, res[0].GeneralizedTime.IsZero())
+	require.True(t, res[0].IsTrusted, res[0].Errors)
+
+	// A backdated timestamp of an untrusted TSA does not make the expired certificate valid.
+	path = tempFile("validate-timestamp-untrusted.pdf")
+	signBackdated(otherPKI, path)
+	res = validateWithPolicy(t, path, policy)
+	require.True(t, res[0].IsVerified, res[0].Errors)
+	require.False(t, res[0].GeneralizedTime.IsZero())
+	require.False(t, res[0].IsTrusted)
+}
+
+func TestOCSPResponseFreshness(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	path := tempFile("validate-ocsp-freshness.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+
+	newResponse := func(status int, thisUpdate, nextUpdate time.Time) []byte {
+		resp, err := ocsp.CreateResponse(pki.caCert, pki.caCert, ocsp.Response{
+			Status:           status,
+			SerialNumber:     pki.leafCert.SerialNumber,
+			ThisUpdate:       thisUpdate,
+			NextUpdate:       nextUpdate,
+			RevokedAt:        time.Now().Add(-time.Minute),
+			RevocationReason: ocsp.KeyCompromise,
+		}, pki.caKey)
+		require.NoError(t, err)
+		return resp
+	}
+	validate := func(resp []byte) model.SignatureValidationResult {
+		res := validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+			TrustedRoots:            []*x509.Certificate{pki.caCert},
+			OCSPResponses:           [][]byte{resp},
+			RequireRevocationStatus: true,
+		})
+		return res[0]
+	}
+
+	// Good statuses of expired responses, or of responses produced after the validation time,
+	// are ignored.
+	res := validate(newResponse(ocsp.Good, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)))
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusUnknown, res.RevocationStatus)
+	res = validate(newResponse(ocsp.Good, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)))
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusUnknown, res.RevocationStatus)
+
+	// Revocations are final.
+	res = validate(newResponse(ocsp.Revoked, time.Now().Add(-time.Minute), time.Now().Add(-time.Second)))
+	require.False(t, res.IsTrusted)
+	require.Equal(t, model.RevocationStatusRevoked, res.RevocationStatus)
+}
+
+func TestValidateSignaturesWithDSS(t *testing.T) {
+	pki := newTestPKI(t)
+	handler, err := sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, nil)
+	require.NoError(t, err)
+	signPAdES(t, testPdfFile1, tempFile("validate-dss-b-b.pdf"), handler)
+
+	// Add the validation material of the signature, without the signer certificate.
+	f, err := os.Open(tempFile("validate-dss-b-b.pdf"))
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	var sig *model.PdfSignature
+	for _, field := range reader.AcroForm.AllFields() {
+		if sigField, ok := field.GetContext().(*model.PdfFieldSignature); ok && sigField.V != nil {
+			sig = sigField.V
+		}
+	}
+	require.NotNil(t, sig)
+	dss := model.NewDSS()
+	require.NoError(t, dss.AddValidationData(sig, []*x509.Certificate{pki.caCert},
+		[][]byte{newTestOCSPResponse(t, pki, pki.leafCert, ocsp.Good)}, nil))
+	appender.SetDSS(dss)
+	path := tempFile("validate-dss-b-lt.pdf")
+	require.NoError(t, appender.WriteToFile(path))
+
+	// The revocation status is found in the document security store.
+	res := validateWithPolicy(t, path, &model.SignatureValidationPolicy{
+		TrustedRoots:            []*x509.Certificate{pki.caCert},
+		RequireRevocationStatus: true,
+	})
+	require.Len(t, res, 1)
+	require.True(t, res[0].IsVerified)
+	require.True(t, res[0].IsTrusted, res[0].Errors)
+	require.Equal(t, model.RevocationStatusGood, res[0].RevocationStatus)
+
+	_, err = reader.ValidateSignaturesWithPolicy(nil, nil)
+	require.Error(t, err)
+}
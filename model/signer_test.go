@@ -0,0 +1,165 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+// opaqueSigner is a crypto.Signer which does not expose its private key, as the signers of
+// hardware security modules.
+type opaqueSigner struct {
+	signer crypto.Signer
+}
+
+func (s *opaqueSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+func (s *opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.Sign(rand, digest, opts)
+}
+
+// newTestSignerCertificate returns a signer certificate of the key of `signer`, issued by the
+// CA of `pki`.
+func newTestSignerCertificate(t *testing.T, pki *testPKI, signer crypto.Signer) *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Test Signer", Organization: []string{"UniPDF Test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, pki.caCert, signer.Public(), pki.caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(data)
+	require.NoError(t, err)
+	return cert
+}
+
+// validateSignatures validates the signatures of the PDF file `path` with `handler`.
+func validateSignatures(t *testing.T, path string, handler model.SignatureHandler) []model.SignatureValidationResult {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	res, err := reader.ValidateSignatures([]model.SignatureHandler{handler})
+	require.NoError(t, err)
+	return res
+}
+
+func TestAppenderSignCryptoSigner(t *testing.T) {
+	pki := newTestPKI(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		opts   sighandler.SignerOptions
+	}{
+		{"rsa-sha256", rsaKey, sighandler.SignerOptions{}},
+		{"rsa-pss-sha384", rsaKey, sighandler.SignerOptions{HashAlgorithm: crypto.SHA384, PSS: true}},
+		{"ecdsa-p256-sha256", p256Key, sighandler.SignerOptions{}},
+		{"ecdsa-p384-sha384", p384Key, sighandler.SignerOptions{HashAlgorithm: crypto.SHA384}},
+		{"ecdsa-p384-sha512", p384Key, sighandler.SignerOptions{HashAlgorithm: crypto.SHA512}},
+		{"ed25519", ed25519Key, sighandler.SignerOptions{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signer := &opaqueSigner{signer: test.signer}
+			cert := newTestSignerCertificate(t, pki, signer)
+			opts := test.opts
+			opts.Chain = []*x509.Certificate{pki.caCert}
+
+			// adbe.pkcs7.detached signature, validated by both handlers.
+			handler, err := sighandler.NewAdobePKCS7DetachedSigner(signer, cert, &opts)
+			require.NoError(t, err)
+			path := tempFile("appender-sign-signer-" + test.name + ".pdf")
+			signPAdES(t, testPdfFile1, path, handler)
+
+			validator, err := sighandler.NewAdobePKCS7DetachedSigner(nil, nil, nil)
+			require.NoError(t, err)
+			legacyValidator, err := sighandler.NewAdobePKCS7Detached(nil, nil)
+			require.NoError(t, err)
+			for _, h := range []model.SignatureHandler{validator, legacyValidator} {
+				res := validateSignatures(t, path, h)
+				require.Len(t, res, 1)
+				require.True(t, res[0].IsVerified, res[0].Errors)
+			}
+
+			// PAdES signature.
+			padesHandler, err := sighandler.NewEtsiPAdES(signer, cert, &sighandler.PAdESOptions{
+				Chain:         opts.Chain,
+				HashAlgorithm: opts.HashAlgorithm,
+				PSS:           opts.PSS,
+			})
+			require.NoError(t, err)
+			path = tempFile("appender-sign-signer-pades-" + test.name + ".pdf")
+			signPAdES(t, testPdfFile1, path, padesHandler)
+			res := validatePAdES(t, path)
+			require.Len(t, res, 1)
+		})
+	}
+
+	// Reserved signature size too small.
+	handler, err := sighandler.NewAdobePKCS7DetachedSigner(pki.leafKey, pki.leafCert, &sighandler.SignerOptions{
+		SignatureSize: 64,
+	})
+	require.NoError(t, err)
+	require.Error(t, signField(t, testPdfFile1, tempFile("appender-sign-signer-small.pdf"), handler, nil))
+
+	// Forbidden algorithms.
+	invalid := []struct {
+		signer crypto.Signer
+		opts   sighandler.SignerOptions
+	}{
+		{rsaKey, sighandler.SignerOptions{HashAlgorithm: crypto.SHA1}},
+		{rsaKey, sighandler.SignerOptions{HashAlgorithm: crypto.MD5}},
+		{p256Key, sighandler.SignerOptions{PSS: true}},
+		{ed25519Key, sighandler.SignerOptions{HashAlgorithm: crypto.SHA256}},
+	}
+	for i, test := range invalid {
+		_, err := sighandler.NewAdobePKCS7DetachedSigner(test.signer, nil, &test.opts)
+		require.Error(t, err, i)
+		_, err = sighandler.NewEtsiPAdES(test.signer, nil, &sighandler.PAdESOptions{
+			HashAlgorithm: test.opts.HashAlgorithm,
+			PSS:           test.opts.PSS,
+		})
+		require.Error(t, err, i)
+	}
+	p224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	_, err = sighandler.NewAdobePKCS7DetachedSigner(p224Key, nil, nil)
+	require.Error(t, err)
+}
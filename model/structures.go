@@ -70,6 +70,16 @@ func (rect *PdfRectangle) Width() float64 {
 	return math.Abs(rect.Urx - rect.Llx)
 }
 
+// Union returns the smallest rectangle containing `rect` and `other`.
+func (rect *PdfRectangle) Union(other PdfRectangle) PdfRectangle {
+	return PdfRectangle{
+		Llx: math.Min(rect.Llx, other.Llx),
+		Lly: math.Min(rect.Lly, other.Lly),
+		Urx: math.Max(rect.Urx, other.Urx),
+		Ury: math.Max(rect.Ury, other.Ury),
+	}
+}
+
 // ToPdfObject converts rectangle to a PDF object.
 func (rect *PdfRectangle) ToPdfObject() core.PdfObject {
 	return core.MakeArray(
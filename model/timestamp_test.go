@@ -0,0 +1,161 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"crypto"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unidoc/timestamp"
+
+	"github.com/loxiouve/unipdf/v3/model"
+	"github.com/loxiouve/unipdf/v3/model/sighandler"
+)
+
+func TestDocTimeStampClient(t *testing.T) {
+	pki := newTestPKI(t)
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+	genTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	tsa.Now = func() time.Time { return genTime }
+
+	// In-process TSA.
+	handler, err := sighandler.NewDocTimeStampWithOpts(&sighandler.DocTimeStampOpts{
+		Client:        tsa,
+		HashAlgorithm: crypto.SHA256,
+	})
+	require.NoError(t, err)
+	path := tempFile("timestamp-client-local.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+
+	// The TSA certificate chain is verified with the trusted roots.
+	validator, err := sighandler.NewDocTimeStampWithOpts(&sighandler.DocTimeStampOpts{
+		TimestampRoots: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	res := validateSignatures(t, path, validator)
+	require.Len(t, res, 1)
+	require.True(t, res[0].IsVerified)
+	require.True(t, res[0].IsTimestampTrusted, res[0].Errors)
+	require.True(t, genTime.Equal(res[0].GeneralizedTime))
+
+	untrusted, err := sighandler.NewDocTimeStampWithOpts(&sighandler.DocTimeStampOpts{
+		TimestampRoots: []*x509.Certificate{newTestPKI(t).caCert},
+	})
+	require.NoError(t, err)
+	res = validateSignatures(t, path, untrusted)
+	require.True(t, res[0].IsVerified)
+	require.False(t, res[0].IsTimestampTrusted)
+	require.NotEmpty(t, res[0].Errors)
+
+	// A client is required for signing.
+	handler, err = sighandler.NewDocTimeStampWithOpts(nil)
+	require.NoError(t, err)
+	require.Error(t, model.NewPdfSignature(handler).Initialize())
+}
+
+func TestHTTPTimestampClient(t *testing.T) {
+	pki := newTestPKI(t)
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+
+	// Timestamp server requiring authentication, failing on the first request.
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		tsa.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := sighandler.NewHTTPTimestampClient(server.URL)
+	client.Username = "user"
+	client.Password = "secret"
+	client.Header = http.Header{"X-Api-Key": []string{"key"}}
+	digest := crypto.SHA256.New().Sum(nil)
+	_, err := client.Timestamp(crypto.SHA256, digest)
+	require.Error(t, err)
+
+	requests = 0
+	client.Retries = 1
+	token, err := client.Timestamp(crypto.SHA256, digest)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.Equal(t, 2, requests)
+
+	// Authentication failures are not retried.
+	requests = 0
+	client.Password = "wrong"
+	_, err = client.Timestamp(crypto.SHA256, digest)
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+
+	// Rejected requests.
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.BadAlgorithm)
+		require.NoError(t, err)
+		w.Write(resp)
+	}))
+	defer rejecting.Close()
+	_, err = sighandler.NewHTTPTimestampClient(rejecting.URL).Timestamp(crypto.SHA256, digest)
+	require.Error(t, err)
+}
+
+func TestSignatureTimestampClient(t *testing.T) {
+	pki := newTestPKI(t)
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+	roots := []*x509.Certificate{pki.caCert}
+
+	// adbe.pkcs7.detached signature with a signature timestamp.
+	handler, err := sighandler.NewAdobePKCS7DetachedSigner(pki.leafKey, pki.leafCert, &sighandler.SignerOptions{
+		Chain:           roots,
+		TimestampClient: tsa,
+	})
+	require.NoError(t, err)
+	path := tempFile("timestamp-client-pkcs7.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+
+	validator, err := sighandler.NewAdobePKCS7DetachedSigner(nil, nil, &sighandler.SignerOptions{
+		TimestampRoots: roots,
+	})
+	require.NoError(t, err)
+	res := validateSignatures(t, path, validator)
+	require.Len(t, res, 1)
+	require.True(t, res[0].IsVerified, res[0].Errors)
+	require.True(t, res[0].IsTimestampTrusted, res[0].Errors)
+	require.False(t, res[0].GeneralizedTime.IsZero())
+
+	// PAdES signature with a signature timestamp, whose TSA is not trusted by default.
+	handler, err = sighandler.NewEtsiPAdES(pki.leafKey, pki.leafCert, &sighandler.PAdESOptions{
+		Chain:           roots,
+		TimestampClient: tsa,
+	})
+	require.NoError(t, err)
+	path = tempFile("timestamp-client-pades.pdf")
+	signPAdES(t, testPdfFile1, path, handler)
+	res = validatePAdES(t, path)
+	require.Len(t, res, 1)
+	require.False(t, res[0].IsTimestampTrusted)
+
+	validator, err = sighandler.NewEtsiPAdES(nil, nil, &sighandler.PAdESOptions{TimestampRoots: roots})
+	require.NoError(t, err)
+	res = validateSignatures(t, path, validator)
+	require.True(t, res[0].IsTimestampTrusted, res[0].Errors)
+}
@@ -0,0 +1,234 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// PageLayout specifies the page layout used by viewers when the document is opened
+// (PageLayout entry of the catalog, Table 28 - p. 73).
+type PageLayout string
+
+// Page layouts.
+const (
+	PageLayoutSinglePage     PageLayout = "SinglePage"     // One page at a time
+	PageLayoutOneColumn      PageLayout = "OneColumn"      // The pages in one column
+	PageLayoutTwoColumnLeft  PageLayout = "TwoColumnLeft"  // The pages in two columns, odd pages on the left
+	PageLayoutTwoColumnRight PageLayout = "TwoColumnRight" // The pages in two columns, odd pages on the right
+	PageLayoutTwoPageLeft    PageLayout = "TwoPageLeft"    // Two pages at a time, odd pages on the left
+	PageLayoutTwoPageRight   PageLayout = "TwoPageRight"   // Two pages at a time, odd pages on the right
+)
+
+// PageMode specifies how the document is displayed by viewers when opened
+// (PageMode entry of the catalog, Table 28 - p. 73).
+type PageMode string
+
+// Page modes.
+const (
+	PageModeUseNone        PageMode = "UseNone"        // Neither the outline nor the thumbnails are visible
+	PageModeUseOutlines    PageMode = "UseOutlines"    // The outline is visible
+	PageModeUseThumbs      PageMode = "UseThumbs"      // The thumbnail images are visible
+	PageModeFullScreen     PageMode = "FullScreen"     // Full-screen mode
+	PageModeUseOC          PageMode = "UseOC"          // The optional content group panel is visible
+	PageModeUseAttachments PageMode = "UseAttachments" // The attachments panel is visible
+)
+
+// ViewerDirection is the predominant reading order of the text of a document.
+type ViewerDirection string
+
+// Reading orders.
+const (
+	ViewerDirectionL2R ViewerDirection = "L2R" // Left to right
+	ViewerDirectionR2L ViewerDirection = "R2L" // Right to left
+)
+
+// ViewerPrintScaling is the page scaling option of the print dialog of viewers.
+type ViewerPrintScaling string
+
+// Page scaling options.
+const (
+	ViewerPrintScalingNone       ViewerPrintScaling = "None"       // No page scaling
+	ViewerPrintScalingAppDefault ViewerPrintScaling = "AppDefault" // Default scaling of the viewer
+)
+
+// ViewerDuplex is the paper handling option of the print dialog of viewers.
+type ViewerDuplex string
+
+// Paper handling options.
+const (
+	ViewerDuplexSimplex       ViewerDuplex = "Simplex"             // Print single-sided
+	ViewerDuplexFlipShortEdge ViewerDuplex = "DuplexFlipShortEdge" // Duplex, flip on the short edge
+	ViewerDuplexFlipLongEdge  ViewerDuplex = "DuplexFlipLongEdge"  // Duplex, flip on the long edge
+)
+
+// ViewerPreferences represents the viewer preferences of a document, specifying how
+// viewers present the document on the screen or in print (section 12.2 p. 362).
+// The unset fields are not written, viewers using their default values.
+type ViewerPreferences struct {
+	HideToolbar     bool // Hide the tool bars of the viewer
+	HideMenubar     bool // Hide the menu bar of the viewer
+	HideWindowUI    bool // Hide the user interface elements of the document window
+	FitWindow       bool // Resize the document window to fit the first page
+	CenterWindow    bool // Center the document window on the screen
+	DisplayDocTitle bool // Display the document title of the Info dictionary in the window title bar
+
+	// NonFullScreenPageMode is the page mode used when exiting full-screen mode.
+	NonFullScreenPageMode PageMode
+
+	Direction    ViewerDirection
+	PrintScaling ViewerPrintScaling
+	Duplex       ViewerDuplex
+
+	// PrintPageRange contains the first and last page numbers of the page ranges initially
+	// selected in the print dialog, the first page being 1.
+	PrintPageRange []int
+
+	// NumCopies is the number of copies initially selected in the print dialog, 0 if unset.
+	NumCopies int
+}
+
+// NewViewerPreferences returns new viewer preferences with default values.
+func NewViewerPreferences() *ViewerPreferences {
+	return &ViewerPreferences{}
+}
+
+// newViewerPreferencesFromPdfObject loads the viewer preferences from the dictionary `obj`.
+func newViewerPreferencesFromPdfObject(obj core.PdfObject) (*ViewerPreferences, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, errors.New("viewer preferences must be a dictionary")
+	}
+
+	flag := func(key core.PdfObjectName) bool {
+		val, _ := core.GetBoolVal(d.Get(key))
+		return val
+	}
+	name := func(key core.PdfObjectName) string {
+		val, _ := core.GetNameVal(d.Get(key))
+		return val
+	}
+
+	prefs := &ViewerPreferences{
+		HideToolbar:           flag("HideToolbar"),
+		HideMenubar:           flag("HideMenubar"),
+		HideWindowUI:          flag("HideWindowUI"),
+		FitWindow:             flag("FitWindow"),
+		CenterWindow:          flag("CenterWindow"),
+		DisplayDocTitle:       flag("DisplayDocTitle"),
+		NonFullScreenPageMode: PageMode(name("NonFullScreenPageMode")),
+		Direction:             ViewerDirection(name("Direction")),
+		PrintScaling:          ViewerPrintScaling(name("PrintScaling")),
+		Duplex:                ViewerDuplex(name("Duplex")),
+	}
+	if arr, ok := core.GetArray(d.Get("PrintPageRange")); ok {
+		pageRange, err := arr.ToIntegerArray()
+		if err != nil || len(pageRange)%2 != 0 {
+			common.Log.Debug("WARN: invalid print page range: %v", arr)
+		} else {
+			prefs.PrintPageRange = pageRange
+		}
+	}
+	if numCopies, ok := core.GetIntVal(d.Get("NumCopies")); ok {
+		prefs.NumCopies = numCopies
+	}
+	return prefs, nil
+}
+
+// ToPdfObject returns the viewer preferences dictionary.
+func (vp *ViewerPreferences) ToPdfObject() core.PdfObject {
+	d := core.MakeDict()
+	flags := []struct {
+		key core.PdfObjectName
+		val bool
+	}{
+		{"HideToolbar", vp.HideToolbar},
+		{"HideMenubar", vp.HideMenubar},
+		{"HideWindowUI", vp.HideWindowUI},
+		{"FitWindow", vp.FitWindow},
+		{"CenterWindow", vp.CenterWindow},
+		{"DisplayDocTitle", vp.DisplayDocTitle},
+	}
+	for _, flag := range flags {
+		if flag.val {
+			d.Set(flag.key, core.MakeBool(true))
+		}
+	}
+
+	names := []struct {
+		key core.PdfObjectName
+		val string
+	}{
+		{"NonFullScreenPageMode", string(vp.NonFullScreenPageMode)},
+		{"Direction", string(vp.Direction)},
+		{"PrintScaling", string(vp.PrintScaling)},
+		{"Duplex", string(vp.Duplex)},
+	}
+	for _, name := range names {
+		if name.val != "" {
+			d.Set(name.key, core.MakeName(name.val))
+		}
+	}
+
+	if len(vp.PrintPageRange) > 0 {
+		d.Set("PrintPageRange", core.MakeArrayFromIntegers(vp.PrintPageRange))
+	}
+	if vp.NumCopies > 0 {
+		d.Set("NumCopies", core.MakeInteger(int64(vp.NumCopies)))
+	}
+	return d
+}
+
+// GetViewerPreferences returns the viewer preferences of the document. Returns nil if the
+// document has no viewer preferences.
+func (r *PdfReader) GetViewerPreferences() (*ViewerPreferences, error) {
+	obj := r.catalog.Get("ViewerPreferences")
+	if obj == nil || core.IsNullObject(obj) {
+		return nil, nil
+	}
+	return newViewerPreferencesFromPdfObject(obj)
+}
+
+// GetPageLayout returns the page layout of the document. Returns PageLayoutSinglePage,
+// the default page layout, if not specified.
+func (r *PdfReader) GetPageLayout() PageLayout {
+	if layout, ok := core.GetNameVal(r.catalog.Get("PageLayout")); ok {
+		return PageLayout(layout)
+	}
+	return PageLayoutSinglePage
+}
+
+// GetPageMode returns the page mode of the document. Returns PageModeUseNone, the default
+// page mode, if not specified.
+func (r *PdfReader) GetPageMode() PageMode {
+	if mode, ok := core.GetNameVal(r.catalog.Get("PageMode")); ok {
+		return PageMode(mode)
+	}
+	return PageModeUseNone
+}
+
+// SetViewerPreferences sets the viewer preferences of the document.
+func (w *PdfWriter) SetViewerPreferences(prefs *ViewerPreferences) error {
+	if prefs == nil {
+		return errors.New("viewer preferences cannot be nil")
+	}
+	w.catalog.Set("ViewerPreferences", prefs.ToPdfObject())
+	return nil
+}
+
+// SetPageLayout sets the page layout used by viewers when the document is opened.
+func (w *PdfWriter) SetPageLayout(layout PageLayout) {
+	w.catalog.Set("PageLayout", core.MakeName(string(layout)))
+}
+
+// SetPageMode sets how the document is displayed by viewers when opened, e.g.
+// PageModeUseOutlines to show the outline panel.
+func (w *PdfWriter) SetPageMode(mode PageMode) {
+	w.catalog.Set("PageMode", core.MakeName(string(mode)))
+}
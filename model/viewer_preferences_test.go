@@ -0,0 +1,61 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+func TestViewerPreferences(t *testing.T) {
+	f, err := os.Open("./testdata/pages3.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+
+	// Preferences of the input file.
+	prefs, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.Equal(t, &model.ViewerPreferences{DisplayDocTitle: true}, prefs)
+	require.Equal(t, model.PageLayoutSinglePage, reader.GetPageLayout())
+	require.Equal(t, model.PageModeUseNone, reader.GetPageMode())
+
+	writer := model.NewPdfWriter()
+	for _, page := range reader.PageList {
+		require.NoError(t, writer.AddPage(page))
+	}
+	prefs = model.NewViewerPreferences()
+	prefs.HideToolbar = true
+	prefs.FitWindow = true
+	prefs.DisplayDocTitle = true
+	prefs.NonFullScreenPageMode = model.PageModeUseOutlines
+	prefs.Direction = model.ViewerDirectionR2L
+	prefs.PrintScaling = model.ViewerPrintScalingNone
+	prefs.Duplex = model.ViewerDuplexFlipLongEdge
+	prefs.PrintPageRange = []int{1, 1, 3, 3}
+	prefs.NumCopies = 2
+	require.NoError(t, writer.SetViewerPreferences(prefs))
+	require.Error(t, writer.SetViewerPreferences(nil))
+	writer.SetPageLayout(model.PageLayoutTwoColumnLeft)
+	writer.SetPageMode(model.PageModeUseAttachments)
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	readPrefs, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.Equal(t, prefs, readPrefs)
+	require.Equal(t, model.PageLayoutTwoColumnLeft, reader.GetPageLayout())
+	require.Equal(t, model.PageModeUseAttachments, reader.GetPageMode())
+}
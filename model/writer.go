@@ -138,6 +138,7 @@ type PdfWriter struct {
 	objectsMap  map[core.PdfObject]struct{} // Quick lookup table.
 	outlines    []*core.PdfIndirectObject
 	outlineTree *PdfOutlineTreeNode
+	javaScripts []documentScript
 	catalog     *core.PdfObjectDictionary
 	fields      []core.PdfObject
 	infoObj     *core.PdfIndirectObject
@@ -961,6 +962,16 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// Document-level scripts.
+	if len(w.javaScripts) > 0 {
+		names := makeJavaScriptNames(w.catalog.Get("Names"), w.javaScripts, true)
+		w.catalog.Set("Names", names)
+		err := w.addObjects(names)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Form fields.
 	if w.acroForm != nil {
 		common.Log.Trace("Writing acro forms")
@@ -0,0 +1,476 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+// XFAFormType represents the type of the XML Forms Architecture (XFA) form of a document.
+type XFAFormType int
+
+const (
+	// XFAFormTypeNone indicates that the document has no XFA form.
+	XFAFormTypeNone XFAFormType = iota
+
+	// XFAFormTypeStatic indicates a static XFA form: the pages are described by the PDF content,
+	// and the XFA fields have AcroForm counterparts (hybrid form).
+	XFAFormTypeStatic
+
+	// XFAFormTypeDynamic indicates a dynamic XFA form: the pages are rendered by the viewer from
+	// the XFA template, and the PDF content is only a placeholder.
+	XFAFormTypeDynamic
+)
+
+// String returns a string representation of the XFA form type.
+func (t XFAFormType) String() string {
+	switch t {
+	case XFAFormTypeStatic:
+		return "static"
+	case XFAFormTypeDynamic:
+		return "dynamic"
+	}
+	return "none"
+}
+
+// xfaDataNamespace is the namespace of the XFA data elements.
+const xfaDataNamespace = "http://www.xfa.org/schema/xfa-data/1.0/"
+
+// dynamicRenderRegexp matches the configuration of the forms rendered dynamically.
+var dynamicRenderRegexp = regexp.MustCompile(`<dynamicRender>\s*required\s*</dynamicRender>`)
+
+// XFAFormType returns the type of the XFA form of the document. A form is dynamic if the document
+// requires rendering by the viewer (NeedsRendering), if its XFA configuration requires dynamic
+// rendering, or if it has no AcroForm fields.
+func (r *PdfReader) XFAFormType() XFAFormType {
+	if !r.AcroForm.HasXFA() {
+		return XFAFormTypeNone
+	}
+	if r.catalog != nil {
+		if needsRendering, ok := core.GetBoolVal(r.catalog.Get("NeedsRendering")); ok && needsRendering {
+			return XFAFormTypeDynamic
+		}
+	}
+	if config, err := r.AcroForm.XFAPacket("config"); err == nil && dynamicRenderRegexp.Match(config) {
+		return XFAFormTypeDynamic
+	}
+	if len(r.AcroForm.AllFields()) == 0 {
+		return XFAFormTypeDynamic
+	}
+	return XFAFormTypeStatic
+}
+
+// HasXFA returns true if the form contains an XFA form (XFA).
+func (form *PdfAcroForm) HasXFA() bool {
+	return form != nil && core.TraceToDirectObject(form.XFA) != nil
+}
+
+// RemoveXFA removes the XFA form, so that viewers display and fill the AcroForm fields. The pages
+// of dynamic XFA forms are only placeholders, which are displayed once the XFA form is removed.
+// The requirement of dynamic rendering (NeedsRendering) of the document catalog is dropped when
+// writing the form with an appender.
+func (form *PdfAcroForm) RemoveXFA() {
+	if form != nil {
+		form.XFA = nil
+	}
+}
+
+// xfaPackets returns the names and streams of the XFA packets. The XFA form is either an array of
+// packets, or a stream containing the whole XML Data Package (XDP), returned as a single packet
+// without name.
+func (form *PdfAcroForm) xfaPackets() ([]string, []*core.PdfObjectStream, error) {
+	switch t := core.TraceToDirectObject(form.XFA).(type) {
+	case *core.PdfObjectStream:
+		return []string{""}, []*core.PdfObjectStream{t}, nil
+	case *core.PdfObjectArray:
+		if t.Len()%2 != 0 {
+			return nil, nil, errors.New("invalid XFA array length")
+		}
+		var names []string
+		var streams []*core.PdfObjectStream
+		for i := 0; i < t.Len(); i += 2 {
+			name, ok := core.GetString(t.Get(i))
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid XFA packet name (%T)", t.Get(i))
+			}
+			stream, ok := core.GetStream(t.Get(i + 1))
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid XFA packet %s (%T)", name.Decoded(), t.Get(i+1))
+			}
+			names = append(names, name.Decoded())
+			streams = append(streams, stream)
+		}
+		return names, streams, nil
+	case nil:
+		return nil, nil, errors.New("XFA form not found")
+	default:
+		return nil, nil, fmt.Errorf("invalid XFA type (%T)", t)
+	}
+}
+
+// XFAPackage returns the whole XML Data Package (XDP) of the XFA form, which is the concatenation
+// of its packets.
+func (form *PdfAcroForm) XFAPackage() ([]byte, error) {
+	_, streams, err := form.xfaPackets()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, stream := range streams {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// XFAPacket returns the XML data of the XFA packet `name`, e.g. "template", "config" or
+// "datasets".
+func (form *PdfAcroForm) XFAPacket(name string) ([]byte, error) {
+	names, streams, err := form.xfaPackets()
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range names {
+		if n == name {
+			return core.DecodeStream(streams[i])
+		}
+	}
+
+	// Look for the packet in the whole package.
+	xdp, err := form.XFAPackage()
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := findXDPPacket(xdp, name)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("XFA packet %s not found", name)
+	}
+	return xdp[start:end], nil
+}
+
+// findXDPPacket returns the offsets of the start and the end of the XFA packet `name` in the XML
+// Data Package `xdp`. The packets are the children of the root element. Returns -1 offsets if
+// not found.
+func findXDPPacket(xdp []byte, name string) (int, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xdp))
+	depth := 0
+	start := -1
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return -1, -1, nil
+		}
+		if err != nil {
+			return -1, -1, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == name {
+				start = offset
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 && start >= 0 {
+				return start, int(decoder.InputOffset()), nil
+			}
+		}
+	}
+}
+
+// findXDPEnd returns the offset of the end element of the xdp root element of the XML Data
+// Package `xdp`, whatever its namespace prefix. Returns -1 if not found.
+func findXDPEnd(xdp []byte) (int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xdp))
+	depth := 0
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 && t.Name.Local == "xdp" {
+				return offset, nil
+			}
+		}
+	}
+}
+
+// XFADatasets returns the datasets packet of the XFA form, containing the data of the XFA fields.
+func (form *PdfAcroForm) XFADatasets() ([]byte, error) {
+	return form.XFAPacket("datasets")
+}
+
+// SetXFADatasets replaces the datasets packet of the XFA form with `datasets`, which must be a
+// complete xfa:datasets element. The packet is added if not present.
+func (form *PdfAcroForm) SetXFADatasets(datasets []byte) error {
+	if err := xml.Unmarshal(datasets, new(struct{})); err != nil {
+		return fmt.Errorf("invalid datasets: %v", err)
+	}
+	stream, err := core.MakeStream(datasets, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	names, streams, err := form.xfaPackets()
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 1 && names[0] == "" {
+		// Replace the packet in the whole package.
+		xdp, err := form.XFAPackage()
+		if err != nil {
+			return err
+		}
+		start, end, err := findXDPPacket(xdp, "datasets")
+		if err != nil {
+			return err
+		}
+		if start < 0 {
+			// Insert before the end of the root element.
+			start, err = findXDPEnd(xdp)
+			if err != nil {
+				return err
+			}
+			if start < 0 {
+				return errors.New("invalid XFA data package: xdp end element not found")
+			}
+			end = start
+		}
+		var buf bytes.Buffer
+		buf.Write(xdp[:start])
+		buf.Write(datasets)
+		buf.Write(xdp[end:])
+		stream, err = core.MakeStream(buf.Bytes(), core.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		form.XFA = stream
+		return nil
+	}
+
+	arr := core.MakeArray()
+	found := false
+	for i, name := range names {
+		if name == "postamble" && !found {
+			arr.Append(core.MakeString("datasets"), stream)
+			found = true
+		}
+		if name == "datasets" {
+			arr.Append(core.MakeString(name), stream)
+			found = true
+			continue
+		}
+		arr.Append(core.MakeString(name), streams[i])
+	}
+	if !found {
+		arr.Append(core.MakeString("datasets"), stream)
+	}
+	form.XFA = arr
+	return nil
+}
+
+// XFAFieldValues returns the values of the XFA data of the form, mapped by the full names of the
+// corresponding AcroForm fields. The XFA fields are matched with the AcroForm fields by the data
+// paths, ignoring the unnamed subforms (#subform) of the AcroForm field names.
+func (form *PdfAcroForm) XFAFieldValues() (map[string]core.PdfObject, error) {
+	datasets, err := form.XFADatasets()
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseXFAData(datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]core.PdfObject{}
+	for _, field := range form.AllFields() {
+		if len(field.Kids) > 0 {
+			continue
+		}
+		fullName, err := field.FullName()
+		if err != nil {
+			continue
+		}
+		val, ok := data[xfaDataPath(fullName)]
+		if !ok {
+			continue
+		}
+		if _, ok := field.GetContext().(*PdfFieldButton); ok {
+			values[fullName] = xfaButtonValue(field, val)
+			continue
+		}
+		values[fullName] = core.MakeString(val)
+	}
+	return values, nil
+}
+
+// FillFromXFA fills the AcroForm fields with the data of the XFA form, so that both forms have the
+// same values. If not nil, `appGen` is used to generate the appearances of the filled fields.
+func (form *PdfAcroForm) FillFromXFA(appGen FieldAppearanceGenerator) error {
+	values, err := form.XFAFieldValues()
+	if err != nil {
+		return err
+	}
+	return form.fill(xfaFieldValues(values), appGen)
+}
+
+// xfaFieldValues is the field value provider of the XFA data.
+type xfaFieldValues map[string]core.PdfObject
+
+// FieldValues implements interface FieldValueProvider.
+func (v xfaFieldValues) FieldValues() (map[string]core.PdfObject, error) {
+	return v, nil
+}
+
+// xfaIndexRegexp matches the index of the XFA names, e.g. "[0]".
+var xfaIndexRegexp = regexp.MustCompile(`\[\d+\]$`)
+
+// xfaDataPath returns the data path of the AcroForm field with full name `fullName`. The unnamed
+// subforms are removed, and the indices are added to the names, e.g. the path of
+// "form1[0].#subform[0].Name" is "form1[0].Name[0]".
+func xfaDataPath(fullName string) string {
+	var parts []string
+	for _, part := range strings.Split(fullName, ".") {
+		if strings.HasPrefix(part, "#") {
+			continue
+		}
+		if !xfaIndexRegexp.MatchString(part) {
+			part += "[0]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// xfaButtonValue returns the state of the button field `field` for the XFA value `val`. The
+// buttons without on state `val` are turned off.
+func xfaButtonValue(field *PdfField, val string) core.PdfObject {
+	for _, wa := range field.Annotations {
+		apDict, ok := core.GetDict(wa.AP)
+		if !ok {
+			continue
+		}
+		if nDict, ok := core.GetDict(apDict.Get("N")); ok && nDict.Get(core.PdfObjectName(val)) != nil {
+			return core.MakeName(val)
+		}
+	}
+	return core.MakeName("Off")
+}
+
+// parseXFAData returns the values of the data elements of the datasets packet `datasets`,
+// mapped by their data paths, e.g. "form1[0].Address[0].City[0]".
+func parseXFAData(datasets []byte) (map[string]string, error) {
+	type node struct {
+		path     string
+		counts   map[string]int
+		hasKids  bool
+		richText bool
+		text     strings.Builder
+	}
+
+	values := map[string]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(datasets))
+	var stack []*node
+	inData := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !inData {
+				if t.Name.Space == xfaDataNamespace && t.Name.Local == "data" {
+					inData = true
+					stack = []*node{{counts: map[string]int{}}}
+				}
+				continue
+			}
+			parent := stack[len(stack)-1]
+			if parent.richText {
+				// The XHTML elements of rich text values are part of the value.
+				stack = append(stack, &node{richText: true})
+				continue
+			}
+			parent.hasKids = true
+
+			idx := parent.counts[t.Name.Local]
+			parent.counts[t.Name.Local]++
+			path := fmt.Sprintf("%s[%d]", t.Name.Local, idx)
+			if parent.path != "" {
+				path = parent.path + "." + path
+			}
+			n := &node{path: path, counts: map[string]int{}}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "contentType" && attr.Value == "text/html" {
+					n.richText = true
+				}
+			}
+			stack = append(stack, n)
+		case xml.CharData:
+			if inData {
+				for i := len(stack) - 1; i >= 0; i-- {
+					stack[i].text.Write(t)
+					if !stack[i].richText || stack[i].path != "" {
+						break
+					}
+				}
+			}
+		case xml.EndElement:
+			if !inData {
+				continue
+			}
+			if len(stack) == 1 {
+				inData = false
+				stack = nil
+				continue
+			}
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if n.path == "" {
+				continue
+			}
+			if !n.hasKids || n.richText {
+				values[n.path] = n.text.String()
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		common.Log.Debug("XFA data not found")
+	}
+	return values, nil
+}
@@ -0,0 +1,195 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+)
+
+const xfaTestDatasets = `<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">
+<xfa:data>
+<form1>
+<Name>John Doe</Name>
+<Address><City>Paris</City></Address>
+<Item>first</Item><Item>second</Item>
+<Agree>1</Agree>
+<Notes xfa:contentType="text/html"><body xmlns="http://www.w3.org/1999/xhtml"><p>Rich <b>text</b></p></body></Notes>
+</form1>
+</xfa:data>
+</xfa:datasets>`
+
+// makeXFATestForm returns an AcroForm with the fields of a hybrid XFA form, whose XFA is made of
+// the packets `packets`, as pairs of names and XML data.
+func makeXFATestForm(t *testing.T, packets ...string) *PdfAcroForm {
+	form := NewPdfAcroForm()
+	form.Fields = &[]*PdfField{}
+
+	root := NewPdfField()
+	root.SetContext(&PdfFieldText{PdfField: root})
+	root.T = core.MakeString("form1[0]")
+	addKid := func(parent *PdfField, field *PdfField, name string) {
+		field.T = core.MakeString(name)
+		field.Parent = parent
+		parent.Kids = append(parent.Kids, field)
+	}
+
+	subform := NewPdfField()
+	subform.SetContext(&PdfFieldText{PdfField: subform})
+	addKid(root, subform, "#subform[0]")
+	for _, name := range []string{"Name[0]", "Item[0]", "Item[1]", "Notes[0]", "Missing[0]"} {
+		field := NewPdfField()
+		field.SetContext(&PdfFieldText{PdfField: field})
+		addKid(subform, field, name)
+	}
+	address := NewPdfField()
+	address.SetContext(&PdfFieldText{PdfField: address})
+	addKid(root, address, "Address[0]")
+	city := NewPdfField()
+	city.SetContext(&PdfFieldText{PdfField: city})
+	addKid(address, city, "City[0]")
+
+	agree := NewPdfField()
+	button := &PdfFieldButton{PdfField: agree}
+	agree.SetContext(button)
+	button.SetType(ButtonTypeCheckbox)
+	widget := NewPdfAnnotationWidget()
+	nDict := core.MakeDict()
+	nDict.Set("1", core.MakeNull())
+	nDict.Set("Off", core.MakeNull())
+	apDict := core.MakeDict()
+	apDict.Set("N", nDict)
+	widget.AP = apDict
+	agree.Annotations = append(agree.Annotations, widget)
+	addKid(subform, agree, "Agree[0]")
+
+	*form.Fields = append(*form.Fields, root)
+
+	xfa := core.MakeArray()
+	for i := 0; i < len(packets); i += 2 {
+		stream, err := core.MakeStream([]byte(packets[i+1]), core.NewFlateEncoder())
+		require.NoError(t, err)
+		xfa.Append(core.MakeString(packets[i]), stream)
+	}
+	form.XFA = xfa
+	return form
+}
+
+func TestXFAPackets(t *testing.T) {
+	form := makeXFATestForm(t,
+		"preamble", `<xdp:xdp xmlns:xdp="http://ns.adobe.com/xdp/">`,
+		"config", `<config><present><pdf><dynamicRender>required</dynamicRender></pdf></present></config>`,
+		"datasets", xfaTestDatasets,
+		"postamble", `</xdp:xdp>`,
+	)
+	require.True(t, form.HasXFA())
+
+	datasets, err := form.XFADatasets()
+	require.NoError(t, err)
+	require.Equal(t, xfaTestDatasets, string(datasets))
+	xdp, err := form.XFAPackage()
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(xdp, []byte("<xdp:xdp")))
+
+	// Replace the datasets.
+	replaced := strings.Replace(xfaTestDatasets, "Paris", "Lyon", 1)
+	require.NoError(t, form.SetXFADatasets([]byte(replaced)))
+	datasets, err = form.XFADatasets()
+	require.NoError(t, err)
+	require.Equal(t, replaced, string(datasets))
+	require.Error(t, form.SetXFADatasets([]byte("<xfa:datasets>")))
+
+	// Packets of a whole package stream.
+	stream, err := core.MakeStream(xdp, core.NewFlateEncoder())
+	require.NoError(t, err)
+	form.XFA = stream
+	config, err := form.XFAPacket("config")
+	require.NoError(t, err)
+	require.True(t, dynamicRenderRegexp.Match(config))
+	require.NoError(t, form.SetXFADatasets([]byte(replaced)))
+	datasets, err = form.XFADatasets()
+	require.NoError(t, err)
+	require.Equal(t, replaced, string(datasets))
+	_, err = form.XFAPacket("template")
+	require.Error(t, err)
+
+	form.RemoveXFA()
+	require.False(t, form.HasXFA())
+	_, err = form.XFADatasets()
+	require.Error(t, err)
+}
+
+func TestXFAAddDatasets(t *testing.T) {
+	form := NewPdfAcroForm()
+	setPackage := func(xdp string) {
+		stream, err := core.MakeStream([]byte(xdp), core.NewFlateEncoder())
+		require.NoError(t, err)
+		form.XFA = stream
+	}
+
+	// The datasets are inserted at the end of the xdp root element, not of the trailing comment.
+	setPackage(`<xdp:xdp xmlns:xdp="http://ns.adobe.com/xdp/"><config/></xdp:xdp>
+<!-- </config> -->`)
+	require.NoError(t, form.SetXFADatasets([]byte(xfaTestDatasets)))
+	xdp, err := form.XFAPackage()
+	require.NoError(t, err)
+	require.Equal(t, `<xdp:xdp xmlns:xdp="http://ns.adobe.com/xdp/"><config/>`+xfaTestDatasets+`</xdp:xdp>
+<!-- </config> -->`, string(xdp))
+	datasets, err := form.XFADatasets()
+	require.NoError(t, err)
+	require.Equal(t, xfaTestDatasets, string(datasets))
+
+	// The package without xdp root element is rejected.
+	setPackage(`<config><present/></config>`)
+	require.Error(t, form.SetXFADatasets([]byte(xfaTestDatasets)))
+}
+
+func TestXFAFormType(t *testing.T) {
+	reader := &PdfReader{catalog: core.MakeDict()}
+	require.Equal(t, XFAFormTypeNone, reader.XFAFormType())
+
+	reader.AcroForm = makeXFATestForm(t, "datasets", xfaTestDatasets)
+	require.Equal(t, XFAFormTypeStatic, reader.XFAFormType())
+
+	reader.catalog.Set("NeedsRendering", core.MakeBool(true))
+	require.Equal(t, XFAFormTypeDynamic, reader.XFAFormType())
+	require.Equal(t, "dynamic", reader.XFAFormType().String())
+}
+
+func TestFillFromXFA(t *testing.T) {
+	form := makeXFATestForm(t, "datasets", xfaTestDatasets)
+
+	values, err := form.XFAFieldValues()
+	require.NoError(t, err)
+	require.Len(t, values, 6)
+	require.Equal(t, "Rich text", values["form1[0].#subform[0].Notes[0]"].(*core.PdfObjectString).Decoded())
+
+	require.NoError(t, form.FillFromXFA(nil))
+	filled := map[string]string{}
+	for _, field := range form.AllFields() {
+		if len(field.Kids) > 0 || field.V == nil {
+			continue
+		}
+		name, err := field.FullName()
+		require.NoError(t, err)
+		filled[name] = fieldValueText(field)
+	}
+	require.Equal(t, map[string]string{
+		"form1[0].#subform[0].Name[0]":  "John Doe",
+		"form1[0].#subform[0].Item[0]":  "first",
+		"form1[0].#subform[0].Item[1]":  "second",
+		"form1[0].#subform[0].Notes[0]": "Rich text",
+		"form1[0].#subform[0].Agree[0]": "1",
+		"form1[0].Address[0].City[0]":   "Paris",
+	}, filled)
+
+	require.Equal(t, "form1[0].Address[0].City[0]", xfaDataPath("form1.#subform[2].Address.City[0]"))
+}
@@ -0,0 +1,880 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/textencoding"
+	"github.com/loxiouve/unipdf/v3/internal/transform"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// maxFormDepth is the maximum nesting level of form XObjects which are processed.
+const maxFormDepth = 20
+
+// Default glyph extents (in glyph space units) used when the font descriptor
+// does not specify the ascent and descent of the font.
+const (
+	defaultAscent  = 800.0
+	defaultDescent = -200.0
+)
+
+// contentRedactor removes the content of a content stream which intersects
+// the redaction areas.
+type contentRedactor struct {
+	// Redaction areas in page space.
+	areas []model.PdfRectangle
+
+	// minGlyphOverlap is the minimum fraction of the bounding box of a glyph
+	// which must be inside an area for the glyph to be removed.
+	minGlyphOverlap float64
+
+	// Loaded fonts, by font object.
+	fonts map[core.PdfObject]*model.PdfFont
+
+	stats Stats
+}
+
+// newContentRedactor returns a new content redactor for the areas. The glyphs
+// are removed when at least the fraction `minGlyphOverlap` of their bounding
+// box is inside an area, any intersecting glyph being removed if zero.
+func newContentRedactor(areas []model.PdfRectangle, minGlyphOverlap float64) *contentRedactor {
+	return &contentRedactor{
+		areas:           areas,
+		minGlyphOverlap: minGlyphOverlap,
+		fonts:           map[core.PdfObject]*model.PdfFont{},
+	}
+}
+
+// textState represents the text state parameters (section 9.3 "Text State
+// Parameters and Operators").
+type textState struct {
+	tc    float64 // Character spacing.
+	tw    float64 // Word spacing.
+	th    float64 // Horizontal scaling (percent).
+	tl    float64 // Leading.
+	tfs   float64 // Font size.
+	trise float64 // Text rise.
+	font  *model.PdfFont
+}
+
+// redactState is the state which is saved and restored by the q/Q operators.
+type redactState struct {
+	text textState
+}
+
+// redact processes the content stream `contents` and returns the redacted
+// operations. `base` is the matrix mapping the content stream space to the
+// page space. The returned flag is true if the content was modified.
+// The `resources` are modified when XObjects are redacted, so they must not
+// be shared with content which is not processed.
+func (cr *contentRedactor) redact(contents string, resources *model.PdfPageResources,
+	base transform.Matrix, level int) (*contentstream.ContentStreamOperations, bool, error) {
+	if level > maxFormDepth {
+		return nil, false, errors.New("form stack overflow")
+	}
+
+	operations, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var out contentstream.ContentStreamOperations
+	var changed bool
+
+	state := redactState{text: textState{th: 100}}
+	var stack []redactState
+
+	// Text matrices.
+	var tm, tlm transform.Matrix
+
+	// Current path.
+	var pathOps []*contentstream.ContentStreamOperation
+	var pathBBox model.PdfRectangle
+	var hasPath, pendingClip bool
+
+	// Open marked content sequences. The ones enclosing removed content get
+	// their replacement texts removed.
+	var markedContent []*contentstream.ContentStreamOperation
+	markRemoved := func() {
+		for _, op := range markedContent {
+			if op == nil || len(op.Params) < 2 {
+				continue
+			}
+			switch t := op.Params[1].(type) {
+			case *core.PdfObjectDictionary:
+				removeReplacementTexts(t)
+			case *core.PdfObjectName:
+				// The named property lists are replaced in the private copy
+				// of the resources, as they can be shared with other content.
+				props, ok := core.GetDict(resources.Properties)
+				if !ok {
+					continue
+				}
+				if dict, ok := core.GetDict(props.Get(*t)); ok {
+					dup := copyDict(dict).(*core.PdfObjectDictionary)
+					removeReplacementTexts(dup)
+					props.Set(*t, dup)
+				}
+			}
+		}
+	}
+
+	addPathPoint := func(ctm transform.Matrix, x, y float64) {
+		x, y = ctm.Transform(x, y)
+		if !hasPath {
+			pathBBox = model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y}
+			hasPath = true
+			return
+		}
+		pathBBox = pathBBox.Union(model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y})
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			ctm := base.Mult(gs.CTM)
+			params, _ := core.GetNumbersAsFloat(op.Params)
+
+			switch op.Operand {
+			case "q":
+				stack = append(stack, state)
+			case "Q":
+				if len(stack) > 0 {
+					state = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			case "BMC":
+				markedContent = append(markedContent, nil)
+			case "BDC":
+				markedContent = append(markedContent, op)
+			case "EMC":
+				if len(markedContent) > 0 {
+					markedContent = markedContent[:len(markedContent)-1]
+				}
+
+			// Path construction.
+			case "m", "l":
+				if len(params) == 2 {
+					addPathPoint(ctm, params[0], params[1])
+				}
+				pathOps = append(pathOps, op)
+				return nil
+			case "c", "v", "y":
+				for i := 0; i+1 < len(params); i += 2 {
+					addPathPoint(ctm, params[i], params[i+1])
+				}
+				pathOps = append(pathOps, op)
+				return nil
+			case "re":
+				if len(params) == 4 {
+					x, y, w, h := params[0], params[1], params[2], params[3]
+					addPathPoint(ctm, x, y)
+					addPathPoint(ctm, x+w, y)
+					addPathPoint(ctm, x, y+h)
+					addPathPoint(ctm, x+w, y+h)
+				}
+				pathOps = append(pathOps, op)
+				return nil
+			case "h":
+				pathOps = append(pathOps, op)
+				return nil
+			case "W", "W*":
+				pendingClip = true
+				pathOps = append(pathOps, op)
+				return nil
+
+			// Path painting.
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+				ops, modified := cr.redactPath(pathOps, op, pathBBox, hasPath, pendingClip, ctm)
+				out = append(out, ops...)
+				if modified {
+					changed = true
+				}
+				pathOps, hasPath, pendingClip = nil, false, false
+				return nil
+
+			case "sh":
+				if hit := cr.intersectingAreas(cr.transformedBBox(ctm, nil)); len(hit) > 0 {
+					// Shadings fill the clipping region: exclude the areas.
+					out = append(out, cr.excludeAreas(ctm, hit, op)...)
+					changed = true
+					return nil
+				}
+
+			// Text state.
+			case "Tc":
+				if len(params) == 1 {
+					state.text.tc = params[0]
+				}
+			case "Tw":
+				if len(params) == 1 {
+					state.text.tw = params[0]
+				}
+			case "Tz":
+				if len(params) == 1 {
+					state.text.th = params[0]
+				}
+			case "TL":
+				if len(params) == 1 {
+					state.text.tl = params[0]
+				}
+			case "Ts":
+				if len(params) == 1 {
+					state.text.trise = params[0]
+				}
+			case "Tf":
+				if len(op.Params) == 2 {
+					if size, err := core.GetNumberAsFloat(op.Params[1]); err == nil {
+						state.text.tfs = size
+					}
+					state.text.font = nil
+					if name, ok := core.GetName(op.Params[0]); ok {
+						state.text.font = cr.getFont(*name, resources)
+					}
+				}
+
+			// Text positioning.
+			case "BT":
+				tm = transform.IdentityMatrix()
+				tlm = transform.IdentityMatrix()
+			case "Td", "TD":
+				if len(params) == 2 {
+					if op.Operand == "TD" {
+						state.text.tl = -params[1]
+					}
+					tlm.Concat(transform.TranslationMatrix(params[0], params[1]))
+					tm = tlm
+				}
+			case "Tm":
+				if len(params) == 6 {
+					tlm = transform.NewMatrix(params[0], params[1], params[2], params[3], params[4], params[5])
+					tm = tlm
+				}
+			case "T*":
+				tlm.Concat(transform.TranslationMatrix(0, -state.text.tl))
+				tm = tlm
+
+			// Text showing.
+			case "Tj", "TJ", "'", "\"":
+				ops, removed := cr.redactText(op, &state.text, &tm, &tlm, ctm)
+				out = append(out, ops...)
+				if removed {
+					changed = true
+					markRemoved()
+				}
+				return nil
+
+			// Images and forms.
+			case "BI":
+				if len(cr.intersectingAreas(cr.transformedBBox(ctm, unitSquare))) > 0 {
+					// Inline images are small and are removed entirely.
+					cr.stats.RemovedImages++
+					changed = true
+					markRemoved()
+					return nil
+				}
+			case "Do":
+				ops, modified, err := cr.redactXObject(op, resources, ctm, level)
+				if err != nil {
+					return err
+				}
+				out = append(out, ops...)
+				if modified {
+					changed = true
+					markRemoved()
+				}
+				return nil
+			}
+
+			out = append(out, op)
+			return nil
+		})
+
+	if err := processor.Process(resources); err != nil {
+		return nil, false, err
+	}
+
+	return &out, changed, nil
+}
+
+// unitSquare is the region painted by images in image space.
+var unitSquare = &model.PdfRectangle{Urx: 1, Ury: 1}
+
+// transformedBBox returns the bounding box of rectangle `r` transformed by
+// `m`. If `r` is nil, the union of the areas is returned, which is the
+// region of interest of operations that paint the whole clipping region.
+func (cr *contentRedactor) transformedBBox(m transform.Matrix, r *model.PdfRectangle) model.PdfRectangle {
+	if r == nil {
+		bbox := cr.areas[0]
+		for _, area := range cr.areas[1:] {
+			bbox = bbox.Union(area)
+		}
+		return bbox
+	}
+	return transformRect(m, *r)
+}
+
+// intersectingAreas returns the redaction areas which intersect `bbox`.
+func (cr *contentRedactor) intersectingAreas(bbox model.PdfRectangle) []model.PdfRectangle {
+	var hit []model.PdfRectangle
+	for _, area := range cr.areas {
+		if overlaps(area, bbox) {
+			hit = append(hit, area)
+		}
+	}
+	return hit
+}
+
+// redactPath returns the operations for the path `pathOps` painted by
+// `paintOp`. Paths fully contained in a redaction area are removed, while
+// paths partially intersecting the areas are clipped, so that they are not
+// painted inside the areas. The returned flag is true if the path was modified.
+func (cr *contentRedactor) redactPath(pathOps []*contentstream.ContentStreamOperation,
+	paintOp *contentstream.ContentStreamOperation, bbox model.PdfRectangle, hasPath, clip bool,
+	ctm transform.Matrix) ([]*contentstream.ContentStreamOperation, bool) {
+	ops := append(append([]*contentstream.ContentStreamOperation{}, pathOps...), paintOp)
+	if !hasPath || paintOp.Operand == "n" {
+		return ops, false
+	}
+
+	hit := cr.intersectingAreas(bbox)
+	if len(hit) == 0 {
+		return ops, false
+	}
+	cr.stats.RemovedPaths++
+
+	// Clipping paths remain in effect after the painting operation.
+	var clipOps []*contentstream.ContentStreamOperation
+	if clip {
+		clipOps = append(clipOps, pathOps...)
+		clipOps = append(clipOps, &contentstream.ContentStreamOperation{Operand: "n"})
+	}
+
+	for _, area := range hit {
+		if rectContains(area, bbox) {
+			return clipOps, true
+		}
+	}
+
+	return append(cr.excludeAreas(ctm, hit, ops...), clipOps...), true
+}
+
+// excludeAreas returns the operations `ops` wrapped in a clipping path which
+// excludes the redaction areas `hit`.
+func (cr *contentRedactor) excludeAreas(ctm transform.Matrix, hit []model.PdfRectangle,
+	ops ...*contentstream.ContentStreamOperation) []*contentstream.ContentStreamOperation {
+	inv, ok := invertMatrix(ctm)
+	if !ok {
+		// Degenerate transformation: nothing is painted.
+		return nil
+	}
+
+	cc := contentstream.NewContentCreator().Add_q()
+
+	// Outer boundary, large enough to contain the page.
+	outer := model.PdfRectangle{Llx: -1e5, Lly: -1e5, Urx: 1e5, Ury: 1e5}
+	addPolygon(cc, inv, outer)
+	for _, area := range hit {
+		addPolygon(cc, inv, area)
+	}
+	cc.Add_W_starred().Add_n()
+
+	result := append([]*contentstream.ContentStreamOperation{}, *cc.Operations()...)
+	result = append(result, ops...)
+	return append(result, &contentstream.ContentStreamOperation{Operand: "Q"})
+}
+
+// addPolygon adds the rectangle `r` transformed by `m` as a closed polygon to
+// the content creator.
+func addPolygon(cc *contentstream.ContentCreator, m transform.Matrix, r model.PdfRectangle) {
+	x0, y0 := m.Transform(r.Llx, r.Lly)
+	x1, y1 := m.Transform(r.Urx, r.Lly)
+	x2, y2 := m.Transform(r.Urx, r.Ury)
+	x3, y3 := m.Transform(r.Llx, r.Ury)
+	cc.Add_m(x0, y0).Add_l(x1, y1).Add_l(x2, y2).Add_l(x3, y3).Add_h()
+}
+
+// getFont returns the font with the specified name from the resources.
+func (cr *contentRedactor) getFont(name core.PdfObjectName, resources *model.PdfPageResources) *model.PdfFont {
+	obj, ok := resources.GetFontByName(name)
+	if !ok {
+		common.Log.Debug("ERROR: font %s not found", name)
+		return nil
+	}
+	if font, ok := cr.fonts[obj]; ok {
+		return font
+	}
+
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to load font %s: %v", name, err)
+		font = nil
+	}
+	cr.fonts[obj] = font
+	return font
+}
+
+// fontExtents returns the descent and ascent of the font, in glyph space units.
+func fontExtents(font *model.PdfFont) (float64, float64) {
+	descent, ascent := defaultDescent, defaultAscent
+	if font == nil {
+		return descent, ascent
+	}
+	if fd := font.FontDescriptor(); fd != nil {
+		if v, err := core.GetNumberAsFloat(fd.Ascent); err == nil && v > 0 {
+			ascent = v
+		}
+		if v, err := core.GetNumberAsFloat(fd.Descent); err == nil && v < 0 {
+			descent = v
+		}
+	}
+	return descent, ascent
+}
+
+// redactText returns the operations for the text showing operation `op`,
+// with the glyphs intersecting the redaction areas removed. The text
+// matrices are updated by the displacement of the shown text. The returned
+// flag is true if any glyphs were removed.
+func (cr *contentRedactor) redactText(op *contentstream.ContentStreamOperation, ts *textState,
+	tm, tlm *transform.Matrix, ctm transform.Matrix) ([]*contentstream.ContentStreamOperation, bool) {
+	// Operations performed before showing the text.
+	var pre []*contentstream.ContentStreamOperation
+
+	var items []core.PdfObject
+	switch op.Operand {
+	case "Tj":
+		if len(op.Params) == 1 {
+			items = op.Params
+		}
+	case "TJ":
+		if len(op.Params) == 1 {
+			if arr, ok := core.GetArray(op.Params[0]); ok {
+				items = arr.Elements()
+			}
+		}
+	case "'":
+		if len(op.Params) == 1 {
+			items = op.Params
+		}
+		pre = append(pre, &contentstream.ContentStreamOperation{Operand: "T*"})
+	case "\"":
+		if len(op.Params) == 3 {
+			items = op.Params[2:]
+			if aw, err := core.GetNumberAsFloat(op.Params[0]); err == nil {
+				ts.tw = aw
+			}
+			if ac, err := core.GetNumberAsFloat(op.Params[1]); err == nil {
+				ts.tc = ac
+			}
+			pre = append(pre,
+				&contentstream.ContentStreamOperation{Operand: "Tw", Params: op.Params[0:1]},
+				&contentstream.ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]},
+				&contentstream.ContentStreamOperation{Operand: "T*"})
+		}
+	}
+	if op.Operand == "'" || op.Operand == "\"" {
+		tlm.Concat(transform.TranslationMatrix(0, -ts.tl))
+		*tm = *tlm
+	}
+
+	th := ts.th / 100
+	descent, ascent := fontExtents(ts.font)
+	stateMatrix := transform.NewMatrix(ts.tfs*th, 0, 0, ts.tfs, 0, ts.trise)
+
+	// Redacted TJ array.
+	result := core.MakeArray()
+	// Pending horizontal adjustment, in thousandths of text space units.
+	var adjust float64
+	flushAdjust := func() {
+		if adjust != 0 {
+			result.Append(core.MakeFloat(adjust))
+			adjust = 0
+		}
+	}
+
+	removed := false
+	for _, item := range items {
+		if num, err := core.GetNumberAsFloat(item); err == nil {
+			adjust += num
+			tm.Concat(transform.TranslationMatrix(-num/1000*ts.tfs*th, 0))
+			continue
+		}
+
+		str, ok := core.GetString(item)
+		if !ok {
+			continue
+		}
+		data := str.Bytes()
+
+		var codes []textencoding.CharCode
+		if ts.font != nil {
+			codes = ts.font.BytesToCharcodes(data)
+		}
+		codeLen := 0
+		switch {
+		case len(codes) == 0:
+		case len(data) == len(codes):
+			codeLen = 1
+		case len(data) == 2*len(codes):
+			codeLen = 2
+		}
+
+		if codeLen == 0 {
+			// The string cannot be split into glyphs: it is removed entirely
+			// if any part of it intersects the areas.
+			hit, tx := cr.textExtent(data, codes, ts, *tm, ctm, stateMatrix, descent, ascent)
+			tm.Concat(transform.TranslationMatrix(tx, 0))
+			if !hit {
+				flushAdjust()
+				result.Append(str)
+				continue
+			}
+			common.Log.Debug("Removing text which cannot be split into glyphs")
+			removed = true
+			cr.stats.RemovedGlyphs += len(codes)
+			if ts.tfs != 0 && th != 0 {
+				adjust -= tx / th / ts.tfs * 1000
+			}
+			continue
+		}
+
+		var kept []byte
+		for i, code := range codes {
+			glyph, tx := glyphBox(ts, code, codeLen == 1, *tm, ctm, stateMatrix, descent, ascent)
+			tm.Concat(transform.TranslationMatrix(tx, 0))
+
+			if !cr.glyphHit(glyph) {
+				if len(kept) == 0 {
+					flushAdjust()
+				}
+				kept = append(kept, data[i*codeLen:(i+1)*codeLen]...)
+				continue
+			}
+
+			removed = true
+			cr.stats.RemovedGlyphs++
+
+			// Replace the glyph by the equivalent displacement.
+			if len(kept) > 0 {
+				result.Append(core.MakeStringFromBytes(kept))
+				kept = nil
+			}
+			if ts.tfs != 0 && th != 0 {
+				adjust -= tx / th / ts.tfs * 1000
+			}
+		}
+		if len(kept) > 0 {
+			result.Append(core.MakeStringFromBytes(kept))
+		}
+	}
+
+	if !removed {
+		return []*contentstream.ContentStreamOperation{op}, false
+	}
+
+	flushAdjust()
+	return append(pre, &contentstream.ContentStreamOperation{
+		Operand: "TJ",
+		Params:  []core.PdfObject{result},
+	}), true
+}
+
+// glyphBox returns the bounding box in device space of the glyph `code`
+// shown with the text matrix `tm`, and its horizontal displacement in
+// unscaled text space units. `single` is true for single byte codes, to which
+// the word spacing applies.
+func glyphBox(ts *textState, code textencoding.CharCode, single bool, tm, ctm, stateMatrix transform.Matrix,
+	descent, ascent float64) (model.PdfRectangle, float64) {
+	w := 0.0
+	if m, ok := ts.font.GetCharMetrics(code); ok {
+		w = m.Wx
+	}
+
+	trm := ctm.Mult(tm).Mult(stateMatrix)
+	glyph := transformRect(trm, model.PdfRectangle{
+		Llx: 0, Lly: descent / 1000, Urx: w / 1000, Ury: ascent / 1000})
+
+	tx := w/1000*ts.tfs + ts.tc
+	if single && code == 32 {
+		tx += ts.tw
+	}
+	return glyph, tx * ts.th / 100
+}
+
+// textExtent returns true if the string `data` with the character codes
+// `codes` shown with the text matrix `tm` intersects the areas, and the
+// displacement of the string. Without a font, the glyphs are assumed to be
+// as wide as the font size, one per byte, and the displacement is unknown.
+func (cr *contentRedactor) textExtent(data []byte, codes []textencoding.CharCode, ts *textState,
+	tm, ctm, stateMatrix transform.Matrix, descent, ascent float64) (bool, float64) {
+	if ts.font == nil {
+		trm := ctm.Mult(tm).Mult(stateMatrix)
+		extent := transformRect(trm, model.PdfRectangle{
+			Llx: 0, Lly: descent / 1000, Urx: float64(len(data)), Ury: ascent / 1000})
+		return len(cr.intersectingAreas(extent)) > 0, 0
+	}
+
+	hit := false
+	var total float64
+	for _, code := range codes {
+		glyph, tx := glyphBox(ts, code, false, tm, ctm, stateMatrix, descent, ascent)
+		tm.Concat(transform.TranslationMatrix(tx, 0))
+		total += tx
+		if cr.glyphHit(glyph) {
+			hit = true
+		}
+	}
+	return hit, total
+}
+
+// glyphHit returns true if the glyph bounding box `glyph` intersects the
+// areas, and is covered by at least the minimum glyph overlap if set.
+func (cr *contentRedactor) glyphHit(glyph model.PdfRectangle) bool {
+	glyphArea := glyph.Width() * glyph.Height()
+	for _, area := range cr.areas {
+		dx := math.Min(area.Urx, glyph.Urx) - math.Max(area.Llx, glyph.Llx)
+		dy := math.Min(area.Ury, glyph.Ury) - math.Max(area.Lly, glyph.Lly)
+		if dx <= 0 || dy <= 0 {
+			// Glyphs only touching an area are kept.
+			continue
+		}
+		if glyphArea <= 0 || dx*dy >= cr.minGlyphOverlap*glyphArea {
+			return true
+		}
+	}
+	return false
+}
+
+// redactXObject returns the operations for the XObject painting operation
+// `op`. Images intersecting the areas have the intersecting samples erased
+// and forms have their content redacted. The redacted XObjects are added to
+// `resources` with new names. The returned flag is true if the XObject was modified.
+func (cr *contentRedactor) redactXObject(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources, ctm transform.Matrix, level int) (
+	[]*contentstream.ContentStreamOperation, bool, error) {
+	keep := []*contentstream.ContentStreamOperation{op}
+	if len(op.Params) != 1 {
+		return keep, false, nil
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		return keep, false, nil
+	}
+
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil {
+		return keep, false, nil
+	}
+
+	var redacted *core.PdfObjectStream
+	switch xtype {
+	case model.XObjectTypeImage:
+		hit := cr.intersectingAreas(transformRect(ctm, *unitSquare))
+		if len(hit) == 0 {
+			return keep, false, nil
+		}
+
+		var err error
+		redacted, err = redactImage(stream, ctm, hit)
+		if err != nil {
+			common.Log.Debug("Unable to edit image %s, removing it: %v", *name, err)
+			cr.stats.RemovedImages++
+			return nil, true, nil
+		}
+		cr.stats.EditedImages++
+	case model.XObjectTypeForm:
+		var modified bool
+		var err error
+		redacted, modified, err = cr.redactForm(stream, resources, ctm, level)
+		if err != nil {
+			return nil, false, err
+		}
+		if !modified {
+			return keep, false, nil
+		}
+	default:
+		return keep, false, nil
+	}
+
+	newName := resources.GenerateXObjectName()
+	if err := resources.SetXObjectByName(newName, redacted); err != nil {
+		return nil, false, err
+	}
+
+	return []*contentstream.ContentStreamOperation{{
+		Operand: "Do",
+		Params:  []core.PdfObject{&newName},
+	}}, true, nil
+}
+
+// redactForm returns a redacted copy of the form XObject `stream`.
+// The returned flag is false if the form content does not intersect the areas.
+func (cr *contentRedactor) redactForm(stream *core.PdfObjectStream, resources *model.PdfPageResources,
+	ctm transform.Matrix, level int) (*core.PdfObjectStream, bool, error) {
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		if f, err := arr.ToFloat64Array(); err == nil && len(f) == 6 {
+			ctm = ctm.Mult(transform.NewMatrix(f[0], f[1], f[2], f[3], f[4], f[5]))
+		}
+	}
+
+	// Skip forms which are entirely outside the areas.
+	if arr, ok := core.GetArray(xform.BBox); ok {
+		if bbox, err := model.NewPdfRectangle(*arr); err == nil {
+			if len(cr.intersectingAreas(transformRect(ctm, *bbox))) == 0 {
+				return nil, false, nil
+			}
+		}
+	}
+
+	content, err := xform.GetContentStream()
+	if err != nil {
+		return nil, false, err
+	}
+
+	formResources := resources
+	if xform.Resources != nil {
+		formResources = copyResources(xform.Resources)
+	} else {
+		formResources = copyResources(resources)
+	}
+
+	ops, modified, err := cr.redact(string(content), formResources, ctm, level+1)
+	if err != nil || !modified {
+		return nil, false, err
+	}
+
+	dup := model.NewXObjectForm()
+	dup.FormType = xform.FormType
+	dup.BBox = xform.BBox
+	dup.Matrix = xform.Matrix
+	dup.Resources = formResources
+	dup.Group = xform.Group
+	dup.OC = xform.OC
+	if err := dup.SetContentStream(ops.Bytes(), core.NewFlateEncoder()); err != nil {
+		return nil, false, err
+	}
+
+	redacted, ok := dup.ToPdfObject().(*core.PdfObjectStream)
+	if !ok {
+		return nil, false, core.ErrTypeError
+	}
+	return redacted, true, nil
+}
+
+// removeReplacementTexts removes the replacement texts of the marked content
+// property list `dict`.
+func removeReplacementTexts(dict *core.PdfObjectDictionary) {
+	dict.Remove("ActualText")
+	dict.Remove("Alt")
+	dict.Remove("E")
+}
+
+// copyResources returns a copy of `res` with private XObject, Font and
+// Properties dictionaries, so that redacted XObjects, overlay fonts and
+// property lists can be added without affecting other content using the same
+// resources.
+func copyResources(res *model.PdfPageResources) *model.PdfPageResources {
+	dict, ok := core.GetDict(res.ToPdfObject())
+	if !ok {
+		return model.NewPdfPageResources()
+	}
+	dup, err := model.NewPdfPageResourcesFromDict(dict)
+	if err != nil {
+		return model.NewPdfPageResources()
+	}
+	dup.XObject = copyDict(dup.XObject)
+	dup.Font = copyDict(dup.Font)
+	dup.Properties = copyDict(dup.Properties)
+	return dup
+}
+
+// copyDict returns a shallow copy of the dictionary `obj`, or nil if `obj` is
+// not a dictionary.
+func copyDict(obj core.PdfObject) core.PdfObject {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil
+	}
+	dup := core.MakeDict()
+	for _, key := range dict.Keys() {
+		dup.Set(key, dict.Get(key))
+	}
+	return dup
+}
+
+// pruneXObjects removes the XObjects of `res` which are not used by `ops`.
+func pruneXObjects(res *model.PdfPageResources, ops *contentstream.ContentStreamOperations) {
+	xobjDict, ok := core.GetDict(res.XObject)
+	if !ok {
+		return
+	}
+
+	used := map[core.PdfObjectName]struct{}{}
+	for _, op := range *ops {
+		if op.Operand != "Do" || len(op.Params) != 1 {
+			continue
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			used[*name] = struct{}{}
+		}
+	}
+
+	for _, key := range xobjDict.Keys() {
+		if _, ok := used[key]; !ok {
+			xobjDict.Remove(key)
+		}
+	}
+}
+
+// transformRect returns the bounding box of rectangle `r` transformed by `m`.
+func transformRect(m transform.Matrix, r model.PdfRectangle) model.PdfRectangle {
+	x0, y0 := m.Transform(r.Llx, r.Lly)
+	bbox := model.PdfRectangle{Llx: x0, Lly: y0, Urx: x0, Ury: y0}
+	for _, c := range [][2]float64{{r.Urx, r.Lly}, {r.Llx, r.Ury}, {r.Urx, r.Ury}} {
+		x, y := m.Transform(c[0], c[1])
+		bbox = bbox.Union(model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y})
+	}
+	return bbox
+}
+
+// invertMatrix returns the inverse of the affine transform `m`.
+func invertMatrix(m transform.Matrix) (transform.Matrix, bool) {
+	a, b, c, d, e, f := m[0], m[1], m[3], m[4], m[6], m[7]
+	det := a*d - b*c
+	if math.Abs(det) < 1e-12 {
+		return transform.Matrix{}, false
+	}
+	return transform.NewMatrix(
+		d/det, -b/det,
+		-c/det, a/det,
+		(c*f-d*e)/det, (b*e-a*f)/det), true
+}
+
+// rectContains returns true if `outer` contains `inner`.
+func rectContains(outer, inner model.PdfRectangle) bool {
+	return outer.Llx <= inner.Llx && outer.Lly <= inner.Lly &&
+		outer.Urx >= inner.Urx && outer.Ury >= inner.Ury
+}
+
+// overlaps returns true if rectangles `r1` and `r2` intersect. Degenerate
+// rectangles (e.g. the bounding boxes of horizontal lines) intersect the
+// rectangles containing them.
+func overlaps(r1, r2 model.PdfRectangle) bool {
+	return math.Min(r1.Urx, r2.Urx) >= math.Max(r1.Llx, r2.Llx) &&
+		math.Min(r1.Ury, r2.Ury) >= math.Max(r1.Lly, r2.Lly)
+}
@@ -0,0 +1,12 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package redactor provides redaction of PDF documents. Unlike covering the
+// sensitive content with a black box, the redaction removes the glyphs,
+// vector paths, image samples and annotations inside the redaction areas, so
+// that the content cannot be extracted from the redacted document.
+// The redaction areas can be specified explicitly, found by searching the
+// text of the pages, or taken from the Redact annotations of the document.
+package redactor
@@ -0,0 +1,147 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/internal/transform"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// redactImage returns a copy of the image XObject `stream`, painted with the
+// transformation `ctm`, with the samples which are painted inside the areas
+// `hit` erased. For rotated or skewed images, all the samples inside the
+// bounding box of the areas in image space are erased.
+func redactImage(stream *core.PdfObjectStream, ctm transform.Matrix,
+	hit []model.PdfRectangle) (*core.PdfObjectStream, error) {
+	dict := stream.PdfObjectDictionary
+
+	width, err := core.GetNumberAsInt64(dict.Get("Width"))
+	if err != nil || width <= 0 {
+		return nil, errors.New("invalid image width")
+	}
+	height, err := core.GetNumberAsInt64(dict.Get("Height"))
+	if err != nil || height <= 0 {
+		return nil, errors.New("invalid image height")
+	}
+
+	// Image masks have one bit per sample. The erased samples are set to the
+	// value which leaves the page unpainted.
+	var bpc int64 = 1
+	components := 1
+	var fill uint32
+	if imageMask, ok := core.GetBoolVal(dict.Get("ImageMask")); ok && imageMask {
+		fill = 1
+		if decode, ok := core.GetArray(dict.Get("Decode")); ok {
+			if vals, err := decode.ToFloat64Array(); err == nil && len(vals) == 2 && vals[0] == 1 {
+				fill = 0
+			}
+		}
+	} else {
+		if bpc, err = core.GetNumberAsInt64(dict.Get("BitsPerComponent")); err != nil {
+			return nil, errors.New("invalid image bits per component")
+		}
+		cs, err := model.NewPdfColorspaceFromPdfObject(dict.Get("ColorSpace"))
+		if err != nil {
+			return nil, err
+		}
+		components = cs.GetNumComponents()
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, errors.New("unsupported bits per component")
+	}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are padded to full bytes.
+	rowBits := int(width) * components * int(bpc)
+	rowBytes := (rowBits + 7) / 8
+	if len(data) < rowBytes*int(height) {
+		return nil, errors.New("not enough image data")
+	}
+
+	inv, ok := invertMatrix(ctm)
+	if !ok {
+		return nil, errors.New("degenerate image transformation")
+	}
+
+	for _, area := range hit {
+		// Area in the image space, where the first row is at the top of the
+		// unit square.
+		r := transformRect(inv, area)
+		x0 := clampInt(int(math.Floor(r.Llx*float64(width))), 0, int(width))
+		x1 := clampInt(int(math.Ceil(r.Urx*float64(width))), 0, int(width))
+		y0 := clampInt(int(math.Floor((1-r.Ury)*float64(height))), 0, int(height))
+		y1 := clampInt(int(math.Ceil((1-r.Lly)*float64(height))), 0, int(height))
+
+		for y := y0; y < y1; y++ {
+			row := data[y*rowBytes : (y+1)*rowBytes]
+			for x := x0; x < x1; x++ {
+				for c := 0; c < components; c++ {
+					setSample(row, (x*components+c)*int(bpc), int(bpc), fill)
+				}
+			}
+		}
+	}
+
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the image dictionary, replacing the stream encoding.
+	redactedDict := encoder.MakeStreamDict()
+	for _, key := range dict.Keys() {
+		switch key {
+		case "Filter", "DecodeParms", "Length":
+			continue
+		}
+		redactedDict.Set(key, dict.Get(key))
+	}
+	redactedDict.Set("Length", core.MakeInteger(int64(len(encoded))))
+
+	return &core.PdfObjectStream{
+		PdfObjectDictionary: redactedDict,
+		Stream:              encoded,
+	}, nil
+}
+
+// setSample sets the sample of `bpc` bits starting at bit `offset` of `row` to `val`.
+func setSample(row []byte, offset, bpc int, val uint32) {
+	switch {
+	case bpc == 16:
+		i := offset / 8
+		row[i] = byte(val >> 8)
+		row[i+1] = byte(val)
+	case bpc == 8:
+		row[offset/8] = byte(val)
+	default:
+		i := offset / 8
+		shift := uint(8 - bpc - offset%8)
+		mask := byte((1<<uint(bpc))-1) << shift
+		row[i] = row[i]&^mask | byte(val)<<shift&mask
+	}
+}
+
+// clampInt returns `v` clamped to the range [min, max].
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
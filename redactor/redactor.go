@@ -0,0 +1,690 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/loxiouve/unipdf/v3/common"
+	"github.com/loxiouve/unipdf/v3/contentstream"
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/extractor"
+	"github.com/loxiouve/unipdf/v3/internal/transform"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// Options defines the appearance of the redacted areas.
+type Options struct {
+	// FillColor is the color painted over the redacted areas. If nil, the
+	// areas are painted in black.
+	FillColor *model.PdfColorDeviceRGB
+
+	// NoFill disables painting over the redacted areas. The content is still
+	// removed, leaving blank areas.
+	NoFill bool
+
+	// OverlayText is the text shown inside the redacted areas, if any.
+	OverlayText string
+
+	// OverlayTextColor is the color of the overlay text. If nil, the text is
+	// shown in white.
+	OverlayTextColor *model.PdfColorDeviceRGB
+
+	// FontSize is the font size of the overlay text. If zero, the font size
+	// is chosen so that the text fits the area, up to 12 points.
+	FontSize float64
+
+	// MinGlyphOverlap is the minimum fraction (between 0 and 1) of the
+	// bounding box of a glyph which must be inside a redaction area for the
+	// glyph to be removed. By default, every glyph intersecting an area is
+	// removed, as partially covered glyphs could otherwise still be
+	// extracted. Setting it keeps the glyphs of adjacent lines whose
+	// ascenders and descenders overlap the areas.
+	MinGlyphOverlap float64
+
+	// KeepOutlines copies the outline (bookmarks) of the document to the
+	// redacted document. The outline is dropped by default, as the titles of
+	// its items may reveal redacted headings.
+	KeepOutlines bool
+}
+
+// RedactionArea represents an area of a page whose content is removed.
+type RedactionArea struct {
+	// PageNum is the number of the page (starting from 1).
+	PageNum int
+
+	// Rect is the area in the default user space of the page.
+	Rect model.PdfRectangle
+
+	// FillColor overrides the fill color of the redaction options, if set.
+	FillColor *model.PdfColorDeviceRGB
+
+	// OverlayText overrides the overlay text of the redaction options, if set.
+	OverlayText string
+}
+
+// Stats contains the number of objects removed or edited by the redaction.
+type Stats struct {
+	RemovedGlyphs      int
+	RemovedPaths       int
+	EditedImages       int
+	RemovedImages      int
+	RemovedAnnotations int
+}
+
+// Redactor removes the content of the pages of a document inside the
+// redaction areas. The areas are specified explicitly, by searching for text
+// or from the Redact annotations of the document. The glyphs, vector paths
+// and image samples inside the areas are removed from the content streams
+// (and not merely covered), together with the annotations intersecting the
+// areas.
+type Redactor struct {
+	reader *model.PdfReader
+	opts   Options
+
+	areas   []RedactionArea
+	applied bool
+	stats   Stats
+
+	// Widget annotations removed from the pages, whose fields are removed
+	// from the AcroForm.
+	removedWidgets map[*model.PdfAnnotation]struct{}
+}
+
+// New returns a new redactor for the document loaded by `reader`. If `opts`
+// is nil, the default options are used.
+func New(reader *model.PdfReader, opts *Options) *Redactor {
+	r := &Redactor{
+		reader:         reader,
+		removedWidgets: map[*model.PdfAnnotation]struct{}{},
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+	return r
+}
+
+// Areas returns the redaction areas added to the redactor.
+func (r *Redactor) Areas() []RedactionArea {
+	return r.areas
+}
+
+// AddArea adds a redaction area.
+func (r *Redactor) AddArea(area RedactionArea) error {
+	if r.applied {
+		return errors.New("redaction already applied")
+	}
+
+	numPages, err := r.reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if area.PageNum < 1 || area.PageNum > numPages {
+		return fmt.Errorf("invalid page number %d", area.PageNum)
+	}
+
+	rect := area.Rect
+	rect.Llx, rect.Urx = math.Min(rect.Llx, rect.Urx), math.Max(rect.Llx, rect.Urx)
+	rect.Lly, rect.Ury = math.Min(rect.Lly, rect.Ury), math.Max(rect.Lly, rect.Ury)
+	if rect.Width() <= 0 || rect.Height() <= 0 {
+		return errors.New("empty redaction area")
+	}
+	area.Rect = rect
+
+	r.areas = append(r.areas, area)
+	return nil
+}
+
+// Search adds redaction areas covering the occurrences of `term` in the text
+// of the pages. Returns the number of occurrences found.
+func (r *Redactor) Search(term string) (int, error) {
+	if term == "" {
+		return 0, errors.New("empty search term")
+	}
+	return r.SearchRegexp(regexp.MustCompile(regexp.QuoteMeta(term)))
+}
+
+// SearchRegexp adds redaction areas covering the matches of `re` in the text
+// of the pages. Returns the number of matches found.
+func (r *Redactor) SearchRegexp(re *regexp.Regexp) (int, error) {
+	numPages, err := r.reader.GetNumPages()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := r.reader.GetPage(pageNum)
+		if err != nil {
+			return count, err
+		}
+		ex, err := extractor.New(page)
+		if err != nil {
+			return count, err
+		}
+		pageText, _, _, err := ex.ExtractPageText()
+		if err != nil {
+			return count, err
+		}
+
+		text := pageText.Text()
+		marks := pageText.Marks()
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matched, err := marks.RangeOffset(loc[0], loc[1])
+			if err != nil {
+				return count, err
+			}
+
+			rects := lineRects(matched.Elements())
+			if len(rects) == 0 {
+				continue
+			}
+			for _, rect := range rects {
+				if err := r.AddArea(RedactionArea{PageNum: pageNum, Rect: rect}); err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// lineRects returns the bounding boxes of the text marks `marks`, merging
+// consecutive marks on the same line.
+func lineRects(marks []extractor.TextMark) []model.PdfRectangle {
+	var rects []model.PdfRectangle
+	for _, mark := range marks {
+		if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+			continue
+		}
+		bbox := mark.BBox
+		if bbox.Width() <= 0 || bbox.Height() <= 0 {
+			continue
+		}
+
+		if n := len(rects); n > 0 {
+			last := rects[n-1]
+			tolerance := 0.5 * math.Min(last.Height(), bbox.Height())
+			if math.Abs(last.Lly-bbox.Lly) < tolerance && math.Abs(last.Ury-bbox.Ury) < tolerance {
+				rects[n-1] = last.Union(bbox)
+				continue
+			}
+		}
+		rects = append(rects, bbox)
+	}
+	return rects
+}
+
+// AddRedactAnnotations adds redaction areas for the Redact annotations of
+// the document. The areas are specified by the QuadPoints of the
+// annotations, or by their Rect if QuadPoints is missing. The interior color
+// and the overlay text of the annotations override the redaction options.
+// Returns the number of annotations found. The Redact annotations are
+// removed when the redaction is applied.
+func (r *Redactor) AddRedactAnnotations() (int, error) {
+	numPages, err := r.reader.GetNumPages()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := r.reader.GetPage(pageNum)
+		if err != nil {
+			return count, err
+		}
+		annotations, err := page.GetAnnotations()
+		if err != nil {
+			return count, err
+		}
+
+		for _, annot := range annotations {
+			redact, ok := annot.GetContext().(*model.PdfAnnotationRedact)
+			if !ok {
+				continue
+			}
+
+			area := RedactionArea{PageNum: pageNum}
+			if color, ok := core.GetArray(redact.IC); ok {
+				if vals, err := color.ToFloat64Array(); err == nil && len(vals) == 3 {
+					area.FillColor = model.NewPdfColorDeviceRGB(vals[0], vals[1], vals[2])
+				}
+			}
+			if text, ok := core.GetString(redact.OverlayText); ok {
+				area.OverlayText = text.Decoded()
+			}
+
+			rects := quadPointRects(redact.QuadPoints)
+			if len(rects) == 0 {
+				if arr, ok := core.GetArray(annot.Rect); ok {
+					if rect, err := model.NewPdfRectangle(*arr); err == nil {
+						rects = append(rects, *rect)
+					}
+				}
+			}
+			if len(rects) == 0 {
+				common.Log.Debug("Redact annotation without area on page %d", pageNum)
+				continue
+			}
+
+			for _, rect := range rects {
+				area.Rect = rect
+				if err := r.AddArea(area); err != nil {
+					return count, err
+				}
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// quadPointRects returns the bounding boxes of the quadrilaterals of the
+// QuadPoints array `obj`.
+func quadPointRects(obj core.PdfObject) []model.PdfRectangle {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil
+	}
+	vals, err := arr.ToFloat64Array()
+	if err != nil {
+		return nil
+	}
+
+	var rects []model.PdfRectangle
+	for i := 0; i+8 <= len(vals); i += 8 {
+		rect := model.PdfRectangle{Llx: vals[i], Lly: vals[i+1], Urx: vals[i], Ury: vals[i+1]}
+		for j := i + 2; j < i+8; j += 2 {
+			rect = rect.Union(model.PdfRectangle{Llx: vals[j], Lly: vals[j+1], Urx: vals[j], Ury: vals[j+1]})
+		}
+		rects = append(rects, rect)
+	}
+	return rects
+}
+
+// Apply applies the redaction to the pages of the document. The content
+// inside the redaction areas is removed, the annotations intersecting the
+// areas are removed and the areas are painted over with the fill color and
+// overlay text. The pages of the reader are modified. Write must be used for
+// writing the redacted document, so that the removed content is not kept in
+// the output file.
+func (r *Redactor) Apply() (Stats, error) {
+	if r.applied {
+		return r.stats, nil
+	}
+
+	numPages, err := r.reader.GetNumPages()
+	if err != nil {
+		return r.stats, err
+	}
+
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		var areas []RedactionArea
+		for _, area := range r.areas {
+			if area.PageNum == pageNum {
+				areas = append(areas, area)
+			}
+		}
+
+		page, err := r.reader.GetPage(pageNum)
+		if err != nil {
+			return r.stats, err
+		}
+		if err := r.redactAnnotations(page, areas); err != nil {
+			return r.stats, err
+		}
+		if len(areas) == 0 {
+			continue
+		}
+		if err := r.redactPage(page, areas); err != nil {
+			return r.stats, err
+		}
+	}
+
+	r.applied = true
+	return r.stats, nil
+}
+
+// redactPage removes the content of `page` inside the redaction `areas` and
+// paints the overlay.
+func (r *Redactor) redactPage(page *model.PdfPage, areas []RedactionArea) error {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+
+	// The resources can be shared with other pages.
+	resources := model.NewPdfPageResources()
+	if page.Resources != nil {
+		resources = copyResources(page.Resources)
+	}
+
+	rects := make([]model.PdfRectangle, len(areas))
+	for i, area := range areas {
+		rects[i] = area.Rect
+	}
+
+	cr := newContentRedactor(rects, r.opts.MinGlyphOverlap)
+	ops, _, err := cr.redact(contents, resources, transform.IdentityMatrix(), 0)
+	if err != nil {
+		return err
+	}
+	pruneXObjects(resources, ops)
+
+	r.stats.RemovedGlyphs += cr.stats.RemovedGlyphs
+	r.stats.RemovedPaths += cr.stats.RemovedPaths
+	r.stats.EditedImages += cr.stats.EditedImages
+	r.stats.RemovedImages += cr.stats.RemovedImages
+
+	// The redacted content is wrapped in q/Q, so that the overlay is drawn
+	// in the default graphics state.
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	for _, op := range *ops {
+		cc.AddOperand(*op)
+	}
+	cc.Add_Q()
+	if err := r.drawOverlay(cc, resources, areas); err != nil {
+		return err
+	}
+
+	page.Resources = resources
+	if err := page.SetContentStreams([]string{cc.String()}, core.NewFlateEncoder()); err != nil {
+		return err
+	}
+
+	// Scrub the page data which can contain the removed content.
+	page.Thumb = nil
+	page.PieceInfo = nil
+	page.Metadata = nil
+	return nil
+}
+
+// drawOverlay adds the operations painting the redaction areas to `cc`.
+func (r *Redactor) drawOverlay(cc *contentstream.ContentCreator, resources *model.PdfPageResources,
+	areas []RedactionArea) error {
+	if r.opts.NoFill && r.opts.OverlayText == "" {
+		hasText := false
+		for _, area := range areas {
+			hasText = hasText || area.OverlayText != ""
+		}
+		if !hasText {
+			return nil
+		}
+	}
+
+	var fontName core.PdfObjectName
+	var font *model.PdfFont
+	for _, area := range areas {
+		rect := area.Rect
+
+		fill := area.FillColor
+		if fill == nil {
+			fill = r.opts.FillColor
+		}
+		if fill != nil || !r.opts.NoFill {
+			if fill == nil {
+				fill = model.NewPdfColorDeviceRGB(0, 0, 0)
+			}
+			cc.Add_q().
+				Add_rg(fill.R(), fill.G(), fill.B()).
+				Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).
+				Add_f().
+				Add_Q()
+		}
+
+		text := area.OverlayText
+		if text == "" {
+			text = r.opts.OverlayText
+		}
+		if text == "" {
+			continue
+		}
+
+		if font == nil {
+			var err error
+			font, err = model.NewStandard14Font(model.HelveticaName)
+			if err != nil {
+				return err
+			}
+			fontName = core.PdfObjectName("RedactF1")
+			for i := 2; resources.HasFontByName(fontName); i++ {
+				fontName = core.PdfObjectName(fmt.Sprintf("RedactF%d", i))
+			}
+			if err := resources.SetFontByName(fontName, font.ToPdfObject()); err != nil {
+				return err
+			}
+		}
+		r.drawOverlayText(cc, font, fontName, text, rect)
+	}
+
+	return nil
+}
+
+// drawOverlayText adds the operations showing `text` centered in `rect` to `cc`.
+func (r *Redactor) drawOverlayText(cc *contentstream.ContentCreator, font *model.PdfFont,
+	fontName core.PdfObjectName, text string, rect model.PdfRectangle) {
+	encoded, _ := font.StringToCharcodeBytes(text)
+
+	// Text width for a font size of 1.
+	var width float64
+	for _, r := range text {
+		if m, ok := font.GetRuneMetrics(r); ok {
+			width += m.Wx / 1000
+		}
+	}
+
+	fontSize := r.opts.FontSize
+	if fontSize <= 0 {
+		fontSize = math.Min(12, 0.8*rect.Height())
+		if width > 0 {
+			fontSize = math.Min(fontSize, 0.9*rect.Width()/width)
+		}
+	}
+	if fontSize <= 0 {
+		return
+	}
+
+	color := r.opts.OverlayTextColor
+	if color == nil {
+		color = model.NewPdfColorDeviceRGB(1, 1, 1)
+	}
+
+	x := rect.Llx + (rect.Width()-width*fontSize)/2
+	y := rect.Lly + (rect.Height()-0.7*fontSize)/2
+
+	cc.Add_q().
+		Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).
+		Add_W().
+		Add_n().
+		Add_rg(color.R(), color.G(), color.B()).
+		Add_BT().
+		Add_Tf(fontName, fontSize).
+		Add_Td(x, y).
+		Add_Tj(*core.MakeStringFromBytes(encoded)).
+		Add_ET().
+		Add_Q()
+}
+
+// redactAnnotations removes the annotations of `page` which intersect the
+// redaction `areas`, the Redact annotations and the popups of the removed
+// annotations.
+func (r *Redactor) redactAnnotations(page *model.PdfPage, areas []RedactionArea) error {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	removed := map[*model.PdfAnnotation]struct{}{}
+	for _, annot := range annotations {
+		if _, ok := annot.GetContext().(*model.PdfAnnotationRedact); ok {
+			removed[annot] = struct{}{}
+			continue
+		}
+
+		arr, ok := core.GetArray(annot.Rect)
+		if !ok {
+			continue
+		}
+		rect, err := model.NewPdfRectangle(*arr)
+		if err != nil {
+			continue
+		}
+		for _, area := range areas {
+			if overlaps(area.Rect, normalizeRect(*rect)) {
+				removed[annot] = struct{}{}
+				break
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	// Remove the popups of the removed annotations.
+	for _, annot := range annotations {
+		popup, ok := annot.GetContext().(*model.PdfAnnotationPopup)
+		if !ok {
+			continue
+		}
+		for parent := range removed {
+			if popup.Parent != nil && core.ResolveReference(popup.Parent) == core.ResolveReference(parent.GetContainingPdfObject()) {
+				removed[annot] = struct{}{}
+				break
+			}
+		}
+	}
+
+	var kept []*model.PdfAnnotation
+	for _, annot := range annotations {
+		if _, ok := removed[annot]; !ok {
+			kept = append(kept, annot)
+			continue
+		}
+		if _, ok := annot.GetContext().(*model.PdfAnnotationWidget); ok {
+			r.removedWidgets[annot] = struct{}{}
+		}
+		if _, ok := annot.GetContext().(*model.PdfAnnotationRedact); !ok {
+			r.stats.RemovedAnnotations++
+		}
+	}
+	page.SetAnnotations(kept)
+	return nil
+}
+
+// normalizeRect returns `r` with the lower left corner before the upper right corner.
+func normalizeRect(r model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(r.Llx, r.Urx),
+		Lly: math.Min(r.Lly, r.Ury),
+		Urx: math.Max(r.Llx, r.Urx),
+		Ury: math.Max(r.Lly, r.Ury),
+	}
+}
+
+// acroForm returns the AcroForm of the document without the fields whose
+// widget annotations have been removed.
+func (r *Redactor) acroForm() *model.PdfAcroForm {
+	form := r.reader.AcroForm
+	if form == nil || form.Fields == nil || len(r.removedWidgets) == 0 {
+		return form
+	}
+
+	fields := filterFields(*form.Fields, r.removedWidgets)
+	form.Fields = &fields
+	return form
+}
+
+// filterFields returns the fields of `fields` which still have widget
+// annotations, removing the widgets in `removed`.
+func filterFields(fields []*model.PdfField, removed map[*model.PdfAnnotation]struct{}) []*model.PdfField {
+	var kept []*model.PdfField
+	for _, field := range fields {
+		hadContent := len(field.Annotations) > 0 || len(field.Kids) > 0
+
+		var widgets []*model.PdfAnnotationWidget
+		for _, widget := range field.Annotations {
+			if _, ok := removed[widget.PdfAnnotation]; !ok {
+				widgets = append(widgets, widget)
+			}
+		}
+		field.Annotations = widgets
+		field.Kids = filterFields(field.Kids, removed)
+
+		if hadContent && len(field.Annotations) == 0 && len(field.Kids) == 0 {
+			continue
+		}
+		kept = append(kept, field)
+	}
+	return kept
+}
+
+// Write applies the redaction, if not already applied, and writes the
+// redacted document to `w`. The document is written from scratch, so that
+// the removed content, the document information dictionary, the XMP
+// metadata and, unless KeepOutlines is set, the outline are not carried over
+// from the original file.
+func (r *Redactor) Write(w io.Writer) error {
+	if _, err := r.Apply(); err != nil {
+		return err
+	}
+
+	numPages, err := r.reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	writer := model.NewPdfWriter()
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := r.reader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+		if err := writer.AddPage(page); err != nil {
+			return err
+		}
+	}
+
+	if form := r.acroForm(); form != nil {
+		if err := writer.SetForms(form); err != nil {
+			return err
+		}
+	}
+	if r.opts.KeepOutlines {
+		if outlines := r.reader.GetOutlineTree(); outlines != nil {
+			writer.AddOutlineTree(outlines)
+		}
+	}
+
+	return writer.Write(w)
+}
+
+// WriteToFile applies the redaction, if not already applied, and writes the
+// redacted document to the file at `outputPath`.
+func (r *Redactor) WriteToFile(outputPath string) error {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.Write(f)
+}
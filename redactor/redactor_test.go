@@ -0,0 +1,285 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package redactor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/creator"
+	"github.com/loxiouve/unipdf/v3/extractor"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// loadPDF returns a reader for the PDF document in `data`.
+func loadPDF(t *testing.T, data []byte) *model.PdfReader {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	return reader
+}
+
+// pageText returns the extracted text of page `pageNum` of `reader`.
+func pageText(t *testing.T, reader *model.PdfReader, pageNum int) string {
+	page, err := reader.GetPage(pageNum)
+	require.NoError(t, err)
+	ex, err := extractor.New(page)
+	require.NoError(t, err)
+	text, err := ex.ExtractText()
+	require.NoError(t, err)
+	return text
+}
+
+func TestRedactSearch(t *testing.T) {
+	c := creator.New()
+	c.NewPage()
+	p := c.NewStyledParagraph()
+	p.Append("Name: John Smith, SSN: 123-45-6789, City: Springfield.")
+	p.SetPos(50, 100)
+	require.NoError(t, c.Draw(p))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader := loadPDF(t, buf.Bytes())
+	require.Contains(t, pageText(t, reader, 1), "123-45-6789")
+
+	r := New(reader, &Options{OverlayText: "REDACTED"})
+	count, err := r.Search("123-45-6789")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Len(t, r.Areas(), 1)
+
+	stats, err := r.Apply()
+	require.NoError(t, err)
+	require.Equal(t, 11, stats.RemovedGlyphs)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out))
+	require.False(t, bytes.Contains(out.Bytes(), []byte("6789")))
+
+	text := pageText(t, loadPDF(t, out.Bytes()), 1)
+	require.NotContains(t, text, "123")
+	require.NotContains(t, text, "6789")
+	require.Contains(t, text, "Name: John Smith, SSN:")
+	require.Contains(t, text, "City: Springfield.")
+}
+
+func TestRedactPartialGlyphs(t *testing.T) {
+	font := model.NewStandard14FontMustCompile(model.HelveticaName)
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 400}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.AddContentStreamByString("BT /F1 20 Tf 100 100 Td (ABCD) Tj ET"))
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	// The area covers about 10% of the width of the D glyph, which spans
+	// 141.1 to 155.6 horizontally.
+	area := RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Llx: 154, Lly: 90, Urx: 200, Ury: 130}}
+	redact := func(opts *Options) string {
+		r := New(loadPDF(t, buf.Bytes()), opts)
+		require.NoError(t, r.AddArea(area))
+		_, err := r.Apply()
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, r.Write(&out))
+		return strings.TrimSpace(pageText(t, loadPDF(t, out.Bytes()), 1))
+	}
+
+	// Partially covered glyphs are removed by default.
+	require.Equal(t, "ABC", redact(&Options{NoFill: true}))
+	require.Equal(t, "ABCD", redact(&Options{NoFill: true, MinGlyphOverlap: 0.3}))
+}
+
+func TestRedactAreas(t *testing.T) {
+	// 2x2 grayscale image, placed at (200, 200) with size 100x100.
+	img := &model.Image{
+		Width:            2,
+		Height:           2,
+		BitsPerComponent: 8,
+		ColorComponents:  1,
+		Data:             []byte{255, 255, 255, 255},
+	}
+	ximg, err := model.NewXObjectImageFromImage(img, nil, core.NewFlateEncoder())
+	require.NoError(t, err)
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 400}
+	require.NoError(t, page.AddImageResource("Im1", ximg))
+	require.NoError(t, page.AddContentStreamByString(strings.Join([]string{
+		"0 0 1 rg 10 10 50 50 re f",
+		"1 0 0 rg 100 10 100 50 re f",
+		"q 100 0 0 100 200 200 cm /Im1 Do Q",
+	}, "\n")))
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader := loadPDF(t, buf.Bytes())
+	r := New(reader, &Options{NoFill: true})
+	// Covers the first rectangle, the right half of the second and the top
+	// left sample of the image.
+	require.NoError(t, r.AddArea(RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Llx: 0, Lly: 0, Urx: 70, Ury: 70}}))
+	require.NoError(t, r.AddArea(RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Llx: 150, Lly: 0, Urx: 250, Ury: 70}}))
+	require.NoError(t, r.AddArea(RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Llx: 190, Lly: 260, Urx: 240, Ury: 310}}))
+	require.Error(t, r.AddArea(RedactionArea{PageNum: 2, Rect: model.PdfRectangle{Urx: 10, Ury: 10}}))
+
+	stats, err := r.Apply()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.RemovedPaths)
+	require.Equal(t, 1, stats.EditedImages)
+
+	redacted, err := reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := redacted.GetAllContentStreams()
+	require.NoError(t, err)
+
+	// The first rectangle is removed and the second one is clipped.
+	require.NotContains(t, contents, "10 10 50 50 re")
+	require.Contains(t, contents, "100 10 100 50 re")
+	require.Contains(t, contents, "W*")
+
+	// The image is replaced by a copy with the top left sample erased.
+	xobjDict, ok := core.GetDict(redacted.Resources.XObject)
+	require.True(t, ok)
+	require.Len(t, xobjDict.Keys(), 1)
+	require.NotEqual(t, core.PdfObjectName("Im1"), xobjDict.Keys()[0])
+
+	stream, ok := core.GetStream(xobjDict.Get(xobjDict.Keys()[0]))
+	require.True(t, ok)
+	data, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 255, 255, 255}, data)
+}
+
+func TestRedactAnnotations(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 400}
+	require.NoError(t, page.AddContentStreamByString("0 0 1 rg 10 10 50 50 re f"))
+
+	redact := model.NewPdfAnnotationRedact()
+	redact.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	redact.IC = core.MakeArrayFromFloats([]float64{1, 0, 0})
+	page.AddAnnotation(redact.PdfAnnotation)
+
+	text := model.NewPdfAnnotationText()
+	text.Rect = core.MakeArrayFromFloats([]float64{20, 20, 40, 40})
+	text.Contents = core.MakeString("secret")
+	page.AddAnnotation(text.PdfAnnotation)
+
+	other := model.NewPdfAnnotationText()
+	other.Rect = core.MakeArrayFromFloats([]float64{200, 200, 220, 220})
+	page.AddAnnotation(other.PdfAnnotation)
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader := loadPDF(t, buf.Bytes())
+	r := New(reader, nil)
+	count, err := r.AddRedactAnnotations()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.NotNil(t, r.Areas()[0].FillColor)
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out))
+	require.False(t, bytes.Contains(out.Bytes(), []byte("secret")))
+
+	redacted, err := loadPDF(t, out.Bytes()).GetPage(1)
+	require.NoError(t, err)
+	annotations, err := redacted.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+
+	contents, err := redacted.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, contents, "10 10 50 50 re")
+	require.Contains(t, contents, "1 0 0 rg")
+}
+
+func TestRedactOutlines(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 400}
+	require.NoError(t, page.AddContentStreamByString("0 0 1 rg 10 10 50 50 re f"))
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	outline := model.NewOutline()
+	outline.Add(model.NewOutlineItem("Project Nightingale", model.NewOutlineDest(0, 10, 60)))
+	writer.AddOutlineTree(outline.ToOutlineTree())
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	redact := func(opts *Options) *model.PdfReader {
+		r := New(loadPDF(t, buf.Bytes()), opts)
+		require.NoError(t, r.AddArea(RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Urx: 70, Ury: 70}}))
+		var out bytes.Buffer
+		require.NoError(t, r.Write(&out))
+		return loadPDF(t, out.Bytes())
+	}
+
+	// The outline is dropped by default.
+	require.Nil(t, redact(nil).GetOutlineTree())
+
+	outline, err := redact(&Options{KeepOutlines: true}).GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Items(), 1)
+	require.Equal(t, "Project Nightingale", outline.Items()[0].Title)
+}
+
+func TestRedactReplacementTexts(t *testing.T) {
+	font := model.NewStandard14FontMustCompile(model.HelveticaName)
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 400, Ury: 400}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	props := core.MakeDict()
+	props.Set("ActualText", core.MakeString("WXYZ"))
+	page.Resources.Properties = core.MakeDict()
+	page.Resources.Properties.(*core.PdfObjectDictionary).Set("P0", props)
+	require.NoError(t, page.AddContentStreamByString(strings.Join([]string{
+		"/Span <</ActualText (ABCD)>> BDC BT /F1 20 Tf 100 100 Td (ABCD) Tj ET EMC",
+		"/Span /P0 BDC BT /F1 20 Tf 100 200 Td (WXYZ) Tj ET EMC",
+	}, "\n")))
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader := loadPDF(t, buf.Bytes())
+	r := New(reader, &Options{NoFill: true})
+	require.NoError(t, r.AddArea(RedactionArea{PageNum: 1, Rect: model.PdfRectangle{Llx: 90, Lly: 90, Urx: 200, Ury: 230}}))
+	_, err := r.Apply()
+	require.NoError(t, err)
+
+	// The replacement texts of the inline and named property lists are removed.
+	redacted, err := reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := redacted.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, contents, "ABCD")
+	require.Contains(t, contents, "/P0 BDC")
+	props, ok := core.GetDict(redacted.Resources.Properties)
+	require.True(t, ok)
+	props, ok = core.GetDict(props.Get("P0"))
+	require.True(t, ok)
+	require.Nil(t, props.Get("ActualText"))
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out))
+	require.False(t, bytes.Contains(out.Bytes(), []byte("WXYZ")))
+}
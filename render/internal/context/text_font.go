@@ -63,6 +63,22 @@ func NewTextFont(font *model.PdfFont, size float64) (*TextFont, error) {
 	}, nil
 }
 
+// NewType3TextFont returns a new text font instance based on the specified
+// Type 3 PDF font and the specified font size. The glyphs of Type 3 fonts are
+// described by content streams, so the returned text font has no font face.
+func NewType3TextFont(font *model.PdfFont, size float64) *TextFont {
+	return &TextFont{
+		Font: font,
+		Size: size,
+	}
+}
+
+// IsType3 returns true if the text font is based on a Type 3 PDF font, the
+// glyphs of which must be drawn by executing their glyph procedures.
+func (tf *TextFont) IsType3() bool {
+	return tf != nil && tf.Face == nil && tf.Font != nil && tf.Font.Subtype() == "Type3"
+}
+
 // NewTextFontFromPath returns a new text font instance based on the specified
 // font file and the specified font size.
 func NewTextFontFromPath(filePath string, size float64) (*TextFont, error) {
@@ -34,11 +34,13 @@ func NewTextState() *TextState {
 }
 
 // ProcTm processes a `Tm` operation, which sets the current text matrix.
+// The matrix is stored in the flipped coordinate system used to draw strings,
+// where the y axis points down.
 //
 // See section 9.4.2 "Text Positioning Operators" and
 // Table 108 (pp. 257-258 PDF32000_2008).
 func (ts *TextState) ProcTm(a, b, c, d, e, f float64) {
-	ts.Tm = transform.NewMatrix(a, b, c, d, e, -f)
+	ts.Tm = transform.NewMatrix(a, -b, -c, d, e, -f)
 	ts.Tlm = ts.Tm.Clone()
 }
 
@@ -114,7 +116,7 @@ func (ts *TextState) ProcTj(data []byte, ctx Context) {
 		tx := (w + ts.Tc + tw) * th
 
 		// Generate new text matrix.
-		ts.Tm = transform.TranslationMatrix(tx, 0).Mult(tm)
+		ts.Tm = tm.Mult(transform.TranslationMatrix(tx, 0))
 	}
 }
 
@@ -148,9 +150,10 @@ func (ts *TextState) ProcTf(font *TextFont) {
 	ts.Tf = font
 }
 
-// Translate translates the current text matrix with `tx`,`ty`.
+// Translate translates the current text matrix with `tx`,`ty`, expressed in
+// text space units.
 func (ts *TextState) Translate(tx, ty float64) {
-	ts.Tm = transform.TranslationMatrix(tx, ty).Mult(ts.Tm)
+	ts.Tm.Concat(transform.TranslationMatrix(tx, ty))
 }
 
 // Reset resets both the text matrix and the line matrix.
@@ -25,6 +25,45 @@ var (
 )
 
 type renderer struct {
+	// type3 is the state of the Type 3 glyph procedures of the rendered page.
+	type3 *type3State
+}
+
+// type3State contains the glyph procedures of the Type 3 fonts of a page.
+type type3State struct {
+	// procs are the parsed glyph procedures, each procedure being parsed
+	// once, however many glyphs it draws.
+	procs map[*core.PdfObjectStream]contentstream.ContentStreamOperations
+
+	// active are the glyph procedures being executed. Glyph procedures may
+	// show text using the Type 3 font they belong to, which must not draw
+	// an active procedure again.
+	active map[*core.PdfObjectStream]bool
+}
+
+// newType3State returns a new state for the Type 3 glyph procedures.
+func newType3State() *type3State {
+	return &type3State{
+		procs:  map[*core.PdfObjectStream]contentstream.ContentStreamOperations{},
+		active: map[*core.PdfObjectStream]bool{},
+	}
+}
+
+// operations returns the parsed operations of the glyph procedure `proc`.
+func (s *type3State) operations(proc *core.PdfObjectStream) (contentstream.ContentStreamOperations, error) {
+	if ops, ok := s.procs[proc]; ok {
+		return ops, nil
+	}
+	content, err := core.DecodeStream(proc)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	s.procs[proc] = *operations
+	return *operations, nil
 }
 
 func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
@@ -32,6 +71,7 @@ func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
 	if err != nil {
 		return err
 	}
+	r.type3 = newType3State()
 
 	// Change coordinate system.
 	ctx.Translate(0, float64(ctx.Height()))
@@ -57,13 +97,17 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 		return err
 	}
 
+	return r.renderOperations(ctx, *operations, resources)
+}
+
+func (r renderer) renderOperations(ctx context.Context, operations contentstream.ContentStreamOperations, resources *model.PdfPageResources) error {
 	textState := ctx.TextState()
 	fontCache := map[string]*context.TextFont{}
 	fontFinder := sysfont.NewFinder(&sysfont.FinderOpts{
 		Extensions: []string{".ttf", ".ttc"},
 	})
 
-	processor := contentstream.NewContentStreamProcessor(*operations)
+	processor := contentstream.NewContentStreamProcessor(operations)
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
 			common.Log.Debug("Processing %s", op.Operand)
@@ -885,6 +929,10 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 				common.Log.Debug("' string: %s", string(charcodes))
 
+				if textState.Tf.IsType3() {
+					textState.ProcTStar()
+					return r.renderType3Text(ctx, textState, gs, charcodes, resources)
+				}
 				textState.ProcQ(charcodes, ctx)
 			// Move to the next line and show text string.
 			case `"`:
@@ -907,6 +955,11 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return errType
 				}
 
+				if textState.Tf.IsType3() {
+					textState.Tw, textState.Tc = aw, ac
+					textState.ProcTStar()
+					return r.renderType3Text(ctx, textState, gs, charcodes, resources)
+				}
 				textState.ProcDQ(charcodes, aw, ac, ctx)
 			// Show text string.
 			case "Tj":
@@ -920,6 +973,9 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 				common.Log.Debug("Tj string: `%s`", string(charcodes))
 
+				if textState.Tf.IsType3() {
+					return r.renderType3Text(ctx, textState, gs, charcodes, resources)
+				}
 				textState.ProcTj(charcodes, ctx)
 			// Show array of text strings.
 			case "TJ":
@@ -937,9 +993,16 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				for _, obj := range array.Elements() {
 					switch t := obj.(type) {
 					case *core.PdfObjectString:
-						if t != nil {
-							textState.ProcTj(t.Bytes(), ctx)
+						if t == nil {
+							continue
 						}
+						if textState.Tf.IsType3() {
+							if err := r.renderType3Text(ctx, textState, gs, t.Bytes(), resources); err != nil {
+								return err
+							}
+							continue
+						}
+						textState.ProcTj(t.Bytes(), ctx)
 					case *core.PdfObjectFloat, *core.PdfObjectInteger:
 						val, err := core.GetNumberAsFloat(t)
 						if err == nil {
@@ -990,6 +1053,12 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return err
 				}
 
+				// The glyphs of Type 3 fonts are drawn by their glyph procedures.
+				if pdfFont.Subtype() == "Type3" {
+					textState.ProcTf(context.NewType3TextFont(pdfFont, fontSize))
+					return nil
+				}
+
 				baseFont := pdfFont.BaseFont()
 				if baseFont == "" {
 					baseFont = fontName.String()
@@ -1062,10 +1131,151 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 			return nil
 		})
 
-	err = processor.Process(resources)
+	return processor.Process(resources)
+}
+
+// renderType3Text draws the text string `data` shown with the Type 3 font of
+// the text state `ts`, by executing the glyph procedures of its characters.
+// The graphics state `gs` provides the colors of uncolored glyphs. Glyphs whose
+// procedure is being executed, shown by glyph procedures using their own font,
+// are skipped.
+//
+// See section 9.6.5 "Type 3 Fonts" (pp. 258-262 PDF32000_2008).
+func (r renderer) renderType3Text(ctx context.Context, ts *context.TextState, gs contentstream.GraphicsState, data []byte, resources *model.PdfPageResources) error {
+	if r.type3 == nil {
+		r.type3 = newType3State()
+	}
+	font := ts.Tf.Font
+	fm := font.FontMatrix()
+	fontMatrix := transform.NewMatrix(fm[0], fm[1], fm[2], fm[3], fm[4], fm[5])
+
+	// The glyph procedures use the resources of the page if the font does
+	// not specify its own resources.
+	glyphResources := font.GetCharProcResources()
+	if glyphResources == nil {
+		glyphResources = resources
+	}
+
+	tfs := ts.Tf.Size
+	th := ts.Th / 100.0
+	stateMatrix := transform.NewMatrix(tfs*th, 0, 0, tfs, 0, ts.Ts)
+
+	for _, code := range font.BytesToCharcodes(data) {
+		// The text matrix of the text state is stored in the flipped
+		// coordinate system used to draw strings. Flip it back to obtain the
+		// glyph space to user space matrix: FontMatrix × Tstate × Tm.
+		tm := ts.Tm
+		glyphMatrix := transform.NewMatrix(tm[0], -tm[1], -tm[3], tm[4], tm[6], -tm[7])
+		glyphMatrix.Concat(stateMatrix)
+		glyphMatrix.Concat(fontMatrix)
+
+		if proc, ok := font.GetCharProc(code); ok && r.type3.active[proc] {
+			common.Log.Debug("ERROR: recursive Type 3 glyph procedure for code %d", code)
+		} else if ok {
+			// Glyph procedures may contain text objects, which must not
+			// affect the text state of the string being shown.
+			saved := *ts
+
+			ctx.Push()
+			ctx.SetMatrix(ctx.Matrix().Mult(glyphMatrix))
+			err := r.renderType3Glyph(ctx, proc, gs, glyphResources)
+			ctx.Pop()
+
+			*ts = saved
+			if err != nil {
+				return err
+			}
+		} else {
+			common.Log.Debug("ERROR: missing Type 3 glyph procedure for code %d", code)
+		}
+
+		// Calculate word spacing.
+		tw := 0.0
+		if code == ' ' {
+			tw = ts.Tw
+		}
+
+		// Calculate glyph spacing. The widths of Type 3 fonts are converted
+		// to thousandths of text space units using the font matrix.
+		var w float64
+		if metrics, ok := font.GetCharMetrics(code); ok {
+			w = metrics.Wx * 0.001 * tfs
+		}
+
+		// Generate new text matrix.
+		ts.Translate((w+ts.Tc+tw)*th, 0)
+	}
+
+	return nil
+}
+
+// renderType3Glyph draws the Type 3 glyph procedure `proc` in glyph space.
+// Glyph procedures start with a d0 operator, for glyphs which specify their
+// own color, or a d1 operator, for glyphs which are only a shape painted with
+// the current color. The color operators of the latter are ignored and the
+// colors of the graphics state `gs` are used instead.
+//
+// See section 9.6.5 "Type 3 Fonts" and Table 113 (p. 261 PDF32000_2008).
+func (r renderer) renderType3Glyph(ctx context.Context, proc *core.PdfObjectStream, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+	operations, err := r.type3.operations(proc)
 	if err != nil {
 		return err
 	}
+	r.type3.active[proc] = true
+	defer delete(r.type3.active, proc)
+
+	var ops contentstream.ContentStreamOperations
+	var uncolored bool
+	for i, op := range operations {
+		switch op.Operand {
+		case "d0", "d1":
+			if i != 0 {
+				common.Log.Debug("ERROR: %s operator must start the glyph procedure", op.Operand)
+			}
+			uncolored = op.Operand == "d1"
+			if uncolored {
+				ops = append(ops, colorOperations(gs)...)
+			}
+			continue
+		case "CS", "cs", "SC", "SCN", "sc", "scn", "G", "g", "RG", "rg", "K", "k":
+			if uncolored {
+				continue
+			}
+		}
+		ops = append(ops, op)
+	}
 
-	return nil
+	return r.renderOperations(ctx, ops, resources)
+}
+
+// colorOperations returns the operations setting the stroking and nonstroking
+// colors of the graphics state `gs`, as RGB colors.
+func colorOperations(gs contentstream.GraphicsState) contentstream.ContentStreamOperations {
+	var ops contentstream.ContentStreamOperations
+	add := func(operand string, cs model.PdfColorspace, color model.PdfColor) {
+		if cs == nil || color == nil {
+			return
+		}
+		color, err := cs.ColorToRGB(color)
+		if err != nil {
+			common.Log.Debug("Error converting color: %v", err)
+			return
+		}
+		rgbColor, ok := color.(*model.PdfColorDeviceRGB)
+		if !ok {
+			common.Log.Debug("Error converting color: %v", color)
+			return
+		}
+		ops = append(ops, &contentstream.ContentStreamOperation{
+			Operand: operand,
+			Params: []core.PdfObject{
+				core.MakeFloat(rgbColor.R()),
+				core.MakeFloat(rgbColor.G()),
+				core.MakeFloat(rgbColor.B()),
+			},
+		})
+	}
+	add("RG", gs.ColorspaceStroking, gs.ColorStroking)
+	add("rg", gs.ColorspaceNonStroking, gs.ColorNonStroking)
+	return ops
 }
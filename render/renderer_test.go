@@ -0,0 +1,62 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loxiouve/unipdf/v3/core"
+	"github.com/loxiouve/unipdf/v3/model"
+)
+
+// isDark returns true if the pixel of `img` at the point (`x`,`y`) of the
+// page of height `height` is dark.
+func isDark(img image.Image, height, x, y int) bool {
+	gray := color.GrayModel.Convert(img.At(x, height-y)).(color.Gray)
+	return gray.Y < 128
+}
+
+func TestRenderType3TextRotated(t *testing.T) {
+	// The glyph is a bar along the x axis of the glyph space.
+	glyph, err := core.MakeStream([]byte("1000 0 0 0 750 250 d1 0 0 750 250 re f"), nil)
+	require.NoError(t, err)
+	charProcs := core.MakeDict()
+	charProcs.Set("A", glyph)
+	encoding := core.MakeDict()
+	encoding.Set("Differences", core.MakeArray(core.MakeInteger(65), core.MakeName("A"), core.MakeName("A")))
+
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("Type3"))
+	font.Set("FontBBox", core.MakeArrayFromIntegers([]int{0, 0, 750, 250}))
+	font.Set("FontMatrix", core.MakeArrayFromFloats([]float64{0.001, 0, 0, 0.001, 0, 0}))
+	font.Set("CharProcs", charProcs)
+	font.Set("Encoding", encoding)
+	font.Set("FirstChar", core.MakeInteger(65))
+	font.Set("LastChar", core.MakeInteger(66))
+	font.Set("Widths", core.MakeArrayFromIntegers([]int{1000, 1000}))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 200}
+	require.NoError(t, page.Resources.SetFontByName("F1", font))
+	require.NoError(t, page.AddContentStreamByString("BT 0 1 -1 0 150 20 Tm /F1 40 Tf (AB) Tj ET"))
+
+	img, err := NewImageDevice().Render(page)
+	require.NoError(t, err)
+
+	// The text is rotated by 90 degrees: the glyphs are vertical bars
+	// spanning 140 to 150 horizontally, advancing upwards from 20.
+	require.True(t, isDark(img, 200, 145, 35))
+	require.True(t, isDark(img, 200, 145, 75))
+	require.False(t, isDark(img, 200, 145, 55))
+	require.False(t, isDark(img, 200, 155, 35))
+	require.False(t, isDark(img, 200, 170, 25))
+	require.False(t, isDark(img, 200, 145, 10))
+}